@@ -0,0 +1,217 @@
+// Package calendar does date arithmetic on working calendars: which days are
+// worked, how much working time lies between two moments, and adding working
+// days or working minutes to a moment. It also reads holidays from
+// iCalendar (RFC 5545) files.
+//
+// Days are taken in the calendar's timezone. Dates that stand for a whole
+// day, such as holidays and date-only due dates, are midnight UTC of that
+// day.
+package calendar
+
+import (
+	"fmt"
+	"strconv"
+	"time"
+)
+
+// maxDays bounds the days searched for working time, so a calendar whose
+// days are all holidays cannot loop forever
+const maxDays = 10 * 366
+
+// Calendar is a working week with daily working hours and holidays, in a
+// timezone
+type Calendar struct {
+	Location    *time.Location
+	WorkingDays [7]bool // By time.Weekday
+	DayStart    int     // Minutes after midnight at which working hours start
+	DayEnd      int     // Minutes after midnight at which they end
+	holidays    map[time.Time]bool
+}
+
+// New returns a calendar working the given weekdays (0 for Sunday) from
+// dayStart to dayEnd minutes after midnight, except on holidays
+func New(location *time.Location, workingDays []int, dayStart, dayEnd int, holidays []time.Time) *Calendar {
+	c := &Calendar{Location: location, DayStart: dayStart, DayEnd: dayEnd, holidays: map[time.Time]bool{}}
+	for _, day := range workingDays {
+		if day >= 0 && day <= 6 {
+			c.WorkingDays[day] = true
+		}
+	}
+	for _, holiday := range holidays {
+		c.holidays[Date(holiday.UTC())] = true
+	}
+	return c
+}
+
+// Default is the calendar of projects that have none: Monday to Friday,
+// 09:00 to 17:00 UTC
+func Default() *Calendar {
+	return New(time.UTC, []int{1, 2, 3, 4, 5}, 9*60, 17*60, nil)
+}
+
+// Date returns midnight UTC of the day of t, as written in t's location
+func Date(t time.Time) time.Time {
+	return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC)
+}
+
+// ParseClock reads a time of day such as "09:30" as minutes after midnight;
+// "24:00" stands for the end of the day
+func ParseClock(text string) (int, error) {
+	invalid := fmt.Errorf("invalid time of day %q, expected HH:MM", text)
+	if len(text) != 5 || text[2] != ':' {
+		return 0, invalid
+	}
+	hours, err := strconv.Atoi(text[:2])
+	if err != nil {
+		return 0, invalid
+	}
+	minutes, err := strconv.Atoi(text[3:])
+	if err != nil || hours < 0 || minutes < 0 || minutes > 59 || hours*60+minutes > 24*60 {
+		return 0, invalid
+	}
+	return hours*60 + minutes, nil
+}
+
+// midnight returns the start of the day of t in the calendar's timezone
+func (c *Calendar) midnight(t time.Time) time.Time {
+	t = t.In(c.Location)
+	return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, c.Location)
+}
+
+// at returns the moment minutes after the start of day, which is midnight
+func (c *Calendar) at(day time.Time, minutes int) time.Time {
+	return time.Date(day.Year(), day.Month(), day.Day(), 0, minutes, 0, 0, c.Location)
+}
+
+// IsWorkingDay reports whether the day of t is worked
+func (c *Calendar) IsWorkingDay(t time.Time) bool {
+	t = t.In(c.Location)
+	return c.WorkingDays[t.Weekday()] && !c.holidays[Date(t)]
+}
+
+// IsHoliday reports whether the day of t is a holiday
+func (c *Calendar) IsHoliday(t time.Time) bool {
+	return c.holidays[Date(t.In(c.Location))]
+}
+
+// WorkingDaysIn counts the working days from the day of from to the day of
+// to, both included
+func (c *Calendar) WorkingDaysIn(from, to time.Time) int {
+	days := 0
+	last := c.midnight(to)
+	for day := c.midnight(from); !day.After(last); day = day.AddDate(0, 0, 1) {
+		if c.IsWorkingDay(day) {
+			days++
+		}
+	}
+	return days
+}
+
+// DaysBetween counts the working days to add to from to reach the day of
+// to: the working days after from up to to, or minus the working days from
+// to up to before from when to comes first
+func (c *Calendar) DaysBetween(from, to time.Time) int {
+	first, last := c.midnight(from), c.midnight(to)
+	switch {
+	case last.After(first):
+		return c.WorkingDaysIn(first.AddDate(0, 0, 1), last)
+	case last.Before(first):
+		return -c.WorkingDaysIn(last, first.AddDate(0, 0, -1))
+	}
+	return 0
+}
+
+// AddWorkingDays moves t forward by days working days, or back when days is
+// negative, keeping its time of day. Each step lands on a working day, so
+// adding one working day to a Saturday gives the following Monday.
+func (c *Calendar) AddWorkingDays(t time.Time, days int) time.Time {
+	step := 1
+	if days < 0 {
+		step, days = -1, -days
+	}
+	t = t.In(c.Location)
+	for i := 0; days > 0 && i < maxDays; i++ {
+		t = t.AddDate(0, 0, step)
+		if c.IsWorkingDay(t) {
+			days--
+		}
+	}
+	return t
+}
+
+// NextWorkingDay returns t if its day is worked, or the same time of day on
+// the next working day
+func (c *Calendar) NextWorkingDay(t time.Time) time.Time {
+	if c.IsWorkingDay(t) {
+		return t
+	}
+	return c.AddWorkingDays(t, 1)
+}
+
+// WorkingMinutes counts the minutes of working hours between from and to
+func (c *Calendar) WorkingMinutes(from, to time.Time) int {
+	if !to.After(from) {
+		return 0
+	}
+	total := time.Duration(0)
+	last := c.midnight(to)
+	for day := c.midnight(from); !day.After(last); day = day.AddDate(0, 0, 1) {
+		if !c.IsWorkingDay(day) {
+			continue
+		}
+		start, end := c.at(day, c.DayStart), c.at(day, c.DayEnd)
+		if start.Before(from) {
+			start = from
+		}
+		if end.After(to) {
+			end = to
+		}
+		if end.After(start) {
+			total += end.Sub(start)
+		}
+	}
+	return int(total / time.Minute)
+}
+
+// AddWorkingMinutes returns the moment at which minutes of working hours
+// have passed since t. Time outside working hours does not count, so
+// counting from a weekend starts on the next working day.
+func (c *Calendar) AddWorkingMinutes(t time.Time, minutes int) time.Time {
+	if minutes <= 0 {
+		return t
+	}
+	left := time.Duration(minutes) * time.Minute
+	day := c.midnight(t)
+	for i := 0; i < maxDays; i, day = i+1, day.AddDate(0, 0, 1) {
+		if !c.IsWorkingDay(day) {
+			continue
+		}
+		start, end := c.at(day, c.DayStart), c.at(day, c.DayEnd)
+		if start.Before(t) {
+			start = t
+		}
+		if !end.After(start) {
+			continue
+		}
+		if worked := end.Sub(start); left > worked {
+			left -= worked
+			continue
+		}
+		return start.Add(left)
+	}
+	return day
+}
+
+// OnDate returns noon of a whole-day date, given as midnight UTC, in the
+// calendar's timezone, for arithmetic on the day it stands for
+func (c *Calendar) OnDate(date time.Time) time.Time {
+	date = date.UTC()
+	return time.Date(date.Year(), date.Month(), date.Day(), 12, 0, 0, 0, c.Location)
+}
+
+// EndOfDate returns the moment a whole-day date, given as midnight UTC,
+// ends in the calendar's timezone
+func (c *Calendar) EndOfDate(date time.Time) time.Time {
+	date = date.UTC()
+	return time.Date(date.Year(), date.Month(), date.Day()+1, 0, 0, 0, 0, c.Location)
+}
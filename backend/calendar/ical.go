@@ -0,0 +1,159 @@
+package calendar
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"time"
+)
+
+// maxEventDays bounds the days a single event can cover
+const maxEventDays = 366
+
+// Holiday is a day off read from an iCalendar file
+type Holiday struct {
+	Date time.Time // Midnight UTC of the day
+	Name string
+}
+
+// ParseHolidays reads the events (VEVENT) of an iCalendar file as holidays,
+// one for each day an event covers, sorted by date. All-day events cover
+// the days from DTSTART up to before DTEND; events with a time of day count
+// for the day they start on, as written. Recurrence rules are not expanded,
+// so a recurring event only gives its first occurrence. When several events
+// fall on the same day the first one names it.
+func ParseHolidays(r io.Reader) ([]Holiday, error) {
+	lines, err := unfold(r)
+	if err != nil {
+		return nil, err
+	}
+	seen := map[time.Time]bool{}
+	var holidays []Holiday
+	var current *event
+	inCalendar := false
+	for number, line := range lines {
+		name, params, value, ok := splitProperty(line)
+		if !ok {
+			return nil, fmt.Errorf("line %d: invalid content line", number+1)
+		}
+		switch {
+		case name == "BEGIN" && strings.EqualFold(value, "VCALENDAR"):
+			inCalendar = true
+		case name == "BEGIN" && strings.EqualFold(value, "VEVENT"):
+			current = &event{}
+		case name == "END" && strings.EqualFold(value, "VEVENT"):
+			if current == nil {
+				return nil, fmt.Errorf("line %d: END:VEVENT without BEGIN:VEVENT", number+1)
+			}
+			days, err := current.days()
+			if err != nil {
+				return nil, fmt.Errorf("line %d: %w", number+1, err)
+			}
+			for _, day := range days {
+				if !seen[day] {
+					seen[day] = true
+					holidays = append(holidays, Holiday{Date: day, Name: unescape(current.summary)})
+				}
+			}
+			current = nil
+		case current != nil && name == "DTSTART":
+			current.start = value
+			current.allDay = len(value) == 8 || strings.Contains(strings.ToUpper(params), "VALUE=DATE;") ||
+				strings.HasSuffix(strings.ToUpper(params), "VALUE=DATE")
+		case current != nil && name == "DTEND":
+			current.end = value
+		case current != nil && name == "SUMMARY":
+			current.summary = value
+		}
+	}
+	if !inCalendar {
+		return nil, fmt.Errorf("not an iCalendar file: BEGIN:VCALENDAR is missing")
+	}
+	sort.Slice(holidays, func(i, j int) bool { return holidays[i].Date.Before(holidays[j].Date) })
+	return holidays, nil
+}
+
+// unfold reads the content lines of a file, joining the lines folded onto
+// the next ones and dropping empty lines
+func unfold(r io.Reader) ([]string, error) {
+	var lines []string
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		line := strings.TrimRight(scanner.Text(), "\r")
+		if (strings.HasPrefix(line, " ") || strings.HasPrefix(line, "\t")) && len(lines) > 0 {
+			lines[len(lines)-1] += line[1:]
+			continue
+		}
+		if strings.TrimSpace(line) != "" {
+			lines = append(lines, line)
+		}
+	}
+	return lines, scanner.Err()
+}
+
+// splitProperty splits a content line such as
+// "DTSTART;VALUE=DATE:20261225" into its upper-cased name, its parameters
+// and its value
+func splitProperty(line string) (string, string, string, bool) {
+	colon := strings.Index(line, ":")
+	if colon < 0 {
+		return "", "", "", false
+	}
+	name, value := line[:colon], line[colon+1:]
+	params := ""
+	if semicolon := strings.Index(name, ";"); semicolon >= 0 {
+		name, params = name[:semicolon], name[semicolon+1:]
+	}
+	return strings.ToUpper(strings.TrimSpace(name)), params, value, true
+}
+
+// parseDay reads the day of a DATE (20261225) or DATE-TIME
+// (20261225T090000Z) value, as written
+func parseDay(value string) (time.Time, error) {
+	if len(value) < 8 {
+		return time.Time{}, fmt.Errorf("invalid date %q", value)
+	}
+	day, err := time.Parse("20060102", value[:8])
+	if err != nil {
+		return time.Time{}, fmt.Errorf("invalid date %q", value)
+	}
+	return day, nil
+}
+
+// event holds the properties of a VEVENT that make a holiday
+type event struct {
+	start, end string
+	allDay     bool
+	summary    string
+}
+
+// days lists the days an event covers
+func (e *event) days() ([]time.Time, error) {
+	if e.start == "" {
+		return nil, fmt.Errorf("event without DTSTART")
+	}
+	start, err := parseDay(e.start)
+	if err != nil {
+		return nil, err
+	}
+	if !e.allDay || e.end == "" {
+		return []time.Time{start}, nil
+	}
+	end, err := parseDay(e.end)
+	if err != nil {
+		return nil, err
+	}
+	days := []time.Time{start}
+	for day := start.AddDate(0, 0, 1); day.Before(end) && len(days) < maxEventDays; day = day.AddDate(0, 0, 1) {
+		days = append(days, day)
+	}
+	return days, nil
+}
+
+// unescape undoes the escaping of iCalendar text values
+func unescape(text string) string {
+	return strings.TrimSpace(strings.NewReplacer(`\n`, " ", `\N`, " ", `\,`, ",", `\;`, ";", `\\`, `\`).Replace(text))
+}
@@ -10,17 +10,137 @@ import (
 // Connect establishes a connection to the database and returns a *gorm.DB instance.
 func Connect() *gorm.DB {
 	dsn := "host=localhost port=5432 user=admin password=1234 dbname=task_manager sslmode=disable"
-	db, err := gorm.Open(postgres.Open(dsn), &gorm.Config{})
+	db, err := gorm.Open(postgres.Open(dsn), &gorm.Config{TranslateError: true})
 	if err != nil {
 		panic("Failed to connect to database: " + err.Error())
 	}
 
 	// Run database migrations
-	err = db.AutoMigrate(&models.User{}, &models.Task{}, &models.Project{}, &models.UserRole{})
+	err = db.AutoMigrate(
+		&models.User{},
+		&models.Task{},
+		&models.Project{},
+		&models.UserRole{},
+		&models.Label{},
+		&models.WorkLog{},
+		&models.Timer{},
+		&models.CustomField{},
+		&models.CustomFieldValue{},
+		&models.Activity{},
+		&models.Comment{},
+		&models.Notification{},
+		&models.NotificationPreference{},
+		&models.DigestSubscription{},
+		&models.TaskReminder{},
+		&models.ReminderPolicy{},
+		&models.Recurrence{},
+		&models.WorkflowStatus{},
+		&models.ProjectTemplate{},
+		&models.TaskTemplate{},
+		&models.AuditLog{},
+		&models.Sprint{},
+		&models.SprintChange{},
+		&models.Milestone{},
+		&models.Epic{},
+		&models.TaskDependency{},
+		&models.UserCapacity{},
+		&models.TimeOff{},
+		&models.WorkCalendar{},
+		&models.Holiday{},
+		&models.SLAPolicy{},
+		&models.SLAClock{},
+		&models.AutomationRule{},
+		&models.AutomationRun{},
+		&models.View{},
+		&models.Webhook{},
+		&models.WebhookDelivery{},
+		&models.Job{},
+		&models.OutboxEvent{},
+	)
 	if err != nil {
 		panic("Failed to migrate database: " + err.Error())
 	}
 
+	// Tasks created before multiple assignees only record theirs in user_id
+	err = db.Exec(`
+        INSERT INTO task_assignees (task_id, user_id)
+        SELECT t.id, t.user_id FROM tasks t JOIN users u ON u.id = t.user_id
+        ON CONFLICT DO NOTHING
+    `).Error
+	if err != nil {
+		panic("Failed to backfill task assignees: " + err.Error())
+	}
+
+	// Preferences saved before email notifications left email switched on
+	err = db.Exec("UPDATE notification_preferences SET email = TRUE WHERE email IS NULL").Error
+	if err != nil {
+		panic("Failed to backfill notification preferences: " + err.Error())
+	}
+
+	// Reminders used to fire once per due date, a day before it
+	err = db.Exec("DROP INDEX IF EXISTS idx_task_reminder").Error
+	if err == nil {
+		err = db.Exec("UPDATE task_reminders SET offset_minutes = 1440 WHERE offset_minutes IS NULL").Error
+	}
+	if err != nil {
+		panic("Failed to backfill task reminders: " + err.Error())
+	}
+
+	// Tasks finished before completion times were recorded count as completed
+	// when they were last updated
+	err = db.Exec(`
+        UPDATE tasks SET completed_at = updated_at
+        WHERE completed_at IS NULL AND (
+            EXISTS (
+                SELECT 1 FROM workflow_statuses ws
+                WHERE ws.project_id = tasks.project_id AND ws.name = tasks.status AND ws.category = ?
+            ) OR (
+                NOT EXISTS (SELECT 1 FROM workflow_statuses ws WHERE ws.project_id = tasks.project_id)
+                AND tasks.status IN ?
+            )
+        )
+    `, models.CategoryDone, []string{models.StatusCompleted, "Done"}).Error
+	if err != nil {
+		panic("Failed to backfill task completion times: " + err.Error())
+	}
+
+	// Label names used to be unique only with the same case; labels whose
+	// name clashes with an older label's get their ID appended
+	err = db.Exec(`
+        UPDATE labels SET name = labels.name || ' (' || labels.id || ')'
+        WHERE EXISTS (
+            SELECT 1 FROM labels older
+            WHERE older.project_id = labels.project_id AND LOWER(older.name) = LOWER(labels.name) AND older.id < labels.id
+        )
+    `).Error
+	if err == nil {
+		err = db.Exec("DROP INDEX IF EXISTS idx_label_project_name").Error
+	}
+	if err == nil {
+		err = db.Exec("CREATE UNIQUE INDEX IF NOT EXISTS idx_label_project_lower_name ON labels (project_id, LOWER(name))").Error
+	}
+	if err != nil {
+		panic("Failed to migrate label names: " + err.Error())
+	}
+
+	// Only successful webhook responses are kept, as the others may come from
+	// internal services reached before delivery addresses were checked
+	err = db.Exec("UPDATE webhook_deliveries SET response_body = '' WHERE response_code < 200 OR response_code >= 300").Error
+	if err != nil {
+		panic("Failed to clear webhook responses: " + err.Error())
+	}
+
+	// Tasks created before board ordering are ranked in creation order
+	err = db.Exec(`
+        UPDATE tasks SET rank = r.rank FROM (
+            SELECT id, lpad(to_hex(ROW_NUMBER() OVER (PARTITION BY project_id ORDER BY id)), 8, '0') || 'i' AS rank
+            FROM tasks WHERE rank IS NULL OR rank = ''
+        ) r WHERE tasks.id = r.id
+    `).Error
+	if err != nil {
+		panic("Failed to backfill task ranks: " + err.Error())
+	}
+
 	return db
 }
 
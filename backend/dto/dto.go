@@ -1,11 +1,43 @@
 package dto
 
-// CreateTaskInput for creating a task
-type CreateTaskInput struct {
-	Title       string `json:"title" binding:"required"`
-	Description string `json:"description"`
-	ProjectID   uint   `json:"project_id" binding:"required"`
-	UserID      uint   `json:"user_id"`
+import (
+	"time"
+
+	"work-management/models"
+)
+
+// TaskInput for creating or updating a task
+type TaskInput struct {
+	Title       string    `json:"title" binding:"required"`
+	Description string    `json:"description"`
+	ProjectID   uint      `json:"project_id" binding:"required"`
+	UserID      uint      `json:"user_id" binding:"required"`
+	Status      string    `json:"status" binding:"required"`
+	DueDate     time.Time `json:"due_date" binding:"required"`
+	// Priority, StoryPoints and the estimates keep their value when omitted
+	// on update. On create Priority defaults to medium and the rest to 0.
+	Priority    *string `json:"priority"`
+	StoryPoints *int    `json:"story_points"`
+	// Estimates are in minutes. RemainingEstimate defaults to
+	// OriginalEstimate when omitted on create.
+	OriginalEstimate  *int `json:"original_estimate"`
+	RemainingEstimate *int `json:"remaining_estimate"`
+	// AssigneeIDs lists additional assignees besides UserID, who is the
+	// primary assignee. On update a nil list leaves the other assignees
+	// untouched, and a new UserID replaces the old primary assignee.
+	AssigneeIDs []uint `json:"assignee_ids"`
+	// CustomFields maps custom field IDs to values. On update a nil map
+	// leaves values untouched and a null value clears the field.
+	CustomFields map[uint]interface{} `json:"custom_fields"`
+	// ParentID makes the task a subtask of another task of the project. On
+	// update nil keeps the parent and 0 makes the task a top-level task.
+	ParentID *uint `json:"parent_id"`
+	// StartDate plans when work on the task starts, no later than DueDate.
+	// On update nil keeps the start date.
+	StartDate *time.Time `json:"start_date"`
+	// DueDateOnly makes the task due on the day of DueDate, as written,
+	// until that day ends in the project's timezone
+	DueDateOnly bool `json:"due_date_only"`
 }
 
 // CreateProjectInput for creating a project
@@ -23,3 +55,514 @@ type AddUserToProjectInput struct {
 type UpdateUserRoleInput struct {
 	Role string `json:"role" binding:"required"`
 }
+
+// TaskFilter narrows and orders task listings. Zero values mean "no filter".
+type TaskFilter struct {
+	// LabelIDs keeps tasks carrying at least one of the given labels
+	LabelIDs []uint
+	// Priorities keeps tasks with any of the given priorities
+	Priorities []string
+	// MinStoryPoints and MaxStoryPoints bound story points when non-nil
+	MinStoryPoints *int
+	MaxStoryPoints *int
+	// AssigneeID keeps tasks assigned to the user
+	AssigneeID uint
+	// InvolvedUserID keeps tasks assigned to or watched by the user
+	InvolvedUserID uint
+	// VisibleToUserID keeps tasks of projects the user holds a role in
+	VisibleToUserID uint
+	// Overdue keeps open tasks whose due date has passed
+	Overdue bool
+	// SLABreached keeps tasks with an SLA clock over its target
+	SLABreached bool
+	// SprintID keeps the tasks planned into the sprint
+	SprintID uint
+	// MilestoneID keeps the tasks of the milestone
+	MilestoneID uint
+	// EpicID keeps the tasks of the epic
+	EpicID uint
+	// Backlog keeps open tasks that are not planned into a sprint
+	Backlog bool
+	// CustomFields keeps tasks whose custom field values match every filter
+	CustomFields []CustomFieldFilter
+	// Sort is a field name, or "cf.<field id>" for a custom field, prefixed
+	// with "-" for descending order
+	Sort string
+	// SortFieldType is the type of the custom field named by Sort, if any
+	SortFieldType string
+}
+
+// CustomFieldFilter compares one custom field of a task with a value
+type CustomFieldFilter struct {
+	FieldID uint
+	Type    string // Field type, resolved by the service
+	Op      string // "eq", "gte" or "lte"
+	Value   interface{}
+}
+
+// NotificationPage is one page of a user's inbox
+type NotificationPage struct {
+	Notifications []models.Notification `json:"notifications"`
+	Total         int64                 `json:"total"`
+	Unread        int64                 `json:"unread"`
+	Page          int                   `json:"page"`
+	PageSize      int                   `json:"page_size"`
+}
+
+// NotificationPreferenceInput changes the channels of one notification type;
+// nil leaves a channel unchanged
+type NotificationPreferenceInput struct {
+	EventType string `json:"event_type" binding:"required"`
+	InApp     *bool  `json:"in_app"`
+	Email     *bool  `json:"email"`
+}
+
+// ReminderPolicyInput replaces a project's reminder offsets, in minutes
+// before the due date; an empty list turns reminders off. EscalateAfter and
+// WorkingTime are left unchanged when nil.
+type ReminderPolicyInput struct {
+	Offsets       []int `json:"offsets"`
+	EscalateAfter *int  `json:"escalate_after"`
+	WorkingTime   *bool `json:"working_time"`
+}
+
+// SLAPolicyInput replaces a project's SLA policy. Targets are in minutes
+// per priority, 0 for none; tasks in a paused status stop the clocks.
+type SLAPolicyInput struct {
+	Targets        []models.SLATarget `json:"targets"`
+	PausedStatuses []string           `json:"paused_statuses"`
+	WorkingTime    bool               `json:"working_time"`
+}
+
+// AutomationRuleInput creates or replaces an automation rule; Enabled is
+// left unchanged when nil, and new rules start enabled
+type AutomationRuleInput struct {
+	Name       string                       `json:"name" binding:"required"`
+	Enabled    *bool                        `json:"enabled"`
+	Trigger    string                       `json:"trigger" binding:"required"`
+	Conditions []models.AutomationCondition `json:"conditions"`
+	Actions    []models.AutomationAction    `json:"actions"`
+}
+
+// WorkflowInput replaces a project's workflow; statuses are listed in board
+// order
+type WorkflowInput struct {
+	Statuses []WorkflowStatusInput `json:"statuses"`
+}
+
+type WorkflowStatusInput struct {
+	Name     string `json:"name"`
+	Category string `json:"category"`
+	// WIPLimit is the most tasks the status should hold, 0 for no limit;
+	// WIPPolicy is "warn" (the default) or "reject"
+	WIPLimit  int    `json:"wip_limit"`
+	WIPPolicy string `json:"wip_policy"`
+	// RenamedFrom moves the tasks of an existing status to this one
+	RenamedFrom string `json:"renamed_from"`
+}
+
+// RecurrenceInput makes a task recurring or edits its series. Rule is
+// required for a new series. Template fields left nil keep their value.
+type RecurrenceInput struct {
+	Rule        string  `json:"rule"`
+	Title       *string `json:"title"`
+	Description *string `json:"description"`
+	LabelIDs    *[]uint `json:"label_ids"`
+	AssigneeIDs *[]uint `json:"assignee_ids"`
+	// ApplyToOpen also applies the template changes to the open occurrences
+	// of the series; otherwise only occurrences generated later get them
+	ApplyToOpen bool `json:"apply_to_open"`
+}
+
+// ProjectTemplateInput creates or replaces a project template
+type ProjectTemplateInput struct {
+	Name         string                  `json:"name" binding:"required"`
+	Description  string                  `json:"description"`
+	Category     string                  `json:"category"`
+	Workflow     []models.TemplateStatus `json:"workflow"`
+	Labels       []models.TemplateLabel  `json:"labels"`
+	CustomFields []models.TemplateField  `json:"custom_fields"`
+	Tasks        []models.TemplateTask   `json:"tasks"`
+	Members      []models.TemplateMember `json:"members"`
+}
+
+// SaveAsTemplateInput saves a project or a task as a template
+type SaveAsTemplateInput struct {
+	Name        string `json:"name" binding:"required"`
+	Description string `json:"description"`
+	// IncludeMembers keeps the project's members and roles in the template
+	IncludeMembers bool `json:"include_members"`
+}
+
+// FromTemplateInput creates a project, or tasks, from a template. Due dates
+// are counted from StartDate, today when omitted.
+type FromTemplateInput struct {
+	Name        string     `json:"name"` // Project name, required for projects
+	Description string     `json:"description"`
+	StartDate   *time.Time `json:"start_date"`
+	ParentID    uint       `json:"parent_id"` // Adds the task of a task template as a subtask
+}
+
+// TaskTemplateInput creates a task template
+type TaskTemplateInput struct {
+	Name string              `json:"name" binding:"required"`
+	Task models.TemplateTask `json:"task"`
+}
+
+// CloneProjectInput copies a project with its settings and workflow. Include
+// lists what else is copied: "tasks", "subtasks", "labels", "custom_fields",
+// "assignees", "watchers" and "members"; nil copies tasks, subtasks, labels
+// and custom fields.
+type CloneProjectInput struct {
+	Name    string   `json:"name" binding:"required"`
+	Include []string `json:"include"`
+}
+
+// CloneTaskInput copies a task into its own project or, with ProjectID, into
+// another one. Include lists what is copied along: "subtasks", "labels",
+// "custom_fields", "assignees" and "watchers"; nil copies all but watchers.
+type CloneTaskInput struct {
+	ProjectID uint     `json:"project_id"`
+	Title     string   `json:"title"` // Defaults to the title of the task
+	Include   []string `json:"include"`
+}
+
+// SprintInput creates a sprint or updates its details
+type SprintInput struct {
+	Name      string    `json:"name" binding:"required"`
+	Goal      string    `json:"goal"`
+	StartDate time.Time `json:"start_date" binding:"required"`
+	EndDate   time.Time `json:"end_date" binding:"required"`
+}
+
+// SprintTasksInput plans tasks of the sprint's project into a sprint
+type SprintTasksInput struct {
+	TaskIDs []uint `json:"task_ids" binding:"required"`
+}
+
+// CloseSprintInput closes a sprint. Unfinished tasks move to the CarryOverTo
+// sprint, a planned sprint of the project; 0 moves them to the backlog and
+// nil to the next planned sprint, or the backlog if there is none.
+type CloseSprintInput struct {
+	CarryOverTo *uint `json:"carry_over_to"`
+}
+
+// SprintReport compares what a sprint committed to with what it completed.
+// Points are story points; for an active sprint the completed and remaining
+// totals are as of now.
+type SprintReport struct {
+	Sprint          models.Sprint         `json:"sprint"`
+	CommittedPoints int                   `json:"committed_points"`
+	CommittedTasks  int                   `json:"committed_tasks"`
+	AddedPoints     int                   `json:"added_points"`   // Tasks added after the start
+	RemovedPoints   int                   `json:"removed_points"` // Tasks removed after the start
+	EstimateChange  int                   `json:"estimate_change"`
+	ScopeChange     int                   `json:"scope_change"` // Net change of all the above
+	CompletedPoints int                   `json:"completed_points"`
+	CompletedTasks  int                   `json:"completed_tasks"`
+	RemainingPoints int                   `json:"remaining_points"`
+	RemainingTasks  int                   `json:"remaining_tasks"`
+	Changes         []models.SprintChange `json:"changes"`
+	Burndown        []BurndownPoint       `json:"burndown"`
+}
+
+// BurndownPoint gives the story points left at the end of a sprint day,
+// along with the ideal linear burndown
+type BurndownPoint struct {
+	Date      string  `json:"date"`
+	Remaining int     `json:"remaining"`
+	Ideal     float64 `json:"ideal"`
+}
+
+// MilestoneInput creates a milestone or updates its details
+type MilestoneInput struct {
+	Name        string    `json:"name" binding:"required"`
+	Description string    `json:"description"`
+	TargetDate  time.Time `json:"target_date" binding:"required"`
+}
+
+// MilestoneTasksInput associates tasks of the milestone's project with a
+// milestone
+type MilestoneTasksInput struct {
+	TaskIDs []uint `json:"task_ids" binding:"required"`
+}
+
+// MilestoneProgress sums up the tasks of a milestone by status category.
+// Health is "completed", "on_track", "at_risk" when the remaining estimates
+// exceed the working time left before the target date, or "overdue".
+type MilestoneProgress struct {
+	Milestone        models.Milestone `json:"milestone"`
+	TotalTasks       int              `json:"total_tasks"`
+	ToDoTasks        int              `json:"todo_tasks"`
+	InProgressTasks  int              `json:"in_progress_tasks"`
+	DoneTasks        int              `json:"done_tasks"`
+	TotalPoints      int              `json:"total_points"`
+	DonePoints       int              `json:"done_points"`
+	Percent          float64          `json:"percent"` // Of story points, or of tasks when none are estimated
+	RemainingMinutes int              `json:"remaining_minutes"`
+	AvailableMinutes int              `json:"available_minutes"` // Working time left before the target date
+	Health           string           `json:"health"`
+}
+
+// ReleaseNotes lists the completed tasks of a milestone grouped by label;
+// a task appears under each of its labels
+type ReleaseNotes struct {
+	Milestone models.Milestone    `json:"milestone"`
+	Groups    []ReleaseNotesGroup `json:"groups"`
+}
+
+type ReleaseNotesGroup struct {
+	Label string        `json:"label"` // Empty for tasks without labels
+	Color string        `json:"color"`
+	Tasks []models.Task `json:"tasks"`
+}
+
+// EpicInput creates an epic or updates its details
+type EpicInput struct {
+	Name        string     `json:"name" binding:"required"`
+	Description string     `json:"description"`
+	StartDate   *time.Time `json:"start_date"`
+	TargetDate  *time.Time `json:"target_date"`
+}
+
+// EpicTasksInput adds tasks, possibly of several projects, to an epic
+type EpicTasksInput struct {
+	TaskIDs []uint `json:"task_ids" binding:"required"`
+}
+
+// EpicRollup sums up the tasks of an epic that the caller can see, overall
+// and per project. Estimates are in minutes.
+type EpicRollup struct {
+	Epic              models.Epic         `json:"epic"`
+	TotalTasks        int                 `json:"total_tasks"`
+	ToDoTasks         int                 `json:"todo_tasks"`
+	InProgressTasks   int                 `json:"in_progress_tasks"`
+	DoneTasks         int                 `json:"done_tasks"`
+	TotalPoints       int                 `json:"total_points"`
+	DonePoints        int                 `json:"done_points"`
+	Percent           float64             `json:"percent"` // Of story points, or of tasks when none are estimated
+	OriginalEstimate  int                 `json:"original_estimate"`
+	RemainingEstimate int                 `json:"remaining_estimate"`
+	Projects          []EpicProjectRollup `json:"projects"`
+}
+
+type EpicProjectRollup struct {
+	ProjectID         uint   `json:"project_id"`
+	ProjectName       string `json:"project_name"`
+	TotalTasks        int    `json:"total_tasks"`
+	DoneTasks         int    `json:"done_tasks"`
+	TotalPoints       int    `json:"total_points"`
+	DonePoints        int    `json:"done_points"`
+	RemainingEstimate int    `json:"remaining_estimate"`
+}
+
+// Timeline lays out tasks over time. Start and End span all the items.
+type Timeline struct {
+	Start *time.Time     `json:"start"`
+	End   *time.Time     `json:"end"`
+	Items []TimelineItem `json:"items"`
+}
+
+// TimelineItem is a task on a timeline; End is its due date, or when it
+// was completed if that was later, and nil when it has neither
+type TimelineItem struct {
+	TaskID      uint       `json:"task_id"`
+	Title       string     `json:"title"`
+	ProjectID   uint       `json:"project_id"`
+	ProjectName string     `json:"project_name"`
+	Status      string     `json:"status"`
+	Category    string     `json:"category"`
+	Start       time.Time  `json:"start"`
+	End         *time.Time `json:"end"`
+}
+
+// MoveTaskInput moves a task on the board: to a status and next to another
+// task of that status. AfterID places it right below that task, otherwise
+// BeforeID right above it; with neither it goes to the bottom of the column.
+type MoveTaskInput struct {
+	Status   string `json:"status" binding:"required"`
+	AfterID  *uint  `json:"after_id"`
+	BeforeID *uint  `json:"before_id"`
+}
+
+// MoveTaskResult is a moved task, with a warning when the move took its
+// status over a WIP limit
+type MoveTaskResult struct {
+	Task       *models.Task `json:"task"`
+	WIPWarning string       `json:"wip_warning,omitempty"`
+}
+
+// ViewInput creates a saved view or replaces its settings. Filter takes the
+// query parameters of task listings, except sort.
+type ViewInput struct {
+	Name    string   `json:"name" binding:"required"`
+	Shared  bool     `json:"shared"`
+	Filter  string   `json:"filter"`
+	GroupBy string   `json:"group_by"`
+	Sort    string   `json:"sort"`
+	Columns []string `json:"columns"`
+}
+
+// ViewResult is the outcome of running a view: its tasks split into groups,
+// in lane order. Without grouping there is a single group.
+type ViewResult struct {
+	View   models.View `json:"view"`
+	Total  int         `json:"total"`
+	Groups []ViewGroup `json:"groups"`
+}
+
+// ViewGroup is one swimlane of a view. Key is the status, priority, user ID
+// or label ID shared by its tasks, empty for tasks without one. A task with
+// several assignees or labels shows up in each of their groups.
+type ViewGroup struct {
+	Key   string        `json:"key"`
+	Name  string        `json:"name"`
+	Tasks []models.Task `json:"tasks"`
+}
+
+// DependencyInput makes a task wait for another task of its project
+type DependencyInput struct {
+	DependsOnID uint `json:"depends_on_id" binding:"required"`
+	LagDays     int  `json:"lag_days"`
+}
+
+// TaskDependencies lists what a task waits for and what waits for it
+type TaskDependencies struct {
+	DependsOn  []models.TaskDependency `json:"depends_on"`
+	Dependents []models.TaskDependency `json:"dependents"`
+}
+
+// ScheduleInput moves a task's dates; tasks depending on it are pushed back
+// as needed. DueDate defaults to keeping the task's duration. DryRun
+// previews the changes without saving them.
+type ScheduleInput struct {
+	StartDate time.Time  `json:"start_date" binding:"required"`
+	DueDate   *time.Time `json:"due_date"`
+	DryRun    bool       `json:"dry_run"`
+}
+
+// ScheduleResult lists the tasks a rescheduling moves, the rescheduled task
+// first
+type ScheduleResult struct {
+	DryRun  bool             `json:"dry_run"`
+	Changes []ScheduleChange `json:"changes"`
+}
+
+type ScheduleChange struct {
+	TaskID       uint       `json:"task_id"`
+	Title        string     `json:"title"`
+	OldStartDate *time.Time `json:"old_start_date"`
+	OldDueDate   time.Time  `json:"old_due_date"`
+	StartDate    *time.Time `json:"start_date"`
+	DueDate      time.Time  `json:"due_date"`
+}
+
+// Gantt is a project's plan for Gantt charts: its tasks over time, the
+// dependencies between them and its milestones. Start and End span them all.
+type Gantt struct {
+	Start        *time.Time              `json:"start"`
+	End          *time.Time              `json:"end"`
+	Tasks        []GanttTask             `json:"tasks"`
+	Dependencies []models.TaskDependency `json:"dependencies"`
+	Milestones   []GanttMilestone        `json:"milestones"`
+}
+
+// GanttTask is a task bar. Start falls back to the due date for tasks
+// without a start date; tasks with neither date are left out.
+type GanttTask struct {
+	ID          uint      `json:"id"`
+	Title       string    `json:"title"`
+	ParentID    *uint     `json:"parent_id"`
+	MilestoneID *uint     `json:"milestone_id"`
+	AssigneeID  uint      `json:"assignee_id"`
+	Status      string    `json:"status"`
+	Category    string    `json:"category"`
+	Start       time.Time `json:"start"`
+	End         time.Time `json:"end"`
+	Duration    int       `json:"duration"` // Days
+	Progress    float64   `json:"progress"` // Percent, from the estimates
+	DependsOn   []uint    `json:"depends_on"`
+}
+
+type GanttMilestone struct {
+	ID       uint       `json:"id"`
+	Name     string     `json:"name"`
+	Date     time.Time  `json:"date"`
+	Released *time.Time `json:"released_at"`
+}
+
+// CapacityInput sets how much work a user takes on per week
+type CapacityInput struct {
+	Unit        string  `json:"unit" binding:"required"`
+	Weekly      float64 `json:"weekly"`
+	WorkingDays []int   `json:"working_days" binding:"required"`
+}
+
+type TimeOffInput struct {
+	StartDate time.Time `json:"start_date" binding:"required"`
+	EndDate   time.Time `json:"end_date" binding:"required"`
+	Reason    string    `json:"reason"`
+}
+
+// Workload spreads the open work assigned to a user, across all their
+// projects, over the working days up to each task's due date, and compares
+// it with their capacity day by day. Amounts are in Unit; work shared by
+// several assignees is split between them.
+type Workload struct {
+	UserID        uint           `json:"user_id"`
+	Name          string         `json:"name"`
+	Unit          string         `json:"unit"`
+	From          time.Time      `json:"from"`
+	To            time.Time      `json:"to"`
+	Capacity      float64        `json:"capacity"`
+	Allocated     float64        `json:"allocated"`
+	OverAllocated bool           `json:"over_allocated"` // On at least one day
+	Unscheduled   float64        `json:"unscheduled"`    // Open work without a due date
+	Days          []WorkloadDay  `json:"days"`
+	Tasks         []WorkloadTask `json:"tasks"`
+}
+
+type WorkloadDay struct {
+	Date          time.Time `json:"date"`
+	Capacity      float64   `json:"capacity"`
+	Allocated     float64   `json:"allocated"`
+	TimeOff       bool      `json:"time_off"`
+	OverAllocated bool      `json:"over_allocated"`
+}
+
+// WorkloadTask is the part of a task's work falling within the range
+type WorkloadTask struct {
+	TaskID      uint      `json:"task_id"`
+	Title       string    `json:"title"`
+	ProjectID   uint      `json:"project_id"`
+	ProjectName string    `json:"project_name"`
+	DueDate     time.Time `json:"due_date"`
+	Allocated   float64   `json:"allocated"`
+}
+
+// WorkCalendarInput replaces a project's working calendar
+type WorkCalendarInput struct {
+	Timezone    string `json:"timezone" binding:"required"`
+	WorkingDays []int  `json:"working_days"` // Weekdays, 0 for Sunday
+	DayStart    string `json:"day_start" binding:"required"`
+	DayEnd      string `json:"day_end" binding:"required"`
+}
+
+// HolidayInput adds a holiday to a project; the day of Date, as written, is
+// taken off
+type HolidayInput struct {
+	Date time.Time `json:"date" binding:"required"`
+	Name string    `json:"name" binding:"required"`
+}
+
+// HolidayImport reports the holidays read from an iCalendar file. Holidays
+// on days the project already had replace them.
+type HolidayImport struct {
+	Imported int              `json:"imported"`
+	Holidays []models.Holiday `json:"holidays"`
+}
+
+// UserPreferences are the settings users choose for themselves
+type UserPreferences struct {
+	Timezone string `json:"timezone" binding:"required"` // IANA name, e.g. "America/New_York"
+}
@@ -0,0 +1,186 @@
+// Archive handlers (archiving projects and the trash)
+package handlers
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/sirupsen/logrus"
+)
+
+func (h *Handler) ArchiveProject(c *gin.Context) {
+	h.setProjectArchived(c, true)
+}
+
+func (h *Handler) UnarchiveProject(c *gin.Context) {
+	h.setProjectArchived(c, false)
+}
+
+func (h *Handler) setProjectArchived(c *gin.Context, archive bool) {
+	userID := c.GetUint("userID")
+	logrus.WithFields(logrus.Fields{
+		"userID": userID,
+		"method": "POST",
+		"path":   c.Request.URL.Path,
+	}).Info("Incoming request")
+	projectID, ok := ParseID(c, c.Param("project_id"), "project_id")
+	if !ok {
+		return
+	}
+	if !CheckProjectAdmin(c, h, userID, projectID) {
+		return
+	}
+	handle := h.Service.UnarchiveProject
+	if archive {
+		handle = h.Service.ArchiveProject
+	}
+	project, err := handle(userID, projectID)
+	if err != nil {
+		SendServiceError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, project)
+}
+
+// GetTrashedProjects lists the projects in the trash that the caller
+// administers
+func (h *Handler) GetTrashedProjects(c *gin.Context) {
+	userID := c.GetUint("userID")
+	logrus.WithFields(logrus.Fields{
+		"userID": userID,
+		"method": "GET",
+		"path":   c.Request.URL.Path,
+	}).Info("Incoming request")
+	projects, err := h.Service.GetTrashedProjects(userID)
+	if err != nil {
+		SendServiceError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, projects)
+}
+
+func (h *Handler) RestoreProject(c *gin.Context) {
+	userID := c.GetUint("userID")
+	logrus.WithFields(logrus.Fields{
+		"userID": userID,
+		"method": "POST",
+		"path":   c.Request.URL.Path,
+	}).Info("Incoming request")
+	projectID, ok := ParseID(c, c.Param("project_id"), "project_id")
+	if !ok {
+		return
+	}
+	if !CheckProjectAdmin(c, h, userID, projectID) {
+		return
+	}
+	project, err := h.Service.RestoreProject(userID, projectID)
+	if err != nil {
+		SendServiceError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, project)
+}
+
+// PurgeProject permanently deletes a project in the trash
+func (h *Handler) PurgeProject(c *gin.Context) {
+	userID := c.GetUint("userID")
+	logrus.WithFields(logrus.Fields{
+		"userID": userID,
+		"method": "DELETE",
+		"path":   c.Request.URL.Path,
+	}).Info("Incoming request")
+	projectID, ok := ParseID(c, c.Param("project_id"), "project_id")
+	if !ok {
+		return
+	}
+	if !CheckProjectAdmin(c, h, userID, projectID) {
+		return
+	}
+	if err := h.Service.PurgeProject(userID, projectID); err != nil {
+		SendServiceError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "project permanently deleted"})
+}
+
+// GetTrashedTasks lists the tasks of a project that are in the trash
+func (h *Handler) GetTrashedTasks(c *gin.Context) {
+	userID := c.GetUint("userID")
+	logrus.WithFields(logrus.Fields{
+		"userID": userID,
+		"method": "GET",
+		"path":   c.Request.URL.Path,
+	}).Info("Incoming request")
+	projectID, ok := ParseID(c, c.Param("project_id"), "project_id")
+	if !ok {
+		return
+	}
+	if !h.Service.IsProjectMember(userID, projectID) {
+		SendError(c, http.StatusForbidden, "insufficient permission")
+		return
+	}
+	tasks, err := h.Service.GetTrashedTasks(projectID)
+	if err != nil {
+		SendServiceError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, tasks)
+}
+
+// trashedTaskProject loads a task in the trash and returns its project
+func (h *Handler) trashedTaskProject(c *gin.Context) (uint, uint, bool) {
+	taskID, ok := ParseID(c, c.Param("task_id"), "task_id")
+	if !ok {
+		return 0, 0, false
+	}
+	task, err := h.Service.GetTrashedTask(taskID)
+	if err != nil {
+		SendError(c, http.StatusNotFound, "task not found in the trash")
+		return 0, 0, false
+	}
+	return taskID, task.ProjectID, true
+}
+
+func (h *Handler) RestoreTask(c *gin.Context) {
+	userID := c.GetUint("userID")
+	logrus.WithFields(logrus.Fields{
+		"userID": userID,
+		"method": "POST",
+		"path":   c.Request.URL.Path,
+	}).Info("Incoming request")
+	taskID, projectID, ok := h.trashedTaskProject(c)
+	if !ok {
+		return
+	}
+	if !CheckProjectPermission(c, h, userID, projectID) {
+		return
+	}
+	task, err := h.Service.RestoreTask(userID, taskID)
+	if err != nil {
+		SendServiceError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, task)
+}
+
+// PurgeTask permanently deletes a task in the trash
+func (h *Handler) PurgeTask(c *gin.Context) {
+	userID := c.GetUint("userID")
+	logrus.WithFields(logrus.Fields{
+		"userID": userID,
+		"method": "DELETE",
+		"path":   c.Request.URL.Path,
+	}).Info("Incoming request")
+	taskID, projectID, ok := h.trashedTaskProject(c)
+	if !ok {
+		return
+	}
+	if !CheckProjectAdmin(c, h, userID, projectID) {
+		return
+	}
+	if err := h.Service.PurgeTask(userID, taskID); err != nil {
+		SendServiceError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "task permanently deleted"})
+}
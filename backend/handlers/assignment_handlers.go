@@ -0,0 +1,142 @@
+// Assignment handlers (assignees, watchers and "my work")
+package handlers
+
+import (
+	"net/http"
+
+	"work-management/models"
+
+	"github.com/gin-gonic/gin"
+	"github.com/sirupsen/logrus"
+)
+
+// parseTaskAndUser reads the task_id and user_id path parameters and loads the task
+func (h *Handler) parseTaskAndUser(c *gin.Context) (*models.Task, uint, bool) {
+	taskID, ok := ParseID(c, c.Param("task_id"), "task_id")
+	if !ok {
+		return nil, 0, false
+	}
+	targetUserID, ok := ParseID(c, c.Param("user_id"), "user_id")
+	if !ok {
+		return nil, 0, false
+	}
+	task, err := h.Service.GetTaskByID(taskID)
+	if err != nil {
+		SendError(c, http.StatusNotFound, "task not found")
+		return nil, 0, false
+	}
+	return task, targetUserID, true
+}
+
+func (h *Handler) AddAssignee(c *gin.Context) {
+	userID := c.GetUint("userID")
+	logrus.WithFields(logrus.Fields{
+		"userID": userID,
+		"method": "POST",
+		"path":   c.Request.URL.Path,
+	}).Info("Incoming request")
+	task, targetUserID, ok := h.parseTaskAndUser(c)
+	if !ok {
+		return
+	}
+	if !CheckProjectPermission(c, h, userID, task.ProjectID) {
+		return
+	}
+	task, err := h.Service.AssignTaskToUser(userID, task.ID, targetUserID)
+	if err != nil {
+		SendServiceError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, task)
+}
+
+func (h *Handler) RemoveAssignee(c *gin.Context) {
+	userID := c.GetUint("userID")
+	logrus.WithFields(logrus.Fields{
+		"userID": userID,
+		"method": "DELETE",
+		"path":   c.Request.URL.Path,
+	}).Info("Incoming request")
+	task, targetUserID, ok := h.parseTaskAndUser(c)
+	if !ok {
+		return
+	}
+	if !CheckProjectPermission(c, h, userID, task.ProjectID) {
+		return
+	}
+	task, err := h.Service.UnassignTaskFromUser(userID, task.ID, targetUserID)
+	if err != nil {
+		SendServiceError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, task)
+}
+
+// AddWatcher lets any project member watch a task; adding someone else as a
+// watcher requires edit permission.
+func (h *Handler) AddWatcher(c *gin.Context) {
+	userID := c.GetUint("userID")
+	logrus.WithFields(logrus.Fields{
+		"userID": userID,
+		"method": "POST",
+		"path":   c.Request.URL.Path,
+	}).Info("Incoming request")
+	task, targetUserID, ok := h.parseTaskAndUser(c)
+	if !ok {
+		return
+	}
+	if targetUserID != userID && !CheckProjectPermission(c, h, userID, task.ProjectID) {
+		return
+	}
+	task, err := h.Service.WatchTask(task.ID, targetUserID)
+	if err != nil {
+		SendServiceError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, task)
+}
+
+// RemoveWatcher lets users stop watching a task; removing someone else
+// requires edit permission.
+func (h *Handler) RemoveWatcher(c *gin.Context) {
+	userID := c.GetUint("userID")
+	logrus.WithFields(logrus.Fields{
+		"userID": userID,
+		"method": "DELETE",
+		"path":   c.Request.URL.Path,
+	}).Info("Incoming request")
+	task, targetUserID, ok := h.parseTaskAndUser(c)
+	if !ok {
+		return
+	}
+	if targetUserID != userID && !CheckProjectPermission(c, h, userID, task.ProjectID) {
+		return
+	}
+	task, err := h.Service.UnwatchTask(task.ID, targetUserID)
+	if err != nil {
+		SendServiceError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, task)
+}
+
+// GetMyWork lists tasks assigned to or watched by the caller. It accepts the
+// same filters as the task listings.
+func (h *Handler) GetMyWork(c *gin.Context) {
+	userID := c.GetUint("userID")
+	logrus.WithFields(logrus.Fields{
+		"userID": userID,
+		"method": "GET",
+		"path":   c.Request.URL.Path,
+	}).Info("Incoming request")
+	filter, ok := parseTaskFilter(c)
+	if !ok {
+		return
+	}
+	tasks, err := h.Service.GetMyWork(userID, filter)
+	if err != nil {
+		SendServiceError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, tasks)
+}
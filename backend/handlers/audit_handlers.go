@@ -0,0 +1,80 @@
+// Audit handlers (the administrative audit log)
+package handlers
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/sirupsen/logrus"
+)
+
+// parseAuditLimit reads the limit query parameter (default 50, at most 500)
+func parseAuditLimit(c *gin.Context) (int, bool) {
+	limit, err := strconv.Atoi(c.DefaultQuery("limit", "50"))
+	if err != nil || limit < 1 || limit > 500 {
+		SendError(c, http.StatusBadRequest, "limit must be between 1 and 500")
+		return 0, false
+	}
+	return limit, true
+}
+
+// GetAuditLogs lists the audit log of the whole installation. Query
+// parameters: project_id, action and limit.
+func (h *Handler) GetAuditLogs(c *gin.Context) {
+	userID := c.GetUint("userID")
+	logrus.WithFields(logrus.Fields{
+		"userID": userID,
+		"method": "GET",
+		"path":   c.Request.URL.Path,
+	}).Info("Incoming request")
+	if !CheckSystemAdmin(c, h, userID) {
+		return
+	}
+	var projectID uint
+	if raw := c.Query("project_id"); raw != "" {
+		id, ok := ParseID(c, raw, "project_id")
+		if !ok {
+			return
+		}
+		projectID = id
+	}
+	limit, ok := parseAuditLimit(c)
+	if !ok {
+		return
+	}
+	entries, err := h.Service.GetAuditLogs(projectID, c.Query("action"), limit)
+	if err != nil {
+		SendServiceError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, entries)
+}
+
+// GetProjectAuditLogs lists a project's audit log for its admins. Query
+// parameters: action and limit.
+func (h *Handler) GetProjectAuditLogs(c *gin.Context) {
+	userID := c.GetUint("userID")
+	logrus.WithFields(logrus.Fields{
+		"userID": userID,
+		"method": "GET",
+		"path":   c.Request.URL.Path,
+	}).Info("Incoming request")
+	projectID, ok := ParseID(c, c.Param("project_id"), "project_id")
+	if !ok {
+		return
+	}
+	if !CheckProjectAdmin(c, h, userID, projectID) {
+		return
+	}
+	limit, ok := parseAuditLimit(c)
+	if !ok {
+		return
+	}
+	entries, err := h.Service.GetAuditLogs(projectID, c.Query("action"), limit)
+	if err != nil {
+		SendServiceError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, entries)
+}
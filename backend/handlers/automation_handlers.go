@@ -0,0 +1,170 @@
+// Automation handlers (project automation rules and their run logs)
+package handlers
+
+import (
+	"net/http"
+
+	"work-management/dto"
+	"work-management/models"
+
+	"github.com/gin-gonic/gin"
+	"github.com/sirupsen/logrus"
+)
+
+func (h *Handler) GetAutomationRules(c *gin.Context) {
+	userID := c.GetUint("userID")
+	logrus.WithFields(logrus.Fields{
+		"userID": userID,
+		"method": "GET",
+		"path":   c.Request.URL.Path,
+	}).Info("Incoming request")
+	projectID, ok := ParseID(c, c.Param("project_id"), "project_id")
+	if !ok {
+		return
+	}
+	if !h.Service.IsProjectMember(userID, projectID) {
+		SendError(c, http.StatusForbidden, "insufficient permission")
+		return
+	}
+	rules, err := h.Service.GetAutomationRules(projectID)
+	if err != nil {
+		SendServiceError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, rules)
+}
+
+// CreateAutomationRule adds a rule to the project; the rule acts as the
+// admin who saved it
+func (h *Handler) CreateAutomationRule(c *gin.Context) {
+	userID := c.GetUint("userID")
+	logrus.WithFields(logrus.Fields{
+		"userID": userID,
+		"method": "POST",
+		"path":   c.Request.URL.Path,
+	}).Info("Incoming request")
+	projectID, ok := ParseID(c, c.Param("project_id"), "project_id")
+	if !ok {
+		return
+	}
+	if !CheckProjectAdminWritable(c, h, userID, projectID) {
+		return
+	}
+	var input dto.AutomationRuleInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		logrus.WithFields(logrus.Fields{
+			"error": err,
+		}).Warn("Invalid input")
+		SendError(c, http.StatusBadRequest, err.Error())
+		return
+	}
+	rule, err := h.Service.CreateAutomationRule(userID, projectID, input)
+	if err != nil {
+		SendServiceError(c, err)
+		return
+	}
+	c.JSON(http.StatusCreated, rule)
+}
+
+// automationRuleFor loads the rule named in the path and checks the caller
+// belongs to its project, or may change its settings when admin is set
+func (h *Handler) automationRuleFor(c *gin.Context, userID uint, admin bool) (*models.AutomationRule, bool) {
+	ruleID, ok := ParseID(c, c.Param("rule_id"), "rule_id")
+	if !ok {
+		return nil, false
+	}
+	rule, err := h.Service.GetAutomationRule(ruleID)
+	if err != nil {
+		SendError(c, http.StatusNotFound, "automation rule not found")
+		return nil, false
+	}
+	if admin {
+		if !CheckProjectAdminWritable(c, h, userID, rule.ProjectID) {
+			return nil, false
+		}
+	} else if !h.Service.IsProjectMember(userID, rule.ProjectID) {
+		SendError(c, http.StatusForbidden, "insufficient permission")
+		return nil, false
+	}
+	return rule, true
+}
+
+func (h *Handler) GetAutomationRule(c *gin.Context) {
+	userID := c.GetUint("userID")
+	logrus.WithFields(logrus.Fields{
+		"userID": userID,
+		"method": "GET",
+		"path":   c.Request.URL.Path,
+	}).Info("Incoming request")
+	rule, ok := h.automationRuleFor(c, userID, false)
+	if !ok {
+		return
+	}
+	c.JSON(http.StatusOK, rule)
+}
+
+// UpdateAutomationRule replaces a rule; from now on it acts as the caller
+func (h *Handler) UpdateAutomationRule(c *gin.Context) {
+	userID := c.GetUint("userID")
+	logrus.WithFields(logrus.Fields{
+		"userID": userID,
+		"method": "PUT",
+		"path":   c.Request.URL.Path,
+	}).Info("Incoming request")
+	rule, ok := h.automationRuleFor(c, userID, true)
+	if !ok {
+		return
+	}
+	var input dto.AutomationRuleInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		logrus.WithFields(logrus.Fields{
+			"error": err,
+		}).Warn("Invalid input")
+		SendError(c, http.StatusBadRequest, err.Error())
+		return
+	}
+	rule, err := h.Service.UpdateAutomationRule(userID, rule.ID, input)
+	if err != nil {
+		SendServiceError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, rule)
+}
+
+func (h *Handler) DeleteAutomationRule(c *gin.Context) {
+	userID := c.GetUint("userID")
+	logrus.WithFields(logrus.Fields{
+		"userID": userID,
+		"method": "DELETE",
+		"path":   c.Request.URL.Path,
+	}).Info("Incoming request")
+	rule, ok := h.automationRuleFor(c, userID, true)
+	if !ok {
+		return
+	}
+	if err := h.Service.DeleteAutomationRule(rule.ID); err != nil {
+		SendServiceError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "automation rule deleted"})
+}
+
+// GetAutomationRuns returns the latest runs of a rule, newest first
+func (h *Handler) GetAutomationRuns(c *gin.Context) {
+	userID := c.GetUint("userID")
+	logrus.WithFields(logrus.Fields{
+		"userID": userID,
+		"method": "GET",
+		"path":   c.Request.URL.Path,
+	}).Info("Incoming request")
+	rule, ok := h.automationRuleFor(c, userID, false)
+	if !ok {
+		return
+	}
+	runs, err := h.Service.GetAutomationRuns(rule.ID)
+	if err != nil {
+		SendServiceError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, runs)
+}
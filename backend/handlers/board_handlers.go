@@ -0,0 +1,41 @@
+// Board handlers (moving tasks between and within board columns)
+package handlers
+
+import (
+	"net/http"
+
+	"work-management/dto"
+
+	"github.com/gin-gonic/gin"
+	"github.com/sirupsen/logrus"
+)
+
+// MoveTask changes a task's status and its position on the board at once.
+// A move over a WIP limit is refused with 409, or answered with a
+// wip_warning, depending on the status' WIP policy.
+func (h *Handler) MoveTask(c *gin.Context) {
+	userID := c.GetUint("userID")
+	logrus.WithFields(logrus.Fields{
+		"userID": userID,
+		"method": "POST",
+		"path":   c.Request.URL.Path,
+	}).Info("Incoming request")
+	taskID, ok := h.taskForEditor(c, userID)
+	if !ok {
+		return
+	}
+	var input dto.MoveTaskInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		logrus.WithFields(logrus.Fields{
+			"error": err,
+		}).Warn("Invalid input")
+		SendError(c, http.StatusBadRequest, err.Error())
+		return
+	}
+	result, err := h.Service.MoveTask(userID, taskID, input)
+	if err != nil {
+		SendServiceError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, result)
+}
@@ -0,0 +1,246 @@
+// Calendar handlers (working calendars, holidays and user timezones)
+package handlers
+
+import (
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"work-management/dto"
+
+	"github.com/gin-gonic/gin"
+	"github.com/sirupsen/logrus"
+)
+
+// maxCalendarFileSize bounds the iCalendar files holidays are imported from
+const maxCalendarFileSize = 1 << 20
+
+func (h *Handler) GetWorkCalendar(c *gin.Context) {
+	userID := c.GetUint("userID")
+	logrus.WithFields(logrus.Fields{
+		"userID": userID,
+		"method": "GET",
+		"path":   c.Request.URL.Path,
+	}).Info("Incoming request")
+	projectID, ok := ParseID(c, c.Param("project_id"), "project_id")
+	if !ok {
+		return
+	}
+	if !h.Service.IsProjectMember(userID, projectID) {
+		SendError(c, http.StatusForbidden, "insufficient permission")
+		return
+	}
+	workCalendar, err := h.Service.GetWorkCalendar(projectID)
+	if err != nil {
+		SendServiceError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, workCalendar)
+}
+
+func (h *Handler) UpdateWorkCalendar(c *gin.Context) {
+	userID := c.GetUint("userID")
+	logrus.WithFields(logrus.Fields{
+		"userID": userID,
+		"method": "PUT",
+		"path":   c.Request.URL.Path,
+	}).Info("Incoming request")
+	projectID, ok := ParseID(c, c.Param("project_id"), "project_id")
+	if !ok {
+		return
+	}
+	if !CheckProjectAdminWritable(c, h, userID, projectID) {
+		return
+	}
+	var input dto.WorkCalendarInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		logrus.WithFields(logrus.Fields{
+			"error": err,
+		}).Warn("Invalid input")
+		SendError(c, http.StatusBadRequest, err.Error())
+		return
+	}
+	workCalendar, err := h.Service.UpdateWorkCalendar(projectID, input)
+	if err != nil {
+		SendServiceError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, workCalendar)
+}
+
+// GetHolidays lists a project's holidays. Query parameters:
+//   - year: only the holidays of that year
+func (h *Handler) GetHolidays(c *gin.Context) {
+	userID := c.GetUint("userID")
+	logrus.WithFields(logrus.Fields{
+		"userID": userID,
+		"method": "GET",
+		"path":   c.Request.URL.Path,
+	}).Info("Incoming request")
+	projectID, ok := ParseID(c, c.Param("project_id"), "project_id")
+	if !ok {
+		return
+	}
+	if !h.Service.IsProjectMember(userID, projectID) {
+		SendError(c, http.StatusForbidden, "insufficient permission")
+		return
+	}
+	year := 0
+	if raw := c.Query("year"); raw != "" {
+		parsed, err := strconv.Atoi(raw)
+		if err != nil || parsed < 1 || parsed > 9999 {
+			SendError(c, http.StatusBadRequest, "invalid year")
+			return
+		}
+		year = parsed
+	}
+	holidays, err := h.Service.GetHolidays(projectID, year)
+	if err != nil {
+		SendServiceError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, holidays)
+}
+
+func (h *Handler) AddHoliday(c *gin.Context) {
+	userID := c.GetUint("userID")
+	logrus.WithFields(logrus.Fields{
+		"userID": userID,
+		"method": "POST",
+		"path":   c.Request.URL.Path,
+	}).Info("Incoming request")
+	projectID, ok := ParseID(c, c.Param("project_id"), "project_id")
+	if !ok {
+		return
+	}
+	if !CheckProjectAdminWritable(c, h, userID, projectID) {
+		return
+	}
+	var input dto.HolidayInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		logrus.WithFields(logrus.Fields{
+			"error": err,
+		}).Warn("Invalid input")
+		SendError(c, http.StatusBadRequest, err.Error())
+		return
+	}
+	holiday, err := h.Service.AddHoliday(projectID, input)
+	if err != nil {
+		SendServiceError(c, err)
+		return
+	}
+	c.JSON(http.StatusCreated, holiday)
+}
+
+// ImportHolidays adds the events of an iCalendar file to a project's
+// holidays. The file is the request body, or the "file" field of a
+// multipart form.
+func (h *Handler) ImportHolidays(c *gin.Context) {
+	userID := c.GetUint("userID")
+	logrus.WithFields(logrus.Fields{
+		"userID": userID,
+		"method": "POST",
+		"path":   c.Request.URL.Path,
+	}).Info("Incoming request")
+	projectID, ok := ParseID(c, c.Param("project_id"), "project_id")
+	if !ok {
+		return
+	}
+	if !CheckProjectAdminWritable(c, h, userID, projectID) {
+		return
+	}
+	c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, maxCalendarFileSize)
+	var file io.Reader = c.Request.Body
+	if strings.HasPrefix(c.ContentType(), "multipart/") {
+		header, err := c.FormFile("file")
+		if err != nil {
+			SendError(c, http.StatusBadRequest, "the iCalendar file is missing from the file field")
+			return
+		}
+		opened, err := header.Open()
+		if err != nil {
+			SendError(c, http.StatusBadRequest, err.Error())
+			return
+		}
+		defer opened.Close()
+		file = opened
+	}
+	result, err := h.Service.ImportHolidays(projectID, file)
+	if err != nil {
+		SendServiceError(c, err)
+		return
+	}
+	c.JSON(http.StatusCreated, result)
+}
+
+func (h *Handler) DeleteHoliday(c *gin.Context) {
+	userID := c.GetUint("userID")
+	logrus.WithFields(logrus.Fields{
+		"userID": userID,
+		"method": "DELETE",
+		"path":   c.Request.URL.Path,
+	}).Info("Incoming request")
+	projectID, ok := ParseID(c, c.Param("project_id"), "project_id")
+	if !ok {
+		return
+	}
+	holidayID, ok := ParseID(c, c.Param("holiday_id"), "holiday_id")
+	if !ok {
+		return
+	}
+	if !CheckProjectAdminWritable(c, h, userID, projectID) {
+		return
+	}
+	if err := h.Service.DeleteHoliday(projectID, holidayID); err != nil {
+		SendServiceError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "holiday deleted"})
+}
+
+func (h *Handler) GetPreferences(c *gin.Context) {
+	userID := c.GetUint("userID")
+	logrus.WithFields(logrus.Fields{
+		"userID": userID,
+		"method": "GET",
+		"path":   c.Request.URL.Path,
+	}).Info("Incoming request")
+	targetID, ok := h.userForViewer(c, userID)
+	if !ok {
+		return
+	}
+	preferences, err := h.Service.GetPreferences(targetID)
+	if err != nil {
+		SendServiceError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, preferences)
+}
+
+func (h *Handler) UpdatePreferences(c *gin.Context) {
+	userID := c.GetUint("userID")
+	logrus.WithFields(logrus.Fields{
+		"userID": userID,
+		"method": "PUT",
+		"path":   c.Request.URL.Path,
+	}).Info("Incoming request")
+	targetID, ok := ParseID(c, c.Param("user_id"), "user_id")
+	if !ok {
+		return
+	}
+	var input dto.UserPreferences
+	if err := c.ShouldBindJSON(&input); err != nil {
+		logrus.WithFields(logrus.Fields{
+			"error": err,
+		}).Warn("Invalid input")
+		SendError(c, http.StatusBadRequest, err.Error())
+		return
+	}
+	preferences, err := h.Service.UpdatePreferences(userID, targetID, input)
+	if err != nil {
+		SendServiceError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, preferences)
+}
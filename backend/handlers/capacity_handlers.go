@@ -0,0 +1,221 @@
+// Capacity handlers (weekly capacity, time off and workload)
+package handlers
+
+import (
+	"net/http"
+	"time"
+
+	"work-management/dto"
+
+	"github.com/gin-gonic/gin"
+	"github.com/sirupsen/logrus"
+)
+
+// defaultWorkloadDays is the number of days a workload covers when the
+// request does not say
+const defaultWorkloadDays = 14
+
+// userForViewer parses the user in the path and checks the caller may see
+// their capacity and workload
+func (h *Handler) userForViewer(c *gin.Context, userID uint) (uint, bool) {
+	targetID, ok := ParseID(c, c.Param("user_id"), "user_id")
+	if !ok {
+		return 0, false
+	}
+	if !h.Service.CanSeeWorkload(userID, targetID) {
+		SendError(c, http.StatusForbidden, "insufficient permission")
+		return 0, false
+	}
+	return targetID, true
+}
+
+// parseWorkloadRange reads the optional from and to dates, which default to
+// the two weeks starting today
+func parseWorkloadRange(c *gin.Context) (time.Time, time.Time, bool) {
+	now := time.Now()
+	from := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.UTC)
+	if raw := c.Query("from"); raw != "" {
+		parsed, err := time.Parse(dateLayout, raw)
+		if err != nil {
+			SendError(c, http.StatusBadRequest, "from must be a date like 2024-01-31")
+			return time.Time{}, time.Time{}, false
+		}
+		from = parsed
+	}
+	to := from.AddDate(0, 0, defaultWorkloadDays-1)
+	if raw := c.Query("to"); raw != "" {
+		parsed, err := time.Parse(dateLayout, raw)
+		if err != nil {
+			SendError(c, http.StatusBadRequest, "to must be a date like 2024-01-31")
+			return time.Time{}, time.Time{}, false
+		}
+		to = parsed
+	}
+	return from, to, true
+}
+
+func (h *Handler) GetCapacity(c *gin.Context) {
+	userID := c.GetUint("userID")
+	logrus.WithFields(logrus.Fields{
+		"userID": userID,
+		"method": "GET",
+		"path":   c.Request.URL.Path,
+	}).Info("Incoming request")
+	targetID, ok := h.userForViewer(c, userID)
+	if !ok {
+		return
+	}
+	capacity, err := h.Service.GetCapacity(targetID)
+	if err != nil {
+		SendServiceError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, capacity)
+}
+
+func (h *Handler) SetCapacity(c *gin.Context) {
+	userID := c.GetUint("userID")
+	logrus.WithFields(logrus.Fields{
+		"userID": userID,
+		"method": "PUT",
+		"path":   c.Request.URL.Path,
+	}).Info("Incoming request")
+	targetID, ok := ParseID(c, c.Param("user_id"), "user_id")
+	if !ok {
+		return
+	}
+	var input dto.CapacityInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		logrus.WithFields(logrus.Fields{
+			"error": err,
+		}).Warn("Invalid input")
+		SendError(c, http.StatusBadRequest, err.Error())
+		return
+	}
+	capacity, err := h.Service.SetCapacity(userID, targetID, input)
+	if err != nil {
+		SendServiceError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, capacity)
+}
+
+// GetTimeOff lists a user's time off that has not ended yet
+func (h *Handler) GetTimeOff(c *gin.Context) {
+	userID := c.GetUint("userID")
+	logrus.WithFields(logrus.Fields{
+		"userID": userID,
+		"method": "GET",
+		"path":   c.Request.URL.Path,
+	}).Info("Incoming request")
+	targetID, ok := h.userForViewer(c, userID)
+	if !ok {
+		return
+	}
+	timeOff, err := h.Service.GetTimeOff(targetID)
+	if err != nil {
+		SendServiceError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, timeOff)
+}
+
+func (h *Handler) AddTimeOff(c *gin.Context) {
+	userID := c.GetUint("userID")
+	logrus.WithFields(logrus.Fields{
+		"userID": userID,
+		"method": "POST",
+		"path":   c.Request.URL.Path,
+	}).Info("Incoming request")
+	targetID, ok := ParseID(c, c.Param("user_id"), "user_id")
+	if !ok {
+		return
+	}
+	var input dto.TimeOffInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		logrus.WithFields(logrus.Fields{
+			"error": err,
+		}).Warn("Invalid input")
+		SendError(c, http.StatusBadRequest, err.Error())
+		return
+	}
+	timeOff, err := h.Service.AddTimeOff(userID, targetID, input)
+	if err != nil {
+		SendServiceError(c, err)
+		return
+	}
+	c.JSON(http.StatusCreated, timeOff)
+}
+
+func (h *Handler) DeleteTimeOff(c *gin.Context) {
+	userID := c.GetUint("userID")
+	logrus.WithFields(logrus.Fields{
+		"userID": userID,
+		"method": "DELETE",
+		"path":   c.Request.URL.Path,
+	}).Info("Incoming request")
+	timeOffID, ok := ParseID(c, c.Param("time_off_id"), "time_off_id")
+	if !ok {
+		return
+	}
+	if err := h.Service.DeleteTimeOff(userID, timeOffID); err != nil {
+		SendServiceError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "time off deleted"})
+}
+
+// GetWorkload spreads a user's open work in all their projects over a date
+// range and flags the days they are over capacity. Query parameters:
+//   - from, to: inclusive dates (YYYY-MM-DD), two weeks from today by default
+func (h *Handler) GetWorkload(c *gin.Context) {
+	userID := c.GetUint("userID")
+	logrus.WithFields(logrus.Fields{
+		"userID": userID,
+		"method": "GET",
+		"path":   c.Request.URL.Path,
+	}).Info("Incoming request")
+	targetID, ok := h.userForViewer(c, userID)
+	if !ok {
+		return
+	}
+	from, to, ok := parseWorkloadRange(c)
+	if !ok {
+		return
+	}
+	workload, err := h.Service.GetWorkload(targetID, from, to)
+	if err != nil {
+		SendServiceError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, workload)
+}
+
+// GetProjectWorkload returns the workload of each member of a project; it
+// takes the query parameters of GetWorkload
+func (h *Handler) GetProjectWorkload(c *gin.Context) {
+	userID := c.GetUint("userID")
+	logrus.WithFields(logrus.Fields{
+		"userID": userID,
+		"method": "GET",
+		"path":   c.Request.URL.Path,
+	}).Info("Incoming request")
+	projectID, ok := ParseID(c, c.Param("project_id"), "project_id")
+	if !ok {
+		return
+	}
+	if !h.Service.IsProjectMember(userID, projectID) {
+		SendError(c, http.StatusForbidden, "insufficient permission")
+		return
+	}
+	from, to, ok := parseWorkloadRange(c)
+	if !ok {
+		return
+	}
+	workloads, err := h.Service.GetProjectWorkload(projectID, from, to)
+	if err != nil {
+		SendServiceError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, workloads)
+}
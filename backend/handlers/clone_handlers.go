@@ -0,0 +1,88 @@
+// Clone handlers (copying projects and tasks)
+package handlers
+
+import (
+	"net/http"
+
+	"work-management/dto"
+
+	"github.com/gin-gonic/gin"
+	"github.com/sirupsen/logrus"
+)
+
+// CloneProject copies a project the caller can edit; the caller becomes the
+// admin of the copy
+func (h *Handler) CloneProject(c *gin.Context) {
+	userID := c.GetUint("userID")
+	logrus.WithFields(logrus.Fields{
+		"userID": userID,
+		"method": "POST",
+		"path":   c.Request.URL.Path,
+	}).Info("Incoming request")
+	projectID, ok := ParseID(c, c.Param("project_id"), "project_id")
+	if !ok {
+		return
+	}
+	if !CheckProjectPermission(c, h, userID, projectID) {
+		return
+	}
+	var input dto.CloneProjectInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		logrus.WithFields(logrus.Fields{
+			"error": err,
+		}).Warn("Invalid input")
+		SendError(c, http.StatusBadRequest, err.Error())
+		return
+	}
+	project, err := h.Service.CloneProject(userID, projectID, input)
+	if err != nil {
+		SendServiceError(c, err)
+		return
+	}
+	c.JSON(http.StatusCreated, project)
+}
+
+// CloneTask copies a task of a project the caller belongs to into a project
+// they can edit, its own by default
+func (h *Handler) CloneTask(c *gin.Context) {
+	userID := c.GetUint("userID")
+	logrus.WithFields(logrus.Fields{
+		"userID": userID,
+		"method": "POST",
+		"path":   c.Request.URL.Path,
+	}).Info("Incoming request")
+	taskID, ok := ParseID(c, c.Param("task_id"), "task_id")
+	if !ok {
+		return
+	}
+	task, err := h.Service.GetTaskByID(taskID)
+	if err != nil {
+		SendError(c, http.StatusNotFound, "task not found")
+		return
+	}
+	if !h.Service.IsProjectMember(userID, task.ProjectID) {
+		SendError(c, http.StatusForbidden, "insufficient permission")
+		return
+	}
+	var input dto.CloneTaskInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		logrus.WithFields(logrus.Fields{
+			"error": err,
+		}).Warn("Invalid input")
+		SendError(c, http.StatusBadRequest, err.Error())
+		return
+	}
+	targetID := input.ProjectID
+	if targetID == 0 {
+		targetID = task.ProjectID
+	}
+	if !CheckProjectPermission(c, h, userID, targetID) {
+		return
+	}
+	clone, err := h.Service.CloneTask(userID, taskID, input)
+	if err != nil {
+		SendServiceError(c, err)
+		return
+	}
+	c.JSON(http.StatusCreated, clone)
+}
@@ -0,0 +1,75 @@
+// Comment handlers (task comments)
+package handlers
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/sirupsen/logrus"
+)
+
+func (h *Handler) GetComments(c *gin.Context) {
+	logrus.WithFields(logrus.Fields{
+		"method": "GET",
+		"path":   c.Request.URL.Path,
+	}).Info("Incoming request")
+	taskID, ok := ParseID(c, c.Param("task_id"), "task_id")
+	if !ok {
+		return
+	}
+	comments, err := h.Service.GetCommentsByTaskID(taskID)
+	if err != nil {
+		SendServiceError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, comments)
+}
+
+// CreateComment posts a comment as the caller. Users mentioned in the body as
+// @email are notified.
+func (h *Handler) CreateComment(c *gin.Context) {
+	userID := c.GetUint("userID")
+	logrus.WithFields(logrus.Fields{
+		"userID": userID,
+		"method": "POST",
+		"path":   c.Request.URL.Path,
+	}).Info("Incoming request")
+	taskID, ok := ParseID(c, c.Param("task_id"), "task_id")
+	if !ok {
+		return
+	}
+	var input struct {
+		Body string `json:"body" binding:"required"`
+	}
+	if err := c.ShouldBindJSON(&input); err != nil {
+		logrus.WithFields(logrus.Fields{
+			"error": err,
+		}).Warn("Invalid input")
+		SendError(c, http.StatusBadRequest, err.Error())
+		return
+	}
+	comment, err := h.Service.AddComment(userID, taskID, input.Body)
+	if err != nil {
+		SendServiceError(c, err)
+		return
+	}
+	c.JSON(http.StatusCreated, comment)
+}
+
+func (h *Handler) DeleteComment(c *gin.Context) {
+	userID := c.GetUint("userID")
+	logrus.WithFields(logrus.Fields{
+		"userID": userID,
+		"method": "DELETE",
+		"path":   c.Request.URL.Path,
+	}).Info("Incoming request")
+	commentID, ok := ParseID(c, c.Param("comment_id"), "comment_id")
+	if !ok {
+		return
+	}
+	if err := h.Service.DeleteComment(userID, commentID); err != nil {
+		SendServiceError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "comment deleted"})
+}
@@ -0,0 +1,138 @@
+// Custom field handlers (project field definitions)
+package handlers
+
+import (
+	"net/http"
+
+	"work-management/models"
+
+	"github.com/gin-gonic/gin"
+	"github.com/sirupsen/logrus"
+)
+
+type customFieldInput struct {
+	Name         string      `json:"name" binding:"required"`
+	Type         string      `json:"type"`
+	Required     bool        `json:"required"`
+	Options      []string    `json:"options"`
+	DefaultValue interface{} `json:"default_value"`
+}
+
+func (h *Handler) GetCustomFields(c *gin.Context) {
+	logrus.WithFields(logrus.Fields{
+		"method": "GET",
+		"path":   c.Request.URL.Path,
+	}).Info("Incoming request")
+	projectID, ok := ParseID(c, c.Param("project_id"), "project_id")
+	if !ok {
+		return
+	}
+	fields, err := h.Service.GetCustomFieldsByProjectID(projectID)
+	if err != nil {
+		SendError(c, http.StatusInternalServerError, err.Error())
+		return
+	}
+	c.JSON(http.StatusOK, fields)
+}
+
+func (h *Handler) CreateCustomField(c *gin.Context) {
+	userID := c.GetUint("userID")
+	logrus.WithFields(logrus.Fields{
+		"userID": userID,
+		"method": "POST",
+		"path":   c.Request.URL.Path,
+	}).Info("Incoming request")
+	projectID, ok := ParseID(c, c.Param("project_id"), "project_id")
+	if !ok {
+		return
+	}
+	if !CheckProjectAdminWritable(c, h, userID, projectID) {
+		return
+	}
+	var input customFieldInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		logrus.WithFields(logrus.Fields{
+			"error": err,
+		}).Warn("Invalid input")
+		SendError(c, http.StatusBadRequest, err.Error())
+		return
+	}
+	field, err := h.Service.CreateCustomField(&models.CustomField{
+		ProjectID:    projectID,
+		Name:         input.Name,
+		Type:         input.Type,
+		Required:     input.Required,
+		Options:      input.Options,
+		DefaultValue: input.DefaultValue,
+	})
+	if err != nil {
+		SendServiceError(c, err)
+		return
+	}
+	c.JSON(http.StatusCreated, field)
+}
+
+func (h *Handler) UpdateCustomField(c *gin.Context) {
+	userID := c.GetUint("userID")
+	logrus.WithFields(logrus.Fields{
+		"userID": userID,
+		"method": "PUT",
+		"path":   c.Request.URL.Path,
+	}).Info("Incoming request")
+	projectID, ok := ParseID(c, c.Param("project_id"), "project_id")
+	if !ok {
+		return
+	}
+	fieldID, ok := ParseID(c, c.Param("field_id"), "field_id")
+	if !ok {
+		return
+	}
+	if !CheckProjectAdminWritable(c, h, userID, projectID) {
+		return
+	}
+	var input customFieldInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		logrus.WithFields(logrus.Fields{
+			"error": err,
+		}).Warn("Invalid input")
+		SendError(c, http.StatusBadRequest, err.Error())
+		return
+	}
+	field, err := h.Service.UpdateCustomField(projectID, fieldID, models.CustomField{
+		Name:         input.Name,
+		Type:         input.Type,
+		Required:     input.Required,
+		Options:      input.Options,
+		DefaultValue: input.DefaultValue,
+	})
+	if err != nil {
+		SendServiceError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, field)
+}
+
+func (h *Handler) DeleteCustomField(c *gin.Context) {
+	userID := c.GetUint("userID")
+	logrus.WithFields(logrus.Fields{
+		"userID": userID,
+		"method": "DELETE",
+		"path":   c.Request.URL.Path,
+	}).Info("Incoming request")
+	projectID, ok := ParseID(c, c.Param("project_id"), "project_id")
+	if !ok {
+		return
+	}
+	fieldID, ok := ParseID(c, c.Param("field_id"), "field_id")
+	if !ok {
+		return
+	}
+	if !CheckProjectAdminWritable(c, h, userID, projectID) {
+		return
+	}
+	if err := h.Service.DeleteCustomField(projectID, fieldID); err != nil {
+		SendServiceError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "custom field deleted"})
+}
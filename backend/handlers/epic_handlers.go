@@ -0,0 +1,227 @@
+// Epic handlers (epics spanning projects, their rollup and timeline)
+package handlers
+
+import (
+	"net/http"
+
+	"work-management/dto"
+	"work-management/models"
+
+	"github.com/gin-gonic/gin"
+	"github.com/sirupsen/logrus"
+)
+
+func (h *Handler) GetEpics(c *gin.Context) {
+	userID := c.GetUint("userID")
+	logrus.WithFields(logrus.Fields{
+		"userID": userID,
+		"method": "GET",
+		"path":   c.Request.URL.Path,
+	}).Info("Incoming request")
+	epics, err := h.Service.GetEpics()
+	if err != nil {
+		SendServiceError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, epics)
+}
+
+func (h *Handler) CreateEpic(c *gin.Context) {
+	userID := c.GetUint("userID")
+	logrus.WithFields(logrus.Fields{
+		"userID": userID,
+		"method": "POST",
+		"path":   c.Request.URL.Path,
+	}).Info("Incoming request")
+	var input dto.EpicInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		logrus.WithFields(logrus.Fields{
+			"error": err,
+		}).Warn("Invalid input")
+		SendError(c, http.StatusBadRequest, err.Error())
+		return
+	}
+	epic, err := h.Service.CreateEpic(userID, input)
+	if err != nil {
+		SendServiceError(c, err)
+		return
+	}
+	c.JSON(http.StatusCreated, epic)
+}
+
+// epicFromPath loads the epic named in the path; permissions are checked by
+// the services, as an epic spans projects
+func (h *Handler) epicFromPath(c *gin.Context) (*models.Epic, bool) {
+	epicID, ok := ParseID(c, c.Param("epic_id"), "epic_id")
+	if !ok {
+		return nil, false
+	}
+	epic, err := h.Service.GetEpic(epicID)
+	if err != nil {
+		SendError(c, http.StatusNotFound, "epic not found")
+		return nil, false
+	}
+	return epic, true
+}
+
+// GetEpic returns an epic with the rollup of the tasks the caller can see
+func (h *Handler) GetEpic(c *gin.Context) {
+	userID := c.GetUint("userID")
+	logrus.WithFields(logrus.Fields{
+		"userID": userID,
+		"method": "GET",
+		"path":   c.Request.URL.Path,
+	}).Info("Incoming request")
+	epic, ok := h.epicFromPath(c)
+	if !ok {
+		return
+	}
+	rollup, err := h.Service.GetEpicRollup(userID, epic.ID)
+	if err != nil {
+		SendServiceError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, rollup)
+}
+
+func (h *Handler) UpdateEpic(c *gin.Context) {
+	userID := c.GetUint("userID")
+	logrus.WithFields(logrus.Fields{
+		"userID": userID,
+		"method": "PUT",
+		"path":   c.Request.URL.Path,
+	}).Info("Incoming request")
+	epic, ok := h.epicFromPath(c)
+	if !ok {
+		return
+	}
+	var input dto.EpicInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		logrus.WithFields(logrus.Fields{
+			"error": err,
+		}).Warn("Invalid input")
+		SendError(c, http.StatusBadRequest, err.Error())
+		return
+	}
+	epic, err := h.Service.UpdateEpic(userID, epic.ID, input)
+	if err != nil {
+		SendServiceError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, epic)
+}
+
+func (h *Handler) DeleteEpic(c *gin.Context) {
+	userID := c.GetUint("userID")
+	logrus.WithFields(logrus.Fields{
+		"userID": userID,
+		"method": "DELETE",
+		"path":   c.Request.URL.Path,
+	}).Info("Incoming request")
+	epic, ok := h.epicFromPath(c)
+	if !ok {
+		return
+	}
+	if err := h.Service.DeleteEpic(userID, epic.ID); err != nil {
+		SendServiceError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "epic deleted"})
+}
+
+// GetEpicTasks lists the tasks of an epic in the projects the caller belongs
+// to; it takes the filters of GetTasks
+func (h *Handler) GetEpicTasks(c *gin.Context) {
+	userID := c.GetUint("userID")
+	logrus.WithFields(logrus.Fields{
+		"userID": userID,
+		"method": "GET",
+		"path":   c.Request.URL.Path,
+	}).Info("Incoming request")
+	epic, ok := h.epicFromPath(c)
+	if !ok {
+		return
+	}
+	filter, ok := parseTaskFilter(c)
+	if !ok {
+		return
+	}
+	tasks, err := h.Service.GetEpicTasks(userID, epic.ID, filter)
+	if err != nil {
+		SendServiceError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, tasks)
+}
+
+// AddEpicTasks adds tasks to an epic; the caller must be able to modify the
+// project of each task
+func (h *Handler) AddEpicTasks(c *gin.Context) {
+	userID := c.GetUint("userID")
+	logrus.WithFields(logrus.Fields{
+		"userID": userID,
+		"method": "POST",
+		"path":   c.Request.URL.Path,
+	}).Info("Incoming request")
+	epic, ok := h.epicFromPath(c)
+	if !ok {
+		return
+	}
+	var input dto.EpicTasksInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		logrus.WithFields(logrus.Fields{
+			"error": err,
+		}).Warn("Invalid input")
+		SendError(c, http.StatusBadRequest, err.Error())
+		return
+	}
+	epic, err := h.Service.AddEpicTasks(userID, epic.ID, input.TaskIDs)
+	if err != nil {
+		SendServiceError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, epic)
+}
+
+func (h *Handler) RemoveEpicTask(c *gin.Context) {
+	userID := c.GetUint("userID")
+	logrus.WithFields(logrus.Fields{
+		"userID": userID,
+		"method": "DELETE",
+		"path":   c.Request.URL.Path,
+	}).Info("Incoming request")
+	epic, ok := h.epicFromPath(c)
+	if !ok {
+		return
+	}
+	taskID, ok := ParseID(c, c.Param("task_id"), "task_id")
+	if !ok {
+		return
+	}
+	if err := h.Service.RemoveEpicTask(userID, epic.ID, taskID); err != nil {
+		SendServiceError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "task removed from epic"})
+}
+
+// GetEpicTimeline lays out the tasks of an epic that the caller can see over
+// time
+func (h *Handler) GetEpicTimeline(c *gin.Context) {
+	userID := c.GetUint("userID")
+	logrus.WithFields(logrus.Fields{
+		"userID": userID,
+		"method": "GET",
+		"path":   c.Request.URL.Path,
+	}).Info("Incoming request")
+	epic, ok := h.epicFromPath(c)
+	if !ok {
+		return
+	}
+	timeline, err := h.Service.GetEpicTimeline(userID, epic.ID)
+	if err != nil {
+		SendServiceError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, timeline)
+}
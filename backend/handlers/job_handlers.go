@@ -0,0 +1,77 @@
+// Job handlers (background job queue administration)
+package handlers
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/sirupsen/logrus"
+)
+
+// GetJobs lists recent jobs. Query parameters: status (pending, running,
+// succeeded, dead), queue and limit (default 50, at most 500).
+func (h *Handler) GetJobs(c *gin.Context) {
+	userID := c.GetUint("userID")
+	logrus.WithFields(logrus.Fields{
+		"userID": userID,
+		"method": "GET",
+		"path":   c.Request.URL.Path,
+	}).Info("Incoming request")
+	if !CheckSystemAdmin(c, h, userID) {
+		return
+	}
+	limit, err := strconv.Atoi(c.DefaultQuery("limit", "50"))
+	if err != nil || limit < 1 || limit > 500 {
+		SendError(c, http.StatusBadRequest, "limit must be between 1 and 500")
+		return
+	}
+	jobs, err := h.Service.GetJobs(c.Query("status"), c.Query("queue"), limit)
+	if err != nil {
+		SendServiceError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, jobs)
+}
+
+// GetJobStats returns the number of jobs per queue and status
+func (h *Handler) GetJobStats(c *gin.Context) {
+	userID := c.GetUint("userID")
+	logrus.WithFields(logrus.Fields{
+		"userID": userID,
+		"method": "GET",
+		"path":   c.Request.URL.Path,
+	}).Info("Incoming request")
+	if !CheckSystemAdmin(c, h, userID) {
+		return
+	}
+	stats, err := h.Service.GetJobStats()
+	if err != nil {
+		SendServiceError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, stats)
+}
+
+// RetryJob requeues a dead job
+func (h *Handler) RetryJob(c *gin.Context) {
+	userID := c.GetUint("userID")
+	logrus.WithFields(logrus.Fields{
+		"userID": userID,
+		"method": "POST",
+		"path":   c.Request.URL.Path,
+	}).Info("Incoming request")
+	if !CheckSystemAdmin(c, h, userID) {
+		return
+	}
+	jobID, ok := ParseID(c, c.Param("job_id"), "job_id")
+	if !ok {
+		return
+	}
+	job, err := h.Service.RetryJob(jobID)
+	if err != nil {
+		SendServiceError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, job)
+}
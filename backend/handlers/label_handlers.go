@@ -0,0 +1,209 @@
+// Label-related handlers (GetLabels, CreateLabel, etc.)
+package handlers
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/sirupsen/logrus"
+)
+
+func (h *Handler) GetLabels(c *gin.Context) {
+	logrus.WithFields(logrus.Fields{
+		"method": "GET",
+		"path":   c.Request.URL.Path,
+	}).Info("Incoming request")
+	projectID, ok := ParseID(c, c.Param("project_id"), "project_id")
+	if !ok {
+		return
+	}
+	labels, err := h.Service.GetLabelsByProjectID(projectID)
+	if err != nil {
+		logrus.WithFields(logrus.Fields{
+			"projectID": projectID,
+			"error":     err,
+		}).Error("Failed to get labels for project")
+		SendError(c, http.StatusInternalServerError, err.Error())
+		return
+	}
+	c.JSON(http.StatusOK, labels)
+}
+
+func (h *Handler) CreateLabel(c *gin.Context) {
+	userID := c.GetUint("userID")
+	logrus.WithFields(logrus.Fields{
+		"userID": userID,
+		"method": "POST",
+		"path":   c.Request.URL.Path,
+	}).Info("Incoming request")
+	projectID, ok := ParseID(c, c.Param("project_id"), "project_id")
+	if !ok {
+		return
+	}
+	if !CheckProjectPermission(c, h, userID, projectID) {
+		return
+	}
+	var input struct {
+		Name  string `json:"name" binding:"required"`
+		Color string `json:"color"`
+	}
+	if err := c.ShouldBindJSON(&input); err != nil {
+		logrus.WithFields(logrus.Fields{
+			"error": err,
+		}).Warn("Invalid input")
+		SendError(c, http.StatusBadRequest, err.Error())
+		return
+	}
+	label, err := h.Service.CreateLabel(projectID, input.Name, input.Color)
+	if err != nil {
+		logrus.WithFields(logrus.Fields{
+			"projectID": projectID,
+			"error":     err,
+		}).Error("Failed to create label")
+		SendServiceError(c, err)
+		return
+	}
+	c.JSON(http.StatusCreated, label)
+}
+
+func (h *Handler) UpdateLabel(c *gin.Context) {
+	userID := c.GetUint("userID")
+	logrus.WithFields(logrus.Fields{
+		"userID": userID,
+		"method": "PUT",
+		"path":   c.Request.URL.Path,
+	}).Info("Incoming request")
+	projectID, ok := ParseID(c, c.Param("project_id"), "project_id")
+	if !ok {
+		return
+	}
+	labelID, ok := ParseID(c, c.Param("label_id"), "label_id")
+	if !ok {
+		return
+	}
+	if !CheckProjectPermission(c, h, userID, projectID) {
+		return
+	}
+	var input struct {
+		Name  string `json:"name" binding:"required"`
+		Color string `json:"color"`
+	}
+	if err := c.ShouldBindJSON(&input); err != nil {
+		logrus.WithFields(logrus.Fields{
+			"error": err,
+		}).Warn("Invalid input")
+		SendError(c, http.StatusBadRequest, err.Error())
+		return
+	}
+	label, err := h.Service.UpdateLabel(projectID, labelID, input.Name, input.Color)
+	if err != nil {
+		logrus.WithFields(logrus.Fields{
+			"labelID": labelID,
+			"error":   err,
+		}).Error("Failed to update label")
+		SendServiceError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, label)
+}
+
+func (h *Handler) DeleteLabel(c *gin.Context) {
+	userID := c.GetUint("userID")
+	logrus.WithFields(logrus.Fields{
+		"userID": userID,
+		"method": "DELETE",
+		"path":   c.Request.URL.Path,
+	}).Info("Incoming request")
+	projectID, ok := ParseID(c, c.Param("project_id"), "project_id")
+	if !ok {
+		return
+	}
+	labelID, ok := ParseID(c, c.Param("label_id"), "label_id")
+	if !ok {
+		return
+	}
+	if !CheckProjectPermission(c, h, userID, projectID) {
+		return
+	}
+	if err := h.Service.DeleteLabel(projectID, labelID); err != nil {
+		logrus.WithFields(logrus.Fields{
+			"labelID": labelID,
+			"error":   err,
+		}).Error("Failed to delete label")
+		SendServiceError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "label deleted"})
+}
+
+func (h *Handler) AddLabelToTask(c *gin.Context) {
+	userID := c.GetUint("userID")
+	logrus.WithFields(logrus.Fields{
+		"userID": userID,
+		"method": "POST",
+		"path":   c.Request.URL.Path,
+	}).Info("Incoming request")
+	taskID, ok := ParseID(c, c.Param("task_id"), "task_id")
+	if !ok {
+		return
+	}
+	labelID, ok := ParseID(c, c.Param("label_id"), "label_id")
+	if !ok {
+		return
+	}
+	task, err := h.Service.GetTaskByID(taskID)
+	if err != nil {
+		SendError(c, http.StatusNotFound, "task not found")
+		return
+	}
+	if !CheckProjectPermission(c, h, userID, task.ProjectID) {
+		return
+	}
+	task, err = h.Service.AddLabelToTask(userID, taskID, labelID)
+	if err != nil {
+		logrus.WithFields(logrus.Fields{
+			"taskID":  taskID,
+			"labelID": labelID,
+			"error":   err,
+		}).Error("Failed to add label to task")
+		SendServiceError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, task)
+}
+
+func (h *Handler) RemoveLabelFromTask(c *gin.Context) {
+	userID := c.GetUint("userID")
+	logrus.WithFields(logrus.Fields{
+		"userID": userID,
+		"method": "DELETE",
+		"path":   c.Request.URL.Path,
+	}).Info("Incoming request")
+	taskID, ok := ParseID(c, c.Param("task_id"), "task_id")
+	if !ok {
+		return
+	}
+	labelID, ok := ParseID(c, c.Param("label_id"), "label_id")
+	if !ok {
+		return
+	}
+	task, err := h.Service.GetTaskByID(taskID)
+	if err != nil {
+		SendError(c, http.StatusNotFound, "task not found")
+		return
+	}
+	if !CheckProjectPermission(c, h, userID, task.ProjectID) {
+		return
+	}
+	task, err = h.Service.RemoveLabelFromTask(userID, taskID, labelID)
+	if err != nil {
+		logrus.WithFields(logrus.Fields{
+			"taskID":  taskID,
+			"labelID": labelID,
+			"error":   err,
+		}).Error("Failed to remove label from task")
+		SendServiceError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, task)
+}
@@ -0,0 +1,319 @@
+// Milestone handlers (milestones, their progress and release notes)
+package handlers
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+
+	"work-management/dto"
+	"work-management/models"
+
+	"github.com/gin-gonic/gin"
+	"github.com/sirupsen/logrus"
+)
+
+func (h *Handler) GetMilestones(c *gin.Context) {
+	userID := c.GetUint("userID")
+	logrus.WithFields(logrus.Fields{
+		"userID": userID,
+		"method": "GET",
+		"path":   c.Request.URL.Path,
+	}).Info("Incoming request")
+	projectID, ok := ParseID(c, c.Param("project_id"), "project_id")
+	if !ok {
+		return
+	}
+	if !h.Service.IsProjectMember(userID, projectID) {
+		SendError(c, http.StatusForbidden, "insufficient permission")
+		return
+	}
+	milestones, err := h.Service.GetMilestones(projectID)
+	if err != nil {
+		SendServiceError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, milestones)
+}
+
+func (h *Handler) CreateMilestone(c *gin.Context) {
+	userID := c.GetUint("userID")
+	logrus.WithFields(logrus.Fields{
+		"userID": userID,
+		"method": "POST",
+		"path":   c.Request.URL.Path,
+	}).Info("Incoming request")
+	projectID, ok := ParseID(c, c.Param("project_id"), "project_id")
+	if !ok {
+		return
+	}
+	if !CheckProjectPermission(c, h, userID, projectID) {
+		return
+	}
+	var input dto.MilestoneInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		logrus.WithFields(logrus.Fields{
+			"error": err,
+		}).Warn("Invalid input")
+		SendError(c, http.StatusBadRequest, err.Error())
+		return
+	}
+	milestone, err := h.Service.CreateMilestone(projectID, input)
+	if err != nil {
+		SendServiceError(c, err)
+		return
+	}
+	c.JSON(http.StatusCreated, milestone)
+}
+
+// milestoneForMember loads the milestone named in the path and checks the
+// caller belongs to its project
+func (h *Handler) milestoneForMember(c *gin.Context, userID uint) (*models.Milestone, bool) {
+	milestoneID, ok := ParseID(c, c.Param("milestone_id"), "milestone_id")
+	if !ok {
+		return nil, false
+	}
+	milestone, err := h.Service.GetMilestone(milestoneID)
+	if err != nil {
+		SendError(c, http.StatusNotFound, "milestone not found")
+		return nil, false
+	}
+	if !h.Service.IsProjectMember(userID, milestone.ProjectID) {
+		SendError(c, http.StatusForbidden, "insufficient permission")
+		return nil, false
+	}
+	return milestone, true
+}
+
+// milestoneForEditor loads the milestone named in the path and checks the
+// caller may modify its project
+func (h *Handler) milestoneForEditor(c *gin.Context, userID uint) (*models.Milestone, bool) {
+	milestoneID, ok := ParseID(c, c.Param("milestone_id"), "milestone_id")
+	if !ok {
+		return nil, false
+	}
+	milestone, err := h.Service.GetMilestone(milestoneID)
+	if err != nil {
+		SendError(c, http.StatusNotFound, "milestone not found")
+		return nil, false
+	}
+	if !CheckProjectPermission(c, h, userID, milestone.ProjectID) {
+		return nil, false
+	}
+	return milestone, true
+}
+
+// GetMilestone returns a milestone with its progress
+func (h *Handler) GetMilestone(c *gin.Context) {
+	userID := c.GetUint("userID")
+	logrus.WithFields(logrus.Fields{
+		"userID": userID,
+		"method": "GET",
+		"path":   c.Request.URL.Path,
+	}).Info("Incoming request")
+	milestone, ok := h.milestoneForMember(c, userID)
+	if !ok {
+		return
+	}
+	progress, err := h.Service.GetMilestoneProgress(milestone.ID)
+	if err != nil {
+		SendServiceError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, progress)
+}
+
+func (h *Handler) UpdateMilestone(c *gin.Context) {
+	userID := c.GetUint("userID")
+	logrus.WithFields(logrus.Fields{
+		"userID": userID,
+		"method": "PUT",
+		"path":   c.Request.URL.Path,
+	}).Info("Incoming request")
+	milestone, ok := h.milestoneForEditor(c, userID)
+	if !ok {
+		return
+	}
+	var input dto.MilestoneInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		logrus.WithFields(logrus.Fields{
+			"error": err,
+		}).Warn("Invalid input")
+		SendError(c, http.StatusBadRequest, err.Error())
+		return
+	}
+	milestone, err := h.Service.UpdateMilestone(milestone.ID, input)
+	if err != nil {
+		SendServiceError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, milestone)
+}
+
+func (h *Handler) DeleteMilestone(c *gin.Context) {
+	userID := c.GetUint("userID")
+	logrus.WithFields(logrus.Fields{
+		"userID": userID,
+		"method": "DELETE",
+		"path":   c.Request.URL.Path,
+	}).Info("Incoming request")
+	milestone, ok := h.milestoneForEditor(c, userID)
+	if !ok {
+		return
+	}
+	if err := h.Service.DeleteMilestone(milestone.ID); err != nil {
+		SendServiceError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "milestone deleted"})
+}
+
+func (h *Handler) ReleaseMilestone(c *gin.Context) {
+	userID := c.GetUint("userID")
+	logrus.WithFields(logrus.Fields{
+		"userID": userID,
+		"method": "POST",
+		"path":   c.Request.URL.Path,
+	}).Info("Incoming request")
+	milestone, ok := h.milestoneForEditor(c, userID)
+	if !ok {
+		return
+	}
+	milestone, err := h.Service.ReleaseMilestone(userID, milestone.ID)
+	if err != nil {
+		SendServiceError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, milestone)
+}
+
+// GetMilestoneTasks lists the tasks of a milestone; it takes the filters of
+// GetTasks
+func (h *Handler) GetMilestoneTasks(c *gin.Context) {
+	userID := c.GetUint("userID")
+	logrus.WithFields(logrus.Fields{
+		"userID": userID,
+		"method": "GET",
+		"path":   c.Request.URL.Path,
+	}).Info("Incoming request")
+	milestone, ok := h.milestoneForMember(c, userID)
+	if !ok {
+		return
+	}
+	filter, ok := parseTaskFilter(c)
+	if !ok {
+		return
+	}
+	tasks, err := h.Service.GetMilestoneTasks(milestone, filter)
+	if err != nil {
+		SendServiceError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, tasks)
+}
+
+func (h *Handler) AddMilestoneTasks(c *gin.Context) {
+	userID := c.GetUint("userID")
+	logrus.WithFields(logrus.Fields{
+		"userID": userID,
+		"method": "POST",
+		"path":   c.Request.URL.Path,
+	}).Info("Incoming request")
+	milestone, ok := h.milestoneForEditor(c, userID)
+	if !ok {
+		return
+	}
+	var input dto.MilestoneTasksInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		logrus.WithFields(logrus.Fields{
+			"error": err,
+		}).Warn("Invalid input")
+		SendError(c, http.StatusBadRequest, err.Error())
+		return
+	}
+	milestone, err := h.Service.AddMilestoneTasks(userID, milestone.ID, input.TaskIDs)
+	if err != nil {
+		SendServiceError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, milestone)
+}
+
+func (h *Handler) RemoveMilestoneTask(c *gin.Context) {
+	userID := c.GetUint("userID")
+	logrus.WithFields(logrus.Fields{
+		"userID": userID,
+		"method": "DELETE",
+		"path":   c.Request.URL.Path,
+	}).Info("Incoming request")
+	milestone, ok := h.milestoneForEditor(c, userID)
+	if !ok {
+		return
+	}
+	taskID, ok := ParseID(c, c.Param("task_id"), "task_id")
+	if !ok {
+		return
+	}
+	if err := h.Service.RemoveMilestoneTask(userID, milestone.ID, taskID); err != nil {
+		SendServiceError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "task removed from milestone"})
+}
+
+// GetReleaseNotes lists the completed tasks of a milestone grouped by label.
+// Query parameters:
+//   - format: "markdown" to download instead of JSON
+func (h *Handler) GetReleaseNotes(c *gin.Context) {
+	userID := c.GetUint("userID")
+	logrus.WithFields(logrus.Fields{
+		"userID": userID,
+		"method": "GET",
+		"path":   c.Request.URL.Path,
+	}).Info("Incoming request")
+	milestone, ok := h.milestoneForMember(c, userID)
+	if !ok {
+		return
+	}
+	notes, err := h.Service.GetReleaseNotes(milestone.ID)
+	if err != nil {
+		SendServiceError(c, err)
+		return
+	}
+	if c.Query("format") == "markdown" {
+		writeReleaseNotesMarkdown(c, notes)
+		return
+	}
+	c.JSON(http.StatusOK, notes)
+}
+
+func writeReleaseNotesMarkdown(c *gin.Context, notes *dto.ReleaseNotes) {
+	var b strings.Builder
+	milestone := notes.Milestone
+	fmt.Fprintf(&b, "# %s\n\n", milestone.Name)
+	if milestone.ReleasedAt != nil {
+		fmt.Fprintf(&b, "Released on %s\n\n", milestone.ReleasedAt.Format(dateLayout))
+	} else {
+		fmt.Fprintf(&b, "Target date: %s\n\n", milestone.TargetDate.Format(dateLayout))
+	}
+	if description := strings.TrimSpace(milestone.Description); description != "" {
+		fmt.Fprintf(&b, "%s\n\n", description)
+	}
+	if len(notes.Groups) == 0 {
+		b.WriteString("No completed tasks.\n")
+	}
+	for _, group := range notes.Groups {
+		label := group.Label
+		if label == "" {
+			label = "Other"
+		}
+		fmt.Fprintf(&b, "## %s\n\n", label)
+		for _, task := range group.Tasks {
+			fmt.Fprintf(&b, "- %s (#%d)\n", task.Title, task.ID)
+		}
+		b.WriteString("\n")
+	}
+	filename := fmt.Sprintf("release_notes_%d.md", milestone.ID)
+	c.Header("Content-Disposition", "attachment; filename="+filename)
+	c.Data(http.StatusOK, "text/markdown; charset=utf-8", []byte(b.String()))
+}
@@ -0,0 +1,170 @@
+// Notification handlers (inbox and preferences)
+package handlers
+
+import (
+	"net/http"
+	"strconv"
+
+	"work-management/dto"
+
+	"github.com/gin-gonic/gin"
+	"github.com/sirupsen/logrus"
+)
+
+// GetNotifications lists the caller's notifications, newest first.
+// Query parameters: page (from 1), page_size (default 20) and unread=true.
+func (h *Handler) GetNotifications(c *gin.Context) {
+	userID := c.GetUint("userID")
+	logrus.WithFields(logrus.Fields{
+		"userID": userID,
+		"method": "GET",
+		"path":   c.Request.URL.Path,
+	}).Info("Incoming request")
+	page, err := strconv.Atoi(c.DefaultQuery("page", "1"))
+	if err != nil {
+		SendError(c, http.StatusBadRequest, "invalid page")
+		return
+	}
+	pageSize, err := strconv.Atoi(c.DefaultQuery("page_size", "20"))
+	if err != nil {
+		SendError(c, http.StatusBadRequest, "invalid page_size")
+		return
+	}
+	unreadOnly := c.Query("unread") == "true"
+	result, err := h.Service.GetNotifications(userID, unreadOnly, page, pageSize)
+	if err != nil {
+		SendServiceError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, result)
+}
+
+func (h *Handler) GetUnreadNotificationCount(c *gin.Context) {
+	userID := c.GetUint("userID")
+	logrus.WithFields(logrus.Fields{
+		"userID": userID,
+		"method": "GET",
+		"path":   c.Request.URL.Path,
+	}).Info("Incoming request")
+	count, err := h.Service.CountUnreadNotifications(userID)
+	if err != nil {
+		SendServiceError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"unread": count})
+}
+
+func (h *Handler) MarkNotificationRead(c *gin.Context) {
+	userID := c.GetUint("userID")
+	logrus.WithFields(logrus.Fields{
+		"userID": userID,
+		"method": "POST",
+		"path":   c.Request.URL.Path,
+	}).Info("Incoming request")
+	notificationID, ok := ParseID(c, c.Param("notification_id"), "notification_id")
+	if !ok {
+		return
+	}
+	if err := h.Service.MarkNotificationRead(userID, notificationID); err != nil {
+		SendServiceError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "notification marked as read"})
+}
+
+func (h *Handler) MarkAllNotificationsRead(c *gin.Context) {
+	userID := c.GetUint("userID")
+	logrus.WithFields(logrus.Fields{
+		"userID": userID,
+		"method": "POST",
+		"path":   c.Request.URL.Path,
+	}).Info("Incoming request")
+	count, err := h.Service.MarkAllNotificationsRead(userID)
+	if err != nil {
+		SendServiceError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"marked": count})
+}
+
+func (h *Handler) GetNotificationPreferences(c *gin.Context) {
+	userID := c.GetUint("userID")
+	logrus.WithFields(logrus.Fields{
+		"userID": userID,
+		"method": "GET",
+		"path":   c.Request.URL.Path,
+	}).Info("Incoming request")
+	preferences, err := h.Service.GetNotificationPreferences(userID)
+	if err != nil {
+		SendServiceError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, preferences)
+}
+
+// UpdateNotificationPreferences accepts a list of {"event_type", "in_app",
+// "email"} settings; types and channels not given are left unchanged.
+func (h *Handler) UpdateNotificationPreferences(c *gin.Context) {
+	userID := c.GetUint("userID")
+	logrus.WithFields(logrus.Fields{
+		"userID": userID,
+		"method": "PUT",
+		"path":   c.Request.URL.Path,
+	}).Info("Incoming request")
+	var input []dto.NotificationPreferenceInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		logrus.WithFields(logrus.Fields{
+			"error": err,
+		}).Warn("Invalid input")
+		SendError(c, http.StatusBadRequest, err.Error())
+		return
+	}
+	preferences, err := h.Service.UpdateNotificationPreferences(userID, input)
+	if err != nil {
+		SendServiceError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, preferences)
+}
+
+func (h *Handler) GetDigestSubscription(c *gin.Context) {
+	userID := c.GetUint("userID")
+	logrus.WithFields(logrus.Fields{
+		"userID": userID,
+		"method": "GET",
+		"path":   c.Request.URL.Path,
+	}).Info("Incoming request")
+	subscription, err := h.Service.GetDigestSubscription(userID)
+	if err != nil {
+		SendServiceError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, subscription)
+}
+
+// UpdateDigestSubscription opts the caller in or out of the activity digest
+// email with {"frequency": "daily" | "weekly" | "off"}
+func (h *Handler) UpdateDigestSubscription(c *gin.Context) {
+	userID := c.GetUint("userID")
+	logrus.WithFields(logrus.Fields{
+		"userID": userID,
+		"method": "PUT",
+		"path":   c.Request.URL.Path,
+	}).Info("Incoming request")
+	var input struct {
+		Frequency string `json:"frequency" binding:"required"`
+	}
+	if err := c.ShouldBindJSON(&input); err != nil {
+		logrus.WithFields(logrus.Fields{
+			"error": err,
+		}).Warn("Invalid input")
+		SendError(c, http.StatusBadRequest, err.Error())
+		return
+	}
+	subscription, err := h.Service.UpdateDigestSubscription(userID, input.Frequency)
+	if err != nil {
+		SendServiceError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, subscription)
+}
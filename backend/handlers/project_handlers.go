@@ -51,7 +51,9 @@ func (h *Handler) GetProjects(c *gin.Context) {
 		"method": "GET",
 		"path":   "/projects",
 	}).Info("Incoming request")
-	projects, err := h.Service.GetProjects(userID)
+	// Archived projects are only listed when asked for
+	archived := c.Query("archived") == "true"
+	projects, err := h.Service.GetProjects(userID, archived)
 	if err != nil {
 		logrus.WithFields(logrus.Fields{
 			"userID": userID,
@@ -116,13 +118,7 @@ func (h *Handler) UpdateProject(c *gin.Context) {
 		return
 	}
 
-	canModify, err := h.Service.CanModifyProject(userID, uint(projectID))
-	if err != nil || !canModify {
-		logrus.WithFields(logrus.Fields{
-			"userID":    userID,
-			"projectID": projectID,
-		}).Warn("Insufficient permission")
-		SendError(c, http.StatusForbidden, "insufficient permission")
+	if !CheckProjectPermission(c, h, userID, uint(projectID)) {
 		return
 	}
 
@@ -139,7 +135,7 @@ func (h *Handler) UpdateProject(c *gin.Context) {
 		SendError(c, http.StatusBadRequest, err.Error())
 		return
 	}
-	project, err := h.Service.UpdateProject(uint(projectID), input.Name, input.Description, input.Category, input.Status)
+	project, err := h.Service.UpdateProject(userID, uint(projectID), input.Name, input.Description, input.Category, input.Status)
 	if err != nil {
 		logrus.WithFields(logrus.Fields{
 			"projectID": projectID,
@@ -172,13 +168,7 @@ func (h *Handler) ToggleFavorite(c *gin.Context) {
 		return
 	}
 
-	canModify, err := h.Service.CanModifyProject(userID, uint(projectID))
-	if err != nil || !canModify {
-		logrus.WithFields(logrus.Fields{
-			"userID":    userID,
-			"projectID": projectID,
-		}).Warn("Insufficient permission")
-		SendError(c, http.StatusForbidden, "insufficient permission")
+	if !CheckProjectPermission(c, h, userID, uint(projectID)) {
 		return
 	}
 
@@ -227,17 +217,12 @@ func (h *Handler) DeleteProject(c *gin.Context) {
 		return
 	}
 
-	canModify, err := h.Service.CanModifyProject(userID, uint(projectID))
-	if err != nil || !canModify {
-		logrus.WithFields(logrus.Fields{
-			"userID":    userID,
-			"projectID": projectID,
-		}).Warn("Insufficient permission")
-		SendError(c, http.StatusForbidden, "insufficient permission")
+	// Deleting moves the project to the trash, which only admins may do
+	if !CheckProjectAdmin(c, h, userID, uint(projectID)) {
 		return
 	}
 
-	if err := h.Service.DeleteProject(uint(projectID)); err != nil {
+	if err := h.Service.DeleteProject(userID, uint(projectID)); err != nil {
 		logrus.WithFields(logrus.Fields{
 			"projectID": projectID,
 			"error":     err,
@@ -247,8 +232,8 @@ func (h *Handler) DeleteProject(c *gin.Context) {
 	}
 	logrus.WithFields(logrus.Fields{
 		"projectID": projectID,
-	}).Info("Project deleted successfully")
-	c.JSON(http.StatusOK, gin.H{"message": "project deleted"})
+	}).Info("Project moved to the trash")
+	c.JSON(http.StatusOK, gin.H{"message": "project moved to the trash"})
 }
 
 func (h *Handler) AddUserToProject(c *gin.Context) {
@@ -280,17 +265,11 @@ func (h *Handler) AddUserToProject(c *gin.Context) {
 		return
 	}
 
-	canModify, err := h.Service.CanModifyProject(userID, uint(projectID))
-	if err != nil || !canModify {
-		logrus.WithFields(logrus.Fields{
-			"userID":    userID,
-			"projectID": projectID,
-		}).Warn("Insufficient permission")
-		SendError(c, http.StatusForbidden, "insufficient permission")
+	if !CheckProjectPermission(c, h, userID, uint(projectID)) {
 		return
 	}
 
-	if err := h.Service.AddUserToProject(input.UserID, uint(projectID), input.Role); err != nil {
+	if err := h.Service.AddUserToProject(userID, input.UserID, uint(projectID), input.Role); err != nil {
 		logrus.WithFields(logrus.Fields{
 			"projectID": projectID,
 			"userID":    input.UserID,
@@ -346,17 +325,11 @@ func (h *Handler) UpdateUserRole(c *gin.Context) {
 		return
 	}
 
-	canModify, err := h.Service.CanModifyProject(userID, uint(projectID))
-	if err != nil || !canModify {
-		logrus.WithFields(logrus.Fields{
-			"userID":    userID,
-			"projectID": projectID,
-		}).Warn("Insufficient permission")
-		SendError(c, http.StatusForbidden, "insufficient permission")
+	if !CheckProjectPermission(c, h, userID, uint(projectID)) {
 		return
 	}
 
-	if err := h.Service.UpdateUserRole(uint(targetUserID), uint(projectID), input.Role); err != nil {
+	if err := h.Service.UpdateUserRole(userID, uint(targetUserID), uint(projectID), input.Role); err != nil {
 		logrus.WithFields(logrus.Fields{
 			"projectID": projectID,
 			"userID":    targetUserID,
@@ -402,17 +375,11 @@ func (h *Handler) RemoveUserFromProject(c *gin.Context) {
 		return
 	}
 
-	canModify, err := h.Service.CanModifyProject(userID, uint(projectID))
-	if err != nil || !canModify {
-		logrus.WithFields(logrus.Fields{
-			"userID":    userID,
-			"projectID": projectID,
-		}).Warn("Insufficient permission")
-		SendError(c, http.StatusForbidden, "insufficient permission")
+	if !CheckProjectPermission(c, h, userID, uint(projectID)) {
 		return
 	}
 
-	if err := h.Service.RemoveUserFromProject(uint(targetUserID), uint(projectID)); err != nil {
+	if err := h.Service.RemoveUserFromProject(userID, uint(targetUserID), uint(projectID)); err != nil {
 		logrus.WithFields(logrus.Fields{
 			"projectID": projectID,
 			"userID":    targetUserID,
@@ -446,14 +413,7 @@ func (h *Handler) ChangeProjectOwner(c *gin.Context) {
 		return
 	}
 
-	// Check if the user is an admin
-	isAdmin, err := h.Service.AdminOnly(userID, uint(projectID))
-	if err != nil || !isAdmin {
-		logrus.WithFields(logrus.Fields{
-			"userID":    userID,
-			"projectID": projectID,
-		}).Warn("Insufficient permission - admin only")
-		SendError(c, http.StatusForbidden, "insufficient permission - admin only")
+	if !CheckProjectAdminWritable(c, h, userID, uint(projectID)) {
 		return
 	}
 
@@ -468,7 +428,7 @@ func (h *Handler) ChangeProjectOwner(c *gin.Context) {
 		return
 	}
 
-	project, err := h.Service.ChangeProjectOwner(uint(projectID), input.NewOwnerID)
+	project, err := h.Service.ChangeProjectOwner(userID, uint(projectID), input.NewOwnerID)
 	if err != nil {
 		logrus.WithFields(logrus.Fields{
 			"projectID":  projectID,
@@ -0,0 +1,55 @@
+// Realtime handlers (WebSocket event stream)
+package handlers
+
+import (
+	"net/http"
+
+	"work-management/realtime"
+
+	"github.com/gin-gonic/gin"
+	"github.com/sirupsen/logrus"
+	"golang.org/x/net/websocket"
+)
+
+// CreateWebSocketTicket issues the single-use ticket a client passes as the
+// ticket query parameter when it opens the WebSocket
+func (h *Handler) CreateWebSocketTicket(c *gin.Context) {
+	userID := c.GetUint("userID")
+	logrus.WithFields(logrus.Fields{
+		"userID": userID,
+		"method": "POST",
+		"path":   c.Request.URL.Path,
+	}).Info("Incoming request")
+	ticket, err := h.Service.Realtime.IssueTicket(userID)
+	if err != nil {
+		logrus.WithFields(logrus.Fields{
+			"error": err,
+		}).Error("Failed to issue WebSocket ticket")
+		SendError(c, http.StatusInternalServerError, "failed to issue ticket")
+		return
+	}
+	c.JSON(http.StatusCreated, gin.H{
+		"ticket":     ticket,
+		"expires_in": int(realtime.TicketTTL.Seconds()),
+	})
+}
+
+// ServeWebSocket upgrades the request to a WebSocket connection on which the
+// user receives the events of their projects as they happen, in the same
+// JSON form as webhook payloads. The handshake is authenticated by a ticket
+// from CreateWebSocketTicket rather than by the access token.
+func (h *Handler) ServeWebSocket(c *gin.Context) {
+	logrus.WithFields(logrus.Fields{
+		"method": "GET",
+		"path":   c.Request.URL.Path,
+	}).Info("Incoming request")
+	userID, ok := h.Service.Realtime.RedeemTicket(c.Query("ticket"))
+	if !ok {
+		logrus.Warn("Invalid WebSocket ticket")
+		SendError(c, http.StatusUnauthorized, "invalid or expired ticket")
+		return
+	}
+	websocket.Handler(func(conn *websocket.Conn) {
+		h.Service.Realtime.Serve(userID, conn)
+	}).ServeHTTP(c.Writer, c.Request)
+}
@@ -0,0 +1,76 @@
+// Recurrence handlers (recurring tasks and their series)
+package handlers
+
+import (
+	"net/http"
+
+	"work-management/dto"
+
+	"github.com/gin-gonic/gin"
+	"github.com/sirupsen/logrus"
+)
+
+func (h *Handler) GetTaskRecurrence(c *gin.Context) {
+	logrus.WithFields(logrus.Fields{
+		"method": "GET",
+		"path":   c.Request.URL.Path,
+	}).Info("Incoming request")
+	taskID, ok := ParseID(c, c.Param("task_id"), "task_id")
+	if !ok {
+		return
+	}
+	series, err := h.Service.GetTaskRecurrence(taskID)
+	if err != nil {
+		SendServiceError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, series)
+}
+
+// SetTaskRecurrence makes a task recurring or edits the series it belongs
+// to. Editing a single occurrence goes through PUT /tasks/:task_id as usual.
+func (h *Handler) SetTaskRecurrence(c *gin.Context) {
+	userID := c.GetUint("userID")
+	logrus.WithFields(logrus.Fields{
+		"userID": userID,
+		"method": "PUT",
+		"path":   c.Request.URL.Path,
+	}).Info("Incoming request")
+	taskID, ok := h.taskForEditor(c, userID)
+	if !ok {
+		return
+	}
+	var input dto.RecurrenceInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		logrus.WithFields(logrus.Fields{
+			"error": err,
+		}).Warn("Invalid input")
+		SendError(c, http.StatusBadRequest, err.Error())
+		return
+	}
+	series, err := h.Service.SetTaskRecurrence(userID, taskID, input)
+	if err != nil {
+		SendServiceError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, series)
+}
+
+// StopTaskRecurrence ends a task's series; its occurrences are kept
+func (h *Handler) StopTaskRecurrence(c *gin.Context) {
+	userID := c.GetUint("userID")
+	logrus.WithFields(logrus.Fields{
+		"userID": userID,
+		"method": "DELETE",
+		"path":   c.Request.URL.Path,
+	}).Info("Incoming request")
+	taskID, ok := h.taskForEditor(c, userID)
+	if !ok {
+		return
+	}
+	if err := h.Service.StopTaskRecurrence(userID, taskID); err != nil {
+		SendServiceError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "recurrence stopped"})
+}
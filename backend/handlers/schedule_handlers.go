@@ -0,0 +1,143 @@
+// Schedule handlers (task dependencies, Gantt timelines and rescheduling)
+package handlers
+
+import (
+	"net/http"
+
+	"work-management/dto"
+
+	"github.com/gin-gonic/gin"
+	"github.com/sirupsen/logrus"
+)
+
+// GetGantt returns a project's plan in the shape of a Gantt chart: dated
+// tasks, their dependencies and the milestones
+func (h *Handler) GetGantt(c *gin.Context) {
+	userID := c.GetUint("userID")
+	logrus.WithFields(logrus.Fields{
+		"userID": userID,
+		"method": "GET",
+		"path":   c.Request.URL.Path,
+	}).Info("Incoming request")
+	projectID, ok := ParseID(c, c.Param("project_id"), "project_id")
+	if !ok {
+		return
+	}
+	if !h.Service.IsProjectMember(userID, projectID) {
+		SendError(c, http.StatusForbidden, "insufficient permission")
+		return
+	}
+	gantt, err := h.Service.GetGantt(projectID)
+	if err != nil {
+		SendServiceError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gantt)
+}
+
+// GetTaskDependencies lists what a task waits for and what waits for it
+func (h *Handler) GetTaskDependencies(c *gin.Context) {
+	userID := c.GetUint("userID")
+	logrus.WithFields(logrus.Fields{
+		"userID": userID,
+		"method": "GET",
+		"path":   c.Request.URL.Path,
+	}).Info("Incoming request")
+	taskID, ok := ParseID(c, c.Param("task_id"), "task_id")
+	if !ok {
+		return
+	}
+	task, err := h.Service.GetTaskByID(taskID)
+	if err != nil {
+		SendError(c, http.StatusNotFound, "task not found")
+		return
+	}
+	if !h.Service.IsProjectMember(userID, task.ProjectID) {
+		SendError(c, http.StatusForbidden, "insufficient permission")
+		return
+	}
+	dependencies, err := h.Service.GetTaskDependencies(taskID)
+	if err != nil {
+		SendServiceError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, dependencies)
+}
+
+func (h *Handler) AddDependency(c *gin.Context) {
+	userID := c.GetUint("userID")
+	logrus.WithFields(logrus.Fields{
+		"userID": userID,
+		"method": "POST",
+		"path":   c.Request.URL.Path,
+	}).Info("Incoming request")
+	taskID, ok := h.taskForEditor(c, userID)
+	if !ok {
+		return
+	}
+	var input dto.DependencyInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		logrus.WithFields(logrus.Fields{
+			"error": err,
+		}).Warn("Invalid input")
+		SendError(c, http.StatusBadRequest, err.Error())
+		return
+	}
+	dependency, err := h.Service.AddDependency(userID, taskID, input)
+	if err != nil {
+		SendServiceError(c, err)
+		return
+	}
+	c.JSON(http.StatusCreated, dependency)
+}
+
+func (h *Handler) RemoveDependency(c *gin.Context) {
+	userID := c.GetUint("userID")
+	logrus.WithFields(logrus.Fields{
+		"userID": userID,
+		"method": "DELETE",
+		"path":   c.Request.URL.Path,
+	}).Info("Incoming request")
+	taskID, ok := h.taskForEditor(c, userID)
+	if !ok {
+		return
+	}
+	dependsOnID, ok := ParseID(c, c.Param("depends_on_id"), "depends_on_id")
+	if !ok {
+		return
+	}
+	if err := h.Service.RemoveDependency(userID, taskID, dependsOnID); err != nil {
+		SendServiceError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "dependency removed"})
+}
+
+// ScheduleTask moves a task's dates and pushes back the tasks waiting for
+// it; with dry_run it only previews the changes
+func (h *Handler) ScheduleTask(c *gin.Context) {
+	userID := c.GetUint("userID")
+	logrus.WithFields(logrus.Fields{
+		"userID": userID,
+		"method": "POST",
+		"path":   c.Request.URL.Path,
+	}).Info("Incoming request")
+	taskID, ok := h.taskForEditor(c, userID)
+	if !ok {
+		return
+	}
+	var input dto.ScheduleInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		logrus.WithFields(logrus.Fields{
+			"error": err,
+		}).Warn("Invalid input")
+		SendError(c, http.StatusBadRequest, err.Error())
+		return
+	}
+	result, err := h.Service.ScheduleTask(userID, taskID, input)
+	if err != nil {
+		SendServiceError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, result)
+}
@@ -0,0 +1,334 @@
+// Sprint handlers (sprint planning, start and close-out, reports)
+package handlers
+
+import (
+	"net/http"
+
+	"work-management/dto"
+	"work-management/models"
+
+	"github.com/gin-gonic/gin"
+	"github.com/sirupsen/logrus"
+)
+
+func (h *Handler) GetSprints(c *gin.Context) {
+	userID := c.GetUint("userID")
+	logrus.WithFields(logrus.Fields{
+		"userID": userID,
+		"method": "GET",
+		"path":   c.Request.URL.Path,
+	}).Info("Incoming request")
+	projectID, ok := ParseID(c, c.Param("project_id"), "project_id")
+	if !ok {
+		return
+	}
+	if !h.Service.IsProjectMember(userID, projectID) {
+		SendError(c, http.StatusForbidden, "insufficient permission")
+		return
+	}
+	sprints, err := h.Service.GetSprints(projectID)
+	if err != nil {
+		SendServiceError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, sprints)
+}
+
+func (h *Handler) CreateSprint(c *gin.Context) {
+	userID := c.GetUint("userID")
+	logrus.WithFields(logrus.Fields{
+		"userID": userID,
+		"method": "POST",
+		"path":   c.Request.URL.Path,
+	}).Info("Incoming request")
+	projectID, ok := ParseID(c, c.Param("project_id"), "project_id")
+	if !ok {
+		return
+	}
+	if !CheckProjectPermission(c, h, userID, projectID) {
+		return
+	}
+	var input dto.SprintInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		logrus.WithFields(logrus.Fields{
+			"error": err,
+		}).Warn("Invalid input")
+		SendError(c, http.StatusBadRequest, err.Error())
+		return
+	}
+	sprint, err := h.Service.CreateSprint(projectID, input)
+	if err != nil {
+		SendServiceError(c, err)
+		return
+	}
+	c.JSON(http.StatusCreated, sprint)
+}
+
+// GetBacklog lists the open tasks of a project that are not planned into a
+// sprint; it takes the filters of GetTasks
+func (h *Handler) GetBacklog(c *gin.Context) {
+	userID := c.GetUint("userID")
+	logrus.WithFields(logrus.Fields{
+		"userID": userID,
+		"method": "GET",
+		"path":   c.Request.URL.Path,
+	}).Info("Incoming request")
+	projectID, ok := ParseID(c, c.Param("project_id"), "project_id")
+	if !ok {
+		return
+	}
+	if !h.Service.IsProjectMember(userID, projectID) {
+		SendError(c, http.StatusForbidden, "insufficient permission")
+		return
+	}
+	filter, ok := parseTaskFilter(c)
+	if !ok {
+		return
+	}
+	tasks, err := h.Service.GetBacklog(projectID, filter)
+	if err != nil {
+		SendServiceError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, tasks)
+}
+
+// sprintForMember loads the sprint named in the path and checks the caller
+// belongs to its project
+func (h *Handler) sprintForMember(c *gin.Context, userID uint) (*models.Sprint, bool) {
+	sprintID, ok := ParseID(c, c.Param("sprint_id"), "sprint_id")
+	if !ok {
+		return nil, false
+	}
+	sprint, err := h.Service.GetSprint(sprintID)
+	if err != nil {
+		SendError(c, http.StatusNotFound, "sprint not found")
+		return nil, false
+	}
+	if !h.Service.IsProjectMember(userID, sprint.ProjectID) {
+		SendError(c, http.StatusForbidden, "insufficient permission")
+		return nil, false
+	}
+	return sprint, true
+}
+
+// sprintForEditor loads the sprint named in the path and checks the caller
+// may modify its project
+func (h *Handler) sprintForEditor(c *gin.Context, userID uint) (*models.Sprint, bool) {
+	sprintID, ok := ParseID(c, c.Param("sprint_id"), "sprint_id")
+	if !ok {
+		return nil, false
+	}
+	sprint, err := h.Service.GetSprint(sprintID)
+	if err != nil {
+		SendError(c, http.StatusNotFound, "sprint not found")
+		return nil, false
+	}
+	if !CheckProjectPermission(c, h, userID, sprint.ProjectID) {
+		return nil, false
+	}
+	return sprint, true
+}
+
+func (h *Handler) GetSprint(c *gin.Context) {
+	userID := c.GetUint("userID")
+	logrus.WithFields(logrus.Fields{
+		"userID": userID,
+		"method": "GET",
+		"path":   c.Request.URL.Path,
+	}).Info("Incoming request")
+	sprint, ok := h.sprintForMember(c, userID)
+	if !ok {
+		return
+	}
+	c.JSON(http.StatusOK, sprint)
+}
+
+func (h *Handler) UpdateSprint(c *gin.Context) {
+	userID := c.GetUint("userID")
+	logrus.WithFields(logrus.Fields{
+		"userID": userID,
+		"method": "PUT",
+		"path":   c.Request.URL.Path,
+	}).Info("Incoming request")
+	sprint, ok := h.sprintForEditor(c, userID)
+	if !ok {
+		return
+	}
+	var input dto.SprintInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		logrus.WithFields(logrus.Fields{
+			"error": err,
+		}).Warn("Invalid input")
+		SendError(c, http.StatusBadRequest, err.Error())
+		return
+	}
+	sprint, err := h.Service.UpdateSprint(sprint.ID, input)
+	if err != nil {
+		SendServiceError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, sprint)
+}
+
+func (h *Handler) DeleteSprint(c *gin.Context) {
+	userID := c.GetUint("userID")
+	logrus.WithFields(logrus.Fields{
+		"userID": userID,
+		"method": "DELETE",
+		"path":   c.Request.URL.Path,
+	}).Info("Incoming request")
+	sprint, ok := h.sprintForEditor(c, userID)
+	if !ok {
+		return
+	}
+	if err := h.Service.DeleteSprint(sprint.ID); err != nil {
+		SendServiceError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "sprint deleted"})
+}
+
+// GetSprintTasks lists the tasks planned into a sprint; it takes the filters
+// of GetTasks
+func (h *Handler) GetSprintTasks(c *gin.Context) {
+	userID := c.GetUint("userID")
+	logrus.WithFields(logrus.Fields{
+		"userID": userID,
+		"method": "GET",
+		"path":   c.Request.URL.Path,
+	}).Info("Incoming request")
+	sprint, ok := h.sprintForMember(c, userID)
+	if !ok {
+		return
+	}
+	filter, ok := parseTaskFilter(c)
+	if !ok {
+		return
+	}
+	tasks, err := h.Service.GetSprintTasks(sprint, filter)
+	if err != nil {
+		SendServiceError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, tasks)
+}
+
+func (h *Handler) AddSprintTasks(c *gin.Context) {
+	userID := c.GetUint("userID")
+	logrus.WithFields(logrus.Fields{
+		"userID": userID,
+		"method": "POST",
+		"path":   c.Request.URL.Path,
+	}).Info("Incoming request")
+	sprint, ok := h.sprintForEditor(c, userID)
+	if !ok {
+		return
+	}
+	var input dto.SprintTasksInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		logrus.WithFields(logrus.Fields{
+			"error": err,
+		}).Warn("Invalid input")
+		SendError(c, http.StatusBadRequest, err.Error())
+		return
+	}
+	sprint, err := h.Service.AddSprintTasks(userID, sprint.ID, input.TaskIDs)
+	if err != nil {
+		SendServiceError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, sprint)
+}
+
+// RemoveSprintTask moves a task of a sprint back to the backlog
+func (h *Handler) RemoveSprintTask(c *gin.Context) {
+	userID := c.GetUint("userID")
+	logrus.WithFields(logrus.Fields{
+		"userID": userID,
+		"method": "DELETE",
+		"path":   c.Request.URL.Path,
+	}).Info("Incoming request")
+	sprint, ok := h.sprintForEditor(c, userID)
+	if !ok {
+		return
+	}
+	taskID, ok := ParseID(c, c.Param("task_id"), "task_id")
+	if !ok {
+		return
+	}
+	if err := h.Service.RemoveSprintTask(userID, sprint.ID, taskID); err != nil {
+		SendServiceError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "task moved to the backlog"})
+}
+
+func (h *Handler) StartSprint(c *gin.Context) {
+	userID := c.GetUint("userID")
+	logrus.WithFields(logrus.Fields{
+		"userID": userID,
+		"method": "POST",
+		"path":   c.Request.URL.Path,
+	}).Info("Incoming request")
+	sprint, ok := h.sprintForEditor(c, userID)
+	if !ok {
+		return
+	}
+	sprint, err := h.Service.StartSprint(userID, sprint.ID)
+	if err != nil {
+		SendServiceError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, sprint)
+}
+
+// CloseSprint closes the active sprint; unfinished tasks are carried over as
+// described by dto.CloseSprintInput. The body is optional.
+func (h *Handler) CloseSprint(c *gin.Context) {
+	userID := c.GetUint("userID")
+	logrus.WithFields(logrus.Fields{
+		"userID": userID,
+		"method": "POST",
+		"path":   c.Request.URL.Path,
+	}).Info("Incoming request")
+	sprint, ok := h.sprintForEditor(c, userID)
+	if !ok {
+		return
+	}
+	var input dto.CloseSprintInput
+	if c.Request.ContentLength != 0 {
+		if err := c.ShouldBindJSON(&input); err != nil {
+			logrus.WithFields(logrus.Fields{
+				"error": err,
+			}).Warn("Invalid input")
+			SendError(c, http.StatusBadRequest, err.Error())
+			return
+		}
+	}
+	sprint, err := h.Service.CloseSprint(userID, sprint.ID, input)
+	if err != nil {
+		SendServiceError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, sprint)
+}
+
+func (h *Handler) GetSprintReport(c *gin.Context) {
+	userID := c.GetUint("userID")
+	logrus.WithFields(logrus.Fields{
+		"userID": userID,
+		"method": "GET",
+		"path":   c.Request.URL.Path,
+	}).Info("Incoming request")
+	sprint, ok := h.sprintForMember(c, userID)
+	if !ok {
+		return
+	}
+	report, err := h.Service.GetSprintReport(sprint.ID)
+	if err != nil {
+		SendServiceError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, report)
+}
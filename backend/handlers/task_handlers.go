@@ -3,8 +3,11 @@ package handlers
 
 import (
 	"net/http"
+	"net/url"
 	"strconv"
-	"time"
+	"strings"
+
+	"work-management/dto"
 
 	"github.com/gin-gonic/gin"
 	"github.com/sirupsen/logrus"
@@ -15,12 +18,16 @@ func (h *Handler) GetTasks(c *gin.Context) {
 		"method": "GET",
 		"path":   "/tasks",
 	}).Info("Incoming request")
-	tasks, err := h.Service.GetTasks()
+	filter, ok := parseTaskFilter(c)
+	if !ok {
+		return
+	}
+	tasks, err := h.Service.GetTasks(filter)
 	if err != nil {
 		logrus.WithFields(logrus.Fields{
 			"error": err,
 		}).Error("Failed to get tasks")
-		SendError(c, http.StatusInternalServerError, err.Error())
+		SendServiceError(c, err)
 		return
 	}
 	c.JSON(http.StatusOK, tasks)
@@ -53,6 +60,24 @@ func (h *Handler) GetTask(c *gin.Context) {
 	c.JSON(http.StatusOK, task)
 }
 
+// GetSubtasks lists the direct subtasks of a task
+func (h *Handler) GetSubtasks(c *gin.Context) {
+	logrus.WithFields(logrus.Fields{
+		"method": "GET",
+		"path":   c.Request.URL.Path,
+	}).Info("Incoming request")
+	taskID, ok := ParseID(c, c.Param("task_id"), "task_id")
+	if !ok {
+		return
+	}
+	tasks, err := h.Service.GetSubtasks(taskID)
+	if err != nil {
+		SendServiceError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, tasks)
+}
+
 func (h *Handler) CreateTask(c *gin.Context) {
 	userID := c.GetUint("userID")
 	logrus.WithFields(logrus.Fields{
@@ -60,14 +85,7 @@ func (h *Handler) CreateTask(c *gin.Context) {
 		"method": "POST",
 		"path":   "/tasks",
 	}).Info("Incoming request")
-	var input struct {
-		Title       string    `json:"title" binding:"required"`
-		Description string    `json:"description"`
-		ProjectID   uint      `json:"project_id" binding:"required"`
-		UserID      uint      `json:"user_id" binding:"required"`
-		Status      string    `json:"status" binding:"required"`
-		DueDate     time.Time `json:"due_date" binding:"required"`
-	}
+	var input dto.TaskInput
 	if err := c.ShouldBindJSON(&input); err != nil {
 		logrus.WithFields(logrus.Fields{
 			"error": err,
@@ -81,12 +99,12 @@ func (h *Handler) CreateTask(c *gin.Context) {
 		return
 	}
 
-	task, err := h.Service.CreateTask(input.Title, input.Description, input.ProjectID, input.UserID, input.Status, input.DueDate)
+	task, err := h.Service.CreateTask(userID, input)
 	if err != nil {
 		logrus.WithFields(logrus.Fields{
 			"error": err,
 		}).Error("Failed to create task")
-		SendError(c, http.StatusInternalServerError, err.Error())
+		SendServiceError(c, err)
 		return
 	}
 	logrus.WithFields(logrus.Fields{
@@ -109,14 +127,7 @@ func (h *Handler) UpdateTask(c *gin.Context) {
 	if !ok {
 		return
 	}
-	var input struct {
-		Title       string    `json:"title" binding:"required"`
-		Description string    `json:"description"`
-		ProjectID   uint      `json:"project_id" binding:"required"`
-		UserID      uint      `json:"user_id" binding:"required"`
-		Status      string    `json:"status" binding:"required"`
-		DueDate     time.Time `json:"due_date" binding:"required"`
-	}
+	var input dto.TaskInput
 	if err := c.ShouldBindJSON(&input); err != nil {
 		logrus.WithFields(logrus.Fields{
 			"error": err,
@@ -125,18 +136,27 @@ func (h *Handler) UpdateTask(c *gin.Context) {
 		return
 	}
 
-	// Integration: Check user permissions before updating a task
-	if !CheckProjectPermission(c, h, userID, input.ProjectID) {
+	// The caller must be able to modify the task's project and, when the
+	// task moves, the project it moves to
+	task, err := h.Service.GetTaskByID(taskID)
+	if err != nil {
+		SendError(c, http.StatusNotFound, "task not found")
+		return
+	}
+	if !CheckProjectPermission(c, h, userID, task.ProjectID) {
+		return
+	}
+	if input.ProjectID != task.ProjectID && !CheckProjectPermission(c, h, userID, input.ProjectID) {
 		return
 	}
 
-	task, err := h.Service.UpdateTask(taskID, input.Title, input.Description, input.ProjectID, input.UserID, input.Status, input.DueDate)
+	task, err = h.Service.UpdateTask(userID, taskID, input)
 	if err != nil {
 		logrus.WithFields(logrus.Fields{
 			"taskID": taskID,
 			"error":  err,
 		}).Error("Failed to update task")
-		SendError(c, http.StatusInternalServerError, err.Error())
+		SendServiceError(c, err)
 		return
 	}
 	logrus.WithFields(logrus.Fields{
@@ -174,17 +194,11 @@ func (h *Handler) DeleteTask(c *gin.Context) {
 	}
 
 	// Integration: Check user permissions before deleting a task
-	canModify, err := h.Service.CanModifyProject(userID, task.ProjectID)
-	if err != nil || !canModify {
-		logrus.WithFields(logrus.Fields{
-			"userID":    userID,
-			"projectID": task.ProjectID,
-		}).Warn("Insufficient permission")
-		SendError(c, http.StatusForbidden, "insufficient permission")
+	if !CheckProjectPermission(c, h, userID, task.ProjectID) {
 		return
 	}
 
-	if err := h.Service.DeleteTask(uint(taskID)); err != nil {
+	if err := h.Service.DeleteTask(userID, uint(taskID)); err != nil {
 		logrus.WithFields(logrus.Fields{
 			"taskID": taskID,
 			"error":  err,
@@ -194,12 +208,16 @@ func (h *Handler) DeleteTask(c *gin.Context) {
 	}
 	logrus.WithFields(logrus.Fields{
 		"taskID": taskID,
-	}).Info("Task deleted successfully")
-	c.JSON(http.StatusOK, gin.H{"message": "task deleted"})
+	}).Info("Task moved to the trash")
+	c.JSON(http.StatusOK, gin.H{"message": "task moved to the trash"})
 }
 
+// AssignTask adds the user given by the user_id query parameter to the
+// task's assignees. Kept for existing clients; see AddAssignee.
 func (h *Handler) AssignTask(c *gin.Context) {
+	actorID := c.GetUint("userID")
 	logrus.WithFields(logrus.Fields{
+		"userID": actorID,
 		"method": "POST",
 		"path":   c.Request.URL.Path,
 	}).Info("Incoming request")
@@ -223,13 +241,21 @@ func (h *Handler) AssignTask(c *gin.Context) {
 		SendError(c, http.StatusBadRequest, "invalid user_id")
 		return
 	}
-	if err := h.Service.AssignTaskToUser(uint(taskID), uint(userID)); err != nil {
+	task, err := h.Service.GetTaskByID(uint(taskID))
+	if err != nil {
+		SendError(c, http.StatusNotFound, "task not found")
+		return
+	}
+	if !CheckProjectPermission(c, h, actorID, task.ProjectID) {
+		return
+	}
+	if _, err := h.Service.AssignTaskToUser(actorID, uint(taskID), uint(userID)); err != nil {
 		logrus.WithFields(logrus.Fields{
 			"taskID": taskID,
 			"userID": userID,
 			"error":  err,
 		}).Error("Failed to assign task")
-		SendError(c, http.StatusInternalServerError, err.Error())
+		SendServiceError(c, err)
 		return
 	}
 	logrus.WithFields(logrus.Fields{
@@ -254,13 +280,17 @@ func (h *Handler) GetTasksByProjectID(c *gin.Context) {
 		SendError(c, http.StatusBadRequest, "invalid project_id")
 		return
 	}
-	tasks, err := h.Service.GetTasksByProjectID(uint(projectID))
+	filter, ok := parseTaskFilter(c)
+	if !ok {
+		return
+	}
+	tasks, err := h.Service.GetTasksByProjectID(uint(projectID), filter)
 	if err != nil {
 		logrus.WithFields(logrus.Fields{
 			"projectID": projectID,
 			"error":     err,
 		}).Error("Failed to get tasks for project")
-		SendError(c, http.StatusInternalServerError, err.Error())
+		SendServiceError(c, err)
 		return
 	}
 	logrus.WithFields(logrus.Fields{
@@ -269,3 +299,91 @@ func (h *Handler) GetTasksByProjectID(c *gin.Context) {
 	}).Info("Tasks retrieved for project successfully")
 	c.JSON(http.StatusOK, tasks)
 }
+
+// parseTaskFilter reads task listing filters from the query string.
+// Supported parameters:
+//   - labels: comma-separated label IDs, matches tasks carrying any of them
+//   - assignee: user ID, matches tasks the user is assigned to
+//   - priority: comma-separated priorities, e.g. "high,urgent"
+//   - min_points / max_points: inclusive story point bounds
+//   - sla_breached: "true" to keep tasks that missed an SLA target
+//   - cf.<field id>[.gte|.lte]: custom field value, e.g. cf.3=prod or cf.4.gte=10
+//   - sort: field to order by, prefixed with "-" for descending, e.g. "-priority"
+//     or "cf.4" for a custom field; "rank" gives the board order
+func parseTaskFilter(c *gin.Context) (dto.TaskFilter, bool) {
+	return parseTaskFilterQuery(c, c.Request.URL.Query())
+}
+
+// parseTaskFilterQuery reads task listing filters from query parameters, as
+// parseTaskFilter does, replying with 400 when they are invalid
+func parseTaskFilterQuery(c *gin.Context, query url.Values) (dto.TaskFilter, bool) {
+	var filter dto.TaskFilter
+	if raw := query.Get("labels"); raw != "" {
+		for _, part := range strings.Split(raw, ",") {
+			labelID, ok := ParseID(c, strings.TrimSpace(part), "labels")
+			if !ok {
+				return filter, false
+			}
+			filter.LabelIDs = append(filter.LabelIDs, labelID)
+		}
+	}
+	if raw := query.Get("assignee"); raw != "" {
+		assigneeID, ok := ParseID(c, raw, "assignee")
+		if !ok {
+			return filter, false
+		}
+		filter.AssigneeID = assigneeID
+	}
+	if raw := query.Get("priority"); raw != "" {
+		for _, part := range strings.Split(raw, ",") {
+			filter.Priorities = append(filter.Priorities, strings.TrimSpace(part))
+		}
+	}
+	for param, target := range map[string]**int{
+		"min_points": &filter.MinStoryPoints,
+		"max_points": &filter.MaxStoryPoints,
+	} {
+		if raw := query.Get(param); raw != "" {
+			points, err := strconv.Atoi(raw)
+			if err != nil {
+				SendError(c, http.StatusBadRequest, "invalid "+param)
+				return filter, false
+			}
+			*target = &points
+		}
+	}
+	if raw := query.Get("sla_breached"); raw != "" {
+		breached, err := strconv.ParseBool(raw)
+		if err != nil {
+			SendError(c, http.StatusBadRequest, "invalid sla_breached")
+			return filter, false
+		}
+		filter.SLABreached = breached
+	}
+	for key, values := range query {
+		if !strings.HasPrefix(key, "cf.") {
+			continue
+		}
+		parts := strings.Split(strings.TrimPrefix(key, "cf."), ".")
+		op := "eq"
+		if len(parts) == 2 && (parts[1] == "gte" || parts[1] == "lte") {
+			op = parts[1]
+		} else if len(parts) != 1 {
+			SendError(c, http.StatusBadRequest, "invalid custom field filter "+key)
+			return filter, false
+		}
+		fieldID, ok := ParseID(c, parts[0], key)
+		if !ok {
+			return filter, false
+		}
+		for _, value := range values {
+			filter.CustomFields = append(filter.CustomFields, dto.CustomFieldFilter{
+				FieldID: fieldID,
+				Op:      op,
+				Value:   value,
+			})
+		}
+	}
+	filter.Sort = query.Get("sort")
+	return filter, true
+}
@@ -0,0 +1,307 @@
+// Template handlers (project and task templates)
+package handlers
+
+import (
+	"net/http"
+
+	"work-management/dto"
+
+	"github.com/gin-gonic/gin"
+	"github.com/sirupsen/logrus"
+)
+
+func (h *Handler) GetProjectTemplates(c *gin.Context) {
+	logrus.WithFields(logrus.Fields{
+		"method": "GET",
+		"path":   c.Request.URL.Path,
+	}).Info("Incoming request")
+	templates, err := h.Service.GetProjectTemplates()
+	if err != nil {
+		SendServiceError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, templates)
+}
+
+func (h *Handler) GetProjectTemplate(c *gin.Context) {
+	logrus.WithFields(logrus.Fields{
+		"method": "GET",
+		"path":   c.Request.URL.Path,
+	}).Info("Incoming request")
+	templateID, ok := ParseID(c, c.Param("template_id"), "template_id")
+	if !ok {
+		return
+	}
+	template, err := h.Service.GetProjectTemplate(templateID)
+	if err != nil {
+		SendServiceError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, template)
+}
+
+func (h *Handler) CreateProjectTemplate(c *gin.Context) {
+	userID := c.GetUint("userID")
+	logrus.WithFields(logrus.Fields{
+		"userID": userID,
+		"method": "POST",
+		"path":   c.Request.URL.Path,
+	}).Info("Incoming request")
+	var input dto.ProjectTemplateInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		logrus.WithFields(logrus.Fields{
+			"error": err,
+		}).Warn("Invalid input")
+		SendError(c, http.StatusBadRequest, err.Error())
+		return
+	}
+	template, err := h.Service.CreateProjectTemplate(userID, input)
+	if err != nil {
+		SendServiceError(c, err)
+		return
+	}
+	c.JSON(http.StatusCreated, template)
+}
+
+func (h *Handler) UpdateProjectTemplate(c *gin.Context) {
+	userID := c.GetUint("userID")
+	logrus.WithFields(logrus.Fields{
+		"userID": userID,
+		"method": "PUT",
+		"path":   c.Request.URL.Path,
+	}).Info("Incoming request")
+	templateID, ok := ParseID(c, c.Param("template_id"), "template_id")
+	if !ok {
+		return
+	}
+	var input dto.ProjectTemplateInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		logrus.WithFields(logrus.Fields{
+			"error": err,
+		}).Warn("Invalid input")
+		SendError(c, http.StatusBadRequest, err.Error())
+		return
+	}
+	template, err := h.Service.UpdateProjectTemplate(userID, templateID, input)
+	if err != nil {
+		SendServiceError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, template)
+}
+
+func (h *Handler) DeleteProjectTemplate(c *gin.Context) {
+	userID := c.GetUint("userID")
+	logrus.WithFields(logrus.Fields{
+		"userID": userID,
+		"method": "DELETE",
+		"path":   c.Request.URL.Path,
+	}).Info("Incoming request")
+	templateID, ok := ParseID(c, c.Param("template_id"), "template_id")
+	if !ok {
+		return
+	}
+	if err := h.Service.DeleteProjectTemplate(userID, templateID); err != nil {
+		SendServiceError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "template deleted"})
+}
+
+// CreateProjectFromTemplate creates a project from a template; the caller
+// becomes its admin
+func (h *Handler) CreateProjectFromTemplate(c *gin.Context) {
+	userID := c.GetUint("userID")
+	logrus.WithFields(logrus.Fields{
+		"userID": userID,
+		"method": "POST",
+		"path":   c.Request.URL.Path,
+	}).Info("Incoming request")
+	templateID, ok := ParseID(c, c.Param("template_id"), "template_id")
+	if !ok {
+		return
+	}
+	var input dto.FromTemplateInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		logrus.WithFields(logrus.Fields{
+			"error": err,
+		}).Warn("Invalid input")
+		SendError(c, http.StatusBadRequest, err.Error())
+		return
+	}
+	project, err := h.Service.CreateProjectFromTemplate(userID, templateID, input)
+	if err != nil {
+		SendServiceError(c, err)
+		return
+	}
+	c.JSON(http.StatusCreated, project)
+}
+
+func (h *Handler) SaveProjectAsTemplate(c *gin.Context) {
+	userID := c.GetUint("userID")
+	logrus.WithFields(logrus.Fields{
+		"userID": userID,
+		"method": "POST",
+		"path":   c.Request.URL.Path,
+	}).Info("Incoming request")
+	projectID, ok := ParseID(c, c.Param("project_id"), "project_id")
+	if !ok {
+		return
+	}
+	if !CheckProjectAdmin(c, h, userID, projectID) {
+		return
+	}
+	var input dto.SaveAsTemplateInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		logrus.WithFields(logrus.Fields{
+			"error": err,
+		}).Warn("Invalid input")
+		SendError(c, http.StatusBadRequest, err.Error())
+		return
+	}
+	template, err := h.Service.SaveProjectAsTemplate(userID, projectID, input)
+	if err != nil {
+		SendServiceError(c, err)
+		return
+	}
+	c.JSON(http.StatusCreated, template)
+}
+
+func (h *Handler) GetTaskTemplates(c *gin.Context) {
+	logrus.WithFields(logrus.Fields{
+		"method": "GET",
+		"path":   c.Request.URL.Path,
+	}).Info("Incoming request")
+	projectID, ok := ParseID(c, c.Param("project_id"), "project_id")
+	if !ok {
+		return
+	}
+	templates, err := h.Service.GetTaskTemplates(projectID)
+	if err != nil {
+		SendServiceError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, templates)
+}
+
+func (h *Handler) CreateTaskTemplate(c *gin.Context) {
+	userID := c.GetUint("userID")
+	logrus.WithFields(logrus.Fields{
+		"userID": userID,
+		"method": "POST",
+		"path":   c.Request.URL.Path,
+	}).Info("Incoming request")
+	projectID, ok := ParseID(c, c.Param("project_id"), "project_id")
+	if !ok {
+		return
+	}
+	if !CheckProjectPermission(c, h, userID, projectID) {
+		return
+	}
+	var input dto.TaskTemplateInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		logrus.WithFields(logrus.Fields{
+			"error": err,
+		}).Warn("Invalid input")
+		SendError(c, http.StatusBadRequest, err.Error())
+		return
+	}
+	template, err := h.Service.CreateTaskTemplate(userID, projectID, input)
+	if err != nil {
+		SendServiceError(c, err)
+		return
+	}
+	c.JSON(http.StatusCreated, template)
+}
+
+func (h *Handler) SaveTaskAsTemplate(c *gin.Context) {
+	userID := c.GetUint("userID")
+	logrus.WithFields(logrus.Fields{
+		"userID": userID,
+		"method": "POST",
+		"path":   c.Request.URL.Path,
+	}).Info("Incoming request")
+	taskID, ok := h.taskForEditor(c, userID)
+	if !ok {
+		return
+	}
+	var input dto.SaveAsTemplateInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		logrus.WithFields(logrus.Fields{
+			"error": err,
+		}).Warn("Invalid input")
+		SendError(c, http.StatusBadRequest, err.Error())
+		return
+	}
+	template, err := h.Service.SaveTaskAsTemplate(userID, taskID, input)
+	if err != nil {
+		SendServiceError(c, err)
+		return
+	}
+	c.JSON(http.StatusCreated, template)
+}
+
+// taskTemplateForEditor loads a task template and checks the caller may
+// modify its project
+func (h *Handler) taskTemplateForEditor(c *gin.Context, userID uint) (uint, bool) {
+	templateID, ok := ParseID(c, c.Param("template_id"), "template_id")
+	if !ok {
+		return 0, false
+	}
+	template, err := h.Service.GetTaskTemplate(templateID)
+	if err != nil {
+		SendError(c, http.StatusNotFound, "task template not found")
+		return 0, false
+	}
+	if !CheckProjectPermission(c, h, userID, template.ProjectID) {
+		return 0, false
+	}
+	return templateID, true
+}
+
+func (h *Handler) DeleteTaskTemplate(c *gin.Context) {
+	userID := c.GetUint("userID")
+	logrus.WithFields(logrus.Fields{
+		"userID": userID,
+		"method": "DELETE",
+		"path":   c.Request.URL.Path,
+	}).Info("Incoming request")
+	templateID, ok := h.taskTemplateForEditor(c, userID)
+	if !ok {
+		return
+	}
+	if err := h.Service.DeleteTaskTemplate(templateID); err != nil {
+		SendServiceError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "task template deleted"})
+}
+
+// CreateTasksFromTemplate adds a task template's task and its subtasks to
+// the template's project
+func (h *Handler) CreateTasksFromTemplate(c *gin.Context) {
+	userID := c.GetUint("userID")
+	logrus.WithFields(logrus.Fields{
+		"userID": userID,
+		"method": "POST",
+		"path":   c.Request.URL.Path,
+	}).Info("Incoming request")
+	templateID, ok := h.taskTemplateForEditor(c, userID)
+	if !ok {
+		return
+	}
+	var input dto.FromTemplateInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		logrus.WithFields(logrus.Fields{
+			"error": err,
+		}).Warn("Invalid input")
+		SendError(c, http.StatusBadRequest, err.Error())
+		return
+	}
+	task, err := h.Service.CreateTasksFromTemplate(userID, templateID, input)
+	if err != nil {
+		SendServiceError(c, err)
+		return
+	}
+	c.JSON(http.StatusCreated, task)
+}
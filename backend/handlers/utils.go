@@ -2,13 +2,19 @@
 package handlers
 
 import (
+	"errors"
+	"fmt"
 	"net/http"
+	"net/url"
 	"strconv"
+	"strings"
+	"time"
 
 	"work-management/services"
 
 	"github.com/gin-gonic/gin"
 	"github.com/sirupsen/logrus"
+	"gorm.io/gorm"
 )
 
 // Handler struct to hold the service dependency
@@ -30,6 +36,22 @@ func SendError(c *gin.Context, status int, message string) {
 	c.JSON(status, gin.H{"error": message})
 }
 
+// SendServiceError maps an error returned by the service layer to a status code
+func SendServiceError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, services.ErrInvalidInput):
+		SendError(c, http.StatusBadRequest, err.Error())
+	case errors.Is(err, services.ErrForbidden):
+		SendError(c, http.StatusForbidden, err.Error())
+	case errors.Is(err, services.ErrProjectArchived), errors.Is(err, services.ErrConflict):
+		SendError(c, http.StatusConflict, err.Error())
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		SendError(c, http.StatusNotFound, err.Error())
+	default:
+		SendError(c, http.StatusInternalServerError, err.Error())
+	}
+}
+
 // ParseID parses a string ID into a uint
 func ParseID(c *gin.Context, param, name string) (uint, bool) {
 	id, err := strconv.ParseUint(param, 10, 32)
@@ -44,9 +66,14 @@ func ParseID(c *gin.Context, param, name string) (uint, bool) {
 	return uint(id), true
 }
 
-// CheckProjectPermission checks if the user can modify a project
+// CheckProjectPermission checks if the user can modify a project, which
+// nobody can while it is archived
 func CheckProjectPermission(c *gin.Context, h *Handler, userID, projectID uint) bool {
 	canModify, err := h.Service.CanModifyProject(userID, projectID)
+	if errors.Is(err, services.ErrProjectArchived) {
+		SendServiceError(c, err)
+		return false
+	}
 	if err != nil || !canModify {
 		logrus.WithFields(logrus.Fields{
 			"userID":    userID,
@@ -57,3 +84,99 @@ func CheckProjectPermission(c *gin.Context, h *Handler, userID, projectID uint)
 	}
 	return true
 }
+
+// CheckProjectAdmin checks if the user is an admin of a project
+func CheckProjectAdmin(c *gin.Context, h *Handler, userID, projectID uint) bool {
+	isAdmin, err := h.Service.AdminOnly(userID, projectID)
+	if err != nil || !isAdmin {
+		logrus.WithFields(logrus.Fields{
+			"userID":    userID,
+			"projectID": projectID,
+		}).Warn("Insufficient permission - admin only")
+		SendError(c, http.StatusForbidden, "insufficient permission - admin only")
+		return false
+	}
+	return true
+}
+
+// CheckProjectAdminWritable checks if the user is an admin of a project and
+// may change its settings, which nobody can while it is archived
+func CheckProjectAdminWritable(c *gin.Context, h *Handler, userID, projectID uint) bool {
+	canAdminister, err := h.Service.CanAdministerProject(userID, projectID)
+	if errors.Is(err, services.ErrProjectArchived) {
+		SendServiceError(c, err)
+		return false
+	}
+	if err != nil || !canAdminister {
+		logrus.WithFields(logrus.Fields{
+			"userID":    userID,
+			"projectID": projectID,
+		}).Warn("Insufficient permission - admin only")
+		SendError(c, http.StatusForbidden, "insufficient permission - admin only")
+		return false
+	}
+	return true
+}
+
+// CheckSystemAdmin checks if the user administers the whole installation
+func CheckSystemAdmin(c *gin.Context, h *Handler, userID uint) bool {
+	if !h.Service.IsSystemAdmin(userID) {
+		logrus.WithFields(logrus.Fields{
+			"userID": userID,
+		}).Warn("Insufficient permission - system admin only")
+		SendError(c, http.StatusForbidden, "insufficient permission - system admin only")
+		return false
+	}
+	return true
+}
+
+// secretQueryParams are query parameters that carry credentials and must not
+// reach the access log
+var secretQueryParams = []string{"access_token", "ticket"}
+
+// AccessLogFormatter formats access log lines like gin's default logger but
+// with credentials in the query string redacted
+func AccessLogFormatter(param gin.LogFormatterParams) string {
+	var statusColor, methodColor, resetColor string
+	if param.IsOutputColor() {
+		statusColor = param.StatusCodeColor()
+		methodColor = param.MethodColor()
+		resetColor = param.ResetColor()
+	}
+	if param.Latency > time.Minute {
+		param.Latency = param.Latency.Truncate(time.Second)
+	}
+	return fmt.Sprintf("[GIN] %v |%s %3d %s| %13v | %15s |%s %-7s %s %#v\n%s",
+		param.TimeStamp.Format("2006/01/02 - 15:04:05"),
+		statusColor, param.StatusCode, resetColor,
+		param.Latency,
+		param.ClientIP,
+		methodColor, param.Method, resetColor,
+		redactQuery(param.Path),
+		param.ErrorMessage,
+	)
+}
+
+// redactQuery replaces the values of secretQueryParams in a request path.
+// A query that cannot be parsed is dropped altogether.
+func redactQuery(path string) string {
+	i := strings.IndexByte(path, '?')
+	if i < 0 {
+		return path
+	}
+	query, err := url.ParseQuery(path[i+1:])
+	if err != nil {
+		return path[:i]
+	}
+	redacted := false
+	for _, name := range secretQueryParams {
+		if query.Has(name) {
+			query.Set(name, "REDACTED")
+			redacted = true
+		}
+	}
+	if !redacted {
+		return path
+	}
+	return path[:i+1] + query.Encode()
+}
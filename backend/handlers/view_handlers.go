@@ -0,0 +1,179 @@
+// View handlers (saved views and their swimlanes)
+package handlers
+
+import (
+	"net/http"
+	"net/url"
+
+	"work-management/dto"
+
+	"github.com/gin-gonic/gin"
+	"github.com/sirupsen/logrus"
+)
+
+// parseViewFilter reads the task listing filters saved in a view
+func parseViewFilter(c *gin.Context, raw string) (dto.TaskFilter, bool) {
+	query, err := url.ParseQuery(raw)
+	if err != nil {
+		SendError(c, http.StatusBadRequest, "invalid filter: "+err.Error())
+		return dto.TaskFilter{}, false
+	}
+	return parseTaskFilterQuery(c, query)
+}
+
+// bindViewInput reads a view from the request body along with its filter
+func bindViewInput(c *gin.Context) (dto.ViewInput, dto.TaskFilter, bool) {
+	var input dto.ViewInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		logrus.WithFields(logrus.Fields{
+			"error": err,
+		}).Warn("Invalid input")
+		SendError(c, http.StatusBadRequest, err.Error())
+		return input, dto.TaskFilter{}, false
+	}
+	filter, ok := parseViewFilter(c, input.Filter)
+	return input, filter, ok
+}
+
+// GetViews lists the caller's views of a project and those shared with it
+func (h *Handler) GetViews(c *gin.Context) {
+	userID := c.GetUint("userID")
+	logrus.WithFields(logrus.Fields{
+		"userID": userID,
+		"method": "GET",
+		"path":   c.Request.URL.Path,
+	}).Info("Incoming request")
+	projectID, ok := ParseID(c, c.Param("project_id"), "project_id")
+	if !ok {
+		return
+	}
+	if !h.Service.IsProjectMember(userID, projectID) {
+		SendError(c, http.StatusForbidden, "insufficient permission")
+		return
+	}
+	views, err := h.Service.GetViews(userID, projectID)
+	if err != nil {
+		SendServiceError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, views)
+}
+
+// CreateView saves a view of a project. Any member can save views for
+// themselves; sharing one with the project takes edit permission.
+func (h *Handler) CreateView(c *gin.Context) {
+	userID := c.GetUint("userID")
+	logrus.WithFields(logrus.Fields{
+		"userID": userID,
+		"method": "POST",
+		"path":   c.Request.URL.Path,
+	}).Info("Incoming request")
+	projectID, ok := ParseID(c, c.Param("project_id"), "project_id")
+	if !ok {
+		return
+	}
+	if !h.Service.IsProjectMember(userID, projectID) {
+		SendError(c, http.StatusForbidden, "insufficient permission")
+		return
+	}
+	input, filter, ok := bindViewInput(c)
+	if !ok {
+		return
+	}
+	view, err := h.Service.CreateView(userID, projectID, input, filter)
+	if err != nil {
+		SendServiceError(c, err)
+		return
+	}
+	c.JSON(http.StatusCreated, view)
+}
+
+func (h *Handler) GetView(c *gin.Context) {
+	userID := c.GetUint("userID")
+	logrus.WithFields(logrus.Fields{
+		"userID": userID,
+		"method": "GET",
+		"path":   c.Request.URL.Path,
+	}).Info("Incoming request")
+	viewID, ok := ParseID(c, c.Param("view_id"), "view_id")
+	if !ok {
+		return
+	}
+	view, err := h.Service.GetView(userID, viewID)
+	if err != nil {
+		SendServiceError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, view)
+}
+
+func (h *Handler) UpdateView(c *gin.Context) {
+	userID := c.GetUint("userID")
+	logrus.WithFields(logrus.Fields{
+		"userID": userID,
+		"method": "PUT",
+		"path":   c.Request.URL.Path,
+	}).Info("Incoming request")
+	viewID, ok := ParseID(c, c.Param("view_id"), "view_id")
+	if !ok {
+		return
+	}
+	input, filter, ok := bindViewInput(c)
+	if !ok {
+		return
+	}
+	view, err := h.Service.UpdateView(userID, viewID, input, filter)
+	if err != nil {
+		SendServiceError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, view)
+}
+
+func (h *Handler) DeleteView(c *gin.Context) {
+	userID := c.GetUint("userID")
+	logrus.WithFields(logrus.Fields{
+		"userID": userID,
+		"method": "DELETE",
+		"path":   c.Request.URL.Path,
+	}).Info("Incoming request")
+	viewID, ok := ParseID(c, c.Param("view_id"), "view_id")
+	if !ok {
+		return
+	}
+	if err := h.Service.DeleteView(userID, viewID); err != nil {
+		SendServiceError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "view deleted"})
+}
+
+// RunView returns the tasks of a view, filtered, sorted and grouped into
+// swimlanes as the view says
+func (h *Handler) RunView(c *gin.Context) {
+	userID := c.GetUint("userID")
+	logrus.WithFields(logrus.Fields{
+		"userID": userID,
+		"method": "GET",
+		"path":   c.Request.URL.Path,
+	}).Info("Incoming request")
+	viewID, ok := ParseID(c, c.Param("view_id"), "view_id")
+	if !ok {
+		return
+	}
+	view, err := h.Service.GetView(userID, viewID)
+	if err != nil {
+		SendServiceError(c, err)
+		return
+	}
+	filter, ok := parseViewFilter(c, view.Filter)
+	if !ok {
+		return
+	}
+	result, err := h.Service.RunView(view, filter)
+	if err != nil {
+		SendServiceError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, result)
+}
@@ -0,0 +1,197 @@
+// Webhook handlers (project webhooks and their deliveries)
+package handlers
+
+import (
+	"net/http"
+
+	"work-management/models"
+
+	"github.com/gin-gonic/gin"
+	"github.com/sirupsen/logrus"
+)
+
+type webhookInput struct {
+	URL    string   `json:"url" binding:"required"`
+	Secret string   `json:"secret"`
+	Events []string `json:"events" binding:"required"`
+	Active *bool    `json:"active"`
+}
+
+// maxWebhookDeliveries is how many recent deliveries are listed per webhook
+const maxWebhookDeliveries = 100
+
+// parseProjectWebhook reads the project_id and webhook_id path parameters and
+// checks that the caller is a project admin, and that the project is not
+// archived when writable is set
+func (h *Handler) parseProjectWebhook(c *gin.Context, userID uint, writable bool) (uint, uint, bool) {
+	projectID, ok := ParseID(c, c.Param("project_id"), "project_id")
+	if !ok {
+		return 0, 0, false
+	}
+	webhookID, ok := ParseID(c, c.Param("webhook_id"), "webhook_id")
+	if !ok {
+		return 0, 0, false
+	}
+	if writable {
+		if !CheckProjectAdminWritable(c, h, userID, projectID) {
+			return 0, 0, false
+		}
+	} else if !CheckProjectAdmin(c, h, userID, projectID) {
+		return 0, 0, false
+	}
+	return projectID, webhookID, true
+}
+
+func (h *Handler) GetWebhooks(c *gin.Context) {
+	userID := c.GetUint("userID")
+	logrus.WithFields(logrus.Fields{
+		"userID": userID,
+		"method": "GET",
+		"path":   c.Request.URL.Path,
+	}).Info("Incoming request")
+	projectID, ok := ParseID(c, c.Param("project_id"), "project_id")
+	if !ok {
+		return
+	}
+	if !CheckProjectAdmin(c, h, userID, projectID) {
+		return
+	}
+	webhooks, err := h.Service.GetWebhooksByProjectID(projectID)
+	if err != nil {
+		SendServiceError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, webhooks)
+}
+
+// CreateWebhook registers an endpoint for the given event types ("*" for
+// all). The response includes the secret used to sign payloads.
+func (h *Handler) CreateWebhook(c *gin.Context) {
+	userID := c.GetUint("userID")
+	logrus.WithFields(logrus.Fields{
+		"userID": userID,
+		"method": "POST",
+		"path":   c.Request.URL.Path,
+	}).Info("Incoming request")
+	projectID, ok := ParseID(c, c.Param("project_id"), "project_id")
+	if !ok {
+		return
+	}
+	if !CheckProjectAdminWritable(c, h, userID, projectID) {
+		return
+	}
+	var input webhookInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		logrus.WithFields(logrus.Fields{
+			"error": err,
+		}).Warn("Invalid input")
+		SendError(c, http.StatusBadRequest, err.Error())
+		return
+	}
+	webhook, err := h.Service.CreateWebhook(&models.Webhook{
+		ProjectID: projectID,
+		URL:       input.URL,
+		Secret:    input.Secret,
+		Events:    input.Events,
+		Active:    input.Active == nil || *input.Active,
+	})
+	if err != nil {
+		SendServiceError(c, err)
+		return
+	}
+	c.JSON(http.StatusCreated, webhook)
+}
+
+func (h *Handler) UpdateWebhook(c *gin.Context) {
+	userID := c.GetUint("userID")
+	logrus.WithFields(logrus.Fields{
+		"userID": userID,
+		"method": "PUT",
+		"path":   c.Request.URL.Path,
+	}).Info("Incoming request")
+	projectID, webhookID, ok := h.parseProjectWebhook(c, userID, true)
+	if !ok {
+		return
+	}
+	var input webhookInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		logrus.WithFields(logrus.Fields{
+			"error": err,
+		}).Warn("Invalid input")
+		SendError(c, http.StatusBadRequest, err.Error())
+		return
+	}
+	webhook, err := h.Service.UpdateWebhook(projectID, webhookID, models.Webhook{
+		URL:    input.URL,
+		Secret: input.Secret,
+		Events: input.Events,
+		Active: input.Active == nil || *input.Active,
+	})
+	if err != nil {
+		SendServiceError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, webhook)
+}
+
+func (h *Handler) DeleteWebhook(c *gin.Context) {
+	userID := c.GetUint("userID")
+	logrus.WithFields(logrus.Fields{
+		"userID": userID,
+		"method": "DELETE",
+		"path":   c.Request.URL.Path,
+	}).Info("Incoming request")
+	projectID, webhookID, ok := h.parseProjectWebhook(c, userID, true)
+	if !ok {
+		return
+	}
+	if err := h.Service.DeleteWebhook(projectID, webhookID); err != nil {
+		SendServiceError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "webhook deleted"})
+}
+
+func (h *Handler) GetWebhookDeliveries(c *gin.Context) {
+	userID := c.GetUint("userID")
+	logrus.WithFields(logrus.Fields{
+		"userID": userID,
+		"method": "GET",
+		"path":   c.Request.URL.Path,
+	}).Info("Incoming request")
+	projectID, webhookID, ok := h.parseProjectWebhook(c, userID, false)
+	if !ok {
+		return
+	}
+	deliveries, err := h.Service.GetWebhookDeliveries(projectID, webhookID, maxWebhookDeliveries)
+	if err != nil {
+		SendServiceError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, deliveries)
+}
+
+// RedeliverWebhook sends the payload of an earlier delivery again as a new
+// delivery
+func (h *Handler) RedeliverWebhook(c *gin.Context) {
+	userID := c.GetUint("userID")
+	logrus.WithFields(logrus.Fields{
+		"userID": userID,
+		"method": "POST",
+		"path":   c.Request.URL.Path,
+	}).Info("Incoming request")
+	projectID, webhookID, ok := h.parseProjectWebhook(c, userID, true)
+	if !ok {
+		return
+	}
+	deliveryID, ok := ParseID(c, c.Param("delivery_id"), "delivery_id")
+	if !ok {
+		return
+	}
+	delivery, err := h.Service.RedeliverWebhook(projectID, webhookID, deliveryID)
+	if err != nil {
+		SendServiceError(c, err)
+		return
+	}
+	c.JSON(http.StatusAccepted, delivery)
+}
@@ -0,0 +1,288 @@
+// Time tracking handlers (work logs, timers and timesheets)
+package handlers
+
+import (
+	"encoding/csv"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"work-management/repository"
+
+	"github.com/gin-gonic/gin"
+	"github.com/sirupsen/logrus"
+)
+
+// dateLayout is the format of date-only query parameters
+const dateLayout = "2006-01-02"
+
+type workLogInput struct {
+	StartedAt time.Time `json:"started_at" binding:"required"`
+	Duration  int       `json:"duration" binding:"required"` // Minutes
+	Note      string    `json:"note"`
+}
+
+// taskForEditor loads a task and checks the caller may modify its project
+func (h *Handler) taskForEditor(c *gin.Context, userID uint) (uint, bool) {
+	taskID, ok := ParseID(c, c.Param("task_id"), "task_id")
+	if !ok {
+		return 0, false
+	}
+	task, err := h.Service.GetTaskByID(taskID)
+	if err != nil {
+		SendError(c, http.StatusNotFound, "task not found")
+		return 0, false
+	}
+	if !CheckProjectPermission(c, h, userID, task.ProjectID) {
+		return 0, false
+	}
+	return taskID, true
+}
+
+func (h *Handler) GetWorkLogs(c *gin.Context) {
+	logrus.WithFields(logrus.Fields{
+		"method": "GET",
+		"path":   c.Request.URL.Path,
+	}).Info("Incoming request")
+	taskID, ok := ParseID(c, c.Param("task_id"), "task_id")
+	if !ok {
+		return
+	}
+	workLogs, err := h.Service.GetWorkLogsByTaskID(taskID)
+	if err != nil {
+		SendError(c, http.StatusInternalServerError, err.Error())
+		return
+	}
+	c.JSON(http.StatusOK, workLogs)
+}
+
+func (h *Handler) CreateWorkLog(c *gin.Context) {
+	userID := c.GetUint("userID")
+	logrus.WithFields(logrus.Fields{
+		"userID": userID,
+		"method": "POST",
+		"path":   c.Request.URL.Path,
+	}).Info("Incoming request")
+	taskID, ok := h.taskForEditor(c, userID)
+	if !ok {
+		return
+	}
+	var input workLogInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		logrus.WithFields(logrus.Fields{
+			"error": err,
+		}).Warn("Invalid input")
+		SendError(c, http.StatusBadRequest, err.Error())
+		return
+	}
+	workLog, err := h.Service.LogWork(taskID, userID, input.StartedAt, input.Duration, input.Note)
+	if err != nil {
+		SendServiceError(c, err)
+		return
+	}
+	c.JSON(http.StatusCreated, workLog)
+}
+
+func (h *Handler) UpdateWorkLog(c *gin.Context) {
+	userID := c.GetUint("userID")
+	logrus.WithFields(logrus.Fields{
+		"userID": userID,
+		"method": "PUT",
+		"path":   c.Request.URL.Path,
+	}).Info("Incoming request")
+	workLogID, ok := ParseID(c, c.Param("worklog_id"), "worklog_id")
+	if !ok {
+		return
+	}
+	var input workLogInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		logrus.WithFields(logrus.Fields{
+			"error": err,
+		}).Warn("Invalid input")
+		SendError(c, http.StatusBadRequest, err.Error())
+		return
+	}
+	workLog, err := h.Service.UpdateWorkLog(workLogID, userID, input.StartedAt, input.Duration, input.Note)
+	if err != nil {
+		SendServiceError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, workLog)
+}
+
+func (h *Handler) DeleteWorkLog(c *gin.Context) {
+	userID := c.GetUint("userID")
+	logrus.WithFields(logrus.Fields{
+		"userID": userID,
+		"method": "DELETE",
+		"path":   c.Request.URL.Path,
+	}).Info("Incoming request")
+	workLogID, ok := ParseID(c, c.Param("worklog_id"), "worklog_id")
+	if !ok {
+		return
+	}
+	if err := h.Service.DeleteWorkLog(workLogID, userID); err != nil {
+		SendServiceError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "work log deleted"})
+}
+
+func (h *Handler) StartTimer(c *gin.Context) {
+	userID := c.GetUint("userID")
+	logrus.WithFields(logrus.Fields{
+		"userID": userID,
+		"method": "POST",
+		"path":   c.Request.URL.Path,
+	}).Info("Incoming request")
+	taskID, ok := h.taskForEditor(c, userID)
+	if !ok {
+		return
+	}
+	var input struct {
+		Note string `json:"note"`
+	}
+	// The body is optional
+	_ = c.ShouldBindJSON(&input)
+	timer, stopped, err := h.Service.StartTimer(taskID, userID, input.Note)
+	if err != nil {
+		SendServiceError(c, err)
+		return
+	}
+	c.JSON(http.StatusCreated, gin.H{"timer": timer, "stopped_work_log": stopped})
+}
+
+func (h *Handler) StopTimer(c *gin.Context) {
+	userID := c.GetUint("userID")
+	logrus.WithFields(logrus.Fields{
+		"userID": userID,
+		"method": "POST",
+		"path":   c.Request.URL.Path,
+	}).Info("Incoming request")
+	workLog, err := h.Service.StopTimer(userID)
+	if err != nil {
+		SendServiceError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, workLog)
+}
+
+func (h *Handler) GetTimer(c *gin.Context) {
+	userID := c.GetUint("userID")
+	logrus.WithFields(logrus.Fields{
+		"userID": userID,
+		"method": "GET",
+		"path":   c.Request.URL.Path,
+	}).Info("Incoming request")
+	timer, err := h.Service.GetRunningTimer(userID)
+	if err != nil {
+		SendError(c, http.StatusNotFound, "no timer running")
+		return
+	}
+	c.JSON(http.StatusOK, timer)
+}
+
+// GetTimesheet aggregates logged time for a date range. Query parameters:
+//   - from, to: inclusive dates (YYYY-MM-DD), required
+//   - group_by: comma-separated subset of user, project, day (default all)
+//   - project_id, user_id: optional narrowing
+//   - format: "csv" to download instead of JSON
+func (h *Handler) GetTimesheet(c *gin.Context) {
+	userID := c.GetUint("userID")
+	logrus.WithFields(logrus.Fields{
+		"userID": userID,
+		"method": "GET",
+		"path":   c.Request.URL.Path,
+	}).Info("Incoming request")
+	from, err := time.Parse(dateLayout, c.Query("from"))
+	if err != nil {
+		SendError(c, http.StatusBadRequest, "from must be a date like 2024-01-31")
+		return
+	}
+	to, err := time.Parse(dateLayout, c.Query("to"))
+	if err != nil {
+		SendError(c, http.StatusBadRequest, "to must be a date like 2024-01-31")
+		return
+	}
+	var projectID, filterUserID uint
+	if raw := c.Query("project_id"); raw != "" {
+		if projectID, err = parseQueryID(c, raw, "project_id"); err != nil {
+			return
+		}
+	}
+	if raw := c.Query("user_id"); raw != "" {
+		if filterUserID, err = parseQueryID(c, raw, "user_id"); err != nil {
+			return
+		}
+	}
+	var groupBy []string
+	if raw := c.Query("group_by"); raw != "" {
+		for _, part := range strings.Split(raw, ",") {
+			groupBy = append(groupBy, strings.TrimSpace(part))
+		}
+	}
+
+	rows, err := h.Service.GetTimesheet(userID, from, to, projectID, filterUserID, groupBy)
+	if err != nil {
+		SendServiceError(c, err)
+		return
+	}
+	if c.Query("format") == "csv" {
+		writeTimesheetCSV(c, rows, groupBy, from, to)
+		return
+	}
+	c.JSON(http.StatusOK, rows)
+}
+
+// parseQueryID parses an ID from the query string, answering 400 on failure
+func parseQueryID(c *gin.Context, raw, name string) (uint, error) {
+	id, err := strconv.ParseUint(raw, 10, 32)
+	if err != nil {
+		SendError(c, http.StatusBadRequest, "invalid "+name)
+		return 0, err
+	}
+	return uint(id), nil
+}
+
+func writeTimesheetCSV(c *gin.Context, rows []repository.TimesheetRow, groupBy []string, from, to time.Time) {
+	if len(groupBy) == 0 {
+		groupBy = []string{"user", "project", "day"}
+	}
+	filename := fmt.Sprintf("timesheet_%s_%s.csv", from.Format(dateLayout), to.Format(dateLayout))
+	c.Header("Content-Type", "text/csv")
+	c.Header("Content-Disposition", "attachment; filename="+filename)
+	c.Status(http.StatusOK)
+
+	w := csv.NewWriter(c.Writer)
+	header := []string{}
+	for _, group := range groupBy {
+		switch group {
+		case "user":
+			header = append(header, "user_id", "user")
+		case "project":
+			header = append(header, "project_id", "project")
+		case "day":
+			header = append(header, "day")
+		}
+	}
+	header = append(header, "hours")
+	_ = w.Write(header)
+	for _, row := range rows {
+		record := []string{}
+		for _, group := range groupBy {
+			switch group {
+			case "user":
+				record = append(record, strconv.FormatUint(uint64(row.UserID), 10), row.UserName)
+			case "project":
+				record = append(record, strconv.FormatUint(uint64(row.ProjectID), 10), row.ProjectName)
+			case "day":
+				record = append(record, row.Day)
+			}
+		}
+		record = append(record, strconv.FormatFloat(float64(row.Minutes)/60, 'f', 2, 64))
+		_ = w.Write(record)
+	}
+	w.Flush()
+}
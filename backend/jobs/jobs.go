@@ -0,0 +1,423 @@
+// Package jobs runs background work from a Postgres-backed queue. Jobs are
+// claimed with SELECT ... FOR UPDATE SKIP LOCKED so that several servers can
+// share the queues, failed jobs are retried with exponential backoff and jobs
+// that run out of attempts are kept as dead letters.
+package jobs
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"sync"
+	"time"
+
+	"work-management/models"
+	"work-management/repository"
+
+	"github.com/sirupsen/logrus"
+)
+
+// Queue defaults
+const (
+	DefaultMaxAttempts = 5
+	// pollInterval is how often an idle worker looks for new jobs
+	pollInterval = time.Second
+	// retryBackoff is the wait before the first retry; it doubles with each
+	// further attempt up to maxRetryBackoff
+	retryBackoff    = 10 * time.Second
+	maxRetryBackoff = time.Hour
+	// heartbeatInterval is how often a worker renews the lock of the job it
+	// is running
+	heartbeatInterval = time.Minute
+	// staleAfter is how long a running job's lock may go without renewal
+	// before it is assumed that its worker died and it is queued again
+	staleAfter = 15 * time.Minute
+	// keepSucceeded is how long finished jobs are kept before cleanup
+	keepSucceeded = 7 * 24 * time.Hour
+)
+
+type handler struct {
+	queue  string
+	handle func(ctx context.Context, payload json.RawMessage) error
+}
+
+type schedule struct {
+	jobType  string
+	interval time.Duration
+}
+
+type loop struct {
+	interval time.Duration
+	run      func()
+	wake     chan struct{}
+}
+
+// Queue enqueues jobs and, once started, runs them with the registered
+// handlers
+type Queue struct {
+	Repo        *repository.Repository
+	workerID    string
+	handlers    map[string]handler
+	concurrency map[string]int
+	schedules   []schedule
+	loops       []loop
+
+	stop    chan struct{}
+	cancel  context.CancelFunc
+	workers sync.WaitGroup
+}
+
+// New creates a queue backed by the repository's database
+func New(repo *repository.Repository) *Queue {
+	hostname, _ := os.Hostname()
+	return &Queue{
+		Repo:        repo,
+		workerID:    fmt.Sprintf("%s:%d", hostname, os.Getpid()),
+		handlers:    map[string]handler{},
+		concurrency: map[string]int{},
+	}
+}
+
+// Register adds the handler of a job type and the queue its jobs run on. The
+// JSON payload of each job is decoded into T before the handler is called.
+// Handlers must be registered before Start.
+func Register[T any](q *Queue, jobType, queue string, handle func(ctx context.Context, payload T) error) {
+	q.handlers[jobType] = handler{
+		queue: queue,
+		handle: func(ctx context.Context, raw json.RawMessage) error {
+			var payload T
+			if err := json.Unmarshal(raw, &payload); err != nil {
+				return fmt.Errorf("decode payload: %w", err)
+			}
+			return handle(ctx, payload)
+		},
+	}
+	if _, ok := q.concurrency[queue]; !ok {
+		q.concurrency[queue] = 1
+	}
+}
+
+// SetConcurrency sets how many jobs of a queue run at the same time on this
+// server
+func (q *Queue) SetConcurrency(queue string, workers int) {
+	q.concurrency[queue] = workers
+}
+
+// Every enqueues a job of the given type, with an empty payload, once per
+// interval. Every server schedules it, but the job key makes sure only one
+// job is created per interval.
+func (q *Queue) Every(interval time.Duration, jobType string) {
+	q.schedules = append(q.schedules, schedule{jobType: jobType, interval: interval})
+}
+
+// Loop calls run every interval on this server, and as soon as possible
+// whenever the returned wake function is called. Calls to wake while run is
+// busy are coalesced into one further call. Loops must be added before Start.
+func (q *Queue) Loop(interval time.Duration, run func()) (wake func()) {
+	l := loop{interval: interval, run: run, wake: make(chan struct{}, 1)}
+	q.loops = append(q.loops, l)
+	return func() {
+		select {
+		case l.wake <- struct{}{}:
+		default:
+		}
+	}
+}
+
+// EnqueueOption customises an enqueued job
+type EnqueueOption func(job *models.Job)
+
+// After delays the job by d
+func After(d time.Duration) EnqueueOption {
+	return func(job *models.Job) { job.RunAt = job.RunAt.Add(d) }
+}
+
+// At runs the job no earlier than t
+func At(t time.Time) EnqueueOption {
+	return func(job *models.Job) { job.RunAt = t }
+}
+
+// WithKey skips the job if a job with the same key was already enqueued
+func WithKey(key string) EnqueueOption {
+	return func(job *models.Job) { job.Key = &key }
+}
+
+// MaxAttempts overrides DefaultMaxAttempts
+func MaxAttempts(n int) EnqueueOption {
+	return func(job *models.Job) { job.MaxAttempts = n }
+}
+
+// Enqueue stores a job of a registered type; payload is encoded as JSON
+func (q *Queue) Enqueue(jobType string, payload interface{}, options ...EnqueueOption) (*models.Job, error) {
+	h, ok := q.handlers[jobType]
+	if !ok {
+		return nil, fmt.Errorf("unknown job type %q", jobType)
+	}
+	raw, err := json.Marshal(payload)
+	if err != nil {
+		return nil, err
+	}
+	job := &models.Job{
+		Queue:       h.queue,
+		Type:        jobType,
+		Payload:     raw,
+		Status:      models.JobPending,
+		RunAt:       time.Now(),
+		MaxAttempts: DefaultMaxAttempts,
+	}
+	for _, option := range options {
+		option(job)
+	}
+	created, err := q.Repo.CreateJob(job)
+	if err != nil {
+		logrus.WithFields(logrus.Fields{
+			"type":  jobType,
+			"error": err,
+		}).Error("Failed to enqueue job")
+		return nil, err
+	}
+	if created {
+		logrus.WithFields(logrus.Fields{
+			"jobID": job.ID,
+			"type":  jobType,
+			"runAt": job.RunAt,
+		}).Debug("Job enqueued")
+	}
+	return job, nil
+}
+
+// Start launches the workers of every queue, the schedules and the
+// maintenance loop. They run until Drain is called.
+func (q *Queue) Start() {
+	ctx, cancel := context.WithCancel(context.Background())
+	q.stop = make(chan struct{})
+	q.cancel = cancel
+	for queue, workers := range q.concurrency {
+		for i := 0; i < workers; i++ {
+			q.workers.Add(1)
+			go q.work(ctx, queue)
+		}
+	}
+	for _, s := range q.schedules {
+		q.workers.Add(1)
+		go q.runSchedule(s)
+	}
+	for _, l := range q.loops {
+		q.workers.Add(1)
+		go q.runLoop(l)
+	}
+	q.workers.Add(1)
+	go q.maintain()
+	logrus.WithFields(logrus.Fields{
+		"workerID": q.workerID,
+		"queues":   q.concurrency,
+	}).Info("Job workers started")
+}
+
+// Drain stops claiming new jobs and waits for running ones to finish. If ctx
+// expires first, the running jobs' contexts are cancelled and ctx's error is
+// returned; those jobs are picked up again once they are considered stale.
+func (q *Queue) Drain(ctx context.Context) error {
+	if q.stop == nil {
+		return nil
+	}
+	close(q.stop)
+	done := make(chan struct{})
+	go func() {
+		q.workers.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+		q.cancel()
+		logrus.Info("Job workers drained")
+		return nil
+	case <-ctx.Done():
+		q.cancel()
+		logrus.Warn("Job workers did not drain in time")
+		return ctx.Err()
+	}
+}
+
+// sleep waits for d and reports false if the queue is stopping
+func (q *Queue) sleep(d time.Duration) bool {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-q.stop:
+		return false
+	case <-timer.C:
+		return true
+	}
+}
+
+func (q *Queue) work(ctx context.Context, queue string) {
+	defer q.workers.Done()
+	for {
+		select {
+		case <-q.stop:
+			return
+		default:
+		}
+		job, err := q.Repo.ClaimJob(queue, q.workerID, time.Now())
+		if err != nil {
+			logrus.WithFields(logrus.Fields{
+				"queue": queue,
+				"error": err,
+			}).Error("Failed to claim job")
+		}
+		if job == nil {
+			if !q.sleep(pollInterval) {
+				return
+			}
+			continue
+		}
+		q.run(ctx, job)
+	}
+}
+
+// run executes a claimed job and records the outcome
+func (q *Queue) run(ctx context.Context, job *models.Job) {
+	fields := logrus.Fields{
+		"jobID":   job.ID,
+		"type":    job.Type,
+		"attempt": job.Attempts,
+	}
+	var err error
+	if h, ok := q.handlers[job.Type]; ok {
+		stop := q.heartbeat(job, fields)
+		err = safeCall(ctx, h.handle, job.Payload)
+		stop()
+	} else {
+		err = fmt.Errorf("no handler registered for job type %q", job.Type)
+	}
+	now := time.Now()
+	held := false
+	switch {
+	case err == nil:
+		held, err = q.Repo.CompleteJob(job, q.workerID, now)
+		logrus.WithFields(fields).Info("Job succeeded")
+	case job.Attempts >= job.MaxAttempts:
+		logrus.WithFields(fields).WithField("error", err).Error("Job failed permanently")
+		held, err = q.Repo.KillJob(job, q.workerID, err.Error(), now)
+	default:
+		logrus.WithFields(fields).WithField("error", err).Warn("Job failed, will retry")
+		held, err = q.Repo.RetryJobLater(job, q.workerID, now.Add(backoff(job.Attempts)), err.Error())
+	}
+	switch {
+	case err != nil:
+		logrus.WithFields(fields).WithField("error", err).Error("Failed to record job outcome")
+	case !held:
+		logrus.WithFields(fields).Warn("Job was requeued while running, outcome discarded")
+	}
+}
+
+// heartbeat renews the job's lock until the returned function is called, so
+// that a job running for longer than staleAfter is not queued again while
+// its worker is still on it
+func (q *Queue) heartbeat(job *models.Job, fields logrus.Fields) (stop func()) {
+	done := make(chan struct{})
+	stopped := make(chan struct{})
+	go func() {
+		defer close(stopped)
+		ticker := time.NewTicker(heartbeatInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-done:
+				return
+			case now := <-ticker.C:
+				if err := q.Repo.RenewJobLock(job, q.workerID, now); err != nil {
+					logrus.WithFields(fields).WithField("error", err).Warn("Failed to renew job lock")
+				}
+			}
+		}
+	}()
+	return func() {
+		close(done)
+		<-stopped
+	}
+}
+
+// safeCall runs a handler, turning a panic into an error
+func safeCall(ctx context.Context, handle func(context.Context, json.RawMessage) error, payload json.RawMessage) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("panic: %v", r)
+		}
+	}()
+	return handle(ctx, payload)
+}
+
+// backoff is the wait before retrying a job that failed its nth attempt
+func backoff(attempt int) time.Duration {
+	wait := retryBackoff
+	for i := 1; i < attempt && wait < maxRetryBackoff; i++ {
+		wait *= 2
+	}
+	if wait > maxRetryBackoff {
+		wait = maxRetryBackoff
+	}
+	return wait
+}
+
+func (q *Queue) runSchedule(s schedule) {
+	defer q.workers.Done()
+	for {
+		slot := time.Now().Truncate(s.interval)
+		key := fmt.Sprintf("%s@%d", s.jobType, slot.Unix())
+		// Errors are logged by Enqueue; the next slot tries again
+		_, _ = q.Enqueue(s.jobType, struct{}{}, At(slot), WithKey(key))
+		if !q.sleep(time.Until(slot.Add(s.interval))) {
+			return
+		}
+	}
+}
+
+func (q *Queue) runLoop(l loop) {
+	defer q.workers.Done()
+	timer := time.NewTimer(l.interval)
+	defer timer.Stop()
+	for {
+		select {
+		case <-q.stop:
+			return
+		case <-l.wake:
+		case <-timer.C:
+		}
+		l.run()
+		if !timer.Stop() {
+			select {
+			case <-timer.C:
+			default:
+			}
+		}
+		timer.Reset(l.interval)
+	}
+}
+
+// maintain requeues jobs of dead workers and removes old finished jobs
+func (q *Queue) maintain() {
+	defer q.workers.Done()
+	for {
+		now := time.Now()
+		if count, err := q.Repo.RequeueStaleJobs(now.Add(-staleAfter), now); err != nil {
+			logrus.WithFields(logrus.Fields{
+				"error": err,
+			}).Error("Failed to requeue stale jobs")
+		} else if count > 0 {
+			logrus.WithFields(logrus.Fields{
+				"count": count,
+			}).Warn("Requeued stale jobs")
+		}
+		if _, err := q.Repo.DeleteFinishedJobs(now.Add(-keepSucceeded)); err != nil {
+			logrus.WithFields(logrus.Fields{
+				"error": err,
+			}).Error("Failed to delete finished jobs")
+		}
+		if !q.sleep(time.Minute) {
+			return
+		}
+	}
+}
@@ -0,0 +1,43 @@
+package mailer
+
+import (
+	"sync"
+
+	"github.com/sirupsen/logrus"
+)
+
+// Capture keeps sent messages in memory instead of delivering them. It is
+// used when no SMTP server is configured and in tests.
+type Capture struct {
+	mu       sync.Mutex
+	messages []Message
+}
+
+func NewCapture() *Capture {
+	return &Capture{}
+}
+
+func (c *Capture) Send(msg Message) error {
+	c.mu.Lock()
+	c.messages = append(c.messages, msg)
+	c.mu.Unlock()
+	logrus.WithFields(logrus.Fields{
+		"to":      msg.To,
+		"subject": msg.Subject,
+	}).Info("Email captured")
+	return nil
+}
+
+// Messages returns a copy of the messages sent so far
+func (c *Capture) Messages() []Message {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return append([]Message(nil), c.messages...)
+}
+
+// Reset forgets the captured messages
+func (c *Capture) Reset() {
+	c.mu.Lock()
+	c.messages = nil
+	c.mu.Unlock()
+}
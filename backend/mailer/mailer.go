@@ -0,0 +1,78 @@
+// Package mailer sends email through a pluggable backend (SMTP in
+// production, an in-memory capture for development and tests).
+package mailer
+
+import (
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+// Message is an email with a plain-text and an HTML part
+type Message struct {
+	To      string
+	Subject string
+	Text    string
+	HTML    string
+}
+
+// Mailer delivers messages
+type Mailer interface {
+	Send(msg Message) error
+}
+
+// Retrying retries failed sends of the wrapped Mailer, doubling the wait
+// after each failed attempt.
+type Retrying struct {
+	Mailer   Mailer
+	Attempts int
+	Backoff  time.Duration
+}
+
+func (r *Retrying) Send(msg Message) error {
+	wait := r.Backoff
+	var err error
+	for attempt := 1; attempt <= r.Attempts; attempt++ {
+		if err = r.Mailer.Send(msg); err == nil {
+			return nil
+		}
+		logrus.WithFields(logrus.Fields{
+			"to":      msg.To,
+			"attempt": attempt,
+			"error":   err,
+		}).Warn("Failed to send email")
+		if attempt < r.Attempts {
+			time.Sleep(wait)
+			wait *= 2
+		}
+	}
+	return err
+}
+
+// FromEnv builds the mailer configured by the environment:
+//   - SMTP_HOST, SMTP_PORT (default 587), SMTP_USERNAME, SMTP_PASSWORD
+//   - MAIL_FROM, the sender address (default no-reply@localhost)
+//
+// Without SMTP_HOST messages are kept in a Capture and logged instead of sent.
+func FromEnv() Mailer {
+	host := os.Getenv("SMTP_HOST")
+	if host == "" {
+		logrus.Info("SMTP_HOST not set, capturing outgoing email")
+		return NewCapture()
+	}
+	port, err := strconv.Atoi(os.Getenv("SMTP_PORT"))
+	if err != nil {
+		port = 587
+	}
+	from := os.Getenv("MAIL_FROM")
+	if from == "" {
+		from = "no-reply@localhost"
+	}
+	return &Retrying{
+		Mailer:   NewSMTP(host, port, os.Getenv("SMTP_USERNAME"), os.Getenv("SMTP_PASSWORD"), from),
+		Attempts: 3,
+		Backoff:  2 * time.Second,
+	}
+}
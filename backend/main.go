@@ -7,8 +7,11 @@ import (
 	"os/signal"
 	"syscall"
 	"time"
+	_ "time/tzdata" // Timezones of calendars and users, wherever the server runs
 	"work-management/db"
 	"work-management/handlers"
+	"work-management/jobs"
+	"work-management/mailer"
 	"work-management/repository"
 	"work-management/services"
 
@@ -36,13 +39,19 @@ func main() {
 		}
 	}()
 
-	// Initialize repository, service, and handler
+	// Initialize repository, job queue, service, and handler
 	repo := repository.NewRepository(dbConn)
-	service := services.NewService(repo)
+	queue := jobs.New(repo)
+	service := services.NewService(repo, mailer.FromEnv(), queue)
 	handler := handlers.NewHandler(service)
 
+	// Start the background job workers (emails, webhooks, reminders, ...) and
+	// the outbox relay
+	queue.Start()
+
 	// Set up Gin router
-	r := gin.Default()
+	r := gin.New()
+	r.Use(gin.LoggerWithFormatter(handlers.AccessLogFormatter), gin.Recovery())
 
 	// Add CORS middleware
 	r.Use(cors.New(cors.Config{
@@ -63,6 +72,8 @@ func main() {
 	r.POST("/users", handler.CreateUser)
 	r.POST("/login", handler.Login)
 	r.POST("/refresh", handler.RefreshToken)
+	// The WebSocket handshake authenticates with a ticket from /ws/ticket
+	r.GET("/ws", handler.ServeWebSocket)
 
 	// Protected routes (require authentication via AuthMiddleware)
 	protected := r.Group("/", handlers.AuthMiddleware())
@@ -73,6 +84,14 @@ func main() {
 	protected.PUT("/tasks/:task_id", handler.UpdateTask)
 	protected.DELETE("/tasks/:task_id", handler.DeleteTask)
 	protected.POST("/tasks/:task_id/assign", handler.AssignTask)
+	protected.GET("/tasks/:task_id/subtasks", handler.GetSubtasks)
+	protected.POST("/tasks/:task_id/clone", handler.CloneTask)
+	protected.POST("/tasks/:task_id/move", handler.MoveTask)
+	protected.POST("/tasks/:task_id/schedule", handler.ScheduleTask)
+	protected.GET("/tasks/:task_id/dependencies", handler.GetTaskDependencies)
+	protected.POST("/tasks/:task_id/dependencies", handler.AddDependency)
+	protected.DELETE("/tasks/:task_id/dependencies/:depends_on_id", handler.RemoveDependency)
+	protected.GET("/projects/:project_id/timeline", handler.GetGantt)
 	protected.GET("/projects/:project_id/tasks", handler.GetTasksByProjectID)
 	protected.GET("/projects/:project_id/activities", handler.GetProjectActivities)
 	protected.GET("/projects/:project_id/analytics", handler.GetProjectAnalytics)
@@ -83,12 +102,165 @@ func main() {
 	protected.PUT("/projects/:project_id", handler.UpdateProject)
 	protected.PUT("/projects/:project_id/favorite", handler.ToggleFavorite) // Added new route
 	protected.DELETE("/projects/:project_id", handler.DeleteProject)
+	protected.POST("/projects/:project_id/archive", handler.ArchiveProject)
+	protected.POST("/projects/:project_id/unarchive", handler.UnarchiveProject)
+	protected.GET("/projects/:project_id/trash", handler.GetTrashedTasks)
+	protected.GET("/projects/:project_id/sprints", handler.GetSprints)
+	protected.POST("/projects/:project_id/sprints", handler.CreateSprint)
+	protected.GET("/projects/:project_id/backlog", handler.GetBacklog)
+	protected.GET("/sprints/:sprint_id", handler.GetSprint)
+	protected.PUT("/sprints/:sprint_id", handler.UpdateSprint)
+	protected.DELETE("/sprints/:sprint_id", handler.DeleteSprint)
+	protected.GET("/sprints/:sprint_id/tasks", handler.GetSprintTasks)
+	protected.POST("/sprints/:sprint_id/tasks", handler.AddSprintTasks)
+	protected.DELETE("/sprints/:sprint_id/tasks/:task_id", handler.RemoveSprintTask)
+	protected.POST("/sprints/:sprint_id/start", handler.StartSprint)
+	protected.POST("/sprints/:sprint_id/close", handler.CloseSprint)
+	protected.GET("/sprints/:sprint_id/report", handler.GetSprintReport)
+	protected.GET("/projects/:project_id/milestones", handler.GetMilestones)
+	protected.POST("/projects/:project_id/milestones", handler.CreateMilestone)
+	protected.GET("/milestones/:milestone_id", handler.GetMilestone)
+	protected.PUT("/milestones/:milestone_id", handler.UpdateMilestone)
+	protected.DELETE("/milestones/:milestone_id", handler.DeleteMilestone)
+	protected.POST("/milestones/:milestone_id/release", handler.ReleaseMilestone)
+	protected.GET("/milestones/:milestone_id/tasks", handler.GetMilestoneTasks)
+	protected.POST("/milestones/:milestone_id/tasks", handler.AddMilestoneTasks)
+	protected.DELETE("/milestones/:milestone_id/tasks/:task_id", handler.RemoveMilestoneTask)
+	protected.GET("/milestones/:milestone_id/release-notes", handler.GetReleaseNotes)
+	protected.GET("/epics", handler.GetEpics)
+	protected.POST("/epics", handler.CreateEpic)
+	protected.GET("/epics/:epic_id", handler.GetEpic)
+	protected.PUT("/epics/:epic_id", handler.UpdateEpic)
+	protected.DELETE("/epics/:epic_id", handler.DeleteEpic)
+	protected.GET("/epics/:epic_id/tasks", handler.GetEpicTasks)
+	protected.POST("/epics/:epic_id/tasks", handler.AddEpicTasks)
+	protected.DELETE("/epics/:epic_id/tasks/:task_id", handler.RemoveEpicTask)
+	protected.GET("/epics/:epic_id/timeline", handler.GetEpicTimeline)
+	protected.GET("/projects/:project_id/views", handler.GetViews)
+	protected.POST("/projects/:project_id/views", handler.CreateView)
+	protected.GET("/views/:view_id", handler.GetView)
+	protected.PUT("/views/:view_id", handler.UpdateView)
+	protected.DELETE("/views/:view_id", handler.DeleteView)
+	protected.GET("/views/:view_id/tasks", handler.RunView)
+	protected.GET("/trash/projects", handler.GetTrashedProjects)
+	protected.POST("/trash/projects/:project_id/restore", handler.RestoreProject)
+	protected.DELETE("/trash/projects/:project_id", handler.PurgeProject)
+	protected.POST("/trash/tasks/:task_id/restore", handler.RestoreTask)
+	protected.DELETE("/trash/tasks/:task_id", handler.PurgeTask)
 	protected.POST("/projects/:project_id/users", handler.AddUserToProject)
 	protected.PUT("/projects/:project_id/users/:user_id", handler.UpdateUserRole)
 	protected.DELETE("/projects/:project_id/users/:user_id", handler.RemoveUserFromProject)
 	protected.PUT("/projects/:project_id/owner", handler.ChangeProjectOwner)
+	protected.POST("/projects/:project_id/clone", handler.CloneProject)
+	protected.GET("/projects/:project_id/audit", handler.GetProjectAuditLogs)
+	protected.GET("/audit", handler.GetAuditLogs)
+	protected.GET("/projects/:project_id/workflow", handler.GetWorkflow)
+	protected.PUT("/projects/:project_id/workflow", handler.UpdateWorkflow)
+	// Template routes
+	protected.GET("/templates", handler.GetProjectTemplates)
+	protected.POST("/templates", handler.CreateProjectTemplate)
+	protected.GET("/templates/:template_id", handler.GetProjectTemplate)
+	protected.PUT("/templates/:template_id", handler.UpdateProjectTemplate)
+	protected.DELETE("/templates/:template_id", handler.DeleteProjectTemplate)
+	protected.POST("/templates/:template_id/projects", handler.CreateProjectFromTemplate)
+	protected.POST("/projects/:project_id/template", handler.SaveProjectAsTemplate)
+	protected.GET("/projects/:project_id/task-templates", handler.GetTaskTemplates)
+	protected.POST("/projects/:project_id/task-templates", handler.CreateTaskTemplate)
+	protected.DELETE("/task-templates/:template_id", handler.DeleteTaskTemplate)
+	protected.POST("/task-templates/:template_id/tasks", handler.CreateTasksFromTemplate)
+	protected.POST("/tasks/:task_id/template", handler.SaveTaskAsTemplate)
+	// Assignment routes
+	protected.POST("/tasks/:task_id/assignees/:user_id", handler.AddAssignee)
+	protected.DELETE("/tasks/:task_id/assignees/:user_id", handler.RemoveAssignee)
+	protected.POST("/tasks/:task_id/watchers/:user_id", handler.AddWatcher)
+	protected.DELETE("/tasks/:task_id/watchers/:user_id", handler.RemoveWatcher)
+	protected.GET("/me/work", handler.GetMyWork)
+	// Reminder routes
+	protected.GET("/projects/:project_id/reminders", handler.GetReminderPolicy)
+	protected.PUT("/projects/:project_id/reminders", handler.UpdateReminderPolicy)
+	protected.GET("/projects/:project_id/tasks/overdue", handler.GetOverdueTasks)
+	protected.GET("/me/overdue", handler.GetMyOverdueTasks)
+	// Calendar routes
+	protected.GET("/projects/:project_id/calendar", handler.GetWorkCalendar)
+	protected.PUT("/projects/:project_id/calendar", handler.UpdateWorkCalendar)
+	protected.GET("/projects/:project_id/holidays", handler.GetHolidays)
+	protected.POST("/projects/:project_id/holidays", handler.AddHoliday)
+	protected.POST("/projects/:project_id/holidays/import", handler.ImportHolidays)
+	protected.DELETE("/projects/:project_id/holidays/:holiday_id", handler.DeleteHoliday)
+	protected.GET("/users/:user_id/preferences", handler.GetPreferences)
+	protected.PUT("/users/:user_id/preferences", handler.UpdatePreferences)
+	// SLA routes
+	protected.GET("/projects/:project_id/sla", handler.GetSLAPolicy)
+	protected.PUT("/projects/:project_id/sla", handler.UpdateSLAPolicy)
+	protected.DELETE("/projects/:project_id/sla", handler.DeleteSLAPolicy)
+	// Automation routes
+	protected.GET("/projects/:project_id/automations", handler.GetAutomationRules)
+	protected.POST("/projects/:project_id/automations", handler.CreateAutomationRule)
+	protected.GET("/automations/:rule_id", handler.GetAutomationRule)
+	protected.PUT("/automations/:rule_id", handler.UpdateAutomationRule)
+	protected.DELETE("/automations/:rule_id", handler.DeleteAutomationRule)
+	protected.GET("/automations/:rule_id/runs", handler.GetAutomationRuns)
+	// Recurrence routes
+	protected.GET("/tasks/:task_id/recurrence", handler.GetTaskRecurrence)
+	protected.PUT("/tasks/:task_id/recurrence", handler.SetTaskRecurrence)
+	protected.DELETE("/tasks/:task_id/recurrence", handler.StopTaskRecurrence)
+	// Label routes
+	protected.GET("/projects/:project_id/labels", handler.GetLabels)
+	protected.POST("/projects/:project_id/labels", handler.CreateLabel)
+	protected.PUT("/projects/:project_id/labels/:label_id", handler.UpdateLabel)
+	protected.DELETE("/projects/:project_id/labels/:label_id", handler.DeleteLabel)
+	protected.POST("/tasks/:task_id/labels/:label_id", handler.AddLabelToTask)
+	protected.DELETE("/tasks/:task_id/labels/:label_id", handler.RemoveLabelFromTask)
+	// Custom field routes
+	protected.GET("/projects/:project_id/custom-fields", handler.GetCustomFields)
+	protected.POST("/projects/:project_id/custom-fields", handler.CreateCustomField)
+	protected.PUT("/projects/:project_id/custom-fields/:field_id", handler.UpdateCustomField)
+	protected.DELETE("/projects/:project_id/custom-fields/:field_id", handler.DeleteCustomField)
+	// Time tracking routes
+	protected.GET("/tasks/:task_id/worklogs", handler.GetWorkLogs)
+	protected.POST("/tasks/:task_id/worklogs", handler.CreateWorkLog)
+	protected.PUT("/worklogs/:worklog_id", handler.UpdateWorkLog)
+	protected.DELETE("/worklogs/:worklog_id", handler.DeleteWorkLog)
+	protected.POST("/tasks/:task_id/timer", handler.StartTimer)
+	protected.GET("/timer", handler.GetTimer)
+	protected.POST("/timer/stop", handler.StopTimer)
+	protected.GET("/timesheets", handler.GetTimesheet)
+	// Comment routes
+	protected.GET("/tasks/:task_id/comments", handler.GetComments)
+	protected.POST("/tasks/:task_id/comments", handler.CreateComment)
+	protected.DELETE("/comments/:comment_id", handler.DeleteComment)
+	// Notification routes
+	protected.GET("/notifications", handler.GetNotifications)
+	protected.GET("/notifications/unread-count", handler.GetUnreadNotificationCount)
+	protected.POST("/notifications/:notification_id/read", handler.MarkNotificationRead)
+	protected.POST("/notifications/read-all", handler.MarkAllNotificationsRead)
+	protected.GET("/notifications/preferences", handler.GetNotificationPreferences)
+	protected.PUT("/notifications/preferences", handler.UpdateNotificationPreferences)
+	protected.GET("/notifications/digest", handler.GetDigestSubscription)
+	protected.PUT("/notifications/digest", handler.UpdateDigestSubscription)
+	// Webhook routes
+	protected.GET("/projects/:project_id/webhooks", handler.GetWebhooks)
+	protected.POST("/projects/:project_id/webhooks", handler.CreateWebhook)
+	protected.PUT("/projects/:project_id/webhooks/:webhook_id", handler.UpdateWebhook)
+	protected.DELETE("/projects/:project_id/webhooks/:webhook_id", handler.DeleteWebhook)
+	protected.GET("/projects/:project_id/webhooks/:webhook_id/deliveries", handler.GetWebhookDeliveries)
+	protected.POST("/projects/:project_id/webhooks/:webhook_id/deliveries/:delivery_id/redeliver", handler.RedeliverWebhook)
+	// Realtime routes
+	protected.POST("/ws/ticket", handler.CreateWebSocketTicket)
 	// User routes
 	protected.GET("/users", handler.GetUsers)
+	// Capacity routes
+	protected.GET("/users/:user_id/capacity", handler.GetCapacity)
+	protected.PUT("/users/:user_id/capacity", handler.SetCapacity)
+	protected.GET("/users/:user_id/time-off", handler.GetTimeOff)
+	protected.POST("/users/:user_id/time-off", handler.AddTimeOff)
+	protected.DELETE("/time-off/:time_off_id", handler.DeleteTimeOff)
+	protected.GET("/users/:user_id/workload", handler.GetWorkload)
+	protected.GET("/projects/:project_id/workload", handler.GetProjectWorkload)
+	// Admin routes
+	protected.GET("/admin/jobs", handler.GetJobs)
+	protected.GET("/admin/jobs/stats", handler.GetJobStats)
+	protected.POST("/admin/jobs/:job_id/retry", handler.RetryJob)
 
 	// Create an HTTP server with the Gin router
 	port := os.Getenv("PORT")
@@ -123,5 +295,15 @@ func main() {
 		log.Fatal("Server shutdown failed: ", err)
 	}
 
+	// Shutdown leaves hijacked WebSocket connections open
+	service.Realtime.Close()
+
+	// Let running jobs finish; unfinished ones are picked up after a restart
+	drainCtx, cancelDrain := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancelDrain()
+	if err := queue.Drain(drainCtx); err != nil {
+		log.Error("Job workers did not finish: ", err)
+	}
+
 	log.Info("Server exited gracefully")
 }
@@ -0,0 +1,17 @@
+package models
+
+import "time"
+
+// AuditLog records an administrative action on a project or task. Unlike
+// the activity feed, entries are kept when the project they describe is
+// deleted.
+type AuditLog struct {
+	ID         uint                   `json:"id" gorm:"primaryKey"`
+	ActorID    uint                   `json:"actor_id" gorm:"index"`
+	Action     string                 `json:"action" gorm:"index"` // e.g. "project.cloned"
+	EntityType string                 `json:"entity_type"`         // "project" or "task"
+	EntityID   uint                   `json:"entity_id"`
+	ProjectID  uint                   `json:"project_id" gorm:"index"` // Project the entity belongs to
+	Details    map[string]interface{} `json:"details" gorm:"type:jsonb;serializer:json"`
+	CreatedAt  time.Time              `json:"created_at"`
+}
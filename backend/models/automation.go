@@ -0,0 +1,125 @@
+package models
+
+import "time"
+
+// Automation triggers, the task changes a rule reacts to
+const (
+	TriggerTaskCreated   = "task_created"
+	TriggerTaskUpdated   = "task_updated" // Any change, status changes and labels included
+	TriggerStatusChanged = "status_changed"
+	TriggerDueDatePassed = "due_date_passed"
+	TriggerCommentAdded  = "comment_added"
+)
+
+// AutomationTriggers lists the triggers a rule can use
+var AutomationTriggers = map[string]bool{
+	TriggerTaskCreated:   true,
+	TriggerTaskUpdated:   true,
+	TriggerStatusChanged: true,
+	TriggerDueDatePassed: true,
+	TriggerCommentAdded:  true,
+}
+
+// Task fields automation conditions can test. previous_status, added_label
+// and comment describe the change that triggered the rule.
+var AutomationConditionFields = map[string]bool{
+	"title":           true,
+	"description":     true,
+	"status":          true,
+	"previous_status": true,
+	"priority":        true,
+	"label":           true,
+	"added_label":     true,
+	"assignee":        true, // User ID
+	"reporter":        true, // User ID
+	"story_points":    true,
+	"due_date":        true, // YYYY-MM-DD
+	"comment":         true,
+}
+
+// Automation condition operators; gt and lt compare numbers, or dates
+// written YYYY-MM-DD
+const (
+	OpEquals    = "eq"
+	OpNotEquals = "ne"
+	OpContains  = "contains"
+	OpGreater   = "gt"
+	OpLess      = "lt"
+	OpEmpty     = "empty"
+	OpNotEmpty  = "not_empty"
+)
+
+// Automation actions
+const (
+	ActionSetField      = "set_field"      // Field and Value
+	ActionAssign        = "assign"         // Value: user ID, "reporter" or "actor"
+	ActionUnassign      = "unassign"       // Value: user ID, "reporter", "actor" or "all"
+	ActionAddLabel      = "add_label"      // Value: label name
+	ActionComment       = "comment"        // Value: comment body
+	ActionMove          = "move"           // Value: status
+	ActionCreateSubtask = "create_subtask" // Value: subtask title
+	ActionNotify        = "notify"         // Value: message, To: who to tell
+	ActionWebhook       = "webhook"        // Value: ID of one of the project's webhooks
+)
+
+// AutomationSetFields are the task fields the set_field action can change
+var AutomationSetFields = map[string]bool{
+	"title":        true,
+	"description":  true,
+	"priority":     true,
+	"story_points": true,
+	"due_date":     true, // YYYY-MM-DD, "+<n>" working days from now, or "" to clear
+}
+
+// Outcomes of an automation run
+const (
+	RunSucceeded = "succeeded"
+	RunFailed    = "failed"
+	RunSkipped   = "skipped" // Held back by the loop protection
+)
+
+// AutomationCondition compares a task field with a value
+type AutomationCondition struct {
+	Field string `json:"field"`
+	Op    string `json:"op"`
+	Value string `json:"value"`
+}
+
+// AutomationAction is one step a rule takes, see the Action constants
+type AutomationAction struct {
+	Type  string `json:"type"`
+	Field string `json:"field,omitempty"`
+	Value string `json:"value,omitempty"`
+	To    string `json:"to,omitempty"` // Recipients of notify: reporter, assignees, watchers, actor or a user ID
+}
+
+// AutomationRule runs its actions on a task of its project when the trigger
+// fires and every condition holds. It acts as the admin who last saved it.
+type AutomationRule struct {
+	ID         uint                  `json:"id" gorm:"primaryKey"`
+	ProjectID  uint                  `json:"project_id" gorm:"index"`
+	Name       string                `json:"name"`
+	Enabled    bool                  `json:"enabled"`
+	Trigger    string                `json:"trigger" gorm:"type:varchar(30)"`
+	Conditions []AutomationCondition `json:"conditions" gorm:"type:text;serializer:json"`
+	Actions    []AutomationAction    `json:"actions" gorm:"type:text;serializer:json"`
+	ActorID    uint                  `json:"actor_id"`
+	CreatedAt  time.Time             `json:"created_at"`
+	UpdatedAt  time.Time             `json:"updated_at"`
+}
+
+// AutomationRun records one execution of a rule. A rule runs at most once
+// per triggering event, so replaying the event does not repeat its actions.
+type AutomationRun struct {
+	ID        uint      `json:"id" gorm:"primaryKey"`
+	RuleID    uint      `json:"rule_id" gorm:"uniqueIndex:idx_automation_run"`
+	ProjectID uint      `json:"project_id" gorm:"index"`
+	TaskID    uint      `json:"task_id" gorm:"uniqueIndex:idx_automation_run"`
+	Event     string    `json:"event" gorm:"type:varchar(40);uniqueIndex:idx_automation_run"`
+	EventAt   time.Time `json:"event_at" gorm:"uniqueIndex:idx_automation_run"`
+	Status    string    `json:"status" gorm:"type:varchar(20)"`
+	Depth     int       `json:"depth"`   // Automations that led to the triggering event
+	Actions   int       `json:"actions"` // Actions carried out
+	Error     string    `json:"error,omitempty"`
+	CreatedAt time.Time `json:"created_at" gorm:"index"`
+}
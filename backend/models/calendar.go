@@ -0,0 +1,43 @@
+package models
+
+import "time"
+
+// Working hours of projects without a working calendar
+const (
+	DefaultDayStart = "09:00"
+	DefaultDayEnd   = "17:00"
+)
+
+// WorkCalendar is the working time of a project: its timezone, working week
+// and daily working hours. Projects without one work DefaultWorkingDays,
+// DefaultDayStart to DefaultDayEnd UTC.
+type WorkCalendar struct {
+	ID          uint      `json:"-" gorm:"primaryKey"`
+	ProjectID   uint      `json:"project_id" gorm:"uniqueIndex"`
+	Timezone    string    `json:"timezone" gorm:"type:varchar(64)"`              // IANA name, e.g. "Europe/Paris"
+	WorkingDays []int     `json:"working_days" gorm:"type:text;serializer:json"` // Weekdays, 0 for Sunday
+	DayStart    string    `json:"day_start" gorm:"type:varchar(5)"`              // HH:MM
+	DayEnd      string    `json:"day_end" gorm:"type:varchar(5)"`                // HH:MM, 24:00 for midnight
+	UpdatedAt   time.Time `json:"updated_at"`
+}
+
+// DefaultWorkCalendar is the calendar of a project that has not set one
+func DefaultWorkCalendar(projectID uint) WorkCalendar {
+	return WorkCalendar{
+		ProjectID:   projectID,
+		Timezone:    "UTC",
+		WorkingDays: append([]int(nil), DefaultWorkingDays...),
+		DayStart:    DefaultDayStart,
+		DayEnd:      DefaultDayEnd,
+	}
+}
+
+// Holiday is a day a project does not work, on top of the days outside its
+// working week
+type Holiday struct {
+	ID        uint      `json:"id" gorm:"primaryKey"`
+	ProjectID uint      `json:"project_id" gorm:"uniqueIndex:idx_holiday_day"`
+	Date      time.Time `json:"date" gorm:"uniqueIndex:idx_holiday_day"` // Midnight UTC of the day
+	Name      string    `json:"name"`
+	CreatedAt time.Time `json:"created_at"`
+}
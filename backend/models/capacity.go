@@ -0,0 +1,50 @@
+package models
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// Capacity units: what a user's weekly capacity and workload are counted in
+const (
+	CapacityHours  = "hours"  // Remaining estimates
+	CapacityPoints = "points" // Story points
+)
+
+// DefaultWeeklyHours is the capacity of users who have not set theirs, over
+// DefaultWorkingDays
+const DefaultWeeklyHours = 40
+
+// DefaultWorkingDays are Monday to Friday
+var DefaultWorkingDays = []int{1, 2, 3, 4, 5}
+
+// UserCapacity is how much work a user takes on per week, spread evenly over
+// their working days
+type UserCapacity struct {
+	ID          uint      `json:"-" gorm:"primaryKey"`
+	UserID      uint      `json:"user_id" gorm:"uniqueIndex"`
+	Unit        string    `json:"unit" gorm:"type:varchar(10)"`
+	Weekly      float64   `json:"weekly"`
+	WorkingDays []int     `json:"working_days" gorm:"type:text;serializer:json"` // Weekdays, 0 for Sunday
+	UpdatedAt   time.Time `json:"updated_at"`
+}
+
+// DefaultCapacity is the capacity of a user who has not set one
+func DefaultCapacity(userID uint) UserCapacity {
+	return UserCapacity{
+		UserID:      userID,
+		Unit:        CapacityHours,
+		Weekly:      DefaultWeeklyHours,
+		WorkingDays: append([]int(nil), DefaultWorkingDays...),
+	}
+}
+
+// TimeOff is a span of days, both included, that a user does not work
+type TimeOff struct {
+	gorm.Model
+	UserID    uint      `json:"user_id" gorm:"index"`
+	StartDate time.Time `json:"start_date"`
+	EndDate   time.Time `json:"end_date"`
+	Reason    string    `json:"reason"`
+}
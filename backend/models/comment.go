@@ -0,0 +1,13 @@
+package models
+
+import "gorm.io/gorm"
+
+// Comment is a message left on a task. Other users can be mentioned in the
+// body as @email.
+type Comment struct {
+	gorm.Model
+	TaskID uint   `json:"task_id" gorm:"index"`
+	UserID uint   `json:"user_id"`
+	Body   string `json:"body" gorm:"type:text"`
+	User   User   `json:"user" gorm:"foreignKey:UserID"`
+}
@@ -0,0 +1,55 @@
+package models
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// Custom field types
+const (
+	FieldTypeText         = "text"
+	FieldTypeNumber       = "number"
+	FieldTypeDate         = "date"
+	FieldTypeSingleSelect = "single_select"
+	FieldTypeMultiSelect  = "multi_select"
+	FieldTypeUser         = "user"
+	FieldTypeURL          = "url"
+)
+
+// CustomField is a project-specific task attribute defined by a project admin
+type CustomField struct {
+	gorm.Model
+	ProjectID    uint        `json:"project_id" gorm:"index"`
+	Name         string      `json:"name"`
+	Type         string      `json:"type" gorm:"type:varchar(20)"`
+	Required     bool        `json:"required"`
+	Options      []string    `json:"options" gorm:"type:text;serializer:json"` // Choices for select fields
+	DefaultValue interface{} `json:"default_value" gorm:"type:text;serializer:json"`
+}
+
+// CustomFieldValue holds one task's value for a custom field. Value keeps the
+// JSON form returned to clients; the typed columns mirror it so that task
+// listings can filter and sort on the database side.
+type CustomFieldValue struct {
+	ID          uint        `json:"-" gorm:"primaryKey"`
+	TaskID      uint        `json:"-" gorm:"uniqueIndex:idx_field_value_task_field"`
+	FieldID     uint        `json:"field_id" gorm:"uniqueIndex:idx_field_value_task_field;index"`
+	Value       interface{} `json:"value" gorm:"type:jsonb;serializer:json"`
+	TextValue   *string     `json:"-"`
+	NumberValue *float64    `json:"-"`
+	DateValue   *time.Time  `json:"-"`
+}
+
+// CustomFieldSortColumn returns the custom_field_values column that orders
+// values of the given field type.
+func CustomFieldSortColumn(fieldType string) string {
+	switch fieldType {
+	case FieldTypeNumber, FieldTypeUser:
+		return "number_value"
+	case FieldTypeDate:
+		return "date_value"
+	default:
+		return "text_value"
+	}
+}
@@ -0,0 +1,13 @@
+package models
+
+import "time"
+
+// TaskDependency makes a task wait for another task of its project
+// (finish-to-start): it can start once DependsOnID is due, LagDays later
+type TaskDependency struct {
+	ID          uint      `json:"id" gorm:"primaryKey"`
+	TaskID      uint      `json:"task_id" gorm:"uniqueIndex:idx_task_dependency"`
+	DependsOnID uint      `json:"depends_on_id" gorm:"uniqueIndex:idx_task_dependency;index"`
+	LagDays     int       `json:"lag_days"`
+	CreatedAt   time.Time `json:"created_at"`
+}
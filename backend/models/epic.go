@@ -0,0 +1,18 @@
+package models
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// Epic is a workspace-wide initiative grouping tasks of several projects.
+// Anyone can see an epic, but only the tasks of projects they belong to.
+type Epic struct {
+	gorm.Model
+	Name        string     `json:"name"`
+	Description string     `json:"description"`
+	OwnerID     uint       `json:"owner_id" gorm:"index"`
+	StartDate   *time.Time `json:"start_date"`
+	TargetDate  *time.Time `json:"target_date"`
+}
@@ -0,0 +1,34 @@
+package models
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// Job states
+const (
+	JobPending   = "pending"
+	JobRunning   = "running"
+	JobSucceeded = "succeeded"
+	JobDead      = "dead" // Gave up after MaxAttempts; kept for inspection and manual retry
+)
+
+// Job is a unit of background work stored in Postgres and picked up by the
+// workers of its queue
+type Job struct {
+	ID          uint            `gorm:"primaryKey" json:"id"`
+	Queue       string          `json:"queue" gorm:"type:varchar(50);index:idx_job_claim,priority:1"`
+	Type        string          `json:"type" gorm:"type:varchar(100)"`
+	Payload     json.RawMessage `json:"payload" gorm:"type:jsonb"`
+	Status      string          `json:"status" gorm:"type:varchar(10);index:idx_job_claim,priority:2"`
+	RunAt       time.Time       `json:"run_at" gorm:"index:idx_job_claim,priority:3"`
+	Attempts    int             `json:"attempts"`
+	MaxAttempts int             `json:"max_attempts"`
+	LastError   string          `json:"last_error" gorm:"type:text"`
+	Key         *string         `json:"key" gorm:"uniqueIndex"` // Optional; a second job with the same key is not enqueued
+	LockedBy    string          `json:"locked_by"`
+	LockedAt    *time.Time      `json:"locked_at"`
+	FinishedAt  *time.Time      `json:"finished_at"`
+	CreatedAt   time.Time       `json:"created_at"`
+	UpdatedAt   time.Time       `json:"updated_at"`
+}
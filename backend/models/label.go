@@ -0,0 +1,15 @@
+package models
+
+import "gorm.io/gorm"
+
+// Label is a project-scoped tag that can be attached to many tasks.
+// Tasks reference labels by ID through the task_labels join table, so
+// renaming or recolouring a label is reflected everywhere it is used.
+// Names are unique within a project ignoring case, through the
+// idx_label_project_lower_name index created at startup.
+type Label struct {
+	gorm.Model
+	ProjectID uint   `json:"project_id" gorm:"index"`
+	Name      string `json:"name"`
+	Color     string `json:"color" gorm:"type:varchar(7)"`
+}
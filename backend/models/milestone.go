@@ -0,0 +1,18 @@
+package models
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// Milestone is a target of a project, typically a release, that tasks are
+// associated with
+type Milestone struct {
+	gorm.Model
+	ProjectID   uint       `json:"project_id" gorm:"index"`
+	Name        string     `json:"name"`
+	Description string     `json:"description"`
+	TargetDate  time.Time  `json:"target_date"`
+	ReleasedAt  *time.Time `json:"released_at"` // Set once the milestone shipped
+}
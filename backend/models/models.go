@@ -11,21 +11,74 @@ type User struct {
 	gorm.Model
 	Name     string
 	Email    string `gorm:"unique;index"`
-	Password string `gorm:"type:varchar(255)"`
+	Password string `json:"-" gorm:"type:varchar(255)"`
+	IsAdmin  bool   `json:"-"`                              // System administrator (job queue, ...); set directly in the database
+	Timezone string `gorm:"type:varchar(64);default:'UTC'"` // IANA name dates and times are shown in
 	Tasks    []Task `gorm:"foreignKey:UserID"`
 }
 
 type Task struct {
 	gorm.Model
-	Title       string    `json:"title"`
-	Description string    `json:"description"`
-	ProjectID   uint      `json:"project_id"`
-	UserID      uint      `json:"user_id"`
-	Status      string    `json:"status"`   // New field for task status (e.g., "To Do", "In Progress", "Completed")
-	DueDate     time.Time `json:"due_date"` // New field for due date
-	User        User      `json:"user" gorm:"foreignKey:UserID"`
-	Project     Project   `json:"project" gorm:"foreignKey:ProjectID"`
+	Title             string             `json:"title"`
+	Description       string             `json:"description"`
+	ProjectID         uint               `json:"project_id"`
+	UserID            uint               `json:"user_id"`  // Primary assignee, kept in step with Assignees
+	Status            string             `json:"status"`   // New field for task status (e.g., "To Do", "In Progress", "Completed")
+	DueDate           time.Time          `json:"due_date"` // New field for due date
+	Priority          string             `json:"priority" gorm:"type:varchar(10);default:'medium'"`
+	StoryPoints       int                `json:"story_points"`
+	OriginalEstimate  int                `json:"original_estimate"`  // Minutes
+	RemainingEstimate int                `json:"remaining_estimate"` // Minutes
+	CompletedAt       *time.Time         `json:"completed_at"`       // Set when the task moves to a completed status
+	OverdueAt         *time.Time         `json:"overdue_at"`         // Set by the scheduler once the due date has passed
+	EscalatedAt       *time.Time         `json:"escalated_at"`       // Set when project admins were told about the overdue task
+	RecurrenceID      *uint              `json:"recurrence_id"`      // Series of a recurring task
+	ParentID          *uint              `json:"parent_id"`          // Parent task of a subtask, in the same project
+	SprintID          *uint              `json:"sprint_id"`          // Sprint the task is planned into, nil for the backlog
+	MilestoneID       *uint              `json:"milestone_id"`       // Milestone the task is part of
+	EpicID            *uint              `json:"epic_id"`            // Epic the task is part of, possibly along with tasks of other projects
+	Rank              string             `json:"rank"`               // Position on the project's board, see RankBetween
+	StartDate         *time.Time         `json:"start_date"`         // Planned start, for timelines and scheduling
+	Duration          int                `json:"duration"`           // Working days from StartDate to DueDate, kept when the task is rescheduled
+	DueDateOnly       bool               `json:"due_date_only"`      // DueDate is a day, midnight UTC, due by its end in the project's timezone
+	ReporterID        uint               `json:"reporter_id"`        // User who created the task, 0 if unknown
+	User              User               `json:"user" gorm:"foreignKey:UserID"`
+	Project           Project            `json:"project" gorm:"foreignKey:ProjectID"`
+	Labels            []Label            `json:"labels" gorm:"many2many:task_labels;"`
+	CustomFields      []CustomFieldValue `json:"custom_fields" gorm:"foreignKey:TaskID"`
+	Assignees         []User             `json:"assignees" gorm:"many2many:task_assignees;"`
+	Watchers          []User             `json:"watchers" gorm:"many2many:task_watchers;"`
+	SLAClocks         []SLAClock         `json:"sla" gorm:"foreignKey:TaskID"`
 }
+
+// Task statuses the backend attaches meaning to
+const (
+	StatusToDo       = "To Do"
+	StatusInProgress = "In Progress"
+	StatusCompleted  = "Completed"
+)
+
+// IsCompletedStatus reports whether a status marks a task as finished
+func IsCompletedStatus(status string) bool {
+	return status == StatusCompleted || status == "Done"
+}
+
+// Task priorities, from lowest to highest
+const (
+	PriorityLow    = "low"
+	PriorityMedium = "medium"
+	PriorityHigh   = "high"
+	PriorityUrgent = "urgent"
+)
+
+// PriorityRank orders priorities so they can be compared and sorted
+var PriorityRank = map[string]int{
+	PriorityLow:    1,
+	PriorityMedium: 2,
+	PriorityHigh:   3,
+	PriorityUrgent: 4,
+}
+
 type Project struct {
 	ID          uint           `gorm:"primaryKey" json:"id"`
 	Name        string         `json:"name"`
@@ -37,8 +90,10 @@ type Project struct {
 	Creator     User           `gorm:"foreignKey:CreatorID" json:"creator"`
 	Tasks       []Task         `json:"tasks"`
 	Users       []UserRole     `gorm:"foreignKey:ProjectID"`
-	CreatedAt   gorm.DeletedAt `json:"created_at"`
-	UpdatedAt   gorm.DeletedAt `json:"updated_at"`
+	CreatedAt   time.Time      `json:"created_at"`
+	UpdatedAt   time.Time      `json:"updated_at"`
+	ArchivedAt  *time.Time     `json:"archived_at"`             // Archived projects are read-only
+	DeletedAt   gorm.DeletedAt `json:"deleted_at" gorm:"index"` // Set while the project is in the trash
 }
 
 type Activity struct {
@@ -0,0 +1,72 @@
+package models
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// Notification is an entry in a user's in-app inbox
+type Notification struct {
+	gorm.Model
+	UserID    uint       `json:"user_id" gorm:"index"`
+	Type      string     `json:"type" gorm:"type:varchar(50)"` // Event type that produced it, e.g. "task.assigned"
+	ProjectID uint       `json:"project_id"`
+	TaskID    uint       `json:"task_id"`
+	ActorID   uint       `json:"actor_id"` // 0 for notifications raised by the system
+	Message   string     `json:"message"`
+	ReadAt    *time.Time `json:"read_at"`
+}
+
+// NotificationPreference overrides whether a user receives a type of
+// notification in the app and by email. Without a row both are on.
+type NotificationPreference struct {
+	ID        uint   `gorm:"primaryKey" json:"-"`
+	UserID    uint   `json:"-" gorm:"uniqueIndex:idx_notification_pref_user_event"`
+	EventType string `json:"event_type" gorm:"type:varchar(50);uniqueIndex:idx_notification_pref_user_event"`
+	InApp     bool   `json:"in_app"`
+	Email     bool   `json:"email"`
+}
+
+// Digest frequencies
+const (
+	DigestDaily  = "daily"
+	DigestWeekly = "weekly"
+)
+
+// DigestSubscription opts a user into a periodic email summarising the
+// activity in their projects
+type DigestSubscription struct {
+	ID         uint       `gorm:"primaryKey" json:"-"`
+	UserID     uint       `json:"-" gorm:"uniqueIndex"`
+	Frequency  string     `json:"frequency" gorm:"type:varchar(10)"`
+	LastSentAt *time.Time `json:"last_sent_at"`
+}
+
+// TaskReminder records that a user was reminded about a task's due date, so
+// that each reminder offset fires once per due date even with several
+// servers
+type TaskReminder struct {
+	ID            uint      `gorm:"primaryKey"`
+	TaskID        uint      `gorm:"uniqueIndex:idx_task_reminder_offset"`
+	UserID        uint      `gorm:"uniqueIndex:idx_task_reminder_offset"`
+	DueDate       time.Time `gorm:"uniqueIndex:idx_task_reminder_offset"`
+	OffsetMinutes int       `gorm:"uniqueIndex:idx_task_reminder_offset"` // How long before the due date
+	SentAt        time.Time
+}
+
+// ReminderPolicy configures the due-date reminders of a project. Projects
+// without one use the defaults of the service layer.
+type ReminderPolicy struct {
+	ID        uint `gorm:"primaryKey" json:"-"`
+	ProjectID uint `gorm:"uniqueIndex" json:"project_id"`
+	// Offsets are the minutes before the due date at which assignees are
+	// reminded, e.g. [1440, 60] for a day and an hour before
+	Offsets []int `gorm:"type:text;serializer:json" json:"offsets"`
+	// EscalateAfter is the grace period, in minutes after the due date, after
+	// which the project admins are told about an open task; 0 disables it
+	EscalateAfter int `json:"escalate_after"`
+	// WorkingTime counts offsets and the grace period in working hours of
+	// the project's calendar rather than around the clock
+	WorkingTime bool `json:"working_time"`
+}
@@ -0,0 +1,31 @@
+package models
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// Outbox event states
+const (
+	OutboxPending   = "pending"
+	OutboxPublished = "published"
+	OutboxFailed    = "failed" // Gave up after too many attempts
+)
+
+// OutboxEvent is a domain event stored in the same transaction as the change
+// it describes, waiting to be published to the in-process subscribers.
+// Events of one aggregate (a task or a project) are published in ID order.
+type OutboxEvent struct {
+	ID            uint            `gorm:"primaryKey" json:"id"`
+	AggregateType string          `json:"aggregate_type" gorm:"type:varchar(20);index:idx_outbox_aggregate,priority:1"`
+	AggregateID   uint            `json:"aggregate_id" gorm:"index:idx_outbox_aggregate,priority:2"`
+	Type          string          `json:"type" gorm:"type:varchar(50)"`
+	Payload       json.RawMessage `json:"payload" gorm:"type:jsonb"`
+	Status        string          `json:"status" gorm:"type:varchar(10);index"`
+	Delivered     []string        `json:"delivered" gorm:"type:text;serializer:json"` // Subscribers that already handled it
+	Attempts      int             `json:"attempts"`
+	LastError     string          `json:"last_error" gorm:"type:text"`
+	NextAttemptAt *time.Time      `json:"next_attempt_at"`
+	PublishedAt   *time.Time      `json:"published_at"`
+	CreatedAt     time.Time       `json:"created_at"`
+}
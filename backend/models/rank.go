@@ -0,0 +1,50 @@
+package models
+
+// Tasks are ordered on a board by Rank, compared byte by byte (COLLATE "C"
+// in SQL). Ranks are strings of rankDigits; a rank can always be found
+// between two different ranks, so moving a card only updates that card.
+// Ranks never end in the lowest digit, which keeps room before every rank.
+const rankDigits = "0123456789abcdefghijklmnopqrstuvwxyz"
+
+// MaxRankLength bounds ranks; the project's ranks are respaced when a move
+// would go past it
+const MaxRankLength = 64
+
+func rankDigit(rank string, i int, fallback int) int {
+	if i >= len(rank) {
+		return fallback
+	}
+	for d := 0; d < len(rankDigits); d++ {
+		if rankDigits[d] == rank[i] {
+			return d
+		}
+	}
+	return fallback
+}
+
+// RankBetween returns a rank sorting after prev and before next. An empty
+// prev is the start of the board and an empty next its end. It reports
+// false when there is no room, i.e. when prev does not sort before next.
+func RankBetween(prev, next string) (string, bool) {
+	if next != "" && prev >= next {
+		return "", false
+	}
+	var rank []byte
+	for i := 0; ; i++ {
+		low := rankDigit(prev, i, 0)
+		high := len(rankDigits)
+		if next != "" {
+			high = rankDigit(next, i, 0)
+		}
+		switch {
+		case high-low > 1:
+			return string(append(rank, rankDigits[(low+high)/2])), true
+		case high-low == 1:
+			// Anything starting with low fits below next; carry on after prev
+			rank = append(rank, rankDigits[low])
+			next = ""
+		default:
+			rank = append(rank, rankDigits[low])
+		}
+	}
+}
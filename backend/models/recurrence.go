@@ -0,0 +1,31 @@
+package models
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// Recurrence is the series a recurring task belongs to: its rule (see the
+// recurrence package) and the template new occurrences are copied from.
+// Each occurrence is an ordinary task pointing back at the series, so
+// editing one occurrence leaves the series alone.
+type Recurrence struct {
+	gorm.Model
+	ProjectID   uint   `json:"project_id" gorm:"index"`
+	Rule        string `json:"rule"`
+	Title       string `json:"title"`
+	Description string `json:"description"`
+	Priority    string `json:"priority" gorm:"type:varchar(10)"`
+	StoryPoints int    `json:"story_points"`
+	LabelIDs    []uint `json:"label_ids" gorm:"type:text;serializer:json"`
+	AssigneeIDs []uint `json:"assignee_ids" gorm:"type:text;serializer:json"`
+	DueDateOnly bool   `json:"due_date_only"` // Occurrences are due on a day rather than at a time
+	ReporterID  uint   `json:"reporter_id"`   // Reporter of the occurrences, that of the task the series started from
+	// AfterCompletion is set for rules anchored on completion; only the other
+	// series are advanced by the scheduler
+	AfterCompletion bool       `json:"after_completion"`
+	LastDueDate     time.Time  `json:"last_due_date"` // Due date of the latest occurrence
+	Occurrences     int        `json:"occurrences"`   // Occurrences so far, skipped ones included
+	EndedAt         *time.Time `json:"ended_at"`      // Set when the series was stopped or ran out
+}
@@ -0,0 +1,109 @@
+package models
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// SLA metrics, each measured by a clock on every task of a project with an
+// SLA policy
+const (
+	SLAResponse   = "response"   // Until the task is commented on or leaves its to-do statuses
+	SLAResolution = "resolution" // Until the task is completed
+)
+
+// SLAMetrics lists the metrics in the order clocks are shown
+var SLAMetrics = []string{SLAResponse, SLAResolution}
+
+// SLA clock states
+const (
+	SLARunning = "running"
+	SLAPaused  = "paused"
+	SLAStopped = "stopped" // The metric is reached, in time or not
+)
+
+// SLATarget is the time allowed, in minutes, for each metric on tasks of a
+// priority; 0 sets no target
+type SLATarget struct {
+	Priority   string `json:"priority"`
+	Response   int    `json:"response"`
+	Resolution int    `json:"resolution"`
+}
+
+// SLAPolicy sets the service level targets of a project's tasks
+type SLAPolicy struct {
+	ID             uint        `json:"-" gorm:"primaryKey"`
+	ProjectID      uint        `json:"project_id" gorm:"uniqueIndex"`
+	Targets        []SLATarget `json:"targets" gorm:"type:text;serializer:json"`
+	PausedStatuses []string    `json:"paused_statuses" gorm:"type:text;serializer:json"` // Clocks do not count while a task is in one of these
+	WorkingTime    bool        `json:"working_time"`                                     // Count only working hours of the project's calendar
+	CreatedAt      time.Time   `json:"created_at"`
+	UpdatedAt      time.Time   `json:"updated_at"`
+}
+
+// Target returns the minutes allowed for a metric on tasks of a priority, 0
+// when there is no target
+func (p *SLAPolicy) Target(priority, metric string) int {
+	for _, target := range p.Targets {
+		if target.Priority != priority {
+			continue
+		}
+		if metric == SLAResponse {
+			return target.Response
+		}
+		return target.Resolution
+	}
+	return 0
+}
+
+// SLAClock measures the time a task takes to reach an SLA metric. The time
+// counted so far is Elapsed plus, while the clock runs, the time since
+// RunningSince.
+type SLAClock struct {
+	ID           uint       `json:"-" gorm:"primaryKey"`
+	TaskID       uint       `json:"task_id" gorm:"uniqueIndex:idx_sla_clock"`
+	ProjectID    uint       `json:"-" gorm:"index"`
+	Metric       string     `json:"metric" gorm:"type:varchar(20);uniqueIndex:idx_sla_clock"`
+	Target       int        `json:"target"`        // Minutes, 0 for none
+	Elapsed      int        `json:"elapsed"`       // Minutes counted before RunningSince
+	RunningSince *time.Time `json:"running_since"` // nil while paused or stopped
+	DueAt        *time.Time `json:"due_at"`        // When the running clock reaches its target
+	StoppedAt    *time.Time `json:"stopped_at"`
+	BreachedAt   *time.Time `json:"breached_at"` // When the clock went over its target
+	SyncedAt     time.Time  `json:"-"`           // Time of the latest task change applied
+	// State, Remaining and Breached are worked out when the clock is loaded.
+	// Remaining is the time left, in minutes, before the target is missed:
+	// until DueAt while the clock runs, what is left of the target otherwise.
+	State     string `json:"state" gorm:"-"`
+	Remaining int    `json:"remaining" gorm:"-"`
+	Breached  bool   `json:"breached" gorm:"-"`
+}
+
+// AfterFind works out the clock's state as of now
+func (c *SLAClock) AfterFind(tx *gorm.DB) error {
+	c.Refresh(time.Now())
+	return nil
+}
+
+// Refresh works out State, Remaining and Breached as of now
+func (c *SLAClock) Refresh(now time.Time) {
+	switch {
+	case c.StoppedAt != nil:
+		c.State = SLAStopped
+	case c.RunningSince != nil:
+		c.State = SLARunning
+	default:
+		c.State = SLAPaused
+	}
+	c.Remaining = 0
+	if c.Target == 0 {
+		c.Breached = false
+		return
+	}
+	c.Remaining = c.Target - c.Elapsed
+	if c.State == SLARunning && c.DueAt != nil {
+		c.Remaining = int(c.DueAt.Sub(now) / time.Minute)
+	}
+	c.Breached = c.BreachedAt != nil || (c.State == SLARunning && c.DueAt != nil && !now.Before(*c.DueAt))
+}
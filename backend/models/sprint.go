@@ -0,0 +1,54 @@
+package models
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// Sprint states; a project has at most one active sprint
+const (
+	SprintPlanned = "planned"
+	SprintActive  = "active"
+	SprintClosed  = "closed"
+)
+
+// Sprint is a time box of a project that tasks are planned into. The
+// committed and completed totals are snapshots taken when the sprint starts
+// and closes, so they stay put when tasks are edited afterwards.
+type Sprint struct {
+	gorm.Model
+	ProjectID       uint       `json:"project_id" gorm:"index"`
+	Name            string     `json:"name"`
+	Goal            string     `json:"goal"`
+	StartDate       time.Time  `json:"start_date"`
+	EndDate         time.Time  `json:"end_date"`
+	State           string     `json:"state" gorm:"type:varchar(10);default:'planned'"`
+	StartedAt       *time.Time `json:"started_at"`
+	ClosedAt        *time.Time `json:"closed_at"`
+	CommittedPoints int        `json:"committed_points"`
+	CommittedTasks  int        `json:"committed_tasks"`
+	CompletedPoints int        `json:"completed_points"`
+	CompletedTasks  int        `json:"completed_tasks"`
+	CarriedOver     []uint     `json:"carried_over" gorm:"type:text;serializer:json"` // Unfinished tasks moved out on close
+	CarriedOverTo   *uint      `json:"carried_over_to"`                               // Sprint they moved to, nil for the backlog
+}
+
+// Kinds of sprint scope changes
+const (
+	ScopeAdded    = "added"
+	ScopeRemoved  = "removed"
+	ScopeEstimate = "estimate"
+)
+
+// SprintChange records a change to the scope of an active sprint: a task
+// added or removed, or the story points of one of its tasks changed
+type SprintChange struct {
+	ID        uint      `json:"id" gorm:"primaryKey"`
+	SprintID  uint      `json:"sprint_id" gorm:"index"`
+	TaskID    uint      `json:"task_id"`
+	Kind      string    `json:"kind"`
+	Points    int       `json:"points"` // Change to the sprint's story points
+	ActorID   uint      `json:"actor_id"`
+	CreatedAt time.Time `json:"created_at"`
+}
@@ -0,0 +1,69 @@
+package models
+
+import "gorm.io/gorm"
+
+// ProjectTemplate is a saved blueprint new projects are created from. Its
+// parts are stored as JSON and refer to each other by name (a task's labels,
+// custom field values and status), so a template stays valid when the
+// project it was saved from changes.
+type ProjectTemplate struct {
+	gorm.Model
+	Name         string           `json:"name"`
+	Description  string           `json:"description"`
+	Category     string           `json:"category"` // Copied to the projects created from it
+	CreatorID    uint             `json:"creator_id" gorm:"index"`
+	Workflow     []TemplateStatus `json:"workflow" gorm:"type:jsonb;serializer:json"` // Empty for the default workflow
+	Labels       []TemplateLabel  `json:"labels" gorm:"type:jsonb;serializer:json"`
+	CustomFields []TemplateField  `json:"custom_fields" gorm:"type:jsonb;serializer:json"`
+	Tasks        []TemplateTask   `json:"tasks" gorm:"type:jsonb;serializer:json"`
+	Members      []TemplateMember `json:"members" gorm:"type:jsonb;serializer:json"` // Added besides the creator
+}
+
+// TaskTemplate is a saved task, with its subtasks, that can be added to its
+// project again
+type TaskTemplate struct {
+	gorm.Model
+	ProjectID uint         `json:"project_id" gorm:"index"`
+	Name      string       `json:"name"`
+	CreatorID uint         `json:"creator_id"`
+	Task      TemplateTask `json:"task" gorm:"type:jsonb;serializer:json"`
+}
+
+type TemplateStatus struct {
+	Name     string `json:"name"`
+	Category string `json:"category"`
+}
+
+type TemplateLabel struct {
+	Name  string `json:"name"`
+	Color string `json:"color"`
+}
+
+type TemplateField struct {
+	Name         string      `json:"name"`
+	Type         string      `json:"type"`
+	Required     bool        `json:"required"`
+	Options      []string    `json:"options"`
+	DefaultValue interface{} `json:"default_value"`
+}
+
+// TemplateTask is a task of a template along with its subtasks
+type TemplateTask struct {
+	Title            string `json:"title"`
+	Description      string `json:"description"`
+	Status           string `json:"status"` // Empty for the first todo status of the workflow
+	Priority         string `json:"priority"`
+	StoryPoints      int    `json:"story_points"`
+	OriginalEstimate int    `json:"original_estimate"` // Minutes
+	// DueOffset is the number of days from the start date the task is due;
+	// nil leaves the task without a due date
+	DueOffset    *int                   `json:"due_offset"`
+	Labels       []string               `json:"labels"`        // Label names
+	CustomFields map[string]interface{} `json:"custom_fields"` // Values by field name
+	Subtasks     []TemplateTask         `json:"subtasks"`
+}
+
+type TemplateMember struct {
+	UserID uint   `json:"user_id"`
+	Role   string `json:"role"`
+}
@@ -0,0 +1,42 @@
+package models
+
+import "gorm.io/gorm"
+
+// Groupings of a view's tasks into swimlanes
+const (
+	GroupByNone     = ""
+	GroupByStatus   = "status"
+	GroupByAssignee = "assignee"
+	GroupByPriority = "priority"
+	GroupByLabel    = "label"
+)
+
+// ViewColumns are the task fields a view can show, besides custom fields
+// ("cf.<field id>")
+var ViewColumns = map[string]bool{
+	"title":              true,
+	"status":             true,
+	"assignee":           true,
+	"priority":           true,
+	"due_date":           true,
+	"labels":             true,
+	"story_points":       true,
+	"original_estimate":  true,
+	"remaining_estimate": true,
+	"sprint":             true,
+	"milestone":          true,
+}
+
+// View is a saved way of looking at a project's tasks, visible to its owner
+// or, when shared, to every member of the project
+type View struct {
+	gorm.Model
+	ProjectID uint     `json:"project_id" gorm:"index"`
+	OwnerID   uint     `json:"owner_id"`
+	Name      string   `json:"name"`
+	Shared    bool     `json:"shared"`
+	Filter    string   `json:"filter"`   // Query string of task listing filters, e.g. "priority=high&labels=3"
+	GroupBy   string   `json:"group_by"` // One of the GroupBy constants
+	Sort      string   `json:"sort"`     // As the sort of task listings
+	Columns   []string `json:"columns" gorm:"type:text;serializer:json"`
+}
@@ -0,0 +1,53 @@
+package models
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// Webhook is an endpoint registered by a project admin to receive the
+// project's events
+type Webhook struct {
+	gorm.Model
+	ProjectID uint     `json:"project_id" gorm:"index"`
+	URL       string   `json:"url"`
+	Secret    string   `json:"secret"`                                  // Key of the HMAC-SHA256 payload signature
+	Events    []string `json:"events" gorm:"type:text;serializer:json"` // Event types to deliver, "*" for all
+	Active    bool     `json:"active"`
+}
+
+// Webhook delivery states
+const (
+	DeliveryPending   = "pending"
+	DeliverySucceeded = "succeeded"
+	DeliveryFailed    = "failed"
+)
+
+// WebhookDelivery is one event sent (or to be sent) to a webhook, with the
+// outcome of the latest attempt
+type WebhookDelivery struct {
+	ID            uint       `gorm:"primaryKey" json:"id"`
+	WebhookID     uint       `json:"webhook_id" gorm:"index"`
+	EventType     string     `json:"event_type" gorm:"type:varchar(50)"`
+	Payload       string     `json:"payload" gorm:"type:text"`
+	Status        string     `json:"status" gorm:"type:varchar(10);index"`
+	Attempts      int        `json:"attempts"`
+	ResponseCode  int        `json:"response_code"`
+	ResponseBody  string     `json:"response_body" gorm:"type:text"` // Start of the body of a successful response
+	Error         string     `json:"error"`
+	NextAttemptAt *time.Time `json:"next_attempt_at" gorm:"index"`
+	DeliveredAt   *time.Time `json:"delivered_at"`
+	CreatedAt     time.Time  `json:"created_at"`
+	UpdatedAt     time.Time  `json:"updated_at"`
+}
+
+// Subscribes reports whether the webhook wants events of the given type
+func (w *Webhook) Subscribes(eventType string) bool {
+	for _, event := range w.Events {
+		if event == "*" || event == eventType {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,38 @@
+package models
+
+// Status categories group the statuses of a workflow
+const (
+	CategoryToDo       = "todo"
+	CategoryInProgress = "in_progress"
+	CategoryDone       = "done"
+)
+
+// WIP policies tell what happens when a status is about to hold more tasks
+// than its WIP limit
+const (
+	WIPWarn   = "warn"   // The move goes through with a warning
+	WIPReject = "reject" // The move is refused
+)
+
+// WorkflowStatus is one status of a project's workflow, in board order.
+// Tasks in a status of the done category count as completed. Projects
+// without saved statuses use DefaultWorkflow and accept any status.
+type WorkflowStatus struct {
+	ID        uint   `json:"id" gorm:"primaryKey"`
+	ProjectID uint   `json:"-" gorm:"uniqueIndex:idx_workflow_project_name"`
+	Name      string `json:"name" gorm:"uniqueIndex:idx_workflow_project_name"`
+	Category  string `json:"category" gorm:"type:varchar(20)"`
+	Position  int    `json:"position"`
+	WIPLimit  int    `json:"wip_limit"`                          // Most tasks the status should hold, 0 for no limit
+	WIPPolicy string `json:"wip_policy" gorm:"type:varchar(10)"` // WIPWarn or WIPReject
+}
+
+// DefaultWorkflow returns the statuses of projects that have not configured
+// a workflow
+func DefaultWorkflow() []WorkflowStatus {
+	return []WorkflowStatus{
+		{Name: StatusToDo, Category: CategoryToDo, Position: 0},
+		{Name: StatusInProgress, Category: CategoryInProgress, Position: 1},
+		{Name: StatusCompleted, Category: CategoryDone, Position: 2},
+	}
+}
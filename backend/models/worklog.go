@@ -0,0 +1,50 @@
+package models
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// MaxWorkLogMinutes is the longest a single work log can be, a day
+const MaxWorkLogMinutes = 24 * 60
+
+// WorkLog records time a user spent on a task
+type WorkLog struct {
+	gorm.Model
+	TaskID    uint      `json:"task_id" gorm:"index"`
+	UserID    uint      `json:"user_id" gorm:"index"`
+	StartedAt time.Time `json:"started_at" gorm:"index"`
+	Duration  int       `json:"duration"` // Minutes
+	Note      string    `json:"note"`
+	User      User      `json:"user" gorm:"foreignKey:UserID"`
+}
+
+// Timer is a running stopwatch on a task. The unique index on UserID keeps
+// at most one timer active per user; stopping it turns it into a WorkLog.
+type Timer struct {
+	ID        uint      `json:"id" gorm:"primaryKey"`
+	UserID    uint      `json:"user_id" gorm:"uniqueIndex"`
+	TaskID    uint      `json:"task_id"`
+	StartedAt time.Time `json:"started_at"`
+	Note      string    `json:"note"`
+	Task      Task      `json:"task" gorm:"foreignKey:TaskID"`
+}
+
+// ToWorkLog converts the timer into a work log ending at end. Durations are
+// rounded up to whole minutes so that a started timer never logs zero, and
+// capped at MaxWorkLogMinutes, as a timer running longer was left on.
+func (t *Timer) ToWorkLog(end time.Time) *WorkLog {
+	minutes := int(end.Sub(t.StartedAt).Minutes())
+	if end.Sub(t.StartedAt) > time.Duration(minutes)*time.Minute || minutes < 1 {
+		minutes++
+	}
+	minutes = min(minutes, MaxWorkLogMinutes)
+	return &WorkLog{
+		TaskID:    t.TaskID,
+		UserID:    t.UserID,
+		StartedAt: t.StartedAt,
+		Duration:  minutes,
+		Note:      t.Note,
+	}
+}
@@ -0,0 +1,128 @@
+// Package realtime pushes messages to users over WebSocket connections.
+// A user may be connected from several browsers at once; every connection
+// receives the user's messages. Delivery is best effort: messages for users
+// who are not connected are dropped, and so are connections that cannot
+// keep up.
+package realtime
+
+import (
+	"encoding/json"
+	"sync"
+
+	"github.com/sirupsen/logrus"
+	"golang.org/x/net/websocket"
+)
+
+// sendBuffer is how many messages may wait for a slow connection before it
+// is dropped
+const sendBuffer = 64
+
+type client struct {
+	userID uint
+	conn   *websocket.Conn
+	send   chan []byte
+}
+
+// Hub keeps track of the open connections of every user
+type Hub struct {
+	mu      sync.RWMutex
+	clients map[uint]map[*client]bool
+
+	ticketsMu sync.Mutex
+	tickets   map[string]ticket
+}
+
+func NewHub() *Hub {
+	return &Hub{clients: map[uint]map[*client]bool{}, tickets: map[string]ticket{}}
+}
+
+// Serve registers a connection of the user and blocks until it is closed.
+// Messages received from the client are ignored.
+func (h *Hub) Serve(userID uint, conn *websocket.Conn) {
+	c := &client{userID: userID, conn: conn, send: make(chan []byte, sendBuffer)}
+	h.mu.Lock()
+	if h.clients[userID] == nil {
+		h.clients[userID] = map[*client]bool{}
+	}
+	h.clients[userID][c] = true
+	h.mu.Unlock()
+	logrus.WithFields(logrus.Fields{
+		"userID": userID,
+	}).Info("Realtime client connected")
+
+	go c.write()
+	var discard string
+	for websocket.Message.Receive(conn, &discard) == nil {
+	}
+	h.remove(c)
+	logrus.WithFields(logrus.Fields{
+		"userID": userID,
+	}).Info("Realtime client disconnected")
+}
+
+func (c *client) write() {
+	for message := range c.send {
+		if err := websocket.Message.Send(c.conn, string(message)); err != nil {
+			c.conn.Close()
+			// Keep draining until the hub removes the client
+			for range c.send {
+			}
+			return
+		}
+	}
+	c.conn.Close()
+}
+
+// remove unregisters a client and stops its writer
+func (h *Hub) remove(c *client) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if !h.clients[c.userID][c] {
+		return
+	}
+	delete(h.clients[c.userID], c)
+	if len(h.clients[c.userID]) == 0 {
+		delete(h.clients, c.userID)
+	}
+	close(c.send)
+}
+
+// Send encodes message as JSON and queues it on every connection of the
+// given users
+func (h *Hub) Send(userIDs []uint, message interface{}) error {
+	raw, err := json.Marshal(message)
+	if err != nil {
+		return err
+	}
+	var slow []*client
+	h.mu.RLock()
+	for _, userID := range userIDs {
+		for c := range h.clients[userID] {
+			select {
+			case c.send <- raw:
+			default:
+				slow = append(slow, c)
+			}
+		}
+	}
+	h.mu.RUnlock()
+	for _, c := range slow {
+		logrus.WithFields(logrus.Fields{
+			"userID": c.userID,
+		}).Warn("Dropping slow realtime client")
+		h.remove(c)
+	}
+	return nil
+}
+
+// Close disconnects every client, e.g. on shutdown
+func (h *Hub) Close() {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	for userID, clients := range h.clients {
+		for c := range clients {
+			close(c.send)
+		}
+		delete(h.clients, userID)
+	}
+}
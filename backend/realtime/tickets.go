@@ -0,0 +1,50 @@
+package realtime
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"time"
+)
+
+// TicketTTL is how long a WebSocket ticket may be redeemed after it is
+// issued
+const TicketTTL = 30 * time.Second
+
+type ticket struct {
+	userID  uint
+	expires time.Time
+}
+
+// IssueTicket returns a single-use ticket that authenticates one WebSocket
+// handshake of the user. Browsers cannot set headers on handshakes, so the
+// ticket travels in the query string instead of the access token.
+func (h *Hub) IssueTicket(userID uint) (string, error) {
+	raw := make([]byte, 32)
+	if _, err := rand.Read(raw); err != nil {
+		return "", err
+	}
+	value := hex.EncodeToString(raw)
+	h.ticketsMu.Lock()
+	defer h.ticketsMu.Unlock()
+	h.tickets[value] = ticket{userID: userID, expires: time.Now().Add(TicketTTL)}
+	return value, nil
+}
+
+// RedeemTicket consumes a ticket and returns the user it was issued to
+func (h *Hub) RedeemTicket(value string) (uint, bool) {
+	now := time.Now()
+	h.ticketsMu.Lock()
+	defer h.ticketsMu.Unlock()
+	t, ok := h.tickets[value]
+	delete(h.tickets, value)
+	// Drop the tickets that were never redeemed
+	for v, other := range h.tickets {
+		if now.After(other.expires) {
+			delete(h.tickets, v)
+		}
+	}
+	if !ok || now.After(t.expires) {
+		return 0, false
+	}
+	return t.userID, true
+}
@@ -0,0 +1,262 @@
+// Package recurrence parses the subset of iCalendar recurrence rules (RFC
+// 5545 RRULE) supported for recurring tasks and computes their occurrences.
+//
+// Supported parts:
+//   - FREQ: DAILY, WEEKLY or MONTHLY
+//   - INTERVAL: every n days, weeks or months (default 1)
+//   - BYDAY: weekdays of weekly rules, e.g. MO,WE,FR
+//   - BYMONTHDAY: day of monthly rules, 1 to 31, or -1 for the last day;
+//     days a month does not have fall on its last day
+//   - COUNT: total number of occurrences
+//   - UNTIL: no occurrences after this time, e.g. 20261231T235959Z
+//   - X-ANCHOR=COMPLETION: the next occurrence is due an interval after the
+//     previous one was completed instead of on a fixed schedule, e.g.
+//     "FREQ=DAILY;INTERVAL=90;X-ANCHOR=COMPLETION"
+package recurrence
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Frequencies
+const (
+	Daily   = "DAILY"
+	Weekly  = "WEEKLY"
+	Monthly = "MONTHLY"
+)
+
+const untilLayout = "20060102T150405Z"
+
+var weekdays = map[string]time.Weekday{
+	"MO": time.Monday, "TU": time.Tuesday, "WE": time.Wednesday, "TH": time.Thursday,
+	"FR": time.Friday, "SA": time.Saturday, "SU": time.Sunday,
+}
+
+var weekdayNames = []string{"SU", "MO", "TU", "WE", "TH", "FR", "SA"}
+
+// Rule is a parsed recurrence rule
+type Rule struct {
+	Freq       string
+	Interval   int
+	ByDay      []time.Weekday // Weekly rules only, in week order from Monday
+	ByMonthDay int            // Monthly rules only; 0 when not given, -1 for the last day
+	Count      int            // 0 means no limit
+	Until      *time.Time
+	// AfterCompletion makes occurrences follow the completion of the
+	// previous one rather than a fixed schedule
+	AfterCompletion bool
+}
+
+// Parse reads a rule such as "FREQ=WEEKLY;BYDAY=MO,TU,WE,TH,FR". An
+// "RRULE:" prefix is accepted.
+func Parse(text string) (*Rule, error) {
+	text = strings.TrimPrefix(strings.TrimSpace(text), "RRULE:")
+	if text == "" {
+		return nil, fmt.Errorf("empty recurrence rule")
+	}
+	rule := &Rule{Interval: 1}
+	seen := map[string]bool{}
+	for _, part := range strings.Split(text, ";") {
+		name, value, ok := strings.Cut(part, "=")
+		name = strings.ToUpper(strings.TrimSpace(name))
+		value = strings.ToUpper(strings.TrimSpace(value))
+		if !ok || value == "" {
+			return nil, fmt.Errorf("invalid rule part %q", part)
+		}
+		if seen[name] {
+			return nil, fmt.Errorf("%s is given twice", name)
+		}
+		seen[name] = true
+		switch name {
+		case "FREQ":
+			if value != Daily && value != Weekly && value != Monthly {
+				return nil, fmt.Errorf("FREQ must be DAILY, WEEKLY or MONTHLY")
+			}
+			rule.Freq = value
+		case "INTERVAL":
+			n, err := strconv.Atoi(value)
+			if err != nil || n < 1 || n > 1000 {
+				return nil, fmt.Errorf("INTERVAL must be between 1 and 1000")
+			}
+			rule.Interval = n
+		case "BYDAY":
+			have := map[time.Weekday]bool{}
+			for _, day := range strings.Split(value, ",") {
+				weekday, ok := weekdays[day]
+				if !ok {
+					return nil, fmt.Errorf("unknown BYDAY weekday %q", day)
+				}
+				have[weekday] = true
+			}
+			for _, weekday := range weekOrder {
+				if have[weekday] {
+					rule.ByDay = append(rule.ByDay, weekday)
+				}
+			}
+		case "BYMONTHDAY":
+			n, err := strconv.Atoi(value)
+			if err != nil || n == 0 || n < -1 || n > 31 {
+				return nil, fmt.Errorf("BYMONTHDAY must be between 1 and 31, or -1")
+			}
+			rule.ByMonthDay = n
+		case "COUNT":
+			n, err := strconv.Atoi(value)
+			if err != nil || n < 1 {
+				return nil, fmt.Errorf("COUNT must be a positive number")
+			}
+			rule.Count = n
+		case "UNTIL":
+			until, err := time.Parse(untilLayout, value)
+			if err != nil {
+				if until, err = time.Parse("20060102", value); err != nil {
+					return nil, fmt.Errorf("UNTIL must look like 20261231T235959Z")
+				}
+				until = until.Add(24*time.Hour - time.Second)
+			}
+			rule.Until = &until
+		case "X-ANCHOR":
+			if value != "COMPLETION" {
+				return nil, fmt.Errorf("X-ANCHOR must be COMPLETION")
+			}
+			rule.AfterCompletion = true
+		default:
+			return nil, fmt.Errorf("unsupported rule part %s", name)
+		}
+	}
+	switch {
+	case rule.Freq == "":
+		return nil, fmt.Errorf("FREQ is required")
+	case rule.Count > 0 && rule.Until != nil:
+		return nil, fmt.Errorf("COUNT and UNTIL cannot be combined")
+	case len(rule.ByDay) > 0 && rule.Freq != Weekly:
+		return nil, fmt.Errorf("BYDAY is only supported with FREQ=WEEKLY")
+	case rule.ByMonthDay != 0 && rule.Freq != Monthly:
+		return nil, fmt.Errorf("BYMONTHDAY is only supported with FREQ=MONTHLY")
+	case rule.AfterCompletion && (len(rule.ByDay) > 0 || rule.ByMonthDay != 0):
+		return nil, fmt.Errorf("X-ANCHOR=COMPLETION cannot be combined with BYDAY or BYMONTHDAY")
+	}
+	return rule, nil
+}
+
+// weekOrder lists the weekdays from Monday, the first day of the week
+var weekOrder = []time.Weekday{
+	time.Monday, time.Tuesday, time.Wednesday, time.Thursday, time.Friday, time.Saturday, time.Sunday,
+}
+
+// weekIndex is the position of a weekday in a week starting on Monday
+func weekIndex(day time.Weekday) int {
+	return (int(day) + 6) % 7
+}
+
+// Anchor fills in the parts the first occurrence implies: the weekday of
+// weekly rules and the day of monthly rules without BYDAY or BYMONTHDAY.
+// This keeps later occurrences from drifting, e.g. after a short month.
+func (r *Rule) Anchor(first time.Time) {
+	if r.AfterCompletion {
+		return
+	}
+	if r.Freq == Weekly && len(r.ByDay) == 0 {
+		r.ByDay = []time.Weekday{first.Weekday()}
+	}
+	if r.Freq == Monthly && r.ByMonthDay == 0 {
+		r.ByMonthDay = first.Day()
+	}
+}
+
+// String formats the rule back into RRULE syntax
+func (r *Rule) String() string {
+	parts := []string{"FREQ=" + r.Freq}
+	if r.Interval > 1 {
+		parts = append(parts, "INTERVAL="+strconv.Itoa(r.Interval))
+	}
+	if len(r.ByDay) > 0 {
+		names := make([]string, 0, len(r.ByDay))
+		for _, day := range r.ByDay {
+			names = append(names, weekdayNames[day])
+		}
+		parts = append(parts, "BYDAY="+strings.Join(names, ","))
+	}
+	if r.ByMonthDay != 0 {
+		parts = append(parts, "BYMONTHDAY="+strconv.Itoa(r.ByMonthDay))
+	}
+	if r.Count > 0 {
+		parts = append(parts, "COUNT="+strconv.Itoa(r.Count))
+	}
+	if r.Until != nil {
+		parts = append(parts, "UNTIL="+r.Until.UTC().Format(untilLayout))
+	}
+	if r.AfterCompletion {
+		parts = append(parts, "X-ANCHOR=COMPLETION")
+	}
+	return strings.Join(parts, ";")
+}
+
+// Next returns the occurrence following prev, at prev's time of day. For
+// rules anchored on completion, prev is the time the previous occurrence was
+// completed. The caller enforces COUNT and UNTIL, see Ends.
+func (r *Rule) Next(prev time.Time) time.Time {
+	if r.AfterCompletion {
+		return r.step(prev, r.Interval)
+	}
+	switch r.Freq {
+	case Weekly:
+		if len(r.ByDay) == 0 {
+			return prev.AddDate(0, 0, 7*r.Interval)
+		}
+		// A later day in the same week, else the first day of the week
+		// Interval weeks on
+		for _, day := range r.ByDay {
+			if weekIndex(day) > weekIndex(prev.Weekday()) {
+				return prev.AddDate(0, 0, weekIndex(day)-weekIndex(prev.Weekday()))
+			}
+		}
+		weekStart := prev.AddDate(0, 0, -weekIndex(prev.Weekday()))
+		return weekStart.AddDate(0, 0, 7*r.Interval+weekIndex(r.ByDay[0]))
+	case Monthly:
+		if r.ByMonthDay == 0 {
+			return r.step(prev, r.Interval)
+		}
+		if candidate := monthDay(prev, 0, r.ByMonthDay); candidate.After(prev) {
+			return candidate
+		}
+		return monthDay(prev, r.Interval, r.ByMonthDay)
+	default:
+		return prev.AddDate(0, 0, r.Interval)
+	}
+}
+
+// step moves t on by n units of the rule's frequency
+func (r *Rule) step(t time.Time, n int) time.Time {
+	switch r.Freq {
+	case Weekly:
+		return t.AddDate(0, 0, 7*n)
+	case Monthly:
+		return monthDay(t, n, t.Day())
+	default:
+		return t.AddDate(0, 0, n)
+	}
+}
+
+// monthDay returns the given day of the month months after t's month, at t's
+// time of day; -1 and days past the end of the month give its last day
+func monthDay(t time.Time, months, day int) time.Time {
+	first := time.Date(t.Year(), t.Month()+time.Month(months), 1, t.Hour(), t.Minute(), t.Second(), t.Nanosecond(), t.Location())
+	last := first.AddDate(0, 1, -1).Day()
+	if day == -1 || day > last {
+		day = last
+	}
+	return first.AddDate(0, 0, day-1)
+}
+
+// Ends reports whether an occurrence due at next, which would be the
+// occurrence with the given 1-based number, falls outside the rule's COUNT or
+// UNTIL
+func (r *Rule) Ends(next time.Time, number int) bool {
+	if r.Count > 0 && number > r.Count {
+		return true
+	}
+	return r.Until != nil && next.After(*r.Until)
+}
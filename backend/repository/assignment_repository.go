@@ -0,0 +1,96 @@
+package repository
+
+import (
+	"work-management/dto"
+	"work-management/models"
+
+	"gorm.io/gorm"
+)
+
+// syncPrimaryAssignees resets tasks.user_id, for the tasks matching the
+// condition, to their lowest remaining assignee (or 0 when none is left).
+func syncPrimaryAssignees(tx *gorm.DB, condition string, args ...interface{}) error {
+	return tx.Model(&models.Task{}).
+		Where(condition, args...).
+		Update("user_id", gorm.Expr("COALESCE((SELECT MIN(ta.user_id) FROM task_assignees ta WHERE ta.task_id = tasks.id), 0)")).
+		Error
+}
+
+// AddTaskAssignee assigns a user to a task, making them the primary assignee
+// if the task had none.
+func (r *Repository) AddTaskAssignee(taskID, userID uint) error {
+	return r.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Exec(
+			"INSERT INTO task_assignees (task_id, user_id) VALUES (?, ?) ON CONFLICT DO NOTHING",
+			taskID, userID,
+		).Error; err != nil {
+			return err
+		}
+		return tx.Model(&models.Task{}).Where("id = ? AND user_id = 0", taskID).Update("user_id", userID).Error
+	})
+}
+
+// RemoveTaskAssignee unassigns a user, handing the primary assignee slot to
+// another assignee if the user held it. It reports whether the user was
+// assigned.
+func (r *Repository) RemoveTaskAssignee(taskID, userID uint) (bool, error) {
+	removed := false
+	err := r.DB.Transaction(func(tx *gorm.DB) error {
+		result := tx.Exec("DELETE FROM task_assignees WHERE task_id = ? AND user_id = ?", taskID, userID)
+		if result.Error != nil || result.RowsAffected == 0 {
+			return result.Error
+		}
+		removed = true
+		return syncPrimaryAssignees(tx, "id = ? AND user_id = ?", taskID, userID)
+	})
+	return removed, err
+}
+
+// replaceTaskAssignees sets the full assignee list of a task
+func replaceTaskAssignees(tx *gorm.DB, taskID uint, userIDs []uint) error {
+	if err := tx.Exec("DELETE FROM task_assignees WHERE task_id = ?", taskID).Error; err != nil {
+		return err
+	}
+	for _, userID := range userIDs {
+		if err := tx.Exec(
+			"INSERT INTO task_assignees (task_id, user_id) VALUES (?, ?) ON CONFLICT DO NOTHING",
+			taskID, userID,
+		).Error; err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (r *Repository) AddTaskWatcher(taskID, userID uint) error {
+	return r.DB.Exec(
+		"INSERT INTO task_watchers (task_id, user_id) VALUES (?, ?) ON CONFLICT DO NOTHING",
+		taskID, userID,
+	).Error
+}
+
+func (r *Repository) RemoveTaskWatcher(taskID, userID uint) error {
+	return r.DB.Exec("DELETE FROM task_watchers WHERE task_id = ? AND user_id = ?", taskID, userID).Error
+}
+
+// GetTaskAssigneeIDs returns the users assigned to a task
+func (r *Repository) GetTaskAssigneeIDs(taskID uint) ([]uint, error) {
+	var userIDs []uint
+	err := r.DB.Table("task_assignees").Where("task_id = ?", taskID).Pluck("user_id", &userIDs).Error
+	return userIDs, err
+}
+
+// GetTaskWatcherIDs returns the users watching a task
+func (r *Repository) GetTaskWatcherIDs(taskID uint) ([]uint, error) {
+	var userIDs []uint
+	err := r.DB.Table("task_watchers").Where("task_id = ?", taskID).Pluck("user_id", &userIDs).Error
+	return userIDs, err
+}
+
+// GetMyWork lists the tasks assigned to or watched by a user in the projects
+// they still belong to.
+func (r *Repository) GetMyWork(userID uint, filter dto.TaskFilter) ([]models.Task, error) {
+	filter.InvolvedUserID = userID
+	filter.VisibleToUserID = userID
+	return r.GetTasks(filter)
+}
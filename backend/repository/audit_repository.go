@@ -0,0 +1,24 @@
+package repository
+
+import (
+	"work-management/models"
+)
+
+func (r *Repository) CreateAuditLog(entry *models.AuditLog) error {
+	return r.DB.Create(entry).Error
+}
+
+// GetAuditLogs lists the latest entries, optionally of one project and
+// action
+func (r *Repository) GetAuditLogs(projectID uint, action string, limit int) ([]models.AuditLog, error) {
+	query := r.DB.Order("id DESC").Limit(limit)
+	if projectID != 0 {
+		query = query.Where("project_id = ?", projectID)
+	}
+	if action != "" {
+		query = query.Where("action = ?", action)
+	}
+	var entries []models.AuditLog
+	err := query.Find(&entries).Error
+	return entries, err
+}
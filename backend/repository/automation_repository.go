@@ -0,0 +1,72 @@
+package repository
+
+import (
+	"time"
+
+	"work-management/models"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+func (r *Repository) GetAutomationRules(projectID uint) ([]models.AutomationRule, error) {
+	var rules []models.AutomationRule
+	err := r.DB.Where("project_id = ?", projectID).Order("id").Find(&rules).Error
+	return rules, err
+}
+
+// GetEnabledAutomationRules lists the enabled rules of a project that use
+// one of the given triggers, in the order they were created
+func (r *Repository) GetEnabledAutomationRules(projectID uint, triggers []string) ([]models.AutomationRule, error) {
+	var rules []models.AutomationRule
+	err := r.DB.
+		Where("project_id = ? AND enabled AND trigger IN ?", projectID, triggers).
+		Order("id").
+		Find(&rules).Error
+	return rules, err
+}
+
+func (r *Repository) GetAutomationRule(ruleID uint) (*models.AutomationRule, error) {
+	var rule models.AutomationRule
+	err := r.DB.First(&rule, ruleID).Error
+	return &rule, err
+}
+
+func (r *Repository) CreateAutomationRule(rule *models.AutomationRule) error {
+	return r.DB.Create(rule).Error
+}
+
+func (r *Repository) UpdateAutomationRule(rule *models.AutomationRule) error {
+	return r.DB.Save(rule).Error
+}
+
+// DeleteAutomationRule deletes a rule along with its runs
+func (r *Repository) DeleteAutomationRule(ruleID uint) error {
+	return r.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("rule_id = ?", ruleID).Delete(&models.AutomationRun{}).Error; err != nil {
+			return err
+		}
+		return tx.Delete(&models.AutomationRule{}, ruleID).Error
+	})
+}
+
+// ClaimAutomationRun records a run unless the rule already ran for the same
+// event, and reports whether it did
+func (r *Repository) ClaimAutomationRun(run *models.AutomationRun) (bool, error) {
+	result := r.DB.Clauses(clause.OnConflict{DoNothing: true}).Create(run)
+	return result.RowsAffected == 1, result.Error
+}
+
+// GetAutomationRuns lists the latest runs of a rule, newest first
+func (r *Repository) GetAutomationRuns(ruleID uint, limit int) ([]models.AutomationRun, error) {
+	var runs []models.AutomationRun
+	err := r.DB.Where("rule_id = ?", ruleID).Order("id DESC").Limit(limit).Find(&runs).Error
+	return runs, err
+}
+
+// DeleteAutomationRuns deletes the runs recorded before the given time and
+// returns how many there were
+func (r *Repository) DeleteAutomationRuns(before time.Time) (int64, error) {
+	result := r.DB.Where("created_at < ?", before).Delete(&models.AutomationRun{})
+	return result.RowsAffected, result.Error
+}
@@ -0,0 +1,91 @@
+package repository
+
+import (
+	"work-management/models"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// rankOrder sorts tasks by rank byte by byte, whatever the database collation
+const rankOrder = `tasks.rank COLLATE "C"`
+
+// respaceRanksSQL gives every task of a project a fresh, evenly spaced rank
+// in its current order; trashed tasks keep their place for when they are
+// restored
+const respaceRanksSQL = `
+	UPDATE tasks SET rank = r.rank FROM (
+		SELECT id, lpad(to_hex(ROW_NUMBER() OVER (ORDER BY rank COLLATE "C", id)), 8, '0') || 'i' AS rank
+		FROM tasks WHERE project_id = ?
+	) r WHERE tasks.id = r.id`
+
+// lastRank returns the highest rank of a project, so that a new task can be
+// ranked after it. It respaces the ranks when they grew too long.
+func lastRank(tx *gorm.DB, projectID uint) (string, error) {
+	var last string
+	for {
+		err := tx.Raw("SELECT rank FROM tasks WHERE project_id = ? ORDER BY rank COLLATE \"C\" DESC LIMIT 1", projectID).
+			Scan(&last).Error
+		if err != nil || len(last) < models.MaxRankLength {
+			return last, err
+		}
+		if err := tx.Exec(respaceRanksSQL, projectID).Error; err != nil {
+			return "", err
+		}
+	}
+}
+
+// LockProject locks a project's row until the end of the transaction, so
+// that concurrent board moves in the project happen one after the other
+func (r *Repository) LockProject(projectID uint) error {
+	var project models.Project
+	return r.DB.Clauses(clause.Locking{Strength: "UPDATE"}).Select("id").First(&project, projectID).Error
+}
+
+// CountTasksInStatus counts the tasks of a project in a status, leaving out
+// the given task
+func (r *Repository) CountTasksInStatus(projectID uint, status string, excludeID uint) (int64, error) {
+	var count int64
+	err := r.DB.Model(&models.Task{}).
+		Where("project_id = ? AND status = ? AND id <> ?", projectID, status, excludeID).
+		Count(&count).Error
+	return count, err
+}
+
+// GetTaskAfter returns the task following after in a board column, or the
+// first task of the column when after is nil, leaving out excludeID
+func (r *Repository) GetTaskAfter(projectID uint, status string, excludeID uint, after *models.Task) (*models.Task, error) {
+	query := r.DB.Where("project_id = ? AND status = ? AND id <> ?", projectID, status, excludeID)
+	if after != nil {
+		query = query.Where(rankOrder+" > ? OR (tasks.rank = ? AND tasks.id > ?)", after.Rank, after.Rank, after.ID)
+	}
+	var task models.Task
+	err := query.Order(rankOrder).Order("tasks.id").First(&task).Error
+	return &task, err
+}
+
+// GetTaskBefore returns the task preceding before in a board column, or the
+// last task of the column when before is nil, leaving out excludeID
+func (r *Repository) GetTaskBefore(projectID uint, status string, excludeID uint, before *models.Task) (*models.Task, error) {
+	query := r.DB.Where("project_id = ? AND status = ? AND id <> ?", projectID, status, excludeID)
+	if before != nil {
+		query = query.Where(rankOrder+" < ? OR (tasks.rank = ? AND tasks.id < ?)", before.Rank, before.Rank, before.ID)
+	}
+	var task models.Task
+	err := query.Order(rankOrder + " DESC").Order("tasks.id DESC").First(&task).Error
+	return &task, err
+}
+
+// MoveTask saves a task's status, position and the fields following its
+// status; the rest of the task is left untouched
+func (r *Repository) MoveTask(task *models.Task) error {
+	return r.DB.Model(task).
+		Select("status", "rank", "completed_at", "remaining_estimate", "updated_at").
+		Updates(task).Error
+}
+
+// RespaceRanks gives every task of a project a fresh rank in its current
+// order, for when there is no room left between two ranks
+func (r *Repository) RespaceRanks(projectID uint) error {
+	return r.DB.Exec(respaceRanksSQL, projectID).Error
+}
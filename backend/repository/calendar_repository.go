@@ -0,0 +1,67 @@
+package repository
+
+import (
+	"time"
+	"work-management/models"
+
+	"gorm.io/gorm/clause"
+)
+
+// taskDeadline is the moment a task falls due: its due date, or the end of
+// its due day in its project's timezone for date-only due dates
+const taskDeadline = `(CASE WHEN tasks.due_date_only THEN
+	((tasks.due_date AT TIME ZONE 'UTC')::date + 1)::timestamp AT TIME ZONE
+	COALESCE((SELECT timezone FROM work_calendars WHERE work_calendars.project_id = tasks.project_id), 'UTC')
+	ELSE tasks.due_date END)`
+
+func (r *Repository) GetWorkCalendar(projectID uint) (*models.WorkCalendar, error) {
+	var workCalendar models.WorkCalendar
+	err := r.DB.Where("project_id = ?", projectID).First(&workCalendar).Error
+	return &workCalendar, err
+}
+
+// SaveWorkCalendar creates or replaces the working calendar of a project
+func (r *Repository) SaveWorkCalendar(workCalendar *models.WorkCalendar) error {
+	return r.DB.Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "project_id"}},
+		DoUpdates: clause.AssignmentColumns([]string{"timezone", "working_days", "day_start", "day_end", "updated_at"}),
+	}).Create(workCalendar).Error
+}
+
+// GetHolidays lists a project's holidays within the given days, by date;
+// zero bounds leave that side open
+func (r *Repository) GetHolidays(projectID uint, from, to time.Time) ([]models.Holiday, error) {
+	var holidays []models.Holiday
+	query := r.DB.Where("project_id = ?", projectID)
+	if !from.IsZero() {
+		query = query.Where("date >= ?", from)
+	}
+	if !to.IsZero() {
+		query = query.Where("date <= ?", to)
+	}
+	err := query.Order("date").Find(&holidays).Error
+	return holidays, err
+}
+
+// SaveHolidays adds holidays to a project, renaming those it already has on
+// the same days
+func (r *Repository) SaveHolidays(holidays []models.Holiday) error {
+	if len(holidays) == 0 {
+		return nil
+	}
+	return r.DB.Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "project_id"}, {Name: "date"}},
+		DoUpdates: clause.AssignmentColumns([]string{"name"}),
+	}).Create(&holidays).Error
+}
+
+// DeleteHoliday deletes a holiday of a project and reports whether there was
+// one
+func (r *Repository) DeleteHoliday(projectID, holidayID uint) (bool, error) {
+	result := r.DB.Where("project_id = ?", projectID).Delete(&models.Holiday{}, holidayID)
+	return result.RowsAffected == 1, result.Error
+}
+
+func (r *Repository) SetUserTimezone(userID uint, timezone string) error {
+	return r.DB.Model(&models.User{}).Where("id = ?", userID).UpdateColumn("timezone", timezone).Error
+}
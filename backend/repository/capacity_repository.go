@@ -0,0 +1,51 @@
+package repository
+
+import (
+	"time"
+	"work-management/models"
+
+	"gorm.io/gorm/clause"
+)
+
+func (r *Repository) GetUserCapacity(userID uint) (*models.UserCapacity, error) {
+	var capacity models.UserCapacity
+	err := r.DB.Where("user_id = ?", userID).First(&capacity).Error
+	return &capacity, err
+}
+
+// SaveUserCapacity creates or replaces a user's capacity
+func (r *Repository) SaveUserCapacity(capacity *models.UserCapacity) error {
+	return r.DB.Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "user_id"}},
+		DoUpdates: clause.AssignmentColumns([]string{"unit", "weekly", "working_days", "updated_at"}),
+	}).Create(capacity).Error
+}
+
+// GetTimeOff lists a user's time off overlapping the given days, by start;
+// zero bounds leave that side open
+func (r *Repository) GetTimeOff(userID uint, from, to time.Time) ([]models.TimeOff, error) {
+	var timeOff []models.TimeOff
+	query := r.DB.Where("user_id = ?", userID)
+	if !from.IsZero() {
+		query = query.Where("end_date >= ?", from)
+	}
+	if !to.IsZero() {
+		query = query.Where("start_date <= ?", to)
+	}
+	err := query.Order("start_date, id").Find(&timeOff).Error
+	return timeOff, err
+}
+
+func (r *Repository) GetTimeOffByID(timeOffID uint) (*models.TimeOff, error) {
+	var timeOff models.TimeOff
+	err := r.DB.First(&timeOff, timeOffID).Error
+	return &timeOff, err
+}
+
+func (r *Repository) CreateTimeOff(timeOff *models.TimeOff) error {
+	return r.DB.Create(timeOff).Error
+}
+
+func (r *Repository) DeleteTimeOff(timeOffID uint) error {
+	return r.DB.Delete(&models.TimeOff{}, timeOffID).Error
+}
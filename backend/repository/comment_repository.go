@@ -0,0 +1,23 @@
+package repository
+
+import "work-management/models"
+
+func (r *Repository) CreateComment(comment *models.Comment) error {
+	return r.DB.Create(comment).Error
+}
+
+func (r *Repository) GetCommentsByTaskID(taskID uint) ([]models.Comment, error) {
+	var comments []models.Comment
+	err := r.DB.Preload("User").Where("task_id = ?", taskID).Order("created_at, id").Find(&comments).Error
+	return comments, err
+}
+
+func (r *Repository) GetCommentByID(commentID uint) (*models.Comment, error) {
+	var comment models.Comment
+	err := r.DB.Preload("User").First(&comment, commentID).Error
+	return &comment, err
+}
+
+func (r *Repository) DeleteComment(commentID uint) error {
+	return r.DB.Delete(&models.Comment{}, commentID).Error
+}
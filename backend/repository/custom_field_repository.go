@@ -0,0 +1,95 @@
+package repository
+
+import (
+	"work-management/models"
+
+	"github.com/sirupsen/logrus"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+func (r *Repository) CreateCustomField(field *models.CustomField) error {
+	return r.DB.Create(field).Error
+}
+
+func (r *Repository) GetCustomFieldsByProjectID(projectID uint) ([]models.CustomField, error) {
+	var fields []models.CustomField
+	err := r.DB.Where("project_id = ?", projectID).Order("id").Find(&fields).Error
+	return fields, err
+}
+
+func (r *Repository) GetCustomFieldByID(fieldID uint) (*models.CustomField, error) {
+	var field models.CustomField
+	err := r.DB.First(&field, fieldID).Error
+	return &field, err
+}
+
+func (r *Repository) UpdateCustomField(field *models.CustomField) error {
+	return r.DB.Save(field).Error
+}
+
+// DeleteCustomField removes a field definition together with every stored
+// value, so tasks never reference a field that no longer exists.
+func (r *Repository) DeleteCustomField(fieldID uint) error {
+	tx := r.DB.Begin()
+	if tx.Error != nil {
+		logrus.WithFields(logrus.Fields{
+			"fieldID": fieldID,
+			"error":   tx.Error,
+		}).Error("Failed to start transaction")
+		return tx.Error
+	}
+
+	if err := tx.Where("field_id = ?", fieldID).Delete(&models.CustomFieldValue{}).Error; err != nil {
+		logrus.WithFields(logrus.Fields{
+			"fieldID": fieldID,
+			"error":   err,
+		}).Error("Failed to delete custom field values")
+		tx.Rollback()
+		return err
+	}
+
+	if err := tx.Unscoped().Delete(&models.CustomField{}, fieldID).Error; err != nil {
+		logrus.WithFields(logrus.Fields{
+			"fieldID": fieldID,
+			"error":   err,
+		}).Error("Failed to delete custom field")
+		tx.Rollback()
+		return err
+	}
+
+	return tx.Commit().Error
+}
+
+func (r *Repository) GetCustomFieldValuesByFieldID(fieldID uint) ([]models.CustomFieldValue, error) {
+	var values []models.CustomFieldValue
+	err := r.DB.Where("field_id = ?", fieldID).Find(&values).Error
+	return values, err
+}
+
+// SaveTaskCustomFields upserts values for a task and removes the values of the
+// fields listed in cleared, all in one transaction.
+func (r *Repository) SaveTaskCustomFields(taskID uint, values []models.CustomFieldValue, cleared []uint) error {
+	return r.DB.Transaction(func(tx *gorm.DB) error {
+		return saveTaskCustomFields(tx, taskID, values, cleared)
+	})
+}
+
+func saveTaskCustomFields(tx *gorm.DB, taskID uint, values []models.CustomFieldValue, cleared []uint) error {
+	if len(cleared) > 0 {
+		if err := tx.Where("task_id = ? AND field_id IN ?", taskID, cleared).Delete(&models.CustomFieldValue{}).Error; err != nil {
+			return err
+		}
+	}
+	for i := range values {
+		values[i].TaskID = taskID
+		err := tx.Clauses(clause.OnConflict{
+			Columns:   []clause.Column{{Name: "task_id"}, {Name: "field_id"}},
+			DoUpdates: clause.AssignmentColumns([]string{"value", "text_value", "number_value", "date_value"}),
+		}).Create(&values[i]).Error
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
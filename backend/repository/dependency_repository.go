@@ -0,0 +1,66 @@
+package repository
+
+import (
+	"work-management/models"
+
+	"gorm.io/gorm"
+)
+
+// liveTask matches IDs of tasks that are not in the trash
+const liveTask = "SELECT id FROM tasks WHERE deleted_at IS NULL"
+
+func (r *Repository) CreateDependency(dependency *models.TaskDependency) error {
+	return r.DB.Create(dependency).Error
+}
+
+// DeleteDependency stops a task waiting for another; it returns
+// gorm.ErrRecordNotFound when it did not
+func (r *Repository) DeleteDependency(taskID, dependsOnID uint) error {
+	result := r.DB.Where("task_id = ? AND depends_on_id = ?", taskID, dependsOnID).Delete(&models.TaskDependency{})
+	if result.Error == nil && result.RowsAffected == 0 {
+		return gorm.ErrRecordNotFound
+	}
+	return result.Error
+}
+
+// DeleteTaskDependencies drops the dependencies of a task in both directions
+func (r *Repository) DeleteTaskDependencies(taskID uint) error {
+	return r.DB.Where("task_id = ? OR depends_on_id = ?", taskID, taskID).Delete(&models.TaskDependency{}).Error
+}
+
+// GetDependencies lists what a task waits for, leaving out trashed tasks
+func (r *Repository) GetDependencies(taskID uint) ([]models.TaskDependency, error) {
+	var dependencies []models.TaskDependency
+	err := r.DB.Where("task_id = ? AND depends_on_id IN ("+liveTask+")", taskID).
+		Order("id").
+		Find(&dependencies).Error
+	return dependencies, err
+}
+
+// GetDependents lists what waits for a task, leaving out trashed tasks
+func (r *Repository) GetDependents(taskID uint) ([]models.TaskDependency, error) {
+	var dependencies []models.TaskDependency
+	err := r.DB.Where("depends_on_id = ? AND task_id IN ("+liveTask+")", taskID).
+		Order("id").
+		Find(&dependencies).Error
+	return dependencies, err
+}
+
+// GetProjectDependencies lists the dependencies between the live tasks of a
+// project
+func (r *Repository) GetProjectDependencies(projectID uint) ([]models.TaskDependency, error) {
+	var dependencies []models.TaskDependency
+	projectTask := liveTask + " AND project_id = ?"
+	err := r.DB.
+		Where("task_id IN ("+projectTask+") AND depends_on_id IN ("+projectTask+")", projectID, projectID).
+		Order("id").
+		Find(&dependencies).Error
+	return dependencies, err
+}
+
+// SetTaskDates saves a task's planned dates, re-arming its overdue marking
+func (r *Repository) SetTaskDates(task *models.Task) error {
+	return r.DB.Model(task).
+		Select("start_date", "due_date", "duration", "overdue_at", "escalated_at", "updated_at").
+		Updates(task).Error
+}
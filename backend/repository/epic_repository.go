@@ -0,0 +1,43 @@
+package repository
+
+import (
+	"work-management/models"
+
+	"gorm.io/gorm"
+)
+
+func (r *Repository) CreateEpic(epic *models.Epic) error {
+	return r.DB.Create(epic).Error
+}
+
+func (r *Repository) GetEpics() ([]models.Epic, error) {
+	var epics []models.Epic
+	err := r.DB.Order("id DESC").Find(&epics).Error
+	return epics, err
+}
+
+func (r *Repository) GetEpicByID(epicID uint) (*models.Epic, error) {
+	var epic models.Epic
+	err := r.DB.First(&epic, epicID).Error
+	return &epic, err
+}
+
+func (r *Repository) UpdateEpic(epic *models.Epic) error {
+	return r.DB.Save(epic).Error
+}
+
+// DeleteEpic deletes an epic, leaving its tasks without one
+func (r *Repository) DeleteEpic(epicID uint) error {
+	return r.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&models.Task{}).Where("epic_id = ?", epicID).Update("epic_id", nil).Error; err != nil {
+			return err
+		}
+		return tx.Delete(&models.Epic{}, epicID).Error
+	})
+}
+
+// SetTasksEpic adds tasks to an epic, or takes them out of theirs when
+// epicID is nil
+func (r *Repository) SetTasksEpic(taskIDs []uint, epicID *uint) error {
+	return r.DB.Model(&models.Task{}).Where("id IN ?", taskIDs).Update("epic_id", epicID).Error
+}
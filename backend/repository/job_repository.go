@@ -0,0 +1,164 @@
+package repository
+
+import (
+	"time"
+
+	"work-management/models"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// CreateJob enqueues a job. A job whose key is already taken is not inserted
+// and false is returned.
+func (r *Repository) CreateJob(job *models.Job) (bool, error) {
+	result := r.DB.Clauses(clause.OnConflict{DoNothing: true}).Create(job)
+	return result.RowsAffected == 1, result.Error
+}
+
+// ClaimJob locks the next due pending job of the queue for the worker and
+// marks it running. Jobs locked by other workers are skipped, so any number
+// of servers can poll the same queue. It returns nil when nothing is due.
+func (r *Repository) ClaimJob(queue, workerID string, now time.Time) (*models.Job, error) {
+	var jobs []models.Job
+	err := r.DB.Raw(`
+        UPDATE jobs SET status = ?, locked_by = ?, locked_at = ?, attempts = attempts + 1, updated_at = ?
+        WHERE id = (
+            SELECT id FROM jobs
+            WHERE queue = ? AND status = ? AND run_at <= ?
+            ORDER BY run_at, id
+            LIMIT 1
+            FOR UPDATE SKIP LOCKED
+        )
+        RETURNING *
+    `, models.JobRunning, workerID, now, now, queue, models.JobPending, now).Scan(&jobs).Error
+	if err != nil || len(jobs) == 0 {
+		return nil, err
+	}
+	return &jobs[0], nil
+}
+
+// finishJob records the outcome of a run of a job, provided the worker still
+// holds the job from that run: a job requeued as stale, and maybe claimed
+// again since, belongs to its new run. It reports whether the lock was held.
+func (r *Repository) finishJob(job *models.Job, workerID string, updates map[string]interface{}) (bool, error) {
+	result := r.DB.Model(&models.Job{}).
+		Where("id = ? AND status = ? AND locked_by = ? AND attempts = ?", job.ID, models.JobRunning, workerID, job.Attempts).
+		Updates(updates)
+	return result.RowsAffected > 0, result.Error
+}
+
+func (r *Repository) CompleteJob(job *models.Job, workerID string, finishedAt time.Time) (bool, error) {
+	return r.finishJob(job, workerID, map[string]interface{}{
+		"status":      models.JobSucceeded,
+		"finished_at": finishedAt,
+		"last_error":  "",
+	})
+}
+
+// RetryJobLater puts a failed job back in its queue to run again at runAt
+func (r *Repository) RetryJobLater(job *models.Job, workerID string, runAt time.Time, lastError string) (bool, error) {
+	return r.finishJob(job, workerID, map[string]interface{}{
+		"status":     models.JobPending,
+		"run_at":     runAt,
+		"last_error": lastError,
+		"locked_by":  "",
+		"locked_at":  nil,
+	})
+}
+
+// KillJob moves a job that exhausted its attempts to the dead letters
+func (r *Repository) KillJob(job *models.Job, workerID string, lastError string, finishedAt time.Time) (bool, error) {
+	return r.finishJob(job, workerID, map[string]interface{}{
+		"status":      models.JobDead,
+		"last_error":  lastError,
+		"finished_at": finishedAt,
+	})
+}
+
+// RenewJobLock records that the worker is still running the job
+func (r *Repository) RenewJobLock(job *models.Job, workerID string, now time.Time) error {
+	return r.DB.Model(&models.Job{}).
+		Where("id = ? AND status = ? AND locked_by = ? AND attempts = ?", job.ID, models.JobRunning, workerID, job.Attempts).
+		Update("locked_at", now).Error
+}
+
+// RequeueStaleJobs returns jobs left running by a worker that died (locked
+// before lockedBefore) to their queue
+func (r *Repository) RequeueStaleJobs(lockedBefore, now time.Time) (int64, error) {
+	result := r.DB.Model(&models.Job{}).
+		Where("status = ? AND locked_at < ?", models.JobRunning, lockedBefore).
+		Updates(map[string]interface{}{
+			"status":     models.JobPending,
+			"run_at":     now,
+			"last_error": "worker stopped before the job finished",
+			"locked_by":  "",
+			"locked_at":  nil,
+		})
+	return result.RowsAffected, result.Error
+}
+
+// DeleteFinishedJobs removes succeeded jobs finished before the given time
+func (r *Repository) DeleteFinishedJobs(finishedBefore time.Time) (int64, error) {
+	result := r.DB.Where("status = ? AND finished_at < ?", models.JobSucceeded, finishedBefore).Delete(&models.Job{})
+	return result.RowsAffected, result.Error
+}
+
+// GetJobs lists jobs, newest first, optionally filtered by status and queue
+func (r *Repository) GetJobs(status, queue string, limit int) ([]models.Job, error) {
+	query := r.DB.Order("id DESC").Limit(limit)
+	if status != "" {
+		query = query.Where("status = ?", status)
+	}
+	if queue != "" {
+		query = query.Where("queue = ?", queue)
+	}
+	var jobs []models.Job
+	err := query.Find(&jobs).Error
+	return jobs, err
+}
+
+func (r *Repository) GetJobByID(jobID uint) (*models.Job, error) {
+	var job models.Job
+	err := r.DB.First(&job, jobID).Error
+	return &job, err
+}
+
+// RetryDeadJob gives a dead job a fresh set of attempts. It returns
+// gorm.ErrRecordNotFound if there is no dead job with that ID.
+func (r *Repository) RetryDeadJob(jobID uint, now time.Time) error {
+	result := r.DB.Model(&models.Job{}).
+		Where("id = ? AND status = ?", jobID, models.JobDead).
+		Updates(map[string]interface{}{
+			"status":      models.JobPending,
+			"attempts":    0,
+			"run_at":      now,
+			"finished_at": nil,
+			"locked_by":   "",
+			"locked_at":   nil,
+		})
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return gorm.ErrRecordNotFound
+	}
+	return nil
+}
+
+// JobCount is the number of jobs of a queue in a given state
+type JobCount struct {
+	Queue  string `json:"queue"`
+	Status string `json:"status"`
+	Count  int64  `json:"count"`
+}
+
+func (r *Repository) CountJobs() ([]JobCount, error) {
+	var counts []JobCount
+	err := r.DB.Model(&models.Job{}).
+		Select("queue, status, COUNT(*) AS count").
+		Group("queue, status").
+		Order("queue, status").
+		Scan(&counts).Error
+	return counts, err
+}
@@ -0,0 +1,117 @@
+package repository
+
+import (
+	"work-management/models"
+
+	"github.com/sirupsen/logrus"
+	"gorm.io/gorm"
+)
+
+func (r *Repository) CreateLabel(label *models.Label) error {
+	return r.DB.Create(label).Error
+}
+
+func (r *Repository) GetLabelsByProjectID(projectID uint) ([]models.Label, error) {
+	var labels []models.Label
+	err := r.DB.Where("project_id = ?", projectID).Order("name").Find(&labels).Error
+	return labels, err
+}
+
+func (r *Repository) GetLabelByID(labelID uint) (*models.Label, error) {
+	var label models.Label
+	err := r.DB.First(&label, labelID).Error
+	return &label, err
+}
+
+// GetLabelByName returns the label of a project with the given name,
+// ignoring case
+func (r *Repository) GetLabelByName(projectID uint, name string) (*models.Label, error) {
+	var label models.Label
+	err := r.DB.Where("project_id = ? AND LOWER(name) = LOWER(?)", projectID, name).First(&label).Error
+	return &label, err
+}
+
+func (r *Repository) UpdateLabel(label *models.Label) error {
+	return r.DB.Save(label).Error
+}
+
+// DeleteLabel removes a label and detaches it from every task in one transaction.
+func (r *Repository) DeleteLabel(labelID uint) error {
+	tx := r.DB.Begin()
+	if tx.Error != nil {
+		logrus.WithFields(logrus.Fields{
+			"labelID": labelID,
+			"error":   tx.Error,
+		}).Error("Failed to start transaction")
+		return tx.Error
+	}
+
+	if err := tx.Exec("DELETE FROM task_labels WHERE label_id = ?", labelID).Error; err != nil {
+		logrus.WithFields(logrus.Fields{
+			"labelID": labelID,
+			"error":   err,
+		}).Error("Failed to detach label from tasks")
+		tx.Rollback()
+		return err
+	}
+
+	if err := tx.Unscoped().Delete(&models.Label{}, labelID).Error; err != nil {
+		logrus.WithFields(logrus.Fields{
+			"labelID": labelID,
+			"error":   err,
+		}).Error("Failed to delete label")
+		tx.Rollback()
+		return err
+	}
+
+	return tx.Commit().Error
+}
+
+func (r *Repository) AddLabelToTask(taskID, labelID uint) error {
+	return r.DB.Exec(
+		"INSERT INTO task_labels (task_id, label_id) VALUES (?, ?) ON CONFLICT DO NOTHING",
+		taskID, labelID,
+	).Error
+}
+
+// replaceTaskLabels sets the labels of a task to exactly the given ones
+func replaceTaskLabels(tx *gorm.DB, taskID uint, labelIDs []uint) error {
+	if err := tx.Exec("DELETE FROM task_labels WHERE task_id = ?", taskID).Error; err != nil {
+		return err
+	}
+	for _, labelID := range labelIDs {
+		if err := tx.Exec(
+			"INSERT INTO task_labels (task_id, label_id) VALUES (?, ?) ON CONFLICT DO NOTHING",
+			taskID, labelID,
+		).Error; err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (r *Repository) RemoveLabelFromTask(taskID, labelID uint) error {
+	return r.DB.Exec("DELETE FROM task_labels WHERE task_id = ? AND label_id = ?", taskID, labelID).Error
+}
+
+// LabelCount is the number of live tasks carrying a label.
+type LabelCount struct {
+	LabelID uint
+	Name    string
+	Color   string
+	Count   int64
+}
+
+func (r *Repository) CountTasksByLabel(projectID uint) ([]LabelCount, error) {
+	var counts []LabelCount
+	err := r.DB.Raw(`
+        SELECT l.id AS label_id, l.name, l.color, COUNT(t.id) AS count
+        FROM labels l
+        LEFT JOIN task_labels tl ON tl.label_id = l.id
+        LEFT JOIN tasks t ON t.id = tl.task_id AND t.deleted_at IS NULL
+        WHERE l.project_id = ? AND l.deleted_at IS NULL
+        GROUP BY l.id, l.name, l.color
+        ORDER BY l.name
+    `, projectID).Scan(&counts).Error
+	return counts, err
+}
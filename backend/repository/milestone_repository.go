@@ -0,0 +1,50 @@
+package repository
+
+import (
+	"work-management/models"
+
+	"gorm.io/gorm"
+)
+
+func (r *Repository) CreateMilestone(milestone *models.Milestone) error {
+	return r.DB.Create(milestone).Error
+}
+
+func (r *Repository) GetMilestonesByProjectID(projectID uint) ([]models.Milestone, error) {
+	var milestones []models.Milestone
+	err := r.DB.Where("project_id = ?", projectID).Order("target_date, id").Find(&milestones).Error
+	return milestones, err
+}
+
+func (r *Repository) GetMilestoneByID(milestoneID uint) (*models.Milestone, error) {
+	var milestone models.Milestone
+	err := r.DB.First(&milestone, milestoneID).Error
+	return &milestone, err
+}
+
+func (r *Repository) UpdateMilestone(milestone *models.Milestone) error {
+	return r.DB.Save(milestone).Error
+}
+
+// DeleteMilestone deletes a milestone, leaving its tasks without one
+func (r *Repository) DeleteMilestone(milestoneID uint) error {
+	return r.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&models.Task{}).Where("milestone_id = ?", milestoneID).Update("milestone_id", nil).Error; err != nil {
+			return err
+		}
+		return tx.Delete(&models.Milestone{}, milestoneID).Error
+	})
+}
+
+// SetTasksMilestone associates tasks with a milestone, or with none when
+// milestoneID is nil
+func (r *Repository) SetTasksMilestone(taskIDs []uint, milestoneID *uint) error {
+	return r.DB.Model(&models.Task{}).Where("id IN ?", taskIDs).Update("milestone_id", milestoneID).Error
+}
+
+// GetMilestoneTasks lists the tasks of a milestone with their labels
+func (r *Repository) GetMilestoneTasks(milestoneID uint) ([]models.Task, error) {
+	var tasks []models.Task
+	err := r.DB.Preload("Labels").Where("milestone_id = ?", milestoneID).Order("id").Find(&tasks).Error
+	return tasks, err
+}
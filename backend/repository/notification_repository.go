@@ -0,0 +1,155 @@
+package repository
+
+import (
+	"time"
+
+	"work-management/models"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// CreateNotifications creates the given notifications in a single insert,
+// so that either all or none of them are created
+func (r *Repository) CreateNotifications(notifications []models.Notification) error {
+	return r.DB.Create(&notifications).Error
+}
+
+// GetNotifications returns one page of a user's inbox, newest first, together
+// with the total number of notifications matching the query.
+func (r *Repository) GetNotifications(userID uint, unreadOnly bool, offset, limit int) ([]models.Notification, int64, error) {
+	query := r.DB.Model(&models.Notification{}).Where("user_id = ?", userID)
+	if unreadOnly {
+		query = query.Where("read_at IS NULL")
+	}
+	var total int64
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+	var notifications []models.Notification
+	err := query.Order("created_at DESC, id DESC").Offset(offset).Limit(limit).Find(&notifications).Error
+	return notifications, total, err
+}
+
+func (r *Repository) CountUnreadNotifications(userID uint) (int64, error) {
+	var count int64
+	err := r.DB.Model(&models.Notification{}).Where("user_id = ? AND read_at IS NULL", userID).Count(&count).Error
+	return count, err
+}
+
+// MarkNotificationRead marks one of the user's notifications as read. It
+// returns gorm.ErrRecordNotFound if the notification is not theirs.
+func (r *Repository) MarkNotificationRead(userID, notificationID uint, readAt time.Time) error {
+	result := r.DB.Model(&models.Notification{}).
+		Where("id = ? AND user_id = ?", notificationID, userID).
+		Update("read_at", gorm.Expr("COALESCE(read_at, ?)", readAt))
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return gorm.ErrRecordNotFound
+	}
+	return nil
+}
+
+func (r *Repository) MarkAllNotificationsRead(userID uint, readAt time.Time) (int64, error) {
+	result := r.DB.Model(&models.Notification{}).
+		Where("user_id = ? AND read_at IS NULL", userID).
+		Update("read_at", readAt)
+	return result.RowsAffected, result.Error
+}
+
+// ClaimTaskReminder records the reminder at the given offset before a due
+// date for the user and reports whether it was new, i.e. whether the caller
+// should send it
+func (r *Repository) ClaimTaskReminder(taskID, userID uint, dueDate time.Time, offsetMinutes int) (bool, error) {
+	result := r.DB.Clauses(clause.OnConflict{DoNothing: true}).Create(&models.TaskReminder{
+		TaskID:        taskID,
+		UserID:        userID,
+		DueDate:       dueDate,
+		OffsetMinutes: offsetMinutes,
+		SentAt:        time.Now(),
+	})
+	return result.RowsAffected == 1, result.Error
+}
+
+func (r *Repository) GetNotificationPreferences(userID uint) ([]models.NotificationPreference, error) {
+	var preferences []models.NotificationPreference
+	err := r.DB.Where("user_id = ?", userID).Find(&preferences).Error
+	return preferences, err
+}
+
+// SaveNotificationPreferences upserts the given preferences of a user
+func (r *Repository) SaveNotificationPreferences(preferences []models.NotificationPreference) error {
+	if len(preferences) == 0 {
+		return nil
+	}
+	return r.DB.Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "user_id"}, {Name: "event_type"}},
+		DoUpdates: clause.AssignmentColumns([]string{"in_app", "email"}),
+	}).Create(&preferences).Error
+}
+
+// GetTasksDueBetween lists open tasks with a deadline in [from, to)
+func (r *Repository) GetTasksDueBetween(from, to time.Time) ([]models.Task, error) {
+	var tasks []models.Task
+	err := r.DB.Preload("Assignees").
+		Where(taskDeadline+" >= ? AND "+taskDeadline+" < ? AND completed_at IS NULL", from, to).
+		Where(inActiveProject).
+		Find(&tasks).Error
+	return tasks, err
+}
+
+func (r *Repository) GetDigestSubscription(userID uint) (*models.DigestSubscription, error) {
+	var subscription models.DigestSubscription
+	err := r.DB.Where("user_id = ?", userID).First(&subscription).Error
+	return &subscription, err
+}
+
+func (r *Repository) GetDigestSubscriptions() ([]models.DigestSubscription, error) {
+	var subscriptions []models.DigestSubscription
+	err := r.DB.Find(&subscriptions).Error
+	return subscriptions, err
+}
+
+// SaveDigestSubscription creates or updates the user's digest frequency
+func (r *Repository) SaveDigestSubscription(subscription *models.DigestSubscription) error {
+	return r.DB.Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "user_id"}},
+		DoUpdates: clause.AssignmentColumns([]string{"frequency"}),
+	}).Create(subscription).Error
+}
+
+func (r *Repository) DeleteDigestSubscription(userID uint) error {
+	return r.DB.Where("user_id = ?", userID).Delete(&models.DigestSubscription{}).Error
+}
+
+func (r *Repository) MarkDigestSent(subscriptionID uint, sentAt time.Time) error {
+	return r.DB.Model(&models.DigestSubscription{}).Where("id = ?", subscriptionID).Update("last_sent_at", sentAt).Error
+}
+
+// DigestEntry is an activity row as shown in a digest email
+type DigestEntry struct {
+	ProjectID   uint
+	ProjectName string
+	UserName    string
+	Action      string
+	Timestamp   time.Time
+}
+
+// GetDigestEntries lists the activity by other users since the given time in
+// the projects the user belongs to, grouped by project
+func (r *Repository) GetDigestEntries(userID uint, since time.Time, limit int) ([]DigestEntry, error) {
+	var entries []DigestEntry
+	err := r.DB.Raw(`
+        SELECT a.project_id, p.name AS project_name, u.name AS user_name, a.action, a.timestamp
+        FROM activities a
+        JOIN user_roles ur ON ur.project_id = a.project_id AND ur.user_id = ? AND ur.deleted_at IS NULL
+        JOIN projects p ON p.id = a.project_id
+        JOIN users u ON u.id = a.user_id
+        WHERE a.deleted_at IS NULL AND a.timestamp >= ? AND a.user_id <> ?
+        ORDER BY p.name, a.project_id, a.timestamp
+        LIMIT ?
+    `, userID, since, userID, limit).Scan(&entries).Error
+	return entries, err
+}
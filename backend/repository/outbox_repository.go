@@ -0,0 +1,58 @@
+package repository
+
+import (
+	"time"
+
+	"work-management/models"
+)
+
+// outboxLockKey identifies the advisory lock held by the outbox relay
+const outboxLockKey = 7104
+
+func (r *Repository) CreateOutboxEvent(event *models.OutboxEvent) error {
+	return r.DB.Create(event).Error
+}
+
+// TryOutboxLock takes the relay's transaction-scoped advisory lock and
+// reports whether it got it. Only one server relays events at a time, which
+// keeps them in order. It must be called inside a transaction.
+func (r *Repository) TryOutboxLock() (bool, error) {
+	var locked bool
+	err := r.DB.Raw("SELECT pg_try_advisory_xact_lock(?)", outboxLockKey).Scan(&locked).Error
+	return locked, err
+}
+
+// GetPendingOutboxEvents returns the oldest events that are due to be
+// published. Events waiting to be retried are left out, together with the
+// later events of their aggregate, so that they cannot fill every batch.
+func (r *Repository) GetPendingOutboxEvents(now time.Time, limit int) ([]models.OutboxEvent, error) {
+	var events []models.OutboxEvent
+	err := r.DB.
+		Where("status = ? AND (next_attempt_at IS NULL OR next_attempt_at <= ?)", models.OutboxPending, now).
+		Where(`NOT EXISTS (
+            SELECT 1 FROM outbox_events earlier
+            WHERE earlier.aggregate_type = outbox_events.aggregate_type
+              AND earlier.aggregate_id = outbox_events.aggregate_id
+              AND earlier.id < outbox_events.id
+              AND earlier.status = ? AND earlier.next_attempt_at > ?
+        )`, models.OutboxPending, now).
+		Order("id").Limit(limit).Find(&events).Error
+	return events, err
+}
+
+func (r *Repository) UpdateOutboxEvent(event *models.OutboxEvent) error {
+	return r.DB.Save(event).Error
+}
+
+// DeletePublishedOutboxEvents removes events published before the cutoff
+func (r *Repository) DeletePublishedOutboxEvents(before time.Time) (int64, error) {
+	result := r.DB.Where("status = ? AND published_at < ?", models.OutboxPublished, before).Delete(&models.OutboxEvent{})
+	return result.RowsAffected, result.Error
+}
+
+// GetProjectMemberIDs returns the users holding a role in the project
+func (r *Repository) GetProjectMemberIDs(projectID uint) ([]uint, error) {
+	var userIDs []uint
+	err := r.DB.Model(&models.UserRole{}).Where("project_id = ?", projectID).Pluck("user_id", &userIDs).Error
+	return userIDs, err
+}
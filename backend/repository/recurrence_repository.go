@@ -0,0 +1,57 @@
+package repository
+
+import (
+	"time"
+
+	"work-management/models"
+)
+
+func (r *Repository) CreateRecurrence(recurrence *models.Recurrence) error {
+	return r.DB.Create(recurrence).Error
+}
+
+func (r *Repository) GetRecurrenceByID(recurrenceID uint) (*models.Recurrence, error) {
+	var recurrence models.Recurrence
+	err := r.DB.First(&recurrence, recurrenceID).Error
+	return &recurrence, err
+}
+
+func (r *Repository) UpdateRecurrence(recurrence *models.Recurrence) error {
+	return r.DB.Save(recurrence).Error
+}
+
+// AdvanceRecurrence moves a series on from the occurrence due at lastDueDate
+// and reports whether it did; it does nothing if another caller advanced or
+// ended the series first. A non-nil endedAt ends the series instead.
+func (r *Repository) AdvanceRecurrence(recurrenceID uint, lastDueDate, nextDueDate time.Time, occurrences int, endedAt *time.Time) (bool, error) {
+	result := r.DB.Model(&models.Recurrence{}).
+		Where("id = ? AND last_due_date = ? AND ended_at IS NULL", recurrenceID, lastDueDate).
+		Updates(map[string]interface{}{
+			"last_due_date": nextDueDate,
+			"occurrences":   occurrences,
+			"ended_at":      endedAt,
+		})
+	return result.RowsAffected == 1, result.Error
+}
+
+// GetScheduledRecurrencesDue lists the running series on a fixed schedule
+// whose latest occurrence was due before now
+func (r *Repository) GetScheduledRecurrencesDue(now time.Time) ([]models.Recurrence, error) {
+	var recurrences []models.Recurrence
+	err := r.DB.
+		Where("ended_at IS NULL AND after_completion = ? AND last_due_date <= ?", false, now).
+		Where(inActiveProject).
+		Find(&recurrences).Error
+	return recurrences, err
+}
+
+// GetOpenOccurrences lists the unfinished tasks of a series
+func (r *Repository) GetOpenOccurrences(recurrenceID uint) ([]models.Task, error) {
+	var tasks []models.Task
+	err := r.DB.Where("recurrence_id = ? AND completed_at IS NULL", recurrenceID).Find(&tasks).Error
+	return tasks, err
+}
+
+func (r *Repository) SetTaskRecurrence(taskID uint, recurrenceID *uint) error {
+	return r.DB.Model(&models.Task{}).Where("id = ?", taskID).UpdateColumn("recurrence_id", recurrenceID).Error
+}
@@ -0,0 +1,76 @@
+package repository
+
+import (
+	"time"
+
+	"work-management/models"
+
+	"gorm.io/gorm/clause"
+)
+
+func (r *Repository) GetReminderPolicies() ([]models.ReminderPolicy, error) {
+	var policies []models.ReminderPolicy
+	err := r.DB.Find(&policies).Error
+	return policies, err
+}
+
+func (r *Repository) GetReminderPolicy(projectID uint) (*models.ReminderPolicy, error) {
+	var policy models.ReminderPolicy
+	err := r.DB.Where("project_id = ?", projectID).First(&policy).Error
+	return &policy, err
+}
+
+// SaveReminderPolicy creates or replaces the reminder policy of a project
+func (r *Repository) SaveReminderPolicy(policy *models.ReminderPolicy) error {
+	return r.DB.Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "project_id"}},
+		DoUpdates: clause.AssignmentColumns([]string{"offsets", "escalate_after", "working_time"}),
+	}).Create(policy).Error
+}
+
+// GetTasksToMarkOverdue lists open tasks whose deadline passed before now
+// and that have not been marked overdue yet
+func (r *Repository) GetTasksToMarkOverdue(now time.Time) ([]models.Task, error) {
+	var tasks []models.Task
+	err := r.DB.Preload("Assignees").
+		Where("due_date > ? AND completed_at IS NULL AND overdue_at IS NULL", time.Time{}).
+		Where(taskDeadline+" < ?", now).
+		Where(inActiveProject).
+		Find(&tasks).Error
+	return tasks, err
+}
+
+// GetTasksToEscalate lists open overdue tasks that were not escalated yet
+func (r *Repository) GetTasksToEscalate() ([]models.Task, error) {
+	var tasks []models.Task
+	err := r.DB.
+		Where("overdue_at IS NOT NULL AND escalated_at IS NULL AND completed_at IS NULL").
+		Where(inActiveProject).
+		Find(&tasks).Error
+	return tasks, err
+}
+
+// MarkTaskOverdue sets overdue_at unless it is already set or the due date
+// changed in the meantime, and reports whether it did
+func (r *Repository) MarkTaskOverdue(taskID uint, dueDate, now time.Time) (bool, error) {
+	result := r.DB.Model(&models.Task{}).
+		Where("id = ? AND due_date = ? AND overdue_at IS NULL", taskID, dueDate).
+		UpdateColumn("overdue_at", now)
+	return result.RowsAffected == 1, result.Error
+}
+
+// MarkTaskEscalated sets escalated_at unless it is already set or the due
+// date changed in the meantime, and reports whether it did
+func (r *Repository) MarkTaskEscalated(taskID uint, dueDate, now time.Time) (bool, error) {
+	result := r.DB.Model(&models.Task{}).
+		Where("id = ? AND due_date = ? AND escalated_at IS NULL", taskID, dueDate).
+		UpdateColumn("escalated_at", now)
+	return result.RowsAffected == 1, result.Error
+}
+
+// GetProjectAdminIDs returns the users holding the admin role in the project
+func (r *Repository) GetProjectAdminIDs(projectID uint) ([]uint, error) {
+	var userIDs []uint
+	err := r.DB.Model(&models.UserRole{}).Where("project_id = ? AND role = ?", projectID, "admin").Pluck("user_id", &userIDs).Error
+	return userIDs, err
+}
@@ -2,10 +2,12 @@ package repository
 
 import (
 	"time"
+	"work-management/dto"
 	"work-management/models"
 
 	"github.com/sirupsen/logrus"
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
 type Repository struct {
@@ -16,6 +18,14 @@ func NewRepository(db *gorm.DB) *Repository {
 	return &Repository{DB: db}
 }
 
+// Transaction runs fn with a repository bound to a new database transaction,
+// committing it if fn returns nil and rolling it back otherwise
+func (r *Repository) Transaction(fn func(repo *Repository) error) error {
+	return r.DB.Transaction(func(tx *gorm.DB) error {
+		return fn(&Repository{DB: tx})
+	})
+}
+
 func (r *Repository) CreateUser(user *models.User) error {
 	return r.DB.Create(user).Error
 }
@@ -26,32 +36,126 @@ func (r *Repository) FindUserByEmail(email string) (*models.User, error) {
 	return &user, err
 }
 
+func (r *Repository) GetUserByID(userID uint) (*models.User, error) {
+	var user models.User
+	err := r.DB.First(&user, userID).Error
+	return &user, err
+}
+
+// taskAssociations are loaded with every task returned by the repository
+var taskAssociations = []string{"User", "Project", "Labels", "CustomFields", "Assignees", "Watchers", "SLAClocks"}
+
+// preloadTask preloads taskAssociations, each prefixed with prefix (e.g.
+// "Tasks." when loading a project's tasks).
+func preloadTask(query *gorm.DB, prefix string) *gorm.DB {
+	for _, association := range taskAssociations {
+		query = query.Preload(prefix + association)
+	}
+	return query
+}
+
+// CreateTask inserts a task with its custom field values and the join rows
+// for its assignees and watchers; the referenced users are not upserted.
+// Tasks without a rank go to the bottom of the board.
 func (r *Repository) CreateTask(task *models.Task) error {
-	return r.DB.Create(task).Error
+	return r.DB.Transaction(func(tx *gorm.DB) error {
+		if task.Rank == "" {
+			last, err := lastRank(tx, task.ProjectID)
+			if err != nil {
+				return err
+			}
+			task.Rank, _ = models.RankBetween(last, "")
+		}
+		return tx.Omit("Assignees.*", "Watchers.*", "Labels.*").Create(task).Error
+	})
 }
 
-func (r *Repository) GetTasks() ([]models.Task, error) {
+func (r *Repository) GetTasks(filter dto.TaskFilter) ([]models.Task, error) {
 	var tasks []models.Task
-	err := r.DB.Preload("User").Preload("Project").Find(&tasks).Error
+	query := applyTaskFilter(r.DB.Model(&models.Task{}), filter)
+	err := preloadTask(query, "").Find(&tasks).Error
 	return tasks, err
 }
 
 func (r *Repository) GetTaskByID(taskID uint) (*models.Task, error) {
 	var task models.Task
-	err := r.DB.Preload("User").Preload("Project").First(&task, taskID).Error
+	err := preloadTask(r.DB, "").First(&task, taskID).Error
 	return &task, err
 }
 
+// TaskUpdate bundles the changes saved in one transaction with a task's own
+// columns by SaveTask.
+type TaskUpdate struct {
+	CustomFields  []models.CustomFieldValue
+	ClearedFields []uint // Custom fields whose values are removed
+	AssigneeIDs   []uint // Replaces the assignee list when non-nil
+	LabelIDs      []uint // Replaces the label list when non-nil
+}
+
+// SaveTask saves a task together with its custom field and assignee changes.
+// The primary assignee (user_id) is always kept among the assignees.
+func (r *Repository) SaveTask(task *models.Task, update TaskUpdate) error {
+	return r.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Omit(clause.Associations, "rank").Save(task).Error; err != nil {
+			return err
+		}
+		if err := saveTaskCustomFields(tx, task.ID, update.CustomFields, update.ClearedFields); err != nil {
+			return err
+		}
+		if update.AssigneeIDs != nil {
+			if err := replaceTaskAssignees(tx, task.ID, update.AssigneeIDs); err != nil {
+				return err
+			}
+		}
+		if update.LabelIDs != nil {
+			if err := replaceTaskLabels(tx, task.ID, update.LabelIDs); err != nil {
+				return err
+			}
+		}
+		if task.UserID == 0 {
+			return nil
+		}
+		return tx.Exec(
+			"INSERT INTO task_assignees (task_id, user_id) VALUES (?, ?) ON CONFLICT DO NOTHING",
+			task.ID, task.UserID,
+		).Error
+	})
+}
+
+// UpdateTask saves the task's own columns. Associations loaded alongside the
+// task (user, project, labels, ...) are managed through their own methods,
+// and its rank through MoveTask, so that saving a task loaded earlier does
+// not undo a concurrent move.
 func (r *Repository) UpdateTask(task *models.Task) error {
-	return r.DB.Save(task).Error
+	return r.DB.Omit(clause.Associations, "rank").Save(task).Error
+}
+
+// DeleteTask moves a task and its subtasks to the trash, marking them with
+// the same deletion time so that RestoreTask brings back exactly those tasks
+func (r *Repository) DeleteTask(taskID uint, deletedAt time.Time) error {
+	return r.DB.Exec(`
+		WITH RECURSIVE tree AS (
+			SELECT id FROM tasks WHERE id = ? AND deleted_at IS NULL
+			UNION ALL
+			SELECT t.id FROM tasks t JOIN tree ON t.parent_id = tree.id WHERE t.deleted_at IS NULL
+		)
+		UPDATE tasks SET deleted_at = ? WHERE id IN (SELECT id FROM tree)`, taskID, deletedAt).Error
 }
 
-func (r *Repository) DeleteTask(taskID uint) error {
-	return r.DB.Unscoped().Delete(&models.Task{}, taskID).Error
+// GetSubtasks lists the direct subtasks of a task
+func (r *Repository) GetSubtasks(taskID uint) ([]models.Task, error) {
+	var tasks []models.Task
+	err := preloadTask(r.DB, "").Where("parent_id = ?", taskID).Order("id").Find(&tasks).Error
+	return tasks, err
 }
 
-func (r *Repository) AssignTaskToUser(taskID, userID uint) error {
-	return r.DB.Model(&models.Task{Model: gorm.Model{ID: taskID}}).Update("user_id", userID).Error
+// DetachSubtasks turns the subtasks of a task into top-level tasks
+func (r *Repository) DetachSubtasks(taskID uint) error {
+	return detachSubtasks(r.DB, taskID)
+}
+
+func detachSubtasks(tx *gorm.DB, taskID uint) error {
+	return tx.Model(&models.Task{}).Where("parent_id = ?", taskID).Update("parent_id", nil).Error
 }
 
 func (r *Repository) CreateProject(project *models.Project) error {
@@ -101,7 +205,9 @@ func (r *Repository) CreateProject(project *models.Project) error {
 	return nil
 }
 
-func (r *Repository) GetProjects(userID uint) ([]models.Project, error) {
+// GetProjects lists the projects of a user that are, depending on archived,
+// either archived or active
+func (r *Repository) GetProjects(userID uint, archived bool) ([]models.Project, error) {
 	var projects []models.Project
 	query := `
         SELECT DISTINCT p.*
@@ -109,8 +215,9 @@ func (r *Repository) GetProjects(userID uint) ([]models.Project, error) {
         LEFT JOIN user_roles ur ON ur.project_id = p.id
         WHERE (ur.user_id = ? OR p.creator_id = ?)
         AND p.deleted_at IS NULL
+        AND (p.archived_at IS NOT NULL) = ?
     `
-	err := r.DB.Raw(query, userID, userID).Scan(&projects).Error
+	err := r.DB.Raw(query, userID, userID, archived).Scan(&projects).Error
 	if err != nil {
 		logrus.WithFields(logrus.Fields{
 			"userID": userID,
@@ -151,11 +258,9 @@ func (r *Repository) GetProjects(userID uint) ([]models.Project, error) {
 
 func (r *Repository) GetProjectByID(projectID uint) (*models.Project, error) {
 	var projects []models.Project
-	err := r.DB.
+	err := preloadTask(r.DB.
 		Preload("Creator").
-		Preload("Tasks", "deleted_at IS NULL").
-		Preload("Tasks.User").
-		Preload("Tasks.Project").
+		Preload("Tasks", "deleted_at IS NULL"), "Tasks.").
 		Preload("Users.User").
 		Where("id = ?", projectID).
 		Find(&projects).Error
@@ -183,63 +288,35 @@ func (r *Repository) UpdateProject(project *models.Project) error {
 	return r.DB.Save(project).Error
 }
 
-func (r *Repository) DeleteProject(projectID uint) error {
-	tx := r.DB.Begin()
-	if tx.Error != nil {
-		logrus.WithFields(logrus.Fields{
-			"projectID": projectID,
-			"error":     tx.Error,
-		}).Error("Failed to start transaction")
-		return tx.Error
-	}
-
-	if err := tx.Where("project_id = ?", projectID).Delete(&models.UserRole{}).Error; err != nil {
-		logrus.WithFields(logrus.Fields{
-			"projectID": projectID,
-			"error":     err,
-		}).Error("Failed to delete user_roles entries")
-		tx.Rollback()
-		return err
-	}
-
-	if err := tx.Where("project_id = ?", projectID).Delete(&models.Task{}).Error; err != nil {
-		logrus.WithFields(logrus.Fields{
-			"projectID": projectID,
-			"error":     err,
-		}).Error("Failed to delete tasks")
-		tx.Rollback()
-		return err
-	}
-
-	if err := tx.Where("project_id = ?", projectID).Delete(&models.Activity{}).Error; err != nil {
-		logrus.WithFields(logrus.Fields{
-			"projectID": projectID,
-			"error":     err,
-		}).Error("Failed to delete activities")
-		tx.Rollback()
-		return err
-	}
-
-	if err := tx.Unscoped().Delete(&models.Project{}, projectID).Error; err != nil {
-		logrus.WithFields(logrus.Fields{
-			"projectID": projectID,
-			"error":     err,
-		}).Error("Failed to delete project")
-		tx.Rollback()
-		return err
-	}
+// DeleteProject moves a project to the trash along with its tasks, which
+// get the project's deletion time so that RestoreProject can tell them from
+// tasks that were in the trash already
+func (r *Repository) DeleteProject(projectID uint, deletedAt time.Time) error {
+	err := r.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&models.Task{}).Where("project_id = ?", projectID).Update("deleted_at", deletedAt).Error; err != nil {
+			logrus.WithFields(logrus.Fields{
+				"projectID": projectID,
+				"error":     err,
+			}).Error("Failed to move tasks to the trash")
+			return err
+		}
 
-	if err := tx.Commit().Error; err != nil {
-		logrus.WithFields(logrus.Fields{
-			"projectID": projectID,
-			"error":     err,
-		}).Error("Failed to commit transaction")
+		if err := tx.Model(&models.Project{}).Where("id = ?", projectID).Update("deleted_at", deletedAt).Error; err != nil {
+			logrus.WithFields(logrus.Fields{
+				"projectID": projectID,
+				"error":     err,
+			}).Error("Failed to move project to the trash")
+			return err
+		}
+		return nil
+	})
+	if err != nil {
 		return err
 	}
 
 	logrus.WithFields(logrus.Fields{
 		"projectID": projectID,
-	}).Info("Project and related records deleted successfully")
+	}).Info("Project moved to the trash")
 	return nil
 }
 
@@ -280,16 +357,27 @@ func (r *Repository) UpdateUserRole(userID, projectID uint, role string) error {
 	return r.DB.Model(&models.UserRole{}).Where("user_id = ? AND project_id = ?", userID, projectID).Update("role", role).Error
 }
 
+// RemoveUserFromProject revokes the user's role and drops them from the
+// assignees and watchers of the project's tasks.
 func (r *Repository) RemoveUserFromProject(userID, projectID uint) error {
-	return r.DB.Where("user_id = ? AND project_id = ?", userID, projectID).Delete(&models.UserRole{}).Error
+	return r.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("user_id = ? AND project_id = ?", userID, projectID).Delete(&models.UserRole{}).Error; err != nil {
+			return err
+		}
+		projectTasks := tx.Session(&gorm.Session{NewDB: true}).Model(&models.Task{}).Select("id").Where("project_id = ?", projectID)
+		for _, table := range []string{"task_assignees", "task_watchers"} {
+			if err := tx.Exec("DELETE FROM "+table+" WHERE user_id = ? AND task_id IN (?)", userID, projectTasks).Error; err != nil {
+				return err
+			}
+		}
+		return syncPrimaryAssignees(tx, "project_id = ? AND user_id = ?", projectID, userID)
+	})
 }
 
-func (r *Repository) GetTasksByProjectID(projectID uint) ([]models.Task, error) {
+func (r *Repository) GetTasksByProjectID(projectID uint, filter dto.TaskFilter) ([]models.Task, error) {
 	var tasks []models.Task
-	err := r.DB.
-		Where("project_id = ? AND deleted_at IS NULL", projectID).
-		Preload("User").
-		Preload("Project").
+	err := preloadTask(applyTaskFilter(r.DB.Model(&models.Task{}), filter), "").
+		Where("tasks.project_id = ? AND tasks.deleted_at IS NULL", projectID).
 		Find(&tasks).Error
 	if err != nil {
 		logrus.WithFields(logrus.Fields{
@@ -0,0 +1,95 @@
+package repository
+
+import (
+	"time"
+
+	"work-management/models"
+
+	"gorm.io/gorm/clause"
+)
+
+func (r *Repository) GetSLAPolicy(projectID uint) (*models.SLAPolicy, error) {
+	var policy models.SLAPolicy
+	err := r.DB.Where("project_id = ?", projectID).First(&policy).Error
+	return &policy, err
+}
+
+// SaveSLAPolicy creates or replaces the SLA policy of a project
+func (r *Repository) SaveSLAPolicy(policy *models.SLAPolicy) error {
+	return r.DB.Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "project_id"}},
+		DoUpdates: clause.AssignmentColumns([]string{"targets", "paused_statuses", "working_time", "updated_at"}),
+	}).Create(policy).Error
+}
+
+// DeleteSLAPolicy deletes the SLA policy of a project with the clocks of its
+// tasks, and reports whether there was one
+func (r *Repository) DeleteSLAPolicy(projectID uint) (bool, error) {
+	if err := r.DB.Where("project_id = ?", projectID).Delete(&models.SLAClock{}).Error; err != nil {
+		return false, err
+	}
+	result := r.DB.Where("project_id = ?", projectID).Delete(&models.SLAPolicy{})
+	return result.RowsAffected > 0, result.Error
+}
+
+// GetOpenTaskIDs lists the tasks of a project that are not completed
+func (r *Repository) GetOpenTaskIDs(projectID uint) ([]uint, error) {
+	var ids []uint
+	err := r.DB.Model(&models.Task{}).
+		Where("project_id = ? AND completed_at IS NULL", projectID).
+		Order("id").
+		Pluck("id", &ids).Error
+	return ids, err
+}
+
+// LockSLAClocks returns the SLA clocks of a task, locked until the end of
+// the transaction
+func (r *Repository) LockSLAClocks(taskID uint) ([]models.SLAClock, error) {
+	var clocks []models.SLAClock
+	err := r.DB.Clauses(clause.Locking{Strength: "UPDATE"}).
+		Where("task_id = ?", taskID).
+		Order("id").
+		Find(&clocks).Error
+	return clocks, err
+}
+
+func (r *Repository) SaveSLAClock(clock *models.SLAClock) error {
+	return r.DB.Save(clock).Error
+}
+
+func (r *Repository) DeleteSLAClock(clockID uint) error {
+	return r.DB.Delete(&models.SLAClock{}, clockID).Error
+}
+
+// HasTaskComments reports whether a task was commented on up to the given
+// time
+func (r *Repository) HasTaskComments(taskID uint, until time.Time) (bool, error) {
+	var count int64
+	err := r.DB.Model(&models.Comment{}).
+		Where("task_id = ? AND created_at <= ?", taskID, until).
+		Limit(1).
+		Count(&count).Error
+	return count > 0, err
+}
+
+// GetSLAClocksToBreach lists the running clocks of live tasks in active
+// projects that reached their target by now and are not marked breached yet
+func (r *Repository) GetSLAClocksToBreach(now time.Time) ([]models.SLAClock, error) {
+	var clocks []models.SLAClock
+	err := r.DB.
+		Where("running_since IS NOT NULL AND breached_at IS NULL AND due_at <= ?", now).
+		Where("task_id IN (SELECT id FROM tasks WHERE deleted_at IS NULL AND " + inActiveProject + ")").
+		Order("due_at, id").
+		Find(&clocks).Error
+	return clocks, err
+}
+
+// MarkSLABreached sets breached_at to the clock's due time unless it is
+// already set or the clock moved on in the meantime, and reports whether it
+// did
+func (r *Repository) MarkSLABreached(clockID uint, dueAt time.Time) (bool, error) {
+	result := r.DB.Model(&models.SLAClock{}).
+		Where("id = ? AND breached_at IS NULL AND running_since IS NOT NULL AND due_at = ?", clockID, dueAt).
+		UpdateColumn("breached_at", dueAt)
+	return result.RowsAffected == 1, result.Error
+}
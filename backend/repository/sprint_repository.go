@@ -0,0 +1,143 @@
+package repository
+
+import (
+	"time"
+	"work-management/models"
+
+	"gorm.io/gorm"
+)
+
+func (r *Repository) CreateSprint(sprint *models.Sprint) error {
+	return r.DB.Create(sprint).Error
+}
+
+func (r *Repository) GetSprintsByProjectID(projectID uint) ([]models.Sprint, error) {
+	var sprints []models.Sprint
+	err := r.DB.Where("project_id = ?", projectID).Order("start_date, id").Find(&sprints).Error
+	return sprints, err
+}
+
+func (r *Repository) GetSprintByID(sprintID uint) (*models.Sprint, error) {
+	var sprint models.Sprint
+	err := r.DB.First(&sprint, sprintID).Error
+	return &sprint, err
+}
+
+func (r *Repository) UpdateSprint(sprint *models.Sprint) error {
+	return r.DB.Save(sprint).Error
+}
+
+// DeleteSprint deletes a sprint and moves its tasks back to the backlog
+func (r *Repository) DeleteSprint(sprintID uint) error {
+	return r.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&models.Task{}).Where("sprint_id = ?", sprintID).Update("sprint_id", nil).Error; err != nil {
+			return err
+		}
+		return tx.Delete(&models.Sprint{}, sprintID).Error
+	})
+}
+
+// GetNextPlannedSprint returns the planned sprint of a project starting
+// first, other than the given one
+func (r *Repository) GetNextPlannedSprint(projectID, excludeID uint) (*models.Sprint, error) {
+	var sprint models.Sprint
+	err := r.DB.
+		Where("project_id = ? AND state = ? AND id <> ?", projectID, models.SprintPlanned, excludeID).
+		Order("start_date, id").
+		First(&sprint).Error
+	return &sprint, err
+}
+
+// SetTasksSprint plans tasks into a sprint, or moves them to the backlog
+// when sprintID is nil
+func (r *Repository) SetTasksSprint(taskIDs []uint, sprintID *uint) error {
+	return r.DB.Model(&models.Task{}).Where("id IN ?", taskIDs).Update("sprint_id", sprintID).Error
+}
+
+// StartSprint activates a planned sprint and records the story points and
+// tasks it starts with. It reports false, changing nothing, when the sprint
+// is no longer planned or another sprint of the project is active.
+func (r *Repository) StartSprint(sprint *models.Sprint, now time.Time) (bool, error) {
+	result := r.DB.Model(&models.Sprint{}).
+		Where("id = ? AND state = ?", sprint.ID, models.SprintPlanned).
+		Where("NOT EXISTS (SELECT 1 FROM sprints s WHERE s.project_id = ? AND s.state = ? AND s.deleted_at IS NULL)",
+			sprint.ProjectID, models.SprintActive).
+		Updates(map[string]interface{}{
+			"state":      models.SprintActive,
+			"started_at": now,
+			"committed_points": gorm.Expr(
+				"(SELECT COALESCE(SUM(story_points), 0) FROM tasks WHERE sprint_id = ? AND deleted_at IS NULL)", sprint.ID),
+			"committed_tasks": gorm.Expr(
+				"(SELECT COUNT(*) FROM tasks WHERE sprint_id = ? AND deleted_at IS NULL)", sprint.ID),
+		})
+	return result.RowsAffected == 1, result.Error
+}
+
+// CloseSprint closes an active sprint, records what was completed and moves
+// the unfinished tasks to the carryOverTo sprint, or the backlog when it is
+// nil. It reports false, changing nothing, when the sprint is not active.
+func (r *Repository) CloseSprint(sprint *models.Sprint, carryOverTo *uint, now time.Time) (bool, error) {
+	closed := false
+	err := r.DB.Transaction(func(tx *gorm.DB) error {
+		var completed struct {
+			Points int
+			Tasks  int
+		}
+		if err := tx.Model(&models.Task{}).
+			Select("COALESCE(SUM(story_points), 0) AS points, COUNT(*) AS tasks").
+			Where("sprint_id = ? AND completed_at IS NOT NULL", sprint.ID).
+			Scan(&completed).Error; err != nil {
+			return err
+		}
+		var unfinished []uint
+		if err := tx.Model(&models.Task{}).
+			Where("sprint_id = ? AND completed_at IS NULL", sprint.ID).
+			Order("id").
+			Pluck("id", &unfinished).Error; err != nil {
+			return err
+		}
+		// Updating from a struct, unlike a map, encodes CarriedOver as JSON
+		result := tx.Model(&models.Sprint{}).
+			Where("id = ? AND state = ?", sprint.ID, models.SprintActive).
+			Select("state", "closed_at", "completed_points", "completed_tasks", "carried_over", "carried_over_to").
+			Updates(&models.Sprint{
+				State:           models.SprintClosed,
+				ClosedAt:        &now,
+				CompletedPoints: completed.Points,
+				CompletedTasks:  completed.Tasks,
+				CarriedOver:     unfinished,
+				CarriedOverTo:   carryOverTo,
+			})
+		if result.Error != nil || result.RowsAffected != 1 {
+			return result.Error
+		}
+		closed = true
+		if len(unfinished) == 0 {
+			return nil
+		}
+		return tx.Model(&models.Task{}).Where("id IN ?", unfinished).Update("sprint_id", carryOverTo).Error
+	})
+	return closed, err
+}
+
+// GetSprintTasks lists the tasks of a sprint along with the given tasks
+// (those carried over when it closed)
+func (r *Repository) GetSprintTasks(sprintID uint, extra []uint) ([]models.Task, error) {
+	var tasks []models.Task
+	query := r.DB.Where("sprint_id = ?", sprintID)
+	if len(extra) > 0 {
+		query = r.DB.Where("sprint_id = ? OR id IN ?", sprintID, extra)
+	}
+	err := query.Order("id").Find(&tasks).Error
+	return tasks, err
+}
+
+func (r *Repository) CreateSprintChange(change *models.SprintChange) error {
+	return r.DB.Create(change).Error
+}
+
+func (r *Repository) GetSprintChanges(sprintID uint) ([]models.SprintChange, error) {
+	var changes []models.SprintChange
+	err := r.DB.Where("sprint_id = ?", sprintID).Order("id").Find(&changes).Error
+	return changes, err
+}
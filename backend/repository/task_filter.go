@@ -0,0 +1,162 @@
+package repository
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"work-management/dto"
+	"work-management/models"
+
+	"gorm.io/gorm"
+)
+
+// taskSortColumns maps the sort names accepted by TaskFilter.Sort to SQL
+var taskSortColumns = map[string]string{
+	"title":              "tasks.title",
+	"status":             "tasks.status",
+	"due_date":           "tasks.due_date",
+	"created_at":         "tasks.created_at",
+	"updated_at":         "tasks.updated_at",
+	"priority":           priorityRankSQL(),
+	"story_points":       "tasks.story_points",
+	"original_estimate":  "tasks.original_estimate",
+	"remaining_estimate": "tasks.remaining_estimate",
+	"rank":               rankOrder,
+}
+
+// customFieldSortPrefix marks a sort on a custom field, e.g. "cf.12"
+const customFieldSortPrefix = "cf."
+
+// IsSortableTaskField reports whether a task listing can be sorted by name.
+// Custom field sorts are validated by the service, which knows the fields.
+func IsSortableTaskField(name string) bool {
+	_, ok := taskSortColumns[strings.TrimPrefix(name, "-")]
+	return ok
+}
+
+// CustomFieldSortID returns the field ID of a "cf.<id>" sort, if it is one
+func CustomFieldSortID(name string) (uint, bool) {
+	name = strings.TrimPrefix(name, "-")
+	if !strings.HasPrefix(name, customFieldSortPrefix) {
+		return 0, false
+	}
+	id, err := strconv.ParseUint(strings.TrimPrefix(name, customFieldSortPrefix), 10, 32)
+	if err != nil {
+		return 0, false
+	}
+	return uint(id), true
+}
+
+// customFieldCondition builds the EXISTS clause matching one custom field filter
+func customFieldCondition(f dto.CustomFieldFilter) (string, []interface{}) {
+	const base = "EXISTS (SELECT 1 FROM custom_field_values v WHERE v.task_id = tasks.id AND v.field_id = ? AND "
+	if f.Type == models.FieldTypeMultiSelect {
+		return base + "v.value @> ?::jsonb)", []interface{}{f.FieldID, f.Value}
+	}
+	op := "="
+	switch f.Op {
+	case "gte":
+		op = ">="
+	case "lte":
+		op = "<="
+	}
+	column := "v." + models.CustomFieldSortColumn(f.Type)
+	return base + column + " " + op + " ?)", []interface{}{f.FieldID, f.Value}
+}
+
+// priorityRankSQL turns models.PriorityRank into a CASE expression so the
+// database orders priorities by rank instead of alphabetically.
+func priorityRankSQL() string {
+	names := make([]string, 0, len(models.PriorityRank))
+	for name := range models.PriorityRank {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	var b strings.Builder
+	b.WriteString("CASE tasks.priority")
+	for _, name := range names {
+		fmt.Fprintf(&b, " WHEN '%s' THEN %d", name, models.PriorityRank[name])
+	}
+	b.WriteString(" ELSE 0 END")
+	return b.String()
+}
+
+// applyTaskFilter adds the WHERE and ORDER BY clauses described by filter to
+// a task query.
+func applyTaskFilter(query *gorm.DB, filter dto.TaskFilter) *gorm.DB {
+	if len(filter.LabelIDs) > 0 {
+		query = query.Where("tasks.id IN (?)",
+			query.Session(&gorm.Session{NewDB: true}).
+				Table("task_labels").
+				Select("task_id").
+				Where("label_id IN ?", filter.LabelIDs))
+	}
+	if len(filter.Priorities) > 0 {
+		query = query.Where("tasks.priority IN ?", filter.Priorities)
+	}
+	if filter.MinStoryPoints != nil {
+		query = query.Where("tasks.story_points >= ?", *filter.MinStoryPoints)
+	}
+	if filter.MaxStoryPoints != nil {
+		query = query.Where("tasks.story_points <= ?", *filter.MaxStoryPoints)
+	}
+
+	if filter.AssigneeID != 0 {
+		query = query.Where("tasks.id IN (SELECT task_id FROM task_assignees WHERE user_id = ?)", filter.AssigneeID)
+	}
+	if filter.InvolvedUserID != 0 {
+		query = query.Where(
+			"tasks.id IN (SELECT task_id FROM task_assignees WHERE user_id = ? UNION SELECT task_id FROM task_watchers WHERE user_id = ?)",
+			filter.InvolvedUserID, filter.InvolvedUserID,
+		)
+	}
+	if filter.VisibleToUserID != 0 {
+		query = query.Where(
+			"tasks.project_id IN (SELECT project_id FROM user_roles WHERE user_id = ? AND deleted_at IS NULL)",
+			filter.VisibleToUserID,
+		)
+		// Archived projects stay out of cross-project lists
+		query = query.Where("tasks.project_id NOT IN (SELECT id FROM projects WHERE archived_at IS NOT NULL)")
+	}
+	if filter.SprintID != 0 {
+		query = query.Where("tasks.sprint_id = ?", filter.SprintID)
+	}
+	if filter.MilestoneID != 0 {
+		query = query.Where("tasks.milestone_id = ?", filter.MilestoneID)
+	}
+	if filter.EpicID != 0 {
+		query = query.Where("tasks.epic_id = ?", filter.EpicID)
+	}
+	if filter.Backlog {
+		query = query.Where("tasks.sprint_id IS NULL AND tasks.completed_at IS NULL")
+	}
+	if filter.Overdue {
+		// Tasks without a due date keep the zero time
+		query = query.Where("tasks.due_date > ? AND "+taskDeadline+" < ? AND tasks.completed_at IS NULL", time.Time{}, time.Now())
+	}
+	if filter.SLABreached {
+		query = query.Where(`EXISTS (SELECT 1 FROM sla_clocks c WHERE c.task_id = tasks.id AND
+			(c.breached_at IS NOT NULL OR (c.running_since IS NOT NULL AND c.due_at <= ?)))`, time.Now())
+	}
+	for _, f := range filter.CustomFields {
+		condition, args := customFieldCondition(f)
+		query = query.Where(condition, args...)
+	}
+
+	direction := "ASC"
+	if strings.HasPrefix(filter.Sort, "-") {
+		direction = "DESC"
+	}
+	if column, ok := taskSortColumns[strings.TrimPrefix(filter.Sort, "-")]; ok {
+		query = query.Order(column + " " + direction)
+	} else if fieldID, ok := CustomFieldSortID(filter.Sort); ok {
+		// Tasks without a value sort last in either direction
+		query = query.
+			Joins("LEFT JOIN custom_field_values sort_value ON sort_value.task_id = tasks.id AND sort_value.field_id = ?", fieldID).
+			Order("sort_value." + models.CustomFieldSortColumn(filter.SortFieldType) + " " + direction + " NULLS LAST")
+	}
+	return query.Order("tasks.id")
+}
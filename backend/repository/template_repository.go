@@ -0,0 +1,49 @@
+package repository
+
+import (
+	"work-management/models"
+)
+
+func (r *Repository) CreateProjectTemplate(template *models.ProjectTemplate) error {
+	return r.DB.Create(template).Error
+}
+
+func (r *Repository) GetProjectTemplates() ([]models.ProjectTemplate, error) {
+	var templates []models.ProjectTemplate
+	err := r.DB.Order("name").Find(&templates).Error
+	return templates, err
+}
+
+func (r *Repository) GetProjectTemplateByID(templateID uint) (*models.ProjectTemplate, error) {
+	var template models.ProjectTemplate
+	err := r.DB.First(&template, templateID).Error
+	return &template, err
+}
+
+func (r *Repository) UpdateProjectTemplate(template *models.ProjectTemplate) error {
+	return r.DB.Save(template).Error
+}
+
+func (r *Repository) DeleteProjectTemplate(templateID uint) error {
+	return r.DB.Delete(&models.ProjectTemplate{}, templateID).Error
+}
+
+func (r *Repository) CreateTaskTemplate(template *models.TaskTemplate) error {
+	return r.DB.Create(template).Error
+}
+
+func (r *Repository) GetTaskTemplatesByProjectID(projectID uint) ([]models.TaskTemplate, error) {
+	var templates []models.TaskTemplate
+	err := r.DB.Where("project_id = ?", projectID).Order("name").Find(&templates).Error
+	return templates, err
+}
+
+func (r *Repository) GetTaskTemplateByID(templateID uint) (*models.TaskTemplate, error) {
+	var template models.TaskTemplate
+	err := r.DB.First(&template, templateID).Error
+	return &template, err
+}
+
+func (r *Repository) DeleteTaskTemplate(templateID uint) error {
+	return r.DB.Delete(&models.TaskTemplate{}, templateID).Error
+}
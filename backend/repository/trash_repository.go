@@ -0,0 +1,219 @@
+package repository
+
+import (
+	"time"
+	"work-management/models"
+
+	"gorm.io/gorm"
+)
+
+// inActiveProject restricts a task query to projects that are neither
+// archived nor in the trash, so that scheduled jobs leave them alone
+const inActiveProject = "project_id IN (SELECT id FROM projects WHERE archived_at IS NULL AND deleted_at IS NULL)"
+
+// SetProjectArchived archives a project at archivedAt, or unarchives it when
+// archivedAt is nil
+func (r *Repository) SetProjectArchived(projectID uint, archivedAt *time.Time) error {
+	return r.DB.Model(&models.Project{}).Where("id = ?", projectID).Update("archived_at", archivedAt).Error
+}
+
+// IsProjectArchived reports whether a project exists and is archived
+func (r *Repository) IsProjectArchived(projectID uint) (bool, error) {
+	var count int64
+	err := r.DB.Model(&models.Project{}).
+		Where("id = ? AND archived_at IS NOT NULL", projectID).
+		Count(&count).Error
+	return count > 0, err
+}
+
+// GetTrashedProjects lists the projects in the trash that a user administers
+func (r *Repository) GetTrashedProjects(userID uint) ([]models.Project, error) {
+	var projects []models.Project
+	err := r.DB.Unscoped().Preload("Creator").
+		Where("deleted_at IS NOT NULL").
+		Where("id IN (SELECT project_id FROM user_roles WHERE user_id = ? AND role = ? AND deleted_at IS NULL)", userID, "admin").
+		Order("deleted_at DESC").
+		Find(&projects).Error
+	return projects, err
+}
+
+func (r *Repository) GetTrashedProject(projectID uint) (*models.Project, error) {
+	var project models.Project
+	err := r.DB.Unscoped().Where("deleted_at IS NOT NULL").First(&project, projectID).Error
+	return &project, err
+}
+
+// RestoreProject takes a project out of the trash with the tasks that were
+// moved there along with it
+func (r *Repository) RestoreProject(projectID uint, deletedAt time.Time) error {
+	return r.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Unscoped().Model(&models.Task{}).
+			Where("project_id = ? AND deleted_at = ?", projectID, deletedAt).
+			Update("deleted_at", nil).Error; err != nil {
+			return err
+		}
+		return tx.Unscoped().Model(&models.Project{}).Where("id = ?", projectID).Update("deleted_at", nil).Error
+	})
+}
+
+// PurgeProject permanently deletes a project with its tasks, members,
+// activities, sprints, milestones, notifications, webhooks and settings
+func (r *Repository) PurgeProject(projectID uint) error {
+	return r.DB.Transaction(func(tx *gorm.DB) error {
+		var taskIDs []uint
+		if err := tx.Unscoped().Model(&models.Task{}).Where("project_id = ?", projectID).Pluck("id", &taskIDs).Error; err != nil {
+			return err
+		}
+		if err := purgeTasks(tx, taskIDs); err != nil {
+			return err
+		}
+		if err := tx.Where("webhook_id IN (?)",
+			tx.Unscoped().Model(&models.Webhook{}).Select("id").Where("project_id = ?", projectID),
+		).Delete(&models.WebhookDelivery{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("sprint_id IN (?)",
+			tx.Unscoped().Model(&models.Sprint{}).Select("id").Where("project_id = ?", projectID),
+		).Delete(&models.SprintChange{}).Error; err != nil {
+			return err
+		}
+		for _, model := range []interface{}{
+			&models.UserRole{},
+			&models.Activity{},
+			&models.Label{},
+			&models.CustomField{},
+			&models.WorkflowStatus{},
+			&models.ReminderPolicy{},
+			&models.Recurrence{},
+			&models.TaskTemplate{},
+			&models.View{},
+			&models.WorkCalendar{},
+			&models.Holiday{},
+			&models.SLAPolicy{},
+			&models.AutomationRule{},
+			&models.AutomationRun{},
+			&models.Webhook{},
+			&models.Notification{},
+			&models.Sprint{},
+			&models.Milestone{},
+		} {
+			if err := tx.Unscoped().Where("project_id = ?", projectID).Delete(model).Error; err != nil {
+				return err
+			}
+		}
+		return tx.Unscoped().Delete(&models.Project{}, projectID).Error
+	})
+}
+
+// GetTrashedTasks lists the tasks of a project that are in the trash
+func (r *Repository) GetTrashedTasks(projectID uint) ([]models.Task, error) {
+	var tasks []models.Task
+	err := r.DB.Unscoped().
+		Where("project_id = ? AND deleted_at IS NOT NULL", projectID).
+		Order("deleted_at DESC, id").
+		Find(&tasks).Error
+	return tasks, err
+}
+
+func (r *Repository) GetTrashedTask(taskID uint) (*models.Task, error) {
+	var task models.Task
+	err := r.DB.Unscoped().Where("deleted_at IS NOT NULL").First(&task, taskID).Error
+	return &task, err
+}
+
+// trashedTree lists a task in the trash and the subtasks that were moved
+// there along with it
+func trashedTree(tx *gorm.DB, taskID uint, deletedAt time.Time) ([]uint, error) {
+	var ids []uint
+	err := tx.Raw(`
+		WITH RECURSIVE tree AS (
+			SELECT id FROM tasks WHERE id = ? AND deleted_at = ?
+			UNION ALL
+			SELECT t.id FROM tasks t JOIN tree ON t.parent_id = tree.id WHERE t.deleted_at = ?
+		)
+		SELECT id FROM tree`, taskID, deletedAt, deletedAt).Scan(&ids).Error
+	return ids, err
+}
+
+// RestoreTask takes a task out of the trash with the subtasks that were
+// moved there along with it. A task whose parent is still in the trash
+// becomes a top-level task.
+func (r *Repository) RestoreTask(taskID uint, deletedAt time.Time) error {
+	return r.DB.Transaction(func(tx *gorm.DB) error {
+		ids, err := trashedTree(tx, taskID, deletedAt)
+		if err != nil {
+			return err
+		}
+		if err := tx.Unscoped().Model(&models.Task{}).Where("id IN ?", ids).Update("deleted_at", nil).Error; err != nil {
+			return err
+		}
+		return tx.Unscoped().Model(&models.Task{}).
+			Where("id = ? AND parent_id IN (SELECT id FROM tasks WHERE deleted_at IS NOT NULL)", taskID).
+			Update("parent_id", nil).Error
+	})
+}
+
+// PurgeTask permanently deletes a task in the trash with the subtasks that
+// were moved there along with it
+func (r *Repository) PurgeTask(taskID uint, deletedAt time.Time) error {
+	return r.DB.Transaction(func(tx *gorm.DB) error {
+		ids, err := trashedTree(tx, taskID, deletedAt)
+		if err != nil {
+			return err
+		}
+		return purgeTasks(tx, ids)
+	})
+}
+
+// purgeTasks permanently deletes tasks and the rows referring to them;
+// subtasks that are not deleted become top-level tasks
+func purgeTasks(tx *gorm.DB, taskIDs []uint) error {
+	if len(taskIDs) == 0 {
+		return nil
+	}
+	for _, table := range []string{"task_labels", "task_assignees", "task_watchers"} {
+		if err := tx.Exec("DELETE FROM "+table+" WHERE task_id IN ?", taskIDs).Error; err != nil {
+			return err
+		}
+	}
+	if err := tx.Where("task_id IN ? OR depends_on_id IN ?", taskIDs, taskIDs).Delete(&models.TaskDependency{}).Error; err != nil {
+		return err
+	}
+	for _, model := range []interface{}{
+		&models.CustomFieldValue{},
+		&models.Comment{},
+		&models.WorkLog{},
+		&models.Timer{},
+		&models.TaskReminder{},
+		&models.SLAClock{},
+		&models.AutomationRun{},
+	} {
+		if err := tx.Unscoped().Where("task_id IN ?", taskIDs).Delete(model).Error; err != nil {
+			return err
+		}
+	}
+	if err := tx.Unscoped().Model(&models.Task{}).
+		Where("parent_id IN ? AND id NOT IN ?", taskIDs, taskIDs).
+		Update("parent_id", nil).Error; err != nil {
+		return err
+	}
+	return tx.Unscoped().Where("id IN ?", taskIDs).Delete(&models.Task{}).Error
+}
+
+// GetExpiredTrash lists the projects and the tasks of remaining projects
+// that were moved to the trash before the given time. Subtasks deleted
+// along with their parent are left out, as they are purged with it.
+func (r *Repository) GetExpiredTrash(before time.Time) ([]models.Project, []models.Task, error) {
+	var projects []models.Project
+	err := r.DB.Unscoped().Where("deleted_at < ?", before).Find(&projects).Error
+	if err != nil {
+		return nil, nil, err
+	}
+	var tasks []models.Task
+	err = r.DB.Unscoped().
+		Where("deleted_at < ?", before).
+		Where("project_id NOT IN (SELECT id FROM projects WHERE deleted_at < ?)", before).
+		Where("parent_id IS NULL OR parent_id NOT IN (SELECT p.id FROM tasks p WHERE p.deleted_at = tasks.deleted_at)").
+		Find(&tasks).Error
+	return projects, tasks, err
+}
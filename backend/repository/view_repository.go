@@ -0,0 +1,31 @@
+package repository
+
+import "work-management/models"
+
+func (r *Repository) CreateView(view *models.View) error {
+	return r.DB.Create(view).Error
+}
+
+// GetViews lists the views of a project that a user can see: their own and
+// the shared ones
+func (r *Repository) GetViews(projectID, userID uint) ([]models.View, error) {
+	var views []models.View
+	err := r.DB.Where("project_id = ? AND (owner_id = ? OR shared)", projectID, userID).
+		Order("name, id").
+		Find(&views).Error
+	return views, err
+}
+
+func (r *Repository) GetViewByID(viewID uint) (*models.View, error) {
+	var view models.View
+	err := r.DB.First(&view, viewID).Error
+	return &view, err
+}
+
+func (r *Repository) UpdateView(view *models.View) error {
+	return r.DB.Save(view).Error
+}
+
+func (r *Repository) DeleteView(viewID uint) error {
+	return r.DB.Delete(&models.View{}, viewID).Error
+}
@@ -0,0 +1,64 @@
+package repository
+
+import (
+	"work-management/models"
+
+	"gorm.io/gorm"
+)
+
+func (r *Repository) CreateWebhook(webhook *models.Webhook) error {
+	return r.DB.Create(webhook).Error
+}
+
+func (r *Repository) GetWebhooksByProjectID(projectID uint) ([]models.Webhook, error) {
+	var webhooks []models.Webhook
+	err := r.DB.Where("project_id = ?", projectID).Order("id").Find(&webhooks).Error
+	return webhooks, err
+}
+
+func (r *Repository) GetActiveWebhooks(projectID uint) ([]models.Webhook, error) {
+	var webhooks []models.Webhook
+	err := r.DB.Where("project_id = ? AND active", projectID).Find(&webhooks).Error
+	return webhooks, err
+}
+
+func (r *Repository) GetWebhookByID(webhookID uint) (*models.Webhook, error) {
+	var webhook models.Webhook
+	err := r.DB.First(&webhook, webhookID).Error
+	return &webhook, err
+}
+
+func (r *Repository) UpdateWebhook(webhook *models.Webhook) error {
+	return r.DB.Save(webhook).Error
+}
+
+// DeleteWebhook removes a webhook together with its delivery history
+func (r *Repository) DeleteWebhook(webhookID uint) error {
+	return r.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("webhook_id = ?", webhookID).Delete(&models.WebhookDelivery{}).Error; err != nil {
+			return err
+		}
+		return tx.Unscoped().Delete(&models.Webhook{}, webhookID).Error
+	})
+}
+
+func (r *Repository) CreateWebhookDelivery(delivery *models.WebhookDelivery) error {
+	return r.DB.Create(delivery).Error
+}
+
+// GetWebhookDeliveries lists a webhook's most recent deliveries first
+func (r *Repository) GetWebhookDeliveries(webhookID uint, limit int) ([]models.WebhookDelivery, error) {
+	var deliveries []models.WebhookDelivery
+	err := r.DB.Where("webhook_id = ?", webhookID).Order("id DESC").Limit(limit).Find(&deliveries).Error
+	return deliveries, err
+}
+
+func (r *Repository) GetWebhookDeliveryByID(deliveryID uint) (*models.WebhookDelivery, error) {
+	var delivery models.WebhookDelivery
+	err := r.DB.First(&delivery, deliveryID).Error
+	return &delivery, err
+}
+
+func (r *Repository) UpdateWebhookDelivery(delivery *models.WebhookDelivery) error {
+	return r.DB.Save(delivery).Error
+}
@@ -0,0 +1,49 @@
+package repository
+
+import (
+	"work-management/models"
+
+	"gorm.io/gorm"
+)
+
+// GetWorkflow returns the saved statuses of a project in board order; the
+// list is empty for projects using the default workflow
+func (r *Repository) GetWorkflow(projectID uint) ([]models.WorkflowStatus, error) {
+	var statuses []models.WorkflowStatus
+	err := r.DB.Where("project_id = ?", projectID).Order("position").Find(&statuses).Error
+	return statuses, err
+}
+
+// ReplaceWorkflow swaps the saved statuses of a project for the given ones
+func (r *Repository) ReplaceWorkflow(projectID uint, statuses []models.WorkflowStatus) error {
+	return r.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("project_id = ?", projectID).Delete(&models.WorkflowStatus{}).Error; err != nil {
+			return err
+		}
+		for i := range statuses {
+			statuses[i].ID = 0
+			statuses[i].ProjectID = projectID
+		}
+		if len(statuses) == 0 {
+			return nil
+		}
+		return tx.Create(&statuses).Error
+	})
+}
+
+// CountTasksOutsideStatuses counts the project's tasks whose status is not
+// among the given ones
+func (r *Repository) CountTasksOutsideStatuses(projectID uint, names []string) (int64, error) {
+	var count int64
+	err := r.DB.Model(&models.Task{}).
+		Where("project_id = ? AND status NOT IN ?", projectID, names).
+		Count(&count).Error
+	return count, err
+}
+
+// RenameTaskStatus moves the project's tasks from one status to another
+func (r *Repository) RenameTaskStatus(projectID uint, from, to string) error {
+	return r.DB.Model(&models.Task{}).
+		Where("project_id = ? AND status = ?", projectID, from).
+		Update("status", to).Error
+}
@@ -0,0 +1,173 @@
+package repository
+
+import (
+	"errors"
+	"strings"
+	"time"
+
+	"work-management/models"
+
+	"github.com/sirupsen/logrus"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+func (r *Repository) CreateWorkLog(workLog *models.WorkLog) error {
+	return r.DB.Create(workLog).Error
+}
+
+func (r *Repository) GetWorkLogByID(workLogID uint) (*models.WorkLog, error) {
+	var workLog models.WorkLog
+	err := r.DB.Preload("User").First(&workLog, workLogID).Error
+	return &workLog, err
+}
+
+func (r *Repository) GetWorkLogsByTaskID(taskID uint) ([]models.WorkLog, error) {
+	var workLogs []models.WorkLog
+	err := r.DB.Where("task_id = ?", taskID).Preload("User").Order("started_at DESC").Find(&workLogs).Error
+	return workLogs, err
+}
+
+func (r *Repository) UpdateWorkLog(workLog *models.WorkLog) error {
+	return r.DB.Save(workLog).Error
+}
+
+func (r *Repository) DeleteWorkLog(workLogID uint) error {
+	return r.DB.Delete(&models.WorkLog{}, workLogID).Error
+}
+
+func (r *Repository) GetTimerByUserID(userID uint) (*models.Timer, error) {
+	var timer models.Timer
+	err := r.DB.Preload("Task").Where("user_id = ?", userID).First(&timer).Error
+	return &timer, err
+}
+
+// StartTimer replaces the user's running timer, if any, with a new one. The
+// replaced timer is converted to a work log in the same transaction and
+// returned, or nil when no timer was running.
+func (r *Repository) StartTimer(timer *models.Timer) (*models.WorkLog, error) {
+	var stopped *models.WorkLog
+	err := r.DB.Transaction(func(tx *gorm.DB) error {
+		var running models.Timer
+		err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Where("user_id = ?", timer.UserID).First(&running).Error
+		switch {
+		case err == nil:
+			stopped = running.ToWorkLog(timer.StartedAt)
+			if err := tx.Create(stopped).Error; err != nil {
+				return err
+			}
+			if err := tx.Delete(&running).Error; err != nil {
+				return err
+			}
+		case !errors.Is(err, gorm.ErrRecordNotFound):
+			return err
+		}
+		return tx.Create(timer).Error
+	})
+	return stopped, err
+}
+
+// StopTimer deletes the user's running timer and stores the resulting work log
+func (r *Repository) StopTimer(userID uint, stoppedAt time.Time) (*models.WorkLog, error) {
+	var workLog *models.WorkLog
+	err := r.DB.Transaction(func(tx *gorm.DB) error {
+		var running models.Timer
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Where("user_id = ?", userID).First(&running).Error; err != nil {
+			return err
+		}
+		workLog = running.ToWorkLog(stoppedAt)
+		if err := tx.Create(workLog).Error; err != nil {
+			return err
+		}
+		return tx.Delete(&running).Error
+	})
+	return workLog, err
+}
+
+// GetProjectIDsForUser lists the projects the user holds any role in
+func (r *Repository) GetProjectIDsForUser(userID uint) ([]uint, error) {
+	var projectIDs []uint
+	err := r.DB.Model(&models.UserRole{}).Where("user_id = ?", userID).Distinct().Pluck("project_id", &projectIDs).Error
+	return projectIDs, err
+}
+
+// TimesheetQuery selects the work logs aggregated into a timesheet
+type TimesheetQuery struct {
+	From       time.Time
+	To         time.Time // Exclusive
+	ProjectIDs []uint    // Projects visible to the caller
+	ProjectID  uint      // Optional narrowing to one project
+	UserID     uint      // Optional narrowing to one user
+	GroupBy    []string  // Any of "user", "project", "day"
+}
+
+// TimesheetRow is one aggregated line of a timesheet. Columns that are not
+// part of the grouping are left zero.
+type TimesheetRow struct {
+	UserID      uint   `json:"user_id,omitempty"`
+	UserName    string `json:"user_name,omitempty"`
+	ProjectID   uint   `json:"project_id,omitempty"`
+	ProjectName string `json:"project_name,omitempty"`
+	Day         string `json:"day,omitempty"`
+	Minutes     int    `json:"minutes"`
+}
+
+// timesheetGroupColumns maps a grouping name to the columns it selects
+var timesheetGroupColumns = map[string][]string{
+	"user":    {"w.user_id AS user_id", "u.name AS user_name"},
+	"project": {"t.project_id AS project_id", "p.name AS project_name"},
+	"day":     {"TO_CHAR(w.started_at, 'YYYY-MM-DD') AS day"},
+}
+
+var timesheetGroupKeys = map[string][]string{
+	"user":    {"w.user_id", "u.name"},
+	"project": {"t.project_id", "p.name"},
+	"day":     {"TO_CHAR(w.started_at, 'YYYY-MM-DD')"},
+}
+
+// IsTimesheetGrouping reports whether name is a supported timesheet grouping
+func IsTimesheetGrouping(name string) bool {
+	_, ok := timesheetGroupColumns[name]
+	return ok
+}
+
+func (r *Repository) GetTimesheet(q TimesheetQuery) ([]TimesheetRow, error) {
+	rows := []TimesheetRow{}
+	if len(q.ProjectIDs) == 0 {
+		return rows, nil
+	}
+	selects := []string{}
+	groups := []string{}
+	for _, group := range q.GroupBy {
+		selects = append(selects, timesheetGroupColumns[group]...)
+		groups = append(groups, timesheetGroupKeys[group]...)
+	}
+	selects = append(selects, "SUM(w.duration) AS minutes")
+
+	query := r.DB.Table("work_logs w").
+		Select(strings.Join(selects, ", ")).
+		Joins("JOIN tasks t ON t.id = w.task_id").
+		Joins("JOIN users u ON u.id = w.user_id").
+		Joins("JOIN projects p ON p.id = t.project_id").
+		Where("w.deleted_at IS NULL").
+		Where("w.started_at >= ? AND w.started_at < ?", q.From, q.To).
+		Where("t.project_id IN ?", q.ProjectIDs)
+	if q.ProjectID != 0 {
+		query = query.Where("t.project_id = ?", q.ProjectID)
+	}
+	if q.UserID != 0 {
+		query = query.Where("w.user_id = ?", q.UserID)
+	}
+	if len(groups) > 0 {
+		query = query.Group(strings.Join(groups, ", ")).Order(strings.Join(groups, ", "))
+	}
+	if err := query.Scan(&rows).Error; err != nil {
+		logrus.WithFields(logrus.Fields{
+			"from":  q.From,
+			"to":    q.To,
+			"error": err,
+		}).Error("Failed to aggregate timesheet")
+		return nil, err
+	}
+	return rows, nil
+}
@@ -0,0 +1,343 @@
+// Archive-related services (archiving projects and the trash)
+package services
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"work-management/models"
+	"work-management/repository"
+
+	"github.com/sirupsen/logrus"
+	"gorm.io/gorm"
+)
+
+// trashRetention is how long deleted projects and tasks stay in the trash
+// before they are purged
+const trashRetention = 30 * 24 * time.Hour
+
+// ensureNotArchived fails with ErrProjectArchived when a project is archived
+func (s *Service) ensureNotArchived(projectID uint) error {
+	archived, err := s.Repo.IsProjectArchived(projectID)
+	if err != nil {
+		logrus.WithFields(logrus.Fields{
+			"projectID": projectID,
+			"error":     err,
+		}).Error("Failed to check whether project is archived")
+		return err
+	}
+	if archived {
+		return fmt.Errorf("%w: unarchive it to make changes", ErrProjectArchived)
+	}
+	return nil
+}
+
+// ArchiveProject makes a project read-only and hides it from project lists
+func (s *Service) ArchiveProject(actorID, projectID uint) (*models.Project, error) {
+	return s.setProjectArchived(actorID, projectID, true)
+}
+
+func (s *Service) UnarchiveProject(actorID, projectID uint) (*models.Project, error) {
+	return s.setProjectArchived(actorID, projectID, false)
+}
+
+func (s *Service) setProjectArchived(actorID, projectID uint, archive bool) (*models.Project, error) {
+	project, err := s.Repo.GetProjectByID(projectID)
+	if err != nil {
+		logrus.WithFields(logrus.Fields{
+			"projectID": projectID,
+			"error":     err,
+		}).Warn("Project not found for archiving")
+		return nil, err
+	}
+	if (project.ArchivedAt != nil) == archive {
+		if archive {
+			return nil, invalidInput("project is already archived")
+		}
+		return nil, invalidInput("project is not archived")
+	}
+	var archivedAt *time.Time
+	action, eventType, verb := AuditProjectUnarchived, EventProjectUnarchived, "unarchived"
+	if archive {
+		now := time.Now()
+		archivedAt = &now
+		action, eventType, verb = AuditProjectArchived, EventProjectArchived, "archived"
+	}
+	err = s.transaction(func(repo *repository.Repository) error {
+		if err := repo.SetProjectArchived(projectID, archivedAt); err != nil {
+			return err
+		}
+		if err := s.audit(repo, models.AuditLog{
+			ActorID:    actorID,
+			Action:     action,
+			EntityType: "project",
+			EntityID:   projectID,
+			ProjectID:  projectID,
+		}); err != nil {
+			return err
+		}
+		return s.recordEvent(repo, Event{
+			Type:      eventType,
+			ProjectID: projectID,
+			ActorID:   actorID,
+			Message:   fmt.Sprintf("%s project %q", verb, project.Name),
+		})
+	})
+	if err != nil {
+		logrus.WithFields(logrus.Fields{
+			"projectID": projectID,
+			"archive":   archive,
+			"error":     err,
+		}).Error("Failed to archive project")
+		return nil, err
+	}
+	logrus.WithFields(logrus.Fields{
+		"projectID": projectID,
+		"archive":   archive,
+	}).Info("Project archive state changed")
+	return s.Repo.GetProjectByID(projectID)
+}
+
+// GetTrashedProjects lists the projects in the trash that a user administers
+func (s *Service) GetTrashedProjects(userID uint) ([]models.Project, error) {
+	projects, err := s.Repo.GetTrashedProjects(userID)
+	if err != nil {
+		logrus.WithFields(logrus.Fields{
+			"userID": userID,
+			"error":  err,
+		}).Error("Failed to retrieve trashed projects")
+		return nil, err
+	}
+	return projects, nil
+}
+
+// RestoreProject takes a project out of the trash along with the tasks that
+// were deleted with it
+func (s *Service) RestoreProject(actorID, projectID uint) (*models.Project, error) {
+	project, err := s.Repo.GetTrashedProject(projectID)
+	if err != nil {
+		logrus.WithFields(logrus.Fields{
+			"projectID": projectID,
+			"error":     err,
+		}).Warn("Project not found in the trash")
+		return nil, err
+	}
+	err = s.transaction(func(repo *repository.Repository) error {
+		if err := repo.RestoreProject(projectID, project.DeletedAt.Time); err != nil {
+			return err
+		}
+		if err := s.audit(repo, models.AuditLog{
+			ActorID:    actorID,
+			Action:     AuditProjectRestored,
+			EntityType: "project",
+			EntityID:   projectID,
+			ProjectID:  projectID,
+			Details:    map[string]interface{}{"name": project.Name},
+		}); err != nil {
+			return err
+		}
+		return s.recordEvent(repo, Event{
+			Type:      EventProjectRestored,
+			ProjectID: projectID,
+			ActorID:   actorID,
+			Message:   fmt.Sprintf("restored project %q from the trash", project.Name),
+		})
+	})
+	if err != nil {
+		logrus.WithFields(logrus.Fields{
+			"projectID": projectID,
+			"error":     err,
+		}).Error("Failed to restore project")
+		return nil, err
+	}
+	logrus.WithFields(logrus.Fields{
+		"projectID": projectID,
+	}).Info("Project restored from the trash")
+	return s.Repo.GetProjectByID(projectID)
+}
+
+// PurgeProject permanently deletes a project in the trash
+func (s *Service) PurgeProject(actorID, projectID uint) error {
+	project, err := s.Repo.GetTrashedProject(projectID)
+	if err != nil {
+		logrus.WithFields(logrus.Fields{
+			"projectID": projectID,
+			"error":     err,
+		}).Warn("Project not found in the trash")
+		return err
+	}
+	return s.purgeProject(actorID, project)
+}
+
+// purgeProject permanently deletes a project; actorID is 0 when the
+// retention period ran out
+func (s *Service) purgeProject(actorID uint, project *models.Project) error {
+	err := s.transaction(func(repo *repository.Repository) error {
+		if err := repo.PurgeProject(project.ID); err != nil {
+			return err
+		}
+		return s.audit(repo, models.AuditLog{
+			ActorID:    actorID,
+			Action:     AuditProjectPurged,
+			EntityType: "project",
+			EntityID:   project.ID,
+			ProjectID:  project.ID,
+			Details: map[string]interface{}{
+				"name":       project.Name,
+				"deleted_at": project.DeletedAt.Time,
+			},
+		})
+	})
+	if err != nil {
+		logrus.WithFields(logrus.Fields{
+			"projectID": project.ID,
+			"error":     err,
+		}).Error("Failed to purge project")
+		return err
+	}
+	logrus.WithFields(logrus.Fields{
+		"projectID": project.ID,
+	}).Info("Project purged")
+	return nil
+}
+
+// GetTrashedTasks lists the tasks of a project that are in the trash
+func (s *Service) GetTrashedTasks(projectID uint) ([]models.Task, error) {
+	tasks, err := s.Repo.GetTrashedTasks(projectID)
+	if err != nil {
+		logrus.WithFields(logrus.Fields{
+			"projectID": projectID,
+			"error":     err,
+		}).Error("Failed to retrieve trashed tasks")
+		return nil, err
+	}
+	return tasks, nil
+}
+
+func (s *Service) GetTrashedTask(taskID uint) (*models.Task, error) {
+	return s.Repo.GetTrashedTask(taskID)
+}
+
+// RestoreTask takes a task out of the trash along with the subtasks that
+// were deleted with it. The task's project must not be in the trash itself.
+func (s *Service) RestoreTask(actorID, taskID uint) (*models.Task, error) {
+	task, err := s.Repo.GetTrashedTask(taskID)
+	if err != nil {
+		logrus.WithFields(logrus.Fields{
+			"taskID": taskID,
+			"error":  err,
+		}).Warn("Task not found in the trash")
+		return nil, err
+	}
+	if _, err := s.Repo.GetProjectByID(task.ProjectID); err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, invalidInput("the task's project is in the trash; restore the project first")
+		}
+		return nil, err
+	}
+	err = s.transaction(func(repo *repository.Repository) error {
+		if err := repo.RestoreTask(taskID, task.DeletedAt.Time); err != nil {
+			return err
+		}
+		if err := s.audit(repo, models.AuditLog{
+			ActorID:    actorID,
+			Action:     AuditTaskRestored,
+			EntityType: "task",
+			EntityID:   taskID,
+			ProjectID:  task.ProjectID,
+			Details:    map[string]interface{}{"title": task.Title},
+		}); err != nil {
+			return err
+		}
+		return s.recordEvent(repo, Event{
+			Type:      EventTaskRestored,
+			ProjectID: task.ProjectID,
+			TaskID:    taskID,
+			ActorID:   actorID,
+			Message:   fmt.Sprintf("restored task %q from the trash", task.Title),
+		})
+	})
+	if err != nil {
+		logrus.WithFields(logrus.Fields{
+			"taskID": taskID,
+			"error":  err,
+		}).Error("Failed to restore task")
+		return nil, err
+	}
+	logrus.WithFields(logrus.Fields{
+		"taskID": taskID,
+	}).Info("Task restored from the trash")
+	return s.Repo.GetTaskByID(taskID)
+}
+
+// PurgeTask permanently deletes a task in the trash with the subtasks that
+// were deleted with it
+func (s *Service) PurgeTask(actorID, taskID uint) error {
+	task, err := s.Repo.GetTrashedTask(taskID)
+	if err != nil {
+		logrus.WithFields(logrus.Fields{
+			"taskID": taskID,
+			"error":  err,
+		}).Warn("Task not found in the trash")
+		return err
+	}
+	return s.purgeTask(actorID, task)
+}
+
+func (s *Service) purgeTask(actorID uint, task *models.Task) error {
+	err := s.transaction(func(repo *repository.Repository) error {
+		if err := repo.PurgeTask(task.ID, task.DeletedAt.Time); err != nil {
+			return err
+		}
+		return s.audit(repo, models.AuditLog{
+			ActorID:    actorID,
+			Action:     AuditTaskPurged,
+			EntityType: "task",
+			EntityID:   task.ID,
+			ProjectID:  task.ProjectID,
+			Details: map[string]interface{}{
+				"title":      task.Title,
+				"deleted_at": task.DeletedAt.Time,
+			},
+		})
+	})
+	if err != nil {
+		logrus.WithFields(logrus.Fields{
+			"taskID": task.ID,
+			"error":  err,
+		}).Error("Failed to purge task")
+		return err
+	}
+	logrus.WithFields(logrus.Fields{
+		"taskID": task.ID,
+	}).Info("Task purged")
+	return nil
+}
+
+// PurgeExpiredTrash permanently deletes the projects and tasks that have
+// been in the trash for longer than trashRetention
+func (s *Service) PurgeExpiredTrash(now time.Time) error {
+	projects, tasks, err := s.Repo.GetExpiredTrash(now.Add(-trashRetention))
+	if err != nil {
+		logrus.WithFields(logrus.Fields{
+			"error": err,
+		}).Error("Failed to retrieve expired trash")
+		return err
+	}
+	for i := range projects {
+		if err := s.purgeProject(0, &projects[i]); err != nil {
+			return err
+		}
+	}
+	for i := range tasks {
+		if err := s.purgeTask(0, &tasks[i]); err != nil {
+			return err
+		}
+	}
+	logrus.WithFields(logrus.Fields{
+		"projects": len(projects),
+		"tasks":    len(tasks),
+	}).Info("Expired trash purged")
+	return nil
+}
@@ -0,0 +1,202 @@
+// Assignment services (assignees, watchers and "my work")
+package services
+
+import (
+	"fmt"
+
+	"work-management/dto"
+	"work-management/models"
+	"work-management/repository"
+
+	"github.com/sirupsen/logrus"
+)
+
+// IsProjectMember reports whether the user holds any role in the project
+func (s *Service) IsProjectMember(userID, projectID uint) bool {
+	_, err := s.Repo.GetUserRole(userID, projectID)
+	return err == nil
+}
+
+// validateAssignees checks that the primary assignee and every additional
+// assignee belong to the project, and returns the de-duplicated list with the
+// primary assignee first.
+func (s *Service) validateAssignees(projectID, primaryID uint, assigneeIDs []uint) ([]uint, error) {
+	ids := []uint{}
+	seen := map[uint]bool{}
+	if primaryID != 0 {
+		ids = append(ids, primaryID)
+		seen[primaryID] = true
+	}
+	for _, id := range assigneeIDs {
+		if !seen[id] {
+			ids = append(ids, id)
+			seen[id] = true
+		}
+	}
+	for _, id := range ids {
+		if !s.IsProjectMember(id, projectID) {
+			return nil, invalidInput("user %d is not a member of the project", id)
+		}
+	}
+	return ids, nil
+}
+
+// AssignTaskToUser adds an assignee to a task. Unlike the original single
+// assignee column this no longer replaces existing assignees.
+func (s *Service) AssignTaskToUser(actorID, taskID, userID uint) (*models.Task, error) {
+	task, err := s.Repo.GetTaskByID(taskID)
+	if err != nil {
+		logrus.WithFields(logrus.Fields{
+			"taskID": taskID,
+			"error":  err,
+		}).Warn("Task not found for assignment")
+		return nil, err
+	}
+	if !s.IsProjectMember(userID, task.ProjectID) {
+		logrus.WithFields(logrus.Fields{
+			"taskID":    taskID,
+			"userID":    userID,
+			"projectID": task.ProjectID,
+		}).Warn("Assignee is not a project member")
+		return nil, invalidInput("user %d is not a member of the project", userID)
+	}
+	wasAssigned := false
+	for _, assignee := range task.Assignees {
+		wasAssigned = wasAssigned || assignee.ID == userID
+	}
+	err = s.transaction(func(repo *repository.Repository) error {
+		if err := repo.AddTaskAssignee(taskID, userID); err != nil {
+			return err
+		}
+		if wasAssigned {
+			return nil
+		}
+		return s.recordEvent(repo, Event{
+			Type:       EventTaskAssigned,
+			ProjectID:  task.ProjectID,
+			TaskID:     taskID,
+			ActorID:    actorID,
+			Message:    fmt.Sprintf("assigned %s to task %q", s.userName(userID), task.Title),
+			Recipients: []uint{userID},
+			Notice:     fmt.Sprintf("assigned you to %q", task.Title),
+		})
+	})
+	if err != nil {
+		logrus.WithFields(logrus.Fields{
+			"taskID": taskID,
+			"userID": userID,
+			"error":  err,
+		}).Error("Failed to assign task to user")
+		return nil, err
+	}
+	logrus.WithFields(logrus.Fields{
+		"taskID": taskID,
+		"userID": userID,
+	}).Info("Task assigned to user successfully")
+	return s.Repo.GetTaskByID(taskID)
+}
+
+func (s *Service) UnassignTaskFromUser(actorID, taskID, userID uint) (*models.Task, error) {
+	task, err := s.Repo.GetTaskByID(taskID)
+	if err != nil {
+		logrus.WithFields(logrus.Fields{
+			"taskID": taskID,
+			"error":  err,
+		}).Warn("Task not found for unassignment")
+		return nil, err
+	}
+	err = s.transaction(func(repo *repository.Repository) error {
+		removed, err := repo.RemoveTaskAssignee(taskID, userID)
+		if err != nil {
+			return err
+		}
+		if !removed {
+			return invalidInput("user %d is not assigned to the task", userID)
+		}
+		return s.recordEvent(repo, Event{
+			Type:      EventTaskUnassigned,
+			ProjectID: task.ProjectID,
+			TaskID:    taskID,
+			ActorID:   actorID,
+			Message:   fmt.Sprintf("unassigned %s from task %q", s.userName(userID), task.Title),
+			Data:      map[string]interface{}{"user_id": userID},
+		})
+	})
+	if err != nil {
+		logrus.WithFields(logrus.Fields{
+			"taskID": taskID,
+			"userID": userID,
+			"error":  err,
+		}).Error("Failed to unassign task from user")
+		return nil, err
+	}
+	logrus.WithFields(logrus.Fields{
+		"taskID": taskID,
+		"userID": userID,
+	}).Info("Task unassigned from user successfully")
+	return s.Repo.GetTaskByID(taskID)
+}
+
+func (s *Service) WatchTask(taskID, userID uint) (*models.Task, error) {
+	task, err := s.Repo.GetTaskByID(taskID)
+	if err != nil {
+		logrus.WithFields(logrus.Fields{
+			"taskID": taskID,
+			"error":  err,
+		}).Warn("Task not found for watching")
+		return nil, err
+	}
+	if !s.IsProjectMember(userID, task.ProjectID) {
+		return nil, invalidInput("user %d is not a member of the project", userID)
+	}
+	if err := s.Repo.AddTaskWatcher(taskID, userID); err != nil {
+		logrus.WithFields(logrus.Fields{
+			"taskID": taskID,
+			"userID": userID,
+			"error":  err,
+		}).Error("Failed to add task watcher")
+		return nil, err
+	}
+	logrus.WithFields(logrus.Fields{
+		"taskID": taskID,
+		"userID": userID,
+	}).Info("Task watcher added successfully")
+	return s.Repo.GetTaskByID(taskID)
+}
+
+func (s *Service) UnwatchTask(taskID, userID uint) (*models.Task, error) {
+	if err := s.Repo.RemoveTaskWatcher(taskID, userID); err != nil {
+		logrus.WithFields(logrus.Fields{
+			"taskID": taskID,
+			"userID": userID,
+			"error":  err,
+		}).Error("Failed to remove task watcher")
+		return nil, err
+	}
+	logrus.WithFields(logrus.Fields{
+		"taskID": taskID,
+		"userID": userID,
+	}).Info("Task watcher removed successfully")
+	return s.Repo.GetTaskByID(taskID)
+}
+
+// GetMyWork lists every task assigned to or watched by the user across the
+// projects they belong to.
+func (s *Service) GetMyWork(userID uint, filter dto.TaskFilter) ([]models.Task, error) {
+	if err := s.prepareTaskFilter(&filter); err != nil {
+		return nil, err
+	}
+	tasks, err := s.Repo.GetMyWork(userID, filter)
+	if err != nil {
+		logrus.WithFields(logrus.Fields{
+			"userID": userID,
+			"error":  err,
+		}).Error("Failed to retrieve work for user")
+		return nil, err
+	}
+	logrus.WithFields(logrus.Fields{
+		"userID":    userID,
+		"taskCount": len(tasks),
+	}).Info("Work retrieved for user successfully")
+	return tasks, nil
+}
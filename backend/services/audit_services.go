@@ -0,0 +1,55 @@
+// Audit-related services (the administrative audit log)
+package services
+
+import (
+	"time"
+
+	"work-management/models"
+	"work-management/repository"
+
+	"github.com/sirupsen/logrus"
+)
+
+// Audited actions
+const (
+	AuditProjectCloned     = "project.cloned"
+	AuditProjectArchived   = "project.archived"
+	AuditProjectUnarchived = "project.unarchived"
+	AuditProjectTrashed    = "project.trashed"
+	AuditProjectRestored   = "project.restored"
+	AuditProjectPurged     = "project.purged"
+	AuditTaskCloned        = "task.cloned"
+	AuditTaskTrashed       = "task.trashed"
+	AuditTaskRestored      = "task.restored"
+	AuditTaskPurged        = "task.purged"
+)
+
+// audit adds an entry to the audit log using repo, so that it is part of the
+// transaction making the change
+func (s *Service) audit(repo *repository.Repository, entry models.AuditLog) error {
+	if entry.CreatedAt.IsZero() {
+		entry.CreatedAt = time.Now()
+	}
+	if err := repo.CreateAuditLog(&entry); err != nil {
+		logrus.WithFields(logrus.Fields{
+			"action": entry.Action,
+			"error":  err,
+		}).Error("Failed to write audit log")
+		return err
+	}
+	return nil
+}
+
+// GetAuditLogs lists the latest audit entries, optionally of one project
+// (0 for all) and action
+func (s *Service) GetAuditLogs(projectID uint, action string, limit int) ([]models.AuditLog, error) {
+	entries, err := s.Repo.GetAuditLogs(projectID, action, limit)
+	if err != nil {
+		logrus.WithFields(logrus.Fields{
+			"projectID": projectID,
+			"error":     err,
+		}).Error("Failed to retrieve audit log")
+		return nil, err
+	}
+	return entries, nil
+}
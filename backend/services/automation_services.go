@@ -0,0 +1,902 @@
+// Automation-related services (project rules, their execution and run logs)
+package services
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"work-management/calendar"
+	"work-management/dto"
+	"work-management/models"
+	"work-management/repository"
+
+	"github.com/sirupsen/logrus"
+	"gorm.io/gorm"
+)
+
+// Automation settings
+const (
+	maxAutomationConditions = 10
+	maxAutomationActions    = 10
+	// maxAutomationDepth bounds chains of rules set off by each other's
+	// changes; a rule is never set off by its own changes
+	maxAutomationDepth = 3
+	// maxAutomationRuns caps the runs listed for a rule
+	maxAutomationRuns = 100
+	// keepAutomationRuns is how long run logs are kept
+	keepAutomationRuns = 30 * 24 * time.Hour
+	// maxDueDateShift bounds the working days of a relative due date
+	maxDueDateShift = 365
+)
+
+// Keys of the event data marking changes made by automations
+const (
+	automationRuleKey  = "automation_rule_id"
+	automationDepthKey = "automation_depth"
+)
+
+// Errors ending a run before its changes are committed
+var (
+	errAlreadyRan        = errors.New("automation already ran for this event")
+	errNoAutomationMatch = errors.New("automation conditions do not hold")
+	errAutomationLoop    = errors.New("automation chain too long")
+)
+
+var automationOps = map[string]bool{
+	models.OpEquals:    true,
+	models.OpNotEquals: true,
+	models.OpContains:  true,
+	models.OpGreater:   true,
+	models.OpLess:      true,
+	models.OpEmpty:     true,
+	models.OpNotEmpty:  true,
+}
+
+// validateAutomationRule checks a rule against the project it belongs to:
+// the statuses, labels, members and webhooks its actions refer to must exist
+func (s *Service) validateAutomationRule(projectID uint, input *dto.AutomationRuleInput) error {
+	input.Name = strings.TrimSpace(input.Name)
+	if input.Name == "" {
+		return invalidInput("rule name is required")
+	}
+	if !models.AutomationTriggers[input.Trigger] {
+		return invalidInput("trigger must be one of task_created, task_updated, status_changed, due_date_passed, comment_added")
+	}
+	if len(input.Conditions) > maxAutomationConditions {
+		return invalidInput("a rule has at most %d conditions", maxAutomationConditions)
+	}
+	for _, condition := range input.Conditions {
+		if !models.AutomationConditionFields[condition.Field] {
+			return invalidInput("unknown condition field %q", condition.Field)
+		}
+		if !automationOps[condition.Op] {
+			return invalidInput("condition operator must be one of eq, ne, contains, gt, lt, empty, not_empty")
+		}
+		if (condition.Op == models.OpGreater || condition.Op == models.OpLess) &&
+			condition.Field != "story_points" && condition.Field != "due_date" {
+			return invalidInput("gt and lt only compare story_points and due_date")
+		}
+	}
+	if len(input.Actions) == 0 {
+		return invalidInput("a rule needs at least one action")
+	}
+	if len(input.Actions) > maxAutomationActions {
+		return invalidInput("a rule has at most %d actions", maxAutomationActions)
+	}
+	for i := range input.Actions {
+		if err := s.validateAutomationAction(projectID, &input.Actions[i]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (s *Service) validateAutomationAction(projectID uint, action *models.AutomationAction) error {
+	action.Value = strings.TrimSpace(action.Value)
+	switch action.Type {
+	case models.ActionSetField:
+		if !models.AutomationSetFields[action.Field] {
+			return invalidInput("set_field changes one of title, description, priority, story_points, due_date")
+		}
+		return validateAutomationFieldValue(action.Field, action.Value)
+	case models.ActionAssign, models.ActionUnassign:
+		switch action.Value {
+		case "reporter", "actor":
+			return nil
+		case "all":
+			if action.Type == models.ActionUnassign {
+				return nil
+			}
+		}
+		return s.validateAutomationUser(projectID, action.Value)
+	case models.ActionAddLabel:
+		if _, err := s.Repo.GetLabelByName(projectID, action.Value); err != nil {
+			return invalidInput("label %q does not exist in the project", action.Value)
+		}
+	case models.ActionComment, models.ActionCreateSubtask:
+		if action.Value == "" {
+			return invalidInput("%s needs a value", action.Type)
+		}
+	case models.ActionMove:
+		if _, err := s.isCompletedStatus(s.Repo, projectID, action.Value); err != nil {
+			return err
+		}
+	case models.ActionNotify:
+		if action.Value == "" {
+			return invalidInput("notify needs a message")
+		}
+		switch action.To {
+		case "reporter", "assignees", "watchers", "actor":
+			return nil
+		}
+		return s.validateAutomationUser(projectID, action.To)
+	case models.ActionWebhook:
+		webhookID, err := strconv.ParseUint(action.Value, 10, 32)
+		if err != nil {
+			return invalidInput("webhook action needs a webhook ID")
+		}
+		webhook, err := s.Repo.GetWebhookByID(uint(webhookID))
+		if err != nil || webhook.ProjectID != projectID {
+			return invalidInput("webhook %d does not exist in the project", webhookID)
+		}
+	default:
+		return invalidInput("unknown action %q", action.Type)
+	}
+	return nil
+}
+
+func validateAutomationFieldValue(field, value string) error {
+	switch field {
+	case "title":
+		if value == "" {
+			return invalidInput("title cannot be empty")
+		}
+	case "priority":
+		if _, ok := models.PriorityRank[value]; !ok {
+			return invalidInput("priority must be one of low, medium, high, urgent")
+		}
+	case "story_points":
+		points, err := strconv.Atoi(value)
+		if err != nil || points < 0 || points > 100 {
+			return invalidInput("story_points must be between 0 and 100")
+		}
+	case "due_date":
+		if value == "" {
+			return nil
+		}
+		if strings.HasPrefix(value, "+") {
+			days, err := strconv.Atoi(value[1:])
+			if err != nil || days < 0 || days > maxDueDateShift {
+				return invalidInput("a relative due date is \"+<working days>\", up to %d", maxDueDateShift)
+			}
+			return nil
+		}
+		if _, err := time.Parse("2006-01-02", value); err != nil {
+			return invalidInput("due_date must be YYYY-MM-DD or \"+<working days>\"")
+		}
+	}
+	return nil
+}
+
+func (s *Service) validateAutomationUser(projectID uint, value string) error {
+	userID, err := strconv.ParseUint(value, 10, 32)
+	if err != nil || !s.IsProjectMember(uint(userID), projectID) {
+		return invalidInput("%q is not a member of the project", value)
+	}
+	return nil
+}
+
+func (s *Service) GetAutomationRules(projectID uint) ([]models.AutomationRule, error) {
+	rules, err := s.Repo.GetAutomationRules(projectID)
+	if err != nil {
+		logrus.WithFields(logrus.Fields{
+			"projectID": projectID,
+			"error":     err,
+		}).Error("Failed to retrieve automation rules")
+		return nil, err
+	}
+	return rules, nil
+}
+
+func (s *Service) GetAutomationRule(ruleID uint) (*models.AutomationRule, error) {
+	return s.Repo.GetAutomationRule(ruleID)
+}
+
+// CreateAutomationRule adds a rule to the project; it acts as actorID
+func (s *Service) CreateAutomationRule(actorID, projectID uint, input dto.AutomationRuleInput) (*models.AutomationRule, error) {
+	if err := s.validateAutomationRule(projectID, &input); err != nil {
+		return nil, err
+	}
+	rule := &models.AutomationRule{ProjectID: projectID, Enabled: true}
+	applyAutomationRuleInput(rule, actorID, input)
+	if err := s.Repo.CreateAutomationRule(rule); err != nil {
+		logrus.WithFields(logrus.Fields{
+			"projectID": projectID,
+			"error":     err,
+		}).Error("Failed to create automation rule")
+		return nil, err
+	}
+	logrus.WithFields(logrus.Fields{
+		"ruleID":    rule.ID,
+		"projectID": projectID,
+		"trigger":   rule.Trigger,
+	}).Info("Automation rule created successfully")
+	return rule, nil
+}
+
+// UpdateAutomationRule replaces a rule; from now on it acts as actorID
+func (s *Service) UpdateAutomationRule(actorID, ruleID uint, input dto.AutomationRuleInput) (*models.AutomationRule, error) {
+	rule, err := s.Repo.GetAutomationRule(ruleID)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.validateAutomationRule(rule.ProjectID, &input); err != nil {
+		return nil, err
+	}
+	applyAutomationRuleInput(rule, actorID, input)
+	if err := s.Repo.UpdateAutomationRule(rule); err != nil {
+		logrus.WithFields(logrus.Fields{
+			"ruleID": ruleID,
+			"error":  err,
+		}).Error("Failed to update automation rule")
+		return nil, err
+	}
+	logrus.WithFields(logrus.Fields{
+		"ruleID": ruleID,
+	}).Info("Automation rule updated successfully")
+	return rule, nil
+}
+
+func applyAutomationRuleInput(rule *models.AutomationRule, actorID uint, input dto.AutomationRuleInput) {
+	rule.Name = input.Name
+	rule.Trigger = input.Trigger
+	rule.Conditions = input.Conditions
+	if rule.Conditions == nil {
+		rule.Conditions = []models.AutomationCondition{}
+	}
+	rule.Actions = input.Actions
+	rule.ActorID = actorID
+	if input.Enabled != nil {
+		rule.Enabled = *input.Enabled
+	}
+}
+
+func (s *Service) DeleteAutomationRule(ruleID uint) error {
+	if err := s.Repo.DeleteAutomationRule(ruleID); err != nil {
+		logrus.WithFields(logrus.Fields{
+			"ruleID": ruleID,
+			"error":  err,
+		}).Error("Failed to delete automation rule")
+		return err
+	}
+	logrus.WithFields(logrus.Fields{
+		"ruleID": ruleID,
+	}).Info("Automation rule deleted successfully")
+	return nil
+}
+
+// GetAutomationRuns lists the latest runs of a rule, newest first
+func (s *Service) GetAutomationRuns(ruleID uint) ([]models.AutomationRun, error) {
+	return s.Repo.GetAutomationRuns(ruleID, maxAutomationRuns)
+}
+
+// CleanupAutomationRuns deletes run logs older than keepAutomationRuns
+func (s *Service) CleanupAutomationRuns(now time.Time) error {
+	count, err := s.Repo.DeleteAutomationRuns(now.Add(-keepAutomationRuns))
+	if err != nil {
+		logrus.WithFields(logrus.Fields{
+			"error": err,
+		}).Error("Failed to clean up automation runs")
+		return err
+	}
+	logrus.WithFields(logrus.Fields{
+		"count": count,
+	}).Info("Automation runs cleaned up")
+	return nil
+}
+
+// automationTriggers returns the triggers an event sets off
+func automationTriggers(event Event) []string {
+	switch event.Type {
+	case EventTaskCreated:
+		return []string{models.TriggerTaskCreated}
+	case EventTaskUpdated:
+		triggers := []string{models.TriggerTaskUpdated}
+		if status, ok := event.Data["status"].(string); ok && event.Data["previous_status"] != status {
+			triggers = append(triggers, models.TriggerStatusChanged)
+		}
+		return triggers
+	case EventTaskOverdue:
+		return []string{models.TriggerDueDatePassed}
+	case EventCommentCreated:
+		return []string{models.TriggerCommentAdded}
+	}
+	return nil
+}
+
+// eventNumber reads a number of the event data, which holds float64 once
+// the event went through the outbox
+func eventNumber(event Event, key string) int {
+	switch value := event.Data[key].(type) {
+	case float64:
+		return int(value)
+	case int:
+		return value
+	case uint:
+		return int(value)
+	}
+	return 0
+}
+
+func eventText(event Event, key string) string {
+	text, _ := event.Data[key].(string)
+	return text
+}
+
+// runAutomations runs the project's rules that the event sets off. Changes
+// made by a rule are recorded as events too, so rules can set each other
+// off, up to maxAutomationDepth in a row; a rule never reacts to its own
+// changes.
+func (s *Service) runAutomations(event Event) error {
+	triggers := automationTriggers(event)
+	if len(triggers) == 0 || event.TaskID == 0 {
+		return nil
+	}
+	rules, err := s.Repo.GetEnabledAutomationRules(event.ProjectID, triggers)
+	if err != nil {
+		logrus.WithFields(logrus.Fields{
+			"projectID": event.ProjectID,
+			"error":     err,
+		}).Error("Failed to retrieve automation rules")
+		return err
+	}
+	source := uint(eventNumber(event, automationRuleKey))
+	for i := range rules {
+		if rules[i].ID == source {
+			continue
+		}
+		if err := s.runAutomationRule(&rules[i], event); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// runAutomationRule runs a rule's actions on the event's task if its
+// conditions hold, all or nothing, and logs the outcome. The task is read
+// in the run's transaction, and the rule only acts while the user it acts as
+// is still an admin of the project. A failed run is logged rather than
+// retried, as its actions would most likely fail again.
+func (s *Service) runAutomationRule(rule *models.AutomationRule, event Event) error {
+	run := &models.AutomationRun{
+		RuleID:    rule.ID,
+		ProjectID: rule.ProjectID,
+		TaskID:    event.TaskID,
+		Event:     event.Type,
+		EventAt:   event.OccurredAt,
+		Depth:     eventNumber(event, automationDepthKey),
+	}
+	exec := &automationExec{s: s, rule: rule, event: event, depth: run.Depth}
+	err := s.transaction(func(repo *repository.Repository) error {
+		exec.repo = repo
+		task, err := repo.GetTaskByID(event.TaskID)
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return errNoAutomationMatch
+		}
+		if err != nil {
+			return err
+		}
+		if task.ProjectID != rule.ProjectID || !automationMatches(rule.Conditions, task, event) {
+			return errNoAutomationMatch
+		}
+		exec.task = task
+		if run.Depth >= maxAutomationDepth {
+			return errAutomationLoop
+		}
+		role, err := repo.GetUserRole(rule.ActorID, rule.ProjectID)
+		if errors.Is(err, gorm.ErrRecordNotFound) || (err == nil && role != "admin") {
+			return fmt.Errorf("the rule acts as user %d, who is no longer an admin of the project", rule.ActorID)
+		}
+		if err != nil {
+			return err
+		}
+		for i, action := range rule.Actions {
+			if err := exec.apply(action); err != nil {
+				return fmt.Errorf("action %d (%s): %w", i+1, action.Type, err)
+			}
+			run.Actions++
+		}
+		run.Status = models.RunSucceeded
+		claimed, err := repo.ClaimAutomationRun(run)
+		if err == nil && !claimed {
+			err = errAlreadyRan
+		}
+		return err
+	})
+	switch {
+	case errors.Is(err, errNoAutomationMatch), errors.Is(err, errAlreadyRan):
+		return nil
+	case errors.Is(err, errAutomationLoop):
+		run.Status = models.RunSkipped
+		run.Error = fmt.Sprintf("set off by a chain of %d automations", run.Depth)
+		logrus.WithFields(logrus.Fields{
+			"ruleID": rule.ID,
+			"taskID": event.TaskID,
+			"depth":  run.Depth,
+		}).Warn("Automation loop stopped")
+		_, err := s.Repo.ClaimAutomationRun(run)
+		return err
+	case err != nil:
+		logrus.WithFields(logrus.Fields{
+			"ruleID": rule.ID,
+			"taskID": event.TaskID,
+			"error":  err,
+		}).Warn("Automation rule failed")
+		run.Status, run.Error, run.Actions = models.RunFailed, err.Error(), 0
+		_, err = s.Repo.ClaimAutomationRun(run)
+		return err
+	}
+	for _, deliveryID := range exec.deliveries {
+		// Enqueue logs its own failures
+		if _, err := s.Jobs.Enqueue(JobDeliverWebhook, webhookJob{DeliveryID: deliveryID}); err != nil {
+			return err
+		}
+	}
+	logrus.WithFields(logrus.Fields{
+		"ruleID":  rule.ID,
+		"taskID":  event.TaskID,
+		"actions": run.Actions,
+	}).Info("Automation rule ran successfully")
+	return nil
+}
+
+// automationValues returns the values of a task field a condition tests,
+// the change described by the event included. Fields the event records are
+// taken from it, as the task may have changed again since.
+func automationValues(field string, task *models.Task, event Event) []string {
+	switch field {
+	case "title":
+		return []string{task.Title}
+	case "description":
+		return []string{task.Description}
+	case "status":
+		if status, ok := event.Data["status"].(string); ok {
+			return []string{status}
+		}
+		return []string{task.Status}
+	case "previous_status":
+		return []string{eventText(event, "previous_status")}
+	case "priority":
+		return []string{task.Priority}
+	case "label":
+		names := make([]string, 0, len(task.Labels))
+		for _, label := range task.Labels {
+			names = append(names, label.Name)
+		}
+		return names
+	case "added_label":
+		return []string{eventText(event, "label_added")}
+	case "assignee":
+		ids := make([]string, 0, len(task.Assignees))
+		for _, assignee := range task.Assignees {
+			ids = append(ids, strconv.FormatUint(uint64(assignee.ID), 10))
+		}
+		return ids
+	case "reporter":
+		if task.ReporterID == 0 {
+			return nil
+		}
+		return []string{strconv.FormatUint(uint64(task.ReporterID), 10)}
+	case "story_points":
+		return []string{strconv.Itoa(task.StoryPoints)}
+	case "due_date":
+		if task.DueDate.IsZero() {
+			return nil
+		}
+		return []string{task.DueDate.Format("2006-01-02")}
+	case "comment":
+		return []string{eventText(event, "body")}
+	}
+	return nil
+}
+
+// automationMatches reports whether every condition holds. Fields with
+// several values (labels, assignees) match eq and contains when one of them
+// does, and ne when none equals the value. Text is compared ignoring case.
+func automationMatches(conditions []models.AutomationCondition, task *models.Task, event Event) bool {
+	for _, condition := range conditions {
+		var values []string
+		for _, value := range automationValues(condition.Field, task, event) {
+			if value != "" {
+				values = append(values, value)
+			}
+		}
+		if !conditionHolds(condition, values) {
+			return false
+		}
+	}
+	return true
+}
+
+func conditionHolds(condition models.AutomationCondition, values []string) bool {
+	switch condition.Op {
+	case models.OpEmpty:
+		return len(values) == 0
+	case models.OpNotEmpty:
+		return len(values) > 0
+	case models.OpNotEquals:
+		for _, value := range values {
+			if strings.EqualFold(value, condition.Value) {
+				return false
+			}
+		}
+		return true
+	}
+	for _, value := range values {
+		switch condition.Op {
+		case models.OpEquals:
+			if strings.EqualFold(value, condition.Value) {
+				return true
+			}
+		case models.OpContains:
+			if strings.Contains(strings.ToLower(value), strings.ToLower(condition.Value)) {
+				return true
+			}
+		case models.OpGreater, models.OpLess:
+			order := compareAutomationValues(value, condition.Value)
+			if (condition.Op == models.OpGreater && order > 0) || (condition.Op == models.OpLess && order < 0) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// compareAutomationValues compares numbers by value and anything else, such
+// as YYYY-MM-DD dates, as text
+func compareAutomationValues(a, b string) int {
+	x, errA := strconv.ParseFloat(a, 64)
+	y, errB := strconv.ParseFloat(b, 64)
+	if errA != nil || errB != nil {
+		return strings.Compare(a, b)
+	}
+	switch {
+	case x < y:
+		return -1
+	case x > y:
+		return 1
+	}
+	return 0
+}
+
+// automationExec carries out the actions of one run of a rule, in the
+// run's transaction
+type automationExec struct {
+	s     *Service
+	repo  *repository.Repository
+	rule  *models.AutomationRule
+	task  *models.Task
+	event Event
+	depth int
+	// deliveries are the webhook deliveries to enqueue once the run commits
+	deliveries []uint
+}
+
+// record records an event for a change made by the rule, marked so that
+// the rule is not set off by it and chains of rules stay bounded
+func (x *automationExec) record(event Event) error {
+	if event.Data == nil {
+		event.Data = map[string]interface{}{}
+	}
+	event.Data[automationRuleKey] = x.rule.ID
+	event.Data[automationDepthKey] = x.depth + 1
+	event.ProjectID = x.task.ProjectID
+	if event.TaskID == 0 {
+		event.TaskID = x.task.ID
+	}
+	if event.ActorID == 0 {
+		event.ActorID = x.rule.ActorID
+	}
+	return x.s.recordEvent(x.repo, event)
+}
+
+// message describes a change for the activity feed, e.g.
+// `automation "Triage bugs" set priority of task "Fix login" to high`
+func (x *automationExec) message(format string, args ...interface{}) string {
+	return fmt.Sprintf("automation %q ", x.rule.Name) + fmt.Sprintf(format, args...)
+}
+
+// users resolves who an action refers to: a user ID, the task's reporter,
+// assignees or watchers, or the user whose change set the rule off
+func (x *automationExec) users(ref string) ([]uint, error) {
+	switch ref {
+	case "reporter":
+		if x.task.ReporterID == 0 {
+			return nil, nil
+		}
+		return []uint{x.task.ReporterID}, nil
+	case "actor":
+		if x.event.ActorID == 0 {
+			return nil, nil
+		}
+		return []uint{x.event.ActorID}, nil
+	case "assignees", "all":
+		return userIDs(x.task.Assignees), nil
+	case "watchers":
+		return userIDs(x.task.Watchers), nil
+	}
+	userID, err := strconv.ParseUint(ref, 10, 32)
+	if err != nil {
+		return nil, invalidInput("unknown user %q", ref)
+	}
+	return []uint{uint(userID)}, nil
+}
+
+func (x *automationExec) apply(action models.AutomationAction) error {
+	task := x.task
+	switch action.Type {
+	case models.ActionSetField:
+		return x.setField(action.Field, action.Value)
+	case models.ActionAssign:
+		users, err := x.users(action.Value)
+		if err != nil {
+			return err
+		}
+		for _, userID := range users {
+			if !x.s.IsProjectMember(userID, task.ProjectID) {
+				return invalidInput("user %d is not a member of the project", userID)
+			}
+			if err := x.repo.AddTaskAssignee(task.ID, userID); err != nil {
+				return err
+			}
+			if err := x.record(Event{
+				Type:       EventTaskAssigned,
+				Message:    x.message("assigned %s to task %q", x.s.userName(userID), task.Title),
+				Recipients: []uint{userID},
+				Notice:     fmt.Sprintf("assigned you to %q", task.Title),
+			}); err != nil {
+				return err
+			}
+		}
+	case models.ActionUnassign:
+		users, err := x.users(action.Value)
+		if err != nil {
+			return err
+		}
+		for _, userID := range users {
+			removed, err := x.repo.RemoveTaskAssignee(task.ID, userID)
+			if err != nil {
+				return err
+			}
+			if !removed {
+				continue
+			}
+			if err := x.record(Event{
+				Type:    EventTaskUnassigned,
+				Message: x.message("unassigned %s from task %q", x.s.userName(userID), task.Title),
+				Data:    map[string]interface{}{"user_id": userID},
+			}); err != nil {
+				return err
+			}
+		}
+	case models.ActionAddLabel:
+		label, err := x.repo.GetLabelByName(task.ProjectID, action.Value)
+		if err != nil {
+			return invalidInput("label %q does not exist in the project", action.Value)
+		}
+		if err := x.repo.AddLabelToTask(task.ID, label.ID); err != nil {
+			return err
+		}
+		return x.record(Event{
+			Type:    EventTaskUpdated,
+			Message: x.message("added label %q to task %q", label.Name, task.Title),
+			Data:    map[string]interface{}{"label_added": label.Name, "label_id": label.ID},
+		})
+	case models.ActionComment:
+		comment := &models.Comment{TaskID: task.ID, UserID: x.rule.ActorID, Body: action.Value}
+		if err := x.repo.CreateComment(comment); err != nil {
+			return err
+		}
+		return x.record(Event{
+			Type:       EventCommentCreated,
+			Message:    x.message("commented on task %q", task.Title),
+			Recipients: userIDs(task.Watchers),
+			Notice:     fmt.Sprintf("commented on %q", task.Title),
+			Data:       map[string]interface{}{"comment_id": comment.ID, "body": comment.Body},
+		})
+	case models.ActionMove:
+		return x.move(action.Value)
+	case models.ActionCreateSubtask:
+		return x.createSubtask(action.Value)
+	case models.ActionNotify:
+		recipients, err := x.users(action.To)
+		if err != nil {
+			return err
+		}
+		if len(recipients) == 0 {
+			return nil
+		}
+		// Sent by the system, so that the rule's own user is told too
+		return x.s.recordEvent(x.repo, Event{
+			Type:       EventAutomationNotice,
+			ProjectID:  task.ProjectID,
+			TaskID:     task.ID,
+			Recipients: recipients,
+			Notice:     fmt.Sprintf("%s (task %q)", action.Value, task.Title),
+			Data: map[string]interface{}{
+				automationRuleKey:  x.rule.ID,
+				automationDepthKey: x.depth + 1,
+				"rule":             x.rule.Name,
+			},
+		})
+	case models.ActionWebhook:
+		return x.queueWebhook(action.Value)
+	default:
+		return invalidInput("unknown action %q", action.Type)
+	}
+	return nil
+}
+
+// setField changes one of the task's own fields; values were validated
+// with the rule
+func (x *automationExec) setField(field, value string) error {
+	task := x.task
+	switch field {
+	case "title":
+		task.Title = value
+	case "description":
+		task.Description = value
+	case "priority":
+		task.Priority = value
+	case "story_points":
+		task.StoryPoints, _ = strconv.Atoi(value)
+	case "due_date":
+		if err := x.setDueDate(value); err != nil {
+			return err
+		}
+	}
+	if err := x.repo.UpdateTask(task); err != nil {
+		return err
+	}
+	return x.record(Event{
+		Type:    EventTaskUpdated,
+		Message: x.message("set %s of task %q", strings.ReplaceAll(field, "_", " "), task.Title),
+		Data:    map[string]interface{}{"field": field, "value": value},
+	})
+}
+
+// setDueDate sets a date-only due date, given as YYYY-MM-DD or as working
+// days from today on the project's calendar, or clears it
+func (x *automationExec) setDueDate(value string) error {
+	task := x.task
+	task.OverdueAt, task.EscalatedAt = nil, nil
+	switch {
+	case value == "":
+		task.DueDate, task.DueDateOnly = time.Time{}, false
+		return nil
+	case strings.HasPrefix(value, "+"):
+		days, _ := strconv.Atoi(value[1:])
+		cal, err := x.s.projectCalendar(x.repo, task.ProjectID)
+		if err != nil {
+			return err
+		}
+		task.DueDateOnly = true
+		setDueDay(task, cal.AddWorkingDays(time.Now(), days), cal)
+	default:
+		day, err := time.Parse("2006-01-02", value)
+		if err != nil {
+			return invalidInput("invalid due date %q", value)
+		}
+		task.DueDate, task.DueDateOnly = calendar.Date(day), true
+	}
+	return nil
+}
+
+// move sets the task's status, as a board move to the end of the column
+// would, and continues its recurrence if that completes it
+func (x *automationExec) move(status string) error {
+	task := x.task
+	if task.Status == status {
+		return nil
+	}
+	completed, err := x.s.isCompletedStatus(x.repo, task.ProjectID, status)
+	if err != nil {
+		return err
+	}
+	if _, err := x.s.checkWIPLimit(x.repo, task.ProjectID, status, task.ID); err != nil {
+		return err
+	}
+	previousStatus, wasCompleted := task.Status, task.CompletedAt != nil
+	setTaskStatus(task, status, completed)
+	if err := x.repo.MoveTask(task); err != nil {
+		return err
+	}
+	if err := x.record(Event{
+		Type:    EventTaskUpdated,
+		Message: x.message("moved task %q to %s", task.Title, task.Status),
+		Data: map[string]interface{}{
+			"status":          task.Status,
+			"previous_status": previousStatus,
+		},
+	}); err != nil {
+		return err
+	}
+	if task.RecurrenceID != nil && task.CompletedAt != nil && !wasCompleted {
+		return x.s.continueRecurrence(x.repo, x.rule.ActorID, task)
+	}
+	return nil
+}
+
+func (x *automationExec) createSubtask(title string) error {
+	status, err := x.s.initialStatus(x.repo, x.task.ProjectID)
+	if err != nil {
+		return err
+	}
+	parentID := x.task.ID
+	subtask := &models.Task{
+		Title:      title,
+		ProjectID:  x.task.ProjectID,
+		Priority:   x.task.Priority,
+		ReporterID: x.rule.ActorID,
+		ParentID:   &parentID,
+	}
+	setTaskStatus(subtask, status, false)
+	if err := x.repo.CreateTask(subtask); err != nil {
+		return err
+	}
+	return x.record(Event{
+		Type:    EventTaskCreated,
+		TaskID:  subtask.ID,
+		Message: x.message("created task %q under %q", subtask.Title, x.task.Title),
+	})
+}
+
+// queueWebhook records a delivery of the run to one of the project's
+// webhooks, whatever events it subscribes to
+func (x *automationExec) queueWebhook(value string) error {
+	webhookID, err := strconv.ParseUint(value, 10, 32)
+	if err != nil {
+		return invalidInput("invalid webhook ID %q", value)
+	}
+	webhook, err := x.repo.GetWebhookByID(uint(webhookID))
+	if err != nil || webhook.ProjectID != x.task.ProjectID {
+		return invalidInput("webhook %d does not exist in the project", webhookID)
+	}
+	if !webhook.Active {
+		return nil
+	}
+	payload, err := json.Marshal(newEventPayload(Event{
+		Type:      EventAutomationTriggered,
+		ProjectID: x.task.ProjectID,
+		TaskID:    x.task.ID,
+		ActorID:   x.event.ActorID,
+		Message:   fmt.Sprintf("automation %q ran on task %q", x.rule.Name, x.task.Title),
+		Data: map[string]interface{}{
+			"rule_id": x.rule.ID,
+			"rule":    x.rule.Name,
+			"trigger": x.event.Type,
+			"task":    x.task,
+		},
+		OccurredAt: time.Now(),
+	}))
+	if err != nil {
+		return err
+	}
+	now := time.Now()
+	delivery := &models.WebhookDelivery{
+		WebhookID:     webhook.ID,
+		EventType:     EventAutomationTriggered,
+		Payload:       string(payload),
+		Status:        models.DeliveryPending,
+		NextAttemptAt: &now,
+	}
+	if err := x.repo.CreateWebhookDelivery(delivery); err != nil {
+		return err
+	}
+	x.deliveries = append(x.deliveries, delivery.ID)
+	return nil
+}
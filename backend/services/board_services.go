@@ -0,0 +1,207 @@
+// Board-related services (task positions, moves and WIP limits)
+package services
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"work-management/dto"
+	"work-management/models"
+	"work-management/repository"
+
+	"github.com/sirupsen/logrus"
+	"gorm.io/gorm"
+)
+
+// checkWIPLimit checks that a task entering a status keeps it within its WIP
+// limit. Over a reject limit it fails with ErrConflict; over a warn limit it
+// returns the warning. It locks the project so that concurrent moves are
+// counted one after the other.
+func (s *Service) checkWIPLimit(repo *repository.Repository, projectID uint, status string, taskID uint) (string, error) {
+	statuses, err := repo.GetWorkflow(projectID)
+	if err != nil {
+		return "", err
+	}
+	var limit *models.WorkflowStatus
+	for i := range statuses {
+		if statuses[i].Name == status && statuses[i].WIPLimit > 0 {
+			limit = &statuses[i]
+		}
+	}
+	if limit == nil {
+		return "", nil
+	}
+	if err := repo.LockProject(projectID); err != nil {
+		return "", err
+	}
+	count, err := repo.CountTasksInStatus(projectID, status, taskID)
+	if err != nil {
+		return "", err
+	}
+	if int(count) < limit.WIPLimit {
+		return "", nil
+	}
+	message := fmt.Sprintf("status %q is limited to %d tasks and already holds %d", status, limit.WIPLimit, count)
+	if limit.WIPPolicy == models.WIPReject {
+		return "", fmt.Errorf("%w: %s", ErrConflict, message)
+	}
+	logrus.WithFields(logrus.Fields{
+		"projectID": projectID,
+		"taskID":    taskID,
+		"status":    status,
+	}).Warn("WIP limit exceeded")
+	return message, nil
+}
+
+// boardAnchor loads the task a moved task is placed next to, which must be
+// in the column it is moved to
+func boardAnchor(repo *repository.Repository, task *models.Task, status string, anchorID uint) (*models.Task, error) {
+	if anchorID == task.ID {
+		return nil, invalidInput("a task cannot be placed next to itself")
+	}
+	anchor, err := repo.GetTaskByID(anchorID)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, fmt.Errorf("%w: task %d no longer exists", ErrConflict, anchorID)
+		}
+		return nil, err
+	}
+	if anchor.ProjectID != task.ProjectID || anchor.Status != status {
+		return nil, fmt.Errorf("%w: task %d is no longer in %q", ErrConflict, anchorID, status)
+	}
+	return anchor, nil
+}
+
+// boardNeighbor returns the task loaded by find, or nil when there is none
+func boardNeighbor(task *models.Task, err error) (*models.Task, error) {
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil
+	}
+	return task, err
+}
+
+// boardRank works out the rank placing a task where the move asks for. The
+// neighbors are read under the project lock, so two tasks dropped in the
+// same spot at once end up one after the other.
+func boardRank(repo *repository.Repository, task *models.Task, input dto.MoveTaskInput) (string, error) {
+	for respaced := false; ; respaced = true {
+		var prev, next *models.Task
+		var err error
+		switch {
+		case input.AfterID != nil:
+			if prev, err = boardAnchor(repo, task, input.Status, *input.AfterID); err == nil {
+				next, err = boardNeighbor(repo.GetTaskAfter(task.ProjectID, input.Status, task.ID, prev))
+			}
+		case input.BeforeID != nil:
+			if next, err = boardAnchor(repo, task, input.Status, *input.BeforeID); err == nil {
+				prev, err = boardNeighbor(repo.GetTaskBefore(task.ProjectID, input.Status, task.ID, next))
+			}
+		default:
+			prev, err = boardNeighbor(repo.GetTaskBefore(task.ProjectID, input.Status, task.ID, nil))
+		}
+		if err != nil {
+			return "", err
+		}
+		var low, high string
+		if prev != nil {
+			low = prev.Rank
+		}
+		if next != nil {
+			high = next.Rank
+		}
+		rank, ok := models.RankBetween(low, high)
+		if ok && (len(rank) <= models.MaxRankLength || respaced) {
+			return rank, nil
+		}
+		if respaced {
+			return "", fmt.Errorf("no rank between %q and %q", low, high)
+		}
+		// Ranks that are equal or too long make room by respacing them all
+		if err := repo.RespaceRanks(task.ProjectID); err != nil {
+			return "", err
+		}
+	}
+}
+
+// MoveTask moves a task on the board to a status and a position in it, in
+// one change. Moves within a project are serialized so that concurrent drags
+// neither lose a position nor slip past a WIP limit.
+func (s *Service) MoveTask(actorID, taskID uint, input dto.MoveTaskInput) (*dto.MoveTaskResult, error) {
+	input.Status = strings.TrimSpace(input.Status)
+	if input.Status == "" {
+		return nil, invalidInput("status is required")
+	}
+	task, err := s.Repo.GetTaskByID(taskID)
+	if err != nil {
+		return nil, err
+	}
+	result := &dto.MoveTaskResult{}
+	err = s.transaction(func(repo *repository.Repository) error {
+		if err := repo.LockProject(task.ProjectID); err != nil {
+			return err
+		}
+		// Reload under the lock, another move may have gone first
+		current, err := repo.GetTaskByID(taskID)
+		if err != nil {
+			return err
+		}
+		if current.ProjectID != task.ProjectID {
+			return fmt.Errorf("%w: the task was moved to another project", ErrConflict)
+		}
+		task = current
+		completed, err := s.isCompletedStatus(repo, task.ProjectID, input.Status)
+		if err != nil {
+			return err
+		}
+		previousStatus := task.Status
+		if input.Status != previousStatus {
+			if result.WIPWarning, err = s.checkWIPLimit(repo, task.ProjectID, input.Status, task.ID); err != nil {
+				return err
+			}
+		}
+		if task.Rank, err = boardRank(repo, task, input); err != nil {
+			return err
+		}
+		wasCompleted := task.CompletedAt != nil
+		setTaskStatus(task, input.Status, completed)
+		if err := repo.MoveTask(task); err != nil {
+			return err
+		}
+		if input.Status == previousStatus {
+			return nil
+		}
+		if err := s.recordEvent(repo, Event{
+			Type:      EventTaskUpdated,
+			ProjectID: task.ProjectID,
+			TaskID:    task.ID,
+			ActorID:   actorID,
+			Message:   fmt.Sprintf("moved task %q to %s", task.Title, task.Status),
+			Data: map[string]interface{}{
+				"status":          task.Status,
+				"previous_status": previousStatus,
+			},
+		}); err != nil {
+			return err
+		}
+		if task.RecurrenceID != nil && task.CompletedAt != nil && !wasCompleted {
+			return s.continueRecurrence(repo, actorID, task)
+		}
+		return nil
+	})
+	if err != nil {
+		logrus.WithFields(logrus.Fields{
+			"taskID": taskID,
+			"error":  err,
+		}).Error("Failed to move task")
+		return nil, err
+	}
+	if result.Task, err = s.Repo.GetTaskByID(taskID); err != nil {
+		return nil, err
+	}
+	logrus.WithFields(logrus.Fields{
+		"taskID": taskID,
+		"status": input.Status,
+	}).Info("Task moved successfully")
+	return result, nil
+}
@@ -0,0 +1,299 @@
+// Calendar-related services (working calendars, holidays, timezones and
+// deadlines)
+package services
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"time"
+
+	"work-management/calendar"
+	"work-management/dto"
+	"work-management/models"
+	"work-management/repository"
+
+	"github.com/sirupsen/logrus"
+	"gorm.io/gorm"
+)
+
+// maxHolidayImport bounds the holidays read from one iCalendar file
+const maxHolidayImport = 1000
+
+// loadLocation looks up an IANA timezone name such as "Europe/Paris"
+func loadLocation(name string) (*time.Location, error) {
+	if name == "" || name == "Local" {
+		return nil, invalidInput("unknown timezone %q", name)
+	}
+	location, err := time.LoadLocation(name)
+	if err != nil {
+		return nil, invalidInput("unknown timezone %q", name)
+	}
+	return location, nil
+}
+
+// GetWorkCalendar returns the project's working calendar, or the default one
+// if it has none
+func (s *Service) GetWorkCalendar(projectID uint) (*models.WorkCalendar, error) {
+	workCalendar, err := s.Repo.GetWorkCalendar(projectID)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		defaultCalendar := models.DefaultWorkCalendar(projectID)
+		return &defaultCalendar, nil
+	}
+	return workCalendar, err
+}
+
+func (s *Service) UpdateWorkCalendar(projectID uint, input dto.WorkCalendarInput) (*models.WorkCalendar, error) {
+	if _, err := loadLocation(input.Timezone); err != nil {
+		return nil, err
+	}
+	dayStart, err := calendar.ParseClock(input.DayStart)
+	if err != nil {
+		return nil, invalidInput("day_start: %v", err)
+	}
+	dayEnd, err := calendar.ParseClock(input.DayEnd)
+	if err != nil {
+		return nil, invalidInput("day_end: %v", err)
+	}
+	if dayEnd <= dayStart {
+		return nil, invalidInput("day_end must be after day_start")
+	}
+	seen := map[int]bool{}
+	days := []int{}
+	for _, day := range input.WorkingDays {
+		if day < 0 || day > 6 {
+			return nil, invalidInput("working days are weekdays from 0 (Sunday) to 6 (Saturday)")
+		}
+		if !seen[day] {
+			seen[day] = true
+			days = append(days, day)
+		}
+	}
+	if len(days) == 0 {
+		return nil, invalidInput("a calendar needs at least one working day")
+	}
+	sort.Ints(days)
+	workCalendar := &models.WorkCalendar{
+		ProjectID:   projectID,
+		Timezone:    input.Timezone,
+		WorkingDays: days,
+		DayStart:    input.DayStart,
+		DayEnd:      input.DayEnd,
+	}
+	if err := s.Repo.SaveWorkCalendar(workCalendar); err != nil {
+		logrus.WithFields(logrus.Fields{
+			"projectID": projectID,
+			"error":     err,
+		}).Error("Failed to save working calendar")
+		return nil, err
+	}
+	logrus.WithFields(logrus.Fields{
+		"projectID": projectID,
+		"timezone":  workCalendar.Timezone,
+	}).Info("Working calendar updated successfully")
+	return s.GetWorkCalendar(projectID)
+}
+
+// projectCalendar builds the calendar a project's dates are worked out on,
+// holidays included
+func (s *Service) projectCalendar(repo *repository.Repository, projectID uint) (*calendar.Calendar, error) {
+	workCalendar, err := repo.GetWorkCalendar(projectID)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		defaultCalendar := models.DefaultWorkCalendar(projectID)
+		workCalendar, err = &defaultCalendar, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	holidays, err := repo.GetHolidays(projectID, time.Time{}, time.Time{})
+	if err != nil {
+		return nil, err
+	}
+	days := make([]time.Time, len(holidays))
+	for i, holiday := range holidays {
+		days[i] = holiday.Date
+	}
+	// Saved calendars were validated, so these only fail on a broken
+	// timezone database, where UTC is the best guess
+	location, err := loadLocation(workCalendar.Timezone)
+	if err != nil {
+		location = time.UTC
+	}
+	dayStart, _ := calendar.ParseClock(workCalendar.DayStart)
+	dayEnd, _ := calendar.ParseClock(workCalendar.DayEnd)
+	return calendar.New(location, workCalendar.WorkingDays, dayStart, dayEnd, days), nil
+}
+
+// calendarLookup returns the calendars of projects, loading each once. A
+// calendar that fails to load is replaced by the default one, so that
+// scheduled jobs go on for the other projects.
+func (s *Service) calendarLookup() func(projectID uint) *calendar.Calendar {
+	calendars := map[uint]*calendar.Calendar{}
+	return func(projectID uint) *calendar.Calendar {
+		if cal, ok := calendars[projectID]; ok {
+			return cal
+		}
+		cal, err := s.projectCalendar(s.Repo, projectID)
+		if err != nil {
+			logrus.WithFields(logrus.Fields{
+				"projectID": projectID,
+				"error":     err,
+			}).Error("Failed to load working calendar")
+			cal = calendar.Default()
+		}
+		calendars[projectID] = cal
+		return cal
+	}
+}
+
+// normalizeDueDate keeps only the day of date-only due dates, as written
+func normalizeDueDate(task *models.Task) {
+	if task.DueDateOnly && !task.DueDate.IsZero() {
+		task.DueDate = calendar.Date(task.DueDate)
+	}
+}
+
+// taskDeadline is the moment a task falls due on its project's calendar
+func taskDeadline(task *models.Task, cal *calendar.Calendar) time.Time {
+	if task.DueDateOnly {
+		return cal.EndOfDate(task.DueDate)
+	}
+	return task.DueDate
+}
+
+// dueText describes when a task is due, in its project's timezone
+func dueText(task *models.Task, cal *calendar.Calendar) string {
+	if task.DueDateOnly {
+		return task.DueDate.UTC().Format("Mon Jan 2")
+	}
+	return task.DueDate.In(cal.Location).Format("Jan 2 15:04 MST")
+}
+
+// GetHolidays lists a project's holidays, only those of one year when year
+// is not 0
+func (s *Service) GetHolidays(projectID uint, year int) ([]models.Holiday, error) {
+	var from, to time.Time
+	if year != 0 {
+		from = time.Date(year, time.January, 1, 0, 0, 0, 0, time.UTC)
+		to = time.Date(year, time.December, 31, 0, 0, 0, 0, time.UTC)
+	}
+	holidays, err := s.Repo.GetHolidays(projectID, from, to)
+	if err != nil {
+		logrus.WithFields(logrus.Fields{
+			"projectID": projectID,
+			"error":     err,
+		}).Error("Failed to retrieve holidays")
+		return nil, err
+	}
+	return holidays, nil
+}
+
+// AddHoliday takes a day off in a project, renaming the holiday it already
+// has on that day
+func (s *Service) AddHoliday(projectID uint, input dto.HolidayInput) (*models.Holiday, error) {
+	name := strings.TrimSpace(input.Name)
+	if name == "" {
+		return nil, invalidInput("a holiday needs a name")
+	}
+	holidays := []models.Holiday{{ProjectID: projectID, Date: calendar.Date(input.Date), Name: name}}
+	if err := s.Repo.SaveHolidays(holidays); err != nil {
+		logrus.WithFields(logrus.Fields{
+			"projectID": projectID,
+			"error":     err,
+		}).Error("Failed to add holiday")
+		return nil, err
+	}
+	return &holidays[0], nil
+}
+
+// ImportHolidays adds the events of an iCalendar file to a project's
+// holidays, renaming those it already has on the same days
+func (s *Service) ImportHolidays(projectID uint, file io.Reader) (*dto.HolidayImport, error) {
+	parsed, err := calendar.ParseHolidays(file)
+	if err != nil {
+		return nil, invalidInput("%v", err)
+	}
+	if len(parsed) > maxHolidayImport {
+		return nil, invalidInput("at most %d holidays can be imported at once", maxHolidayImport)
+	}
+	holidays := make([]models.Holiday, 0, len(parsed))
+	for _, holiday := range parsed {
+		name := holiday.Name
+		if name == "" {
+			name = "Holiday"
+		}
+		holidays = append(holidays, models.Holiday{ProjectID: projectID, Date: holiday.Date, Name: name})
+	}
+	if err := s.Repo.SaveHolidays(holidays); err != nil {
+		logrus.WithFields(logrus.Fields{
+			"projectID": projectID,
+			"error":     err,
+		}).Error("Failed to import holidays")
+		return nil, err
+	}
+	logrus.WithFields(logrus.Fields{
+		"projectID": projectID,
+		"imported":  len(holidays),
+	}).Info("Holidays imported successfully")
+	return &dto.HolidayImport{Imported: len(holidays), Holidays: holidays}, nil
+}
+
+func (s *Service) DeleteHoliday(projectID, holidayID uint) error {
+	deleted, err := s.Repo.DeleteHoliday(projectID, holidayID)
+	if err != nil {
+		return err
+	}
+	if !deleted {
+		return fmt.Errorf("holiday %d: %w", holidayID, gorm.ErrRecordNotFound)
+	}
+	return nil
+}
+
+// userLocation returns the timezone a user chose, UTC by default
+func (s *Service) userLocation(userID uint) *time.Location {
+	user, err := s.Repo.GetUserByID(userID)
+	if err != nil || user.Timezone == "" {
+		return time.UTC
+	}
+	location, err := loadLocation(user.Timezone)
+	if err != nil {
+		return time.UTC
+	}
+	return location
+}
+
+func (s *Service) GetPreferences(userID uint) (*dto.UserPreferences, error) {
+	user, err := s.Repo.GetUserByID(userID)
+	if err != nil {
+		return nil, err
+	}
+	preferences := &dto.UserPreferences{Timezone: user.Timezone}
+	if preferences.Timezone == "" {
+		preferences.Timezone = "UTC"
+	}
+	return preferences, nil
+}
+
+// UpdatePreferences saves a user's preferences; only the user and system
+// administrators may change them
+func (s *Service) UpdatePreferences(actorID, userID uint, input dto.UserPreferences) (*dto.UserPreferences, error) {
+	if actorID != userID && !s.IsSystemAdmin(actorID) {
+		return nil, fmt.Errorf("%w: only the user can change their preferences", ErrForbidden)
+	}
+	if _, err := loadLocation(input.Timezone); err != nil {
+		return nil, err
+	}
+	if _, err := s.Repo.GetUserByID(userID); err != nil {
+		return nil, err
+	}
+	if err := s.Repo.SetUserTimezone(userID, input.Timezone); err != nil {
+		logrus.WithFields(logrus.Fields{
+			"userID": userID,
+			"error":  err,
+		}).Error("Failed to save preferences")
+		return nil, err
+	}
+	return s.GetPreferences(userID)
+}
@@ -0,0 +1,312 @@
+// Capacity-related services (weekly capacity, time off and workload)
+package services
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+	"time"
+
+	"work-management/dto"
+	"work-management/models"
+
+	"github.com/sirupsen/logrus"
+	"gorm.io/gorm"
+)
+
+// maxWorkloadRange bounds the days a workload covers
+const maxWorkloadRange = 92
+
+// dateOf returns the day of t, as midnight UTC
+func dateOf(t time.Time) time.Time {
+	return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC)
+}
+
+// daysBetween counts the calendar days from the day of from to the day of to
+func daysBetween(from, to time.Time) int {
+	from = time.Date(from.Year(), from.Month(), from.Day(), 0, 0, 0, 0, time.UTC)
+	to = time.Date(to.Year(), to.Month(), to.Day(), 0, 0, 0, 0, time.UTC)
+	return int(to.Sub(from).Hours() / 24)
+}
+
+// roundAmount rounds a workload amount to hundredths
+func roundAmount(amount float64) float64 {
+	return math.Round(amount*100) / 100
+}
+
+// checkOwnCapacity lets users manage their own capacity and time off, and
+// system administrators anyone's
+func (s *Service) checkOwnCapacity(actorID, userID uint) error {
+	if actorID != userID && !s.IsSystemAdmin(actorID) {
+		return fmt.Errorf("%w: only the user can change their capacity and time off", ErrForbidden)
+	}
+	return nil
+}
+
+// CanSeeWorkload reports whether a user may see another's capacity and
+// workload: their own, someone sharing a project with them, or anyone for
+// system administrators
+func (s *Service) CanSeeWorkload(actorID, userID uint) bool {
+	if actorID == userID || s.IsSystemAdmin(actorID) {
+		return true
+	}
+	projectIDs, err := s.Repo.GetProjectIDsForUser(userID)
+	if err != nil {
+		return false
+	}
+	for _, projectID := range projectIDs {
+		if s.IsProjectMember(actorID, projectID) {
+			return true
+		}
+	}
+	return false
+}
+
+// GetCapacity returns a user's capacity, or the default one if they have
+// not set it
+func (s *Service) GetCapacity(userID uint) (*models.UserCapacity, error) {
+	capacity, err := s.Repo.GetUserCapacity(userID)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		defaultCapacity := models.DefaultCapacity(userID)
+		return &defaultCapacity, nil
+	}
+	return capacity, err
+}
+
+func (s *Service) SetCapacity(actorID, userID uint, input dto.CapacityInput) (*models.UserCapacity, error) {
+	if err := s.checkOwnCapacity(actorID, userID); err != nil {
+		return nil, err
+	}
+	if input.Unit != models.CapacityHours && input.Unit != models.CapacityPoints {
+		return nil, invalidInput("unit must be one of hours, points")
+	}
+	if input.Weekly < 0 {
+		return nil, invalidInput("weekly capacity cannot be negative")
+	}
+	seen := map[int]bool{}
+	days := []int{}
+	for _, day := range input.WorkingDays {
+		if day < 0 || day > 6 {
+			return nil, invalidInput("working days are weekdays from 0 (Sunday) to 6 (Saturday)")
+		}
+		if !seen[day] {
+			seen[day] = true
+			days = append(days, day)
+		}
+	}
+	sort.Ints(days)
+	capacity := &models.UserCapacity{UserID: userID, Unit: input.Unit, Weekly: input.Weekly, WorkingDays: days}
+	if err := s.Repo.SaveUserCapacity(capacity); err != nil {
+		logrus.WithFields(logrus.Fields{
+			"userID": userID,
+			"error":  err,
+		}).Error("Failed to save capacity")
+		return nil, err
+	}
+	return s.GetCapacity(userID)
+}
+
+// GetTimeOff lists a user's time off that has not ended yet
+func (s *Service) GetTimeOff(userID uint) ([]models.TimeOff, error) {
+	return s.Repo.GetTimeOff(userID, dateOf(time.Now()), time.Time{})
+}
+
+func (s *Service) AddTimeOff(actorID, userID uint, input dto.TimeOffInput) (*models.TimeOff, error) {
+	if err := s.checkOwnCapacity(actorID, userID); err != nil {
+		return nil, err
+	}
+	timeOff := &models.TimeOff{
+		UserID:    userID,
+		StartDate: dateOf(input.StartDate),
+		EndDate:   dateOf(input.EndDate),
+		Reason:    strings.TrimSpace(input.Reason),
+	}
+	if timeOff.EndDate.Before(timeOff.StartDate) {
+		return nil, invalidInput("end_date is before start_date")
+	}
+	if err := s.Repo.CreateTimeOff(timeOff); err != nil {
+		logrus.WithFields(logrus.Fields{
+			"userID": userID,
+			"error":  err,
+		}).Error("Failed to add time off")
+		return nil, err
+	}
+	return timeOff, nil
+}
+
+func (s *Service) DeleteTimeOff(actorID, timeOffID uint) error {
+	timeOff, err := s.Repo.GetTimeOffByID(timeOffID)
+	if err != nil {
+		return err
+	}
+	if err := s.checkOwnCapacity(actorID, timeOff.UserID); err != nil {
+		return err
+	}
+	return s.Repo.DeleteTimeOff(timeOffID)
+}
+
+// distributeWorkload spreads the work of a user's open tasks evenly over the
+// days each task can still be worked on: working days that are not time off,
+// from today or the task's start date up to its due date. Work that is late
+// lands on today, and work with no such day on its due date.
+func distributeWorkload(userID uint, capacity models.UserCapacity, timeOff []models.TimeOff, tasks []models.Task, from, to, today time.Time) *dto.Workload {
+	workload := &dto.Workload{
+		UserID: userID,
+		Unit:   capacity.Unit,
+		From:   from,
+		To:     to,
+		Days:   []dto.WorkloadDay{},
+		Tasks:  []dto.WorkloadTask{},
+	}
+	workDays := map[time.Weekday]bool{}
+	for _, day := range capacity.WorkingDays {
+		workDays[time.Weekday(day)] = true
+	}
+	isOff := func(day time.Time) bool {
+		for _, off := range timeOff {
+			if !day.Before(off.StartDate) && !day.After(off.EndDate) {
+				return true
+			}
+		}
+		return false
+	}
+	works := func(day time.Time) bool {
+		return workDays[day.Weekday()] && !isOff(day)
+	}
+	daily := 0.0
+	if len(workDays) > 0 {
+		daily = capacity.Weekly / float64(len(workDays))
+	}
+	allocated := map[time.Time]float64{}
+	for _, task := range tasks {
+		amount := float64(task.StoryPoints)
+		if capacity.Unit == models.CapacityHours {
+			amount = float64(task.RemainingEstimate) / 60
+		}
+		amount /= float64(max(len(task.Assignees), 1))
+		if amount == 0 {
+			continue
+		}
+		if task.DueDate.IsZero() {
+			workload.Unscheduled += amount
+			continue
+		}
+		start, end := today, dateOf(task.DueDate)
+		if task.StartDate != nil && dateOf(*task.StartDate).After(start) {
+			start = dateOf(*task.StartDate)
+		}
+		if end.Before(start) {
+			end = start
+		}
+		var days []time.Time
+		for day := start; !day.After(end); day = day.AddDate(0, 0, 1) {
+			if works(day) {
+				days = append(days, day)
+			}
+		}
+		if len(days) == 0 {
+			days = []time.Time{end}
+		}
+		share := amount / float64(len(days))
+		inRange := 0.0
+		for _, day := range days {
+			if !day.Before(from) && !day.After(to) {
+				allocated[day] += share
+				inRange += share
+			}
+		}
+		if inRange > 0 {
+			workload.Tasks = append(workload.Tasks, dto.WorkloadTask{
+				TaskID:      task.ID,
+				Title:       task.Title,
+				ProjectID:   task.ProjectID,
+				ProjectName: task.Project.Name,
+				DueDate:     task.DueDate,
+				Allocated:   roundAmount(inRange),
+			})
+		}
+	}
+	for day := from; !day.After(to); day = day.AddDate(0, 0, 1) {
+		entry := dto.WorkloadDay{Date: day, Allocated: roundAmount(allocated[day]), TimeOff: isOff(day)}
+		if works(day) {
+			entry.Capacity = roundAmount(daily)
+		}
+		entry.OverAllocated = entry.Allocated > entry.Capacity
+		workload.OverAllocated = workload.OverAllocated || entry.OverAllocated
+		workload.Capacity += entry.Capacity
+		workload.Allocated += allocated[day]
+		workload.Days = append(workload.Days, entry)
+	}
+	workload.Capacity = roundAmount(workload.Capacity)
+	workload.Allocated = roundAmount(workload.Allocated)
+	workload.Unscheduled = roundAmount(workload.Unscheduled)
+	sort.SliceStable(workload.Tasks, func(i, j int) bool {
+		return workload.Tasks[i].DueDate.Before(workload.Tasks[j].DueDate)
+	})
+	return workload
+}
+
+// GetWorkload spreads a user's open work over the days from from to to, both
+// included, and compares it with their capacity
+func (s *Service) GetWorkload(userID uint, from, to time.Time) (*dto.Workload, error) {
+	from, to = dateOf(from), dateOf(to)
+	if to.Before(from) {
+		return nil, invalidInput("to must not be before from")
+	}
+	if daysBetween(from, to) >= maxWorkloadRange {
+		return nil, invalidInput("a workload covers at most %d days", maxWorkloadRange)
+	}
+	user, err := s.Repo.GetUserByID(userID)
+	if err != nil {
+		return nil, err
+	}
+	capacity, err := s.GetCapacity(userID)
+	if err != nil {
+		return nil, err
+	}
+	// Days start and end in the user's timezone
+	today := dateOf(time.Now().In(s.userLocation(userID)))
+	// Time off before today does not matter, work is only planned from today
+	timeOff, err := s.Repo.GetTimeOff(userID, today, time.Time{})
+	if err != nil {
+		return nil, err
+	}
+	tasks, err := s.Repo.GetTasks(dto.TaskFilter{AssigneeID: userID, VisibleToUserID: userID})
+	if err != nil {
+		logrus.WithFields(logrus.Fields{
+			"userID": userID,
+			"error":  err,
+		}).Error("Failed to load assigned tasks")
+		return nil, err
+	}
+	open := tasks[:0]
+	for _, task := range tasks {
+		if task.CompletedAt == nil {
+			open = append(open, task)
+		}
+	}
+	workload := distributeWorkload(userID, *capacity, timeOff, open, from, to, today)
+	workload.Name = user.Name
+	return workload, nil
+}
+
+// GetProjectWorkload returns the workload of every member of a project,
+// counting their work in all their projects
+func (s *Service) GetProjectWorkload(projectID uint, from, to time.Time) ([]dto.Workload, error) {
+	userIDs, err := s.Repo.GetProjectMemberIDs(projectID)
+	if err != nil {
+		return nil, err
+	}
+	sort.Slice(userIDs, func(i, j int) bool { return userIDs[i] < userIDs[j] })
+	workloads := make([]dto.Workload, 0, len(userIDs))
+	for _, userID := range userIDs {
+		workload, err := s.GetWorkload(userID, from, to)
+		if err != nil {
+			return nil, err
+		}
+		workloads = append(workloads, *workload)
+	}
+	return workloads, nil
+}
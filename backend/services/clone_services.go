@@ -0,0 +1,444 @@
+// Clone-related services (copying projects and tasks)
+package services
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"work-management/dto"
+	"work-management/models"
+	"work-management/repository"
+
+	"github.com/sirupsen/logrus"
+	"gorm.io/gorm"
+)
+
+// Parts a clone can include
+const (
+	CloneTasks        = "tasks"
+	CloneSubtasks     = "subtasks"
+	CloneLabels       = "labels"
+	CloneCustomFields = "custom_fields"
+	CloneAssignees    = "assignees"
+	CloneWatchers     = "watchers"
+	CloneMembers      = "members"
+)
+
+var (
+	defaultProjectClone = []string{CloneTasks, CloneSubtasks, CloneLabels, CloneCustomFields}
+	defaultTaskClone    = []string{CloneSubtasks, CloneLabels, CloneCustomFields, CloneAssignees}
+)
+
+// cloneOptions checks the parts a clone includes and fills in the defaults
+func cloneOptions(include, defaults []string, forProject bool) (map[string]bool, error) {
+	if include == nil {
+		include = defaults
+	}
+	options := map[string]bool{}
+	for _, part := range include {
+		switch part {
+		case CloneSubtasks, CloneLabels, CloneCustomFields, CloneAssignees, CloneWatchers:
+		case CloneTasks, CloneMembers:
+			if !forProject {
+				return nil, invalidInput("%q can only be included when cloning a project", part)
+			}
+		default:
+			return nil, invalidInput("cannot include %q in a clone", part)
+		}
+		options[part] = true
+	}
+	return options, nil
+}
+
+func optionList(options map[string]bool) []string {
+	list := make([]string, 0, len(options))
+	for part := range options {
+		list = append(list, part)
+	}
+	sort.Strings(list)
+	return list
+}
+
+// cloner copies tasks into a target project
+type cloner struct {
+	target  *templateTarget
+	include map[string]bool
+	// fields are the custom fields of the source project by ID
+	fields  map[uint]*models.CustomField
+	members map[uint]bool // Members of the target project
+}
+
+// newCloner resolves the target project's members for the assignees and
+// watchers of the copies
+func newCloner(repo *repository.Repository, target *templateTarget, include map[string]bool, fields []models.CustomField) (*cloner, error) {
+	c := &cloner{
+		target:  target,
+		include: include,
+		fields:  map[uint]*models.CustomField{},
+		members: map[uint]bool{},
+	}
+	for i := range fields {
+		c.fields[fields[i].ID] = &fields[i]
+	}
+	memberIDs, err := repo.GetProjectMemberIDs(target.projectID)
+	if err != nil {
+		return nil, err
+	}
+	for _, id := range memberIDs {
+		c.members[id] = true
+	}
+	return c, nil
+}
+
+// cloneTasks copies tasks, and their subtasks if included, under parentID.
+// Statuses the target workflow lacks fall back to its initial status;
+// labels are matched by name and created in the target project when
+// missing; custom field values are matched by field name and type; assignees
+// and watchers who are not members of the target project are left out.
+func (s *Service) cloneTasks(repo *repository.Repository, c *cloner, tasks []*models.Task, children map[uint][]*models.Task, parentID *uint) error {
+	for _, source := range tasks {
+		task := &models.Task{
+			Title:             source.Title,
+			Description:       source.Description,
+			ProjectID:         c.target.projectID,
+			DueDate:           source.DueDate,
+			DueDateOnly:       source.DueDateOnly,
+			StartDate:         source.StartDate,
+			Duration:          source.Duration,
+			Priority:          source.Priority,
+			StoryPoints:       source.StoryPoints,
+			OriginalEstimate:  source.OriginalEstimate,
+			RemainingEstimate: source.RemainingEstimate,
+			ReporterID:        c.target.reporterID,
+			ParentID:          parentID,
+		}
+		status, completed := c.target.status(source.Status)
+		setTaskStatus(task, status, completed)
+		if completed && source.CompletedAt != nil {
+			task.CompletedAt = source.CompletedAt
+		}
+		if c.include[CloneLabels] {
+			for _, label := range source.Labels {
+				labelID, ok := c.target.label(label.Name)
+				if !ok {
+					created := &models.Label{ProjectID: c.target.projectID, Name: label.Name, Color: label.Color}
+					if err := repo.CreateLabel(created); err != nil {
+						return err
+					}
+					labelID = created.ID
+					c.target.addLabel(label.Name, labelID)
+				}
+				task.Labels = append(task.Labels, models.Label{Model: gorm.Model{ID: labelID}})
+			}
+		}
+		given := map[string]interface{}{}
+		if c.include[CloneCustomFields] {
+			for _, value := range source.CustomFields {
+				field, ok := c.fields[value.FieldID]
+				if !ok {
+					continue
+				}
+				if targetField, ok := c.target.fields[field.Name]; ok && targetField.Type == field.Type {
+					given[field.Name] = value.Value
+				}
+			}
+		}
+		values, err := s.fieldValues(c.target, task.Title, given)
+		if err != nil {
+			return err
+		}
+		task.CustomFields = values
+		if c.include[CloneAssignees] {
+			for _, user := range source.Assignees {
+				if c.members[user.ID] {
+					task.Assignees = append(task.Assignees, models.User{Model: gorm.Model{ID: user.ID}})
+				}
+			}
+			if c.members[source.UserID] {
+				task.UserID = source.UserID
+			} else if len(task.Assignees) > 0 {
+				task.UserID = task.Assignees[0].ID
+			}
+		}
+		if c.include[CloneWatchers] {
+			for _, user := range source.Watchers {
+				if c.members[user.ID] {
+					task.Watchers = append(task.Watchers, models.User{Model: gorm.Model{ID: user.ID}})
+				}
+			}
+		}
+		if err := repo.CreateTask(task); err != nil {
+			return err
+		}
+		c.target.created = append(c.target.created, task)
+		if c.include[CloneSubtasks] {
+			if err := s.cloneTasks(repo, c, children[source.ID], children, &task.ID); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// CloneProject copies a project with its settings and workflow, and the parts
+// listed in input.Include, in one transaction. The actor becomes the admin
+// of the copy.
+func (s *Service) CloneProject(actorID, projectID uint, input dto.CloneProjectInput) (*models.Project, error) {
+	include, err := cloneOptions(input.Include, defaultProjectClone, true)
+	if err != nil {
+		return nil, err
+	}
+	name := strings.TrimSpace(input.Name)
+	if name == "" {
+		return nil, invalidInput("project name is required")
+	}
+	source, err := s.Repo.GetProjectByID(projectID)
+	if err != nil {
+		return nil, err
+	}
+	statuses, err := s.Repo.GetWorkflow(projectID)
+	if err != nil {
+		return nil, err
+	}
+	labels, err := s.Repo.GetLabelsByProjectID(projectID)
+	if err != nil {
+		return nil, err
+	}
+	fields, err := s.Repo.GetCustomFieldsByProjectID(projectID)
+	if err != nil {
+		return nil, err
+	}
+	tasks := make([]*models.Task, 0, len(source.Tasks))
+	for i := range source.Tasks {
+		tasks = append(tasks, &source.Tasks[i])
+	}
+	project := models.Project{
+		Name:        name,
+		Description: source.Description,
+		Category:    source.Category,
+		Status:      source.Status,
+		CreatorID:   actorID,
+	}
+	var target *templateTarget
+	err = s.transaction(func(repo *repository.Repository) error {
+		if err := insertProject(repo, &project); err != nil {
+			return err
+		}
+		target = newTemplateTarget(project.ID, time.Time{})
+		target.reporterID = actorID
+		if len(statuses) > 0 {
+			workflow := make([]models.WorkflowStatus, 0, len(statuses))
+			for _, status := range statuses {
+				workflow = append(workflow, models.WorkflowStatus{
+					Name:      status.Name,
+					Category:  status.Category,
+					Position:  status.Position,
+					WIPLimit:  status.WIPLimit,
+					WIPPolicy: status.WIPPolicy,
+				})
+			}
+			if err := repo.ReplaceWorkflow(project.ID, workflow); err != nil {
+				return err
+			}
+			target.setWorkflow(workflow)
+		}
+		if include[CloneLabels] {
+			for _, existing := range labels {
+				label := &models.Label{ProjectID: project.ID, Name: existing.Name, Color: existing.Color}
+				if err := repo.CreateLabel(label); err != nil {
+					return err
+				}
+				target.addLabel(label.Name, label.ID)
+			}
+		}
+		if include[CloneCustomFields] {
+			for _, existing := range fields {
+				field := &models.CustomField{
+					ProjectID:    project.ID,
+					Name:         existing.Name,
+					Type:         existing.Type,
+					Required:     existing.Required,
+					Options:      existing.Options,
+					DefaultValue: existing.DefaultValue,
+				}
+				if err := repo.CreateCustomField(field); err != nil {
+					return err
+				}
+				target.fields[field.Name] = field
+			}
+		}
+		var added []models.UserRole
+		if include[CloneMembers] {
+			for _, role := range source.Users {
+				if role.UserID == actorID {
+					continue
+				}
+				if err := repo.AddUserToProject(role.UserID, project.ID, role.Role); err != nil {
+					return err
+				}
+				added = append(added, role)
+			}
+		}
+		if include[CloneTasks] {
+			c, err := newCloner(repo, target, include, fields)
+			if err != nil {
+				return err
+			}
+			roots, children := taskTree(tasks)
+			if err := s.cloneTasks(repo, c, roots, children, nil); err != nil {
+				return err
+			}
+		}
+		if err := s.recordEvent(repo, Event{
+			Type:      EventProjectCreated,
+			ProjectID: project.ID,
+			ActorID:   actorID,
+			Message:   fmt.Sprintf("created project %q as a copy of %q", project.Name, source.Name),
+			Data:      map[string]interface{}{"source_project_id": source.ID, "task_count": len(target.created)},
+		}); err != nil {
+			return err
+		}
+		for _, role := range added {
+			if err := s.recordEvent(repo, Event{
+				Type:       EventMemberAdded,
+				ProjectID:  project.ID,
+				ActorID:    actorID,
+				Message:    fmt.Sprintf("added %s as %s", s.userName(role.UserID), role.Role),
+				Recipients: []uint{role.UserID},
+				Notice:     fmt.Sprintf("added you to %q as %s", project.Name, role.Role),
+				Data:       map[string]interface{}{"user_id": role.UserID, "role": role.Role},
+			}); err != nil {
+				return err
+			}
+		}
+		return s.audit(repo, models.AuditLog{
+			ActorID:    actorID,
+			Action:     AuditProjectCloned,
+			EntityType: "project",
+			EntityID:   source.ID,
+			ProjectID:  source.ID,
+			Details: map[string]interface{}{
+				"copy_project_id": project.ID,
+				"include":         optionList(include),
+				"task_count":      len(target.created),
+			},
+		})
+	})
+	if err != nil {
+		logrus.WithFields(logrus.Fields{
+			"projectID": projectID,
+			"userID":    actorID,
+			"error":     err,
+		}).Error("Failed to clone project")
+		return nil, err
+	}
+	logrus.WithFields(logrus.Fields{
+		"projectID": projectID,
+		"copyID":    project.ID,
+		"taskCount": len(target.created),
+	}).Info("Project cloned successfully")
+	return s.Repo.GetProjectByID(project.ID)
+}
+
+// CloneTask copies a task, and the parts listed in input.Include, into its
+// own project or another one in one transaction. Within its own project the
+// copy keeps the task's parent.
+func (s *Service) CloneTask(actorID, taskID uint, input dto.CloneTaskInput) (*models.Task, error) {
+	include, err := cloneOptions(input.Include, defaultTaskClone, false)
+	if err != nil {
+		return nil, err
+	}
+	source, err := s.Repo.GetTaskByID(taskID)
+	if err != nil {
+		return nil, err
+	}
+	projectID := input.ProjectID
+	if projectID == 0 {
+		projectID = source.ProjectID
+	}
+	tasks := []*models.Task{source}
+	children := map[uint][]*models.Task{}
+	if include[CloneSubtasks] {
+		if tasks, children, err = s.loadTaskTree(source); err != nil {
+			return nil, err
+		}
+	}
+	fields, err := s.Repo.GetCustomFieldsByProjectID(source.ProjectID)
+	if err != nil {
+		return nil, err
+	}
+	root := *source
+	if title := strings.TrimSpace(input.Title); title != "" {
+		root.Title = title
+	}
+	var parentID *uint
+	if projectID == source.ProjectID {
+		parentID = source.ParentID
+	}
+	var target *templateTarget
+	err = s.transaction(func(repo *repository.Repository) error {
+		if target, err = loadTemplateTarget(repo, projectID, time.Time{}); err != nil {
+			return err
+		}
+		target.reporterID = actorID
+		c, err := newCloner(repo, target, include, fields)
+		if err != nil {
+			return err
+		}
+		if err := s.cloneTasks(repo, c, []*models.Task{&root}, children, parentID); err != nil {
+			return err
+		}
+		for _, task := range target.created {
+			if err := s.recordEvent(repo, Event{
+				Type:      EventTaskCreated,
+				ProjectID: task.ProjectID,
+				TaskID:    task.ID,
+				ActorID:   actorID,
+				Message:   fmt.Sprintf("created task %q", task.Title),
+				Data:      map[string]interface{}{"source_task_id": source.ID},
+			}); err != nil {
+				return err
+			}
+			if err := s.recordEvent(repo, Event{
+				Type:       EventTaskAssigned,
+				ProjectID:  task.ProjectID,
+				TaskID:     task.ID,
+				ActorID:    actorID,
+				Recipients: userIDs(task.Assignees),
+				Notice:     fmt.Sprintf("assigned you to %q", task.Title),
+			}); err != nil {
+				return err
+			}
+		}
+		return s.audit(repo, models.AuditLog{
+			ActorID:    actorID,
+			Action:     AuditTaskCloned,
+			EntityType: "task",
+			EntityID:   source.ID,
+			ProjectID:  source.ProjectID,
+			Details: map[string]interface{}{
+				"copy_task_id":      target.created[0].ID,
+				"target_project_id": projectID,
+				"include":           optionList(include),
+				"task_count":        len(target.created),
+			},
+		})
+	})
+	if err != nil {
+		logrus.WithFields(logrus.Fields{
+			"taskID": taskID,
+			"userID": actorID,
+			"error":  err,
+		}).Error("Failed to clone task")
+		return nil, err
+	}
+	logrus.WithFields(logrus.Fields{
+		"taskID":    taskID,
+		"copyID":    target.created[0].ID,
+		"projectID": projectID,
+		"taskCount": len(tasks),
+	}).Info("Task cloned successfully")
+	return s.Repo.GetTaskByID(target.created[0].ID)
+}
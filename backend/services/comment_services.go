@@ -0,0 +1,166 @@
+// Comment-related services (task comments and @mentions)
+package services
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"work-management/models"
+	"work-management/repository"
+
+	"github.com/sirupsen/logrus"
+)
+
+// mentionPattern matches "@" followed by an email address
+var mentionPattern = regexp.MustCompile(`@([A-Za-z0-9._%+\-]+@[A-Za-z0-9.\-]+\.[A-Za-z]{2,})`)
+
+// mentionedUsers resolves the @email mentions in a comment body to the users
+// of the project they refer to. Unknown addresses are ignored.
+func (s *Service) mentionedUsers(projectID uint, body string) []uint {
+	var userIDs []uint
+	seen := map[string]bool{}
+	for _, match := range mentionPattern.FindAllStringSubmatch(body, -1) {
+		email := strings.TrimRight(match[1], ".")
+		if seen[email] {
+			continue
+		}
+		seen[email] = true
+		user, err := s.Repo.FindUserByEmail(email)
+		if err != nil || !s.IsProjectMember(user.ID, projectID) {
+			continue
+		}
+		userIDs = append(userIDs, user.ID)
+	}
+	return userIDs
+}
+
+// AddComment posts a comment on a task, notifying the mentioned users and the
+// task's watchers
+func (s *Service) AddComment(actorID, taskID uint, body string) (*models.Comment, error) {
+	body = strings.TrimSpace(body)
+	if body == "" {
+		return nil, invalidInput("comment body is required")
+	}
+	task, err := s.Repo.GetTaskByID(taskID)
+	if err != nil {
+		logrus.WithFields(logrus.Fields{
+			"taskID": taskID,
+			"error":  err,
+		}).Warn("Task not found for comment")
+		return nil, err
+	}
+	if !s.IsProjectMember(actorID, task.ProjectID) {
+		return nil, fmt.Errorf("%w: only project members can comment", ErrForbidden)
+	}
+	if err := s.ensureNotArchived(task.ProjectID); err != nil {
+		return nil, err
+	}
+	mentioned := s.mentionedUsers(task.ProjectID, body)
+	isMentioned := map[uint]bool{}
+	for _, userID := range mentioned {
+		isMentioned[userID] = true
+	}
+	// Watchers who were also mentioned only get the mention
+	var watchers []uint
+	for _, watcher := range task.Watchers {
+		if !isMentioned[watcher.ID] {
+			watchers = append(watchers, watcher.ID)
+		}
+	}
+	comment := &models.Comment{TaskID: taskID, UserID: actorID, Body: body}
+	err = s.transaction(func(repo *repository.Repository) error {
+		if err := repo.CreateComment(comment); err != nil {
+			return err
+		}
+		data := map[string]interface{}{"comment_id": comment.ID, "body": body}
+		if err := s.recordEvent(repo, Event{
+			Type:       EventCommentCreated,
+			ProjectID:  task.ProjectID,
+			TaskID:     taskID,
+			ActorID:    actorID,
+			Message:    fmt.Sprintf("commented on task %q", task.Title),
+			Recipients: watchers,
+			Notice:     fmt.Sprintf("commented on %q", task.Title),
+			Data:       data,
+		}); err != nil {
+			return err
+		}
+		if len(mentioned) == 0 {
+			return nil
+		}
+		return s.recordEvent(repo, Event{
+			Type:       EventCommentMentioned,
+			ProjectID:  task.ProjectID,
+			TaskID:     taskID,
+			ActorID:    actorID,
+			Recipients: mentioned,
+			Notice:     fmt.Sprintf("mentioned you on %q", task.Title),
+			Data:       data,
+		})
+	})
+	if err != nil {
+		logrus.WithFields(logrus.Fields{
+			"taskID": taskID,
+			"userID": actorID,
+			"error":  err,
+		}).Error("Failed to create comment")
+		return nil, err
+	}
+	logrus.WithFields(logrus.Fields{
+		"commentID": comment.ID,
+		"taskID":    taskID,
+		"userID":    actorID,
+	}).Info("Comment created successfully")
+	return s.Repo.GetCommentByID(comment.ID)
+}
+
+func (s *Service) GetCommentsByTaskID(taskID uint) ([]models.Comment, error) {
+	comments, err := s.Repo.GetCommentsByTaskID(taskID)
+	if err != nil {
+		logrus.WithFields(logrus.Fields{
+			"taskID": taskID,
+			"error":  err,
+		}).Error("Failed to retrieve comments")
+		return nil, err
+	}
+	logrus.WithFields(logrus.Fields{
+		"taskID":       taskID,
+		"commentCount": len(comments),
+	}).Info("Comments retrieved successfully")
+	return comments, nil
+}
+
+// DeleteComment removes a comment; authors can delete their own comments and
+// project admins anyone's
+func (s *Service) DeleteComment(actorID, commentID uint) error {
+	comment, err := s.Repo.GetCommentByID(commentID)
+	if err != nil {
+		logrus.WithFields(logrus.Fields{
+			"commentID": commentID,
+			"error":     err,
+		}).Warn("Comment not found")
+		return err
+	}
+	if comment.UserID != actorID {
+		task, err := s.Repo.GetTaskByID(comment.TaskID)
+		if err != nil {
+			return err
+		}
+		if isAdmin, err := s.AdminOnly(actorID, task.ProjectID); err != nil || !isAdmin {
+			return fmt.Errorf("%w: only the author or a project admin can delete a comment", ErrForbidden)
+		}
+	}
+	if err := s.Repo.DeleteComment(commentID); err != nil {
+		logrus.WithFields(logrus.Fields{
+			"commentID": commentID,
+			"error":     err,
+		}).Error("Failed to delete comment")
+		return err
+	}
+	logrus.WithFields(logrus.Fields{
+		"commentID": commentID,
+		"userID":    actorID,
+	}).Info("Comment deleted successfully")
+	return nil
+}
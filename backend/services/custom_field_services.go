@@ -0,0 +1,433 @@
+// Custom field services (field definitions and typed task values)
+package services
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+
+	"work-management/dto"
+	"work-management/models"
+	"work-management/repository"
+
+	"github.com/sirupsen/logrus"
+)
+
+var validFieldTypes = map[string]bool{
+	models.FieldTypeText:         true,
+	models.FieldTypeNumber:       true,
+	models.FieldTypeDate:         true,
+	models.FieldTypeSingleSelect: true,
+	models.FieldTypeMultiSelect:  true,
+	models.FieldTypeUser:         true,
+	models.FieldTypeURL:          true,
+}
+
+// maxTextFieldLength bounds text custom field values
+const maxTextFieldLength = 10000
+
+func isSelectField(fieldType string) bool {
+	return fieldType == models.FieldTypeSingleSelect || fieldType == models.FieldTypeMultiSelect
+}
+
+// validateFieldDefinition checks a field's options and that its default value
+// would itself be a valid value for the field.
+func (s *Service) validateFieldDefinition(field *models.CustomField) error {
+	if strings.TrimSpace(field.Name) == "" {
+		return invalidInput("field name is required")
+	}
+	if !validFieldTypes[field.Type] {
+		return invalidInput("unknown field type %q", field.Type)
+	}
+	if isSelectField(field.Type) {
+		if len(field.Options) == 0 {
+			return invalidInput("select fields need at least one option")
+		}
+		seen := map[string]bool{}
+		for _, option := range field.Options {
+			if strings.TrimSpace(option) == "" || seen[option] {
+				return invalidInput("select options must be non-empty and unique")
+			}
+			seen[option] = true
+		}
+	} else {
+		field.Options = nil
+	}
+	if field.DefaultValue != nil {
+		value, err := s.normalizeFieldValue(field, field.DefaultValue)
+		if err != nil {
+			return invalidInput("default_value: %v", err)
+		}
+		field.DefaultValue = value.Value
+	}
+	return nil
+}
+
+func (s *Service) CreateCustomField(field *models.CustomField) (*models.CustomField, error) {
+	field.Name = strings.TrimSpace(field.Name)
+	if err := s.validateFieldDefinition(field); err != nil {
+		logrus.WithFields(logrus.Fields{
+			"projectID": field.ProjectID,
+			"error":     err,
+		}).Warn("Invalid custom field")
+		return nil, err
+	}
+	if err := s.Repo.CreateCustomField(field); err != nil {
+		logrus.WithFields(logrus.Fields{
+			"projectID": field.ProjectID,
+			"error":     err,
+		}).Error("Failed to create custom field")
+		return nil, err
+	}
+	logrus.WithFields(logrus.Fields{
+		"fieldID":   field.ID,
+		"projectID": field.ProjectID,
+		"type":      field.Type,
+	}).Info("Custom field created successfully")
+	return field, nil
+}
+
+func (s *Service) GetCustomFieldsByProjectID(projectID uint) ([]models.CustomField, error) {
+	fields, err := s.Repo.GetCustomFieldsByProjectID(projectID)
+	if err != nil {
+		logrus.WithFields(logrus.Fields{
+			"projectID": projectID,
+			"error":     err,
+		}).Error("Failed to retrieve custom fields")
+		return nil, err
+	}
+	return fields, nil
+}
+
+// getProjectCustomField returns a field only if it belongs to the project
+func (s *Service) getProjectCustomField(projectID, fieldID uint) (*models.CustomField, error) {
+	field, err := s.Repo.GetCustomFieldByID(fieldID)
+	if err != nil {
+		logrus.WithFields(logrus.Fields{
+			"fieldID": fieldID,
+			"error":   err,
+		}).Warn("Custom field not found")
+		return nil, err
+	}
+	if field.ProjectID != projectID {
+		return nil, invalidInput("custom field %d does not belong to this project", fieldID)
+	}
+	return field, nil
+}
+
+// UpdateCustomField changes a field's name, flags, options and default. The
+// type is fixed at creation; values using options that were removed are
+// cleaned up so tasks only ever hold valid choices.
+func (s *Service) UpdateCustomField(projectID, fieldID uint, update models.CustomField) (*models.CustomField, error) {
+	field, err := s.getProjectCustomField(projectID, fieldID)
+	if err != nil {
+		return nil, err
+	}
+	if update.Type != "" && update.Type != field.Type {
+		return nil, invalidInput("the type of a custom field cannot be changed")
+	}
+	field.Name = strings.TrimSpace(update.Name)
+	field.Required = update.Required
+	field.Options = update.Options
+	field.DefaultValue = update.DefaultValue
+	if err := s.validateFieldDefinition(field); err != nil {
+		logrus.WithFields(logrus.Fields{
+			"fieldID": fieldID,
+			"error":   err,
+		}).Warn("Invalid custom field")
+		return nil, err
+	}
+	if err := s.Repo.UpdateCustomField(field); err != nil {
+		logrus.WithFields(logrus.Fields{
+			"fieldID": fieldID,
+			"error":   err,
+		}).Error("Failed to update custom field")
+		return nil, err
+	}
+	if isSelectField(field.Type) {
+		if err := s.pruneRemovedOptions(field); err != nil {
+			return nil, err
+		}
+	}
+	logrus.WithFields(logrus.Fields{
+		"fieldID": fieldID,
+	}).Info("Custom field updated successfully")
+	return field, nil
+}
+
+// pruneRemovedOptions drops choices that are no longer offered from the
+// stored values of a select field.
+func (s *Service) pruneRemovedOptions(field *models.CustomField) error {
+	values, err := s.Repo.GetCustomFieldValuesByFieldID(field.ID)
+	if err != nil {
+		return err
+	}
+	for _, stored := range values {
+		kept := filterOptions(stored.Value, field.Options)
+		if len(kept) == countOptions(stored.Value) {
+			continue
+		}
+		var cleared []uint
+		var updated []models.CustomFieldValue
+		if len(kept) == 0 {
+			cleared = []uint{field.ID}
+		} else {
+			value, err := s.normalizeFieldValue(field, kept)
+			if err != nil {
+				return err
+			}
+			updated = []models.CustomFieldValue{value}
+		}
+		if err := s.Repo.SaveTaskCustomFields(stored.TaskID, updated, cleared); err != nil {
+			logrus.WithFields(logrus.Fields{
+				"fieldID": field.ID,
+				"taskID":  stored.TaskID,
+				"error":   err,
+			}).Error("Failed to prune removed select option")
+			return err
+		}
+	}
+	return nil
+}
+
+// filterOptions returns the chosen options of a stored select value that are
+// still in options. Single select values are treated as a one-element list.
+func filterOptions(value interface{}, options []string) []interface{} {
+	allowed := map[string]bool{}
+	for _, option := range options {
+		allowed[option] = true
+	}
+	kept := []interface{}{}
+	for _, chosen := range selectedOptions(value) {
+		if allowed[chosen] {
+			kept = append(kept, chosen)
+		}
+	}
+	return kept
+}
+
+func countOptions(value interface{}) int {
+	return len(selectedOptions(value))
+}
+
+func selectedOptions(value interface{}) []string {
+	switch v := value.(type) {
+	case string:
+		return []string{v}
+	case []interface{}:
+		chosen := make([]string, 0, len(v))
+		for _, item := range v {
+			if str, ok := item.(string); ok {
+				chosen = append(chosen, str)
+			}
+		}
+		return chosen
+	}
+	return nil
+}
+
+func (s *Service) DeleteCustomField(projectID, fieldID uint) error {
+	if _, err := s.getProjectCustomField(projectID, fieldID); err != nil {
+		return err
+	}
+	if err := s.Repo.DeleteCustomField(fieldID); err != nil {
+		logrus.WithFields(logrus.Fields{
+			"fieldID": fieldID,
+			"error":   err,
+		}).Error("Failed to delete custom field")
+		return err
+	}
+	logrus.WithFields(logrus.Fields{
+		"fieldID": fieldID,
+	}).Info("Custom field deleted successfully")
+	return nil
+}
+
+// parseFieldDate accepts a date (2006-01-02) or an RFC 3339 timestamp and
+// returns midnight UTC of that day.
+func parseFieldDate(raw string) (time.Time, error) {
+	if t, err := time.Parse("2006-01-02", raw); err == nil {
+		return t, nil
+	}
+	t, err := time.Parse(time.RFC3339, raw)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("expected a date like 2024-01-31")
+	}
+	return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC), nil
+}
+
+// normalizeFieldValue validates a raw JSON-decoded value against a field and
+// returns it in stored form, with the typed column matching the field type.
+func (s *Service) normalizeFieldValue(field *models.CustomField, raw interface{}) (models.CustomFieldValue, error) {
+	value := models.CustomFieldValue{FieldID: field.ID}
+	switch field.Type {
+	case models.FieldTypeText, models.FieldTypeURL, models.FieldTypeSingleSelect:
+		str, ok := raw.(string)
+		if !ok {
+			return value, fmt.Errorf("%s expects a string", field.Name)
+		}
+		if field.Type == models.FieldTypeText && len(str) > maxTextFieldLength {
+			return value, fmt.Errorf("%s is longer than %d characters", field.Name, maxTextFieldLength)
+		}
+		if field.Type == models.FieldTypeURL {
+			parsed, err := url.ParseRequestURI(str)
+			if err != nil || (parsed.Scheme != "http" && parsed.Scheme != "https") || parsed.Host == "" {
+				return value, fmt.Errorf("%s expects an http(s) URL", field.Name)
+			}
+		}
+		if field.Type == models.FieldTypeSingleSelect && len(filterOptions(str, field.Options)) == 0 {
+			return value, fmt.Errorf("%q is not an option of %s", str, field.Name)
+		}
+		value.Value = str
+		value.TextValue = &str
+	case models.FieldTypeNumber:
+		number, ok := raw.(float64)
+		if !ok {
+			return value, fmt.Errorf("%s expects a number", field.Name)
+		}
+		value.Value = number
+		value.NumberValue = &number
+	case models.FieldTypeUser:
+		number, ok := raw.(float64)
+		if !ok || number <= 0 || number != float64(uint(number)) {
+			return value, fmt.Errorf("%s expects a user ID", field.Name)
+		}
+		if field.ProjectID != 0 {
+			if _, err := s.Repo.GetUserRole(uint(number), field.ProjectID); err != nil {
+				return value, fmt.Errorf("user %d is not a member of the project", uint(number))
+			}
+		}
+		value.Value = number
+		value.NumberValue = &number
+	case models.FieldTypeDate:
+		str, ok := raw.(string)
+		if !ok {
+			return value, fmt.Errorf("%s expects a date string", field.Name)
+		}
+		date, err := parseFieldDate(str)
+		if err != nil {
+			return value, fmt.Errorf("%s: %v", field.Name, err)
+		}
+		formatted := date.Format("2006-01-02")
+		value.Value = formatted
+		value.TextValue = &formatted
+		value.DateValue = &date
+	case models.FieldTypeMultiSelect:
+		items, ok := raw.([]interface{})
+		if !ok {
+			return value, fmt.Errorf("%s expects a list of options", field.Name)
+		}
+		chosen := []string{}
+		seen := map[string]bool{}
+		for _, item := range items {
+			str, ok := item.(string)
+			if !ok || len(filterOptions(str, field.Options)) == 0 {
+				return value, fmt.Errorf("%v is not an option of %s", item, field.Name)
+			}
+			if !seen[str] {
+				seen[str] = true
+				chosen = append(chosen, str)
+			}
+		}
+		joined := strings.Join(chosen, ", ")
+		value.Value = chosen
+		value.TextValue = &joined
+	}
+	return value, nil
+}
+
+// resolveTaskCustomFields validates the custom field values sent with a task.
+// When creating, fields left out get their default, and required fields must
+// end up with a value. It returns the values to store and the fields to clear.
+func (s *Service) resolveTaskCustomFields(projectID uint, input map[uint]interface{}, creating bool) ([]models.CustomFieldValue, []uint, error) {
+	if input == nil && !creating {
+		return nil, nil, nil
+	}
+	fields, err := s.Repo.GetCustomFieldsByProjectID(projectID)
+	if err != nil {
+		return nil, nil, err
+	}
+	known := map[uint]bool{}
+	var values []models.CustomFieldValue
+	var cleared []uint
+	for i := range fields {
+		field := &fields[i]
+		known[field.ID] = true
+		raw, provided := input[field.ID]
+		if !provided && creating {
+			raw, provided = field.DefaultValue, field.DefaultValue != nil
+		}
+		if !provided {
+			if creating && field.Required {
+				return nil, nil, invalidInput("custom field %s is required", field.Name)
+			}
+			continue
+		}
+		if raw == nil {
+			if field.Required {
+				return nil, nil, invalidInput("custom field %s is required", field.Name)
+			}
+			cleared = append(cleared, field.ID)
+			continue
+		}
+		value, err := s.normalizeFieldValue(field, raw)
+		if err != nil {
+			return nil, nil, invalidInput("%v", err)
+		}
+		values = append(values, value)
+	}
+	for fieldID := range input {
+		if !known[fieldID] {
+			return nil, nil, invalidInput("custom field %d does not belong to this project", fieldID)
+		}
+	}
+	return values, cleared, nil
+}
+
+// resolveCustomFieldFilters fills in field types on custom field filters and
+// converts their query-string values to the typed column they compare with.
+func (s *Service) resolveCustomFieldFilters(filter *dto.TaskFilter) error {
+	for i := range filter.CustomFields {
+		f := &filter.CustomFields[i]
+		field, err := s.Repo.GetCustomFieldByID(f.FieldID)
+		if err != nil {
+			return invalidInput("unknown custom field %d", f.FieldID)
+		}
+		f.Type = field.Type
+		raw, _ := f.Value.(string)
+		switch field.Type {
+		case models.FieldTypeNumber, models.FieldTypeUser:
+			var number float64
+			if _, err := fmt.Sscan(raw, &number); err != nil {
+				return invalidInput("custom field %d filter expects a number", f.FieldID)
+			}
+			f.Value = number
+		case models.FieldTypeDate:
+			date, err := parseFieldDate(raw)
+			if err != nil {
+				return invalidInput("custom field %d filter: %v", f.FieldID, err)
+			}
+			f.Value = date
+		case models.FieldTypeMultiSelect:
+			if f.Op != "eq" {
+				return invalidInput("multi select fields only support equality filters")
+			}
+			encoded, _ := json.Marshal([]string{raw})
+			f.Value = string(encoded)
+		default:
+			if f.Op != "eq" {
+				return invalidInput("%s fields only support equality filters", field.Type)
+			}
+		}
+	}
+	if fieldID, ok := repository.CustomFieldSortID(filter.Sort); ok {
+		field, err := s.Repo.GetCustomFieldByID(fieldID)
+		if err != nil {
+			return invalidInput("unknown custom field %d", fieldID)
+		}
+		filter.SortFieldType = field.Type
+	}
+	return nil
+}
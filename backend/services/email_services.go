@@ -0,0 +1,229 @@
+// Email-related services (transactional emails and activity digests)
+package services
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"time"
+
+	"work-management/jobs"
+	"work-management/mailer"
+	"work-management/models"
+	"work-management/repository"
+
+	"github.com/sirupsen/logrus"
+	"gorm.io/gorm"
+)
+
+// emailTemplates maps the events that are also sent by email to the mailer
+// template used for them
+var emailTemplates = map[string]string{
+	EventTaskAssigned:     "task_assigned",
+	EventCommentMentioned: "mentioned",
+	EventTaskDueSoon:      "due_soon",
+	EventTaskOverdue:      "overdue",
+	EventTaskEscalated:    "overdue",
+	EventTaskSLABreached:  "sla_breached",
+}
+
+// maxDigestEntries caps the number of activities listed in one digest
+const maxDigestEntries = 200
+
+// digestPeriods is how long a subscriber waits between two digests. An hour
+// is taken off so that a digest is not pushed back by the scheduler's tick.
+var digestPeriods = map[string]time.Duration{
+	models.DigestDaily:  24*time.Hour - time.Hour,
+	models.DigestWeekly: 7*24*time.Hour - time.Hour,
+}
+
+// appURL is the address of the web app used for links in emails (APP_URL)
+func appURL() string {
+	if url := os.Getenv("APP_URL"); url != "" {
+		return url
+	}
+	return "http://localhost:3000"
+}
+
+func projectLink(projectID uint) string {
+	return fmt.Sprintf("%s/project/%d", appURL(), projectID)
+}
+
+// sendEmail queues a message for delivery by the email workers
+func (s *Service) sendEmail(msg mailer.Message, options ...jobs.EnqueueOption) error {
+	// Enqueue logs its own failures
+	_, err := s.Jobs.Enqueue(JobSendEmail, msg, options...)
+	return err
+}
+
+// sendNotificationEmails emails the recipients of events that have an email
+// template, unless they switched email off for the event type. The job key
+// keeps a recipient from getting the same email twice when the event is
+// published again.
+func (s *Service) sendNotificationEmails(event Event) error {
+	templateName, ok := emailTemplates[event.Type]
+	if !ok || s.Mailer == nil || event.Notice == "" {
+		return nil
+	}
+	message := s.noticeText(event)
+	taskTitle := ""
+	if task, err := s.Repo.GetTaskByID(event.TaskID); err == nil {
+		taskTitle = task.Title
+	}
+	excerpt, _ := event.Data["body"].(string)
+	for _, userID := range eventRecipients(event) {
+		if !s.notificationPreference(userID, event.Type).Email {
+			continue
+		}
+		user, err := s.Repo.GetUserByID(userID)
+		if err != nil {
+			logrus.WithFields(logrus.Fields{
+				"userID": userID,
+				"error":  err,
+			}).Warn("Email recipient not found")
+			continue
+		}
+		msg, err := mailer.Render(templateName, map[string]interface{}{
+			"Name":      user.Name,
+			"Message":   message,
+			"TaskTitle": taskTitle,
+			"Excerpt":   excerpt,
+			"Link":      projectLink(event.ProjectID),
+		})
+		if err != nil {
+			logrus.WithFields(logrus.Fields{
+				"template": templateName,
+				"error":    err,
+			}).Error("Failed to render email")
+			return err
+		}
+		msg.To = user.Email
+		key := fmt.Sprintf("%s:%d:%d:%d:%d", event.Type, event.ProjectID, event.TaskID, userID, event.OccurredAt.UnixNano())
+		if err := s.sendEmail(msg, jobs.WithKey(key)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (s *Service) GetDigestSubscription(userID uint) (*models.DigestSubscription, error) {
+	subscription, err := s.Repo.GetDigestSubscription(userID)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return &models.DigestSubscription{UserID: userID, Frequency: "off"}, nil
+	}
+	if err != nil {
+		logrus.WithFields(logrus.Fields{
+			"userID": userID,
+			"error":  err,
+		}).Error("Failed to retrieve digest subscription")
+		return nil, err
+	}
+	return subscription, nil
+}
+
+// UpdateDigestSubscription sets the user's digest frequency: "daily",
+// "weekly" or "off"
+func (s *Service) UpdateDigestSubscription(userID uint, frequency string) (*models.DigestSubscription, error) {
+	var err error
+	switch frequency {
+	case "off":
+		err = s.Repo.DeleteDigestSubscription(userID)
+	case models.DigestDaily, models.DigestWeekly:
+		err = s.Repo.SaveDigestSubscription(&models.DigestSubscription{UserID: userID, Frequency: frequency})
+	default:
+		return nil, invalidInput("frequency must be one of daily, weekly, off")
+	}
+	if err != nil {
+		logrus.WithFields(logrus.Fields{
+			"userID":    userID,
+			"frequency": frequency,
+			"error":     err,
+		}).Error("Failed to update digest subscription")
+		return nil, err
+	}
+	logrus.WithFields(logrus.Fields{
+		"userID":    userID,
+		"frequency": frequency,
+	}).Info("Digest subscription updated successfully")
+	return s.GetDigestSubscription(userID)
+}
+
+// digestProject groups the digest entries of one project
+type digestProject struct {
+	Name    string
+	Entries []repository.DigestEntry
+}
+
+// sendDigest emails the activity since the subscriber's last digest. Nothing
+// is sent when there was no activity.
+func (s *Service) sendDigest(subscription models.DigestSubscription, now time.Time) error {
+	since := now.Add(-digestPeriods[subscription.Frequency])
+	if subscription.LastSentAt != nil {
+		since = *subscription.LastSentAt
+	}
+	entries, err := s.Repo.GetDigestEntries(subscription.UserID, since, maxDigestEntries)
+	if err != nil {
+		return err
+	}
+	if len(entries) > 0 {
+		user, err := s.Repo.GetUserByID(subscription.UserID)
+		if err != nil {
+			return err
+		}
+		var projects []*digestProject
+		for _, entry := range entries {
+			if len(projects) == 0 || projects[len(projects)-1].Name != entry.ProjectName {
+				projects = append(projects, &digestProject{Name: entry.ProjectName})
+			}
+			last := projects[len(projects)-1]
+			last.Entries = append(last.Entries, entry)
+		}
+		msg, err := mailer.Render("digest", map[string]interface{}{
+			"Name":      user.Name,
+			"Frequency": subscription.Frequency,
+			"Since":     since,
+			"Projects":  projects,
+			"Link":      appURL(),
+		})
+		if err != nil {
+			return err
+		}
+		msg.To = user.Email
+		if err := s.sendEmail(msg); err != nil {
+			return err
+		}
+	}
+	return s.Repo.MarkDigestSent(subscription.ID, now)
+}
+
+// SendDueDigests sends the digests whose period has elapsed
+func (s *Service) SendDueDigests(now time.Time) error {
+	if s.Mailer == nil {
+		return nil
+	}
+	subscriptions, err := s.Repo.GetDigestSubscriptions()
+	if err != nil {
+		logrus.WithFields(logrus.Fields{
+			"error": err,
+		}).Error("Failed to retrieve digest subscriptions")
+		return err
+	}
+	sent := 0
+	for _, subscription := range subscriptions {
+		if subscription.LastSentAt != nil && now.Sub(*subscription.LastSentAt) < digestPeriods[subscription.Frequency] {
+			continue
+		}
+		if err := s.sendDigest(subscription, now); err != nil {
+			logrus.WithFields(logrus.Fields{
+				"userID": subscription.UserID,
+				"error":  err,
+			}).Error("Failed to send digest")
+			continue
+		}
+		sent++
+	}
+	logrus.WithFields(logrus.Fields{
+		"count": sent,
+	}).Info("Digests processed")
+	return nil
+}
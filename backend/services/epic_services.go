@@ -0,0 +1,355 @@
+// Epic-related services (epics spanning projects, their rollup and timeline)
+package services
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"work-management/dto"
+	"work-management/models"
+	"work-management/repository"
+
+	"github.com/sirupsen/logrus"
+	"gorm.io/gorm"
+)
+
+func validateEpicInput(input *dto.EpicInput) error {
+	input.Name = strings.TrimSpace(input.Name)
+	if input.Name == "" {
+		return invalidInput("epic name is required")
+	}
+	if input.StartDate != nil && input.TargetDate != nil && input.TargetDate.Before(*input.StartDate) {
+		return invalidInput("target date is before the start date")
+	}
+	return nil
+}
+
+func (s *Service) GetEpics() ([]models.Epic, error) {
+	epics, err := s.Repo.GetEpics()
+	if err != nil {
+		logrus.WithFields(logrus.Fields{
+			"error": err,
+		}).Error("Failed to retrieve epics")
+		return nil, err
+	}
+	return epics, nil
+}
+
+func (s *Service) GetEpic(epicID uint) (*models.Epic, error) {
+	return s.Repo.GetEpicByID(epicID)
+}
+
+func (s *Service) CreateEpic(ownerID uint, input dto.EpicInput) (*models.Epic, error) {
+	if err := validateEpicInput(&input); err != nil {
+		return nil, err
+	}
+	epic := &models.Epic{
+		Name:        input.Name,
+		Description: input.Description,
+		OwnerID:     ownerID,
+		StartDate:   input.StartDate,
+		TargetDate:  input.TargetDate,
+	}
+	if err := s.Repo.CreateEpic(epic); err != nil {
+		logrus.WithFields(logrus.Fields{
+			"ownerID": ownerID,
+			"error":   err,
+		}).Error("Failed to create epic")
+		return nil, err
+	}
+	logrus.WithFields(logrus.Fields{
+		"epicID": epic.ID,
+	}).Info("Epic created successfully")
+	return epic, nil
+}
+
+// epicForOwner loads an epic that the actor may change: its owner or a
+// system administrator
+func (s *Service) epicForOwner(actorID, epicID uint) (*models.Epic, error) {
+	epic, err := s.Repo.GetEpicByID(epicID)
+	if err != nil {
+		return nil, err
+	}
+	if epic.OwnerID != actorID && !s.IsSystemAdmin(actorID) {
+		return nil, fmt.Errorf("%w: only the owner can change an epic", ErrForbidden)
+	}
+	return epic, nil
+}
+
+func (s *Service) UpdateEpic(actorID, epicID uint, input dto.EpicInput) (*models.Epic, error) {
+	if err := validateEpicInput(&input); err != nil {
+		return nil, err
+	}
+	epic, err := s.epicForOwner(actorID, epicID)
+	if err != nil {
+		return nil, err
+	}
+	epic.Name = input.Name
+	epic.Description = input.Description
+	epic.StartDate = input.StartDate
+	epic.TargetDate = input.TargetDate
+	if err := s.Repo.UpdateEpic(epic); err != nil {
+		logrus.WithFields(logrus.Fields{
+			"epicID": epicID,
+			"error":  err,
+		}).Error("Failed to update epic")
+		return nil, err
+	}
+	return epic, nil
+}
+
+func (s *Service) DeleteEpic(actorID, epicID uint) error {
+	if _, err := s.epicForOwner(actorID, epicID); err != nil {
+		return err
+	}
+	if err := s.Repo.DeleteEpic(epicID); err != nil {
+		logrus.WithFields(logrus.Fields{
+			"epicID": epicID,
+			"error":  err,
+		}).Error("Failed to delete epic")
+		return err
+	}
+	return nil
+}
+
+// GetEpicTasks lists the tasks of an epic in the projects the user belongs to
+func (s *Service) GetEpicTasks(userID, epicID uint, filter dto.TaskFilter) ([]models.Task, error) {
+	filter.EpicID = epicID
+	filter.VisibleToUserID = userID
+	return s.GetTasks(filter)
+}
+
+// epicTaskForEditor loads a task and checks the actor may modify its project,
+// which is required to add it to or take it out of an epic
+func (s *Service) epicTaskForEditor(actorID, taskID uint) (*models.Task, error) {
+	task, err := s.Repo.GetTaskByID(taskID)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, invalidInput("task %d not found", taskID)
+		}
+		return nil, err
+	}
+	canModify, err := s.CanModifyProject(actorID, task.ProjectID)
+	if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, err
+	}
+	if !canModify {
+		return nil, fmt.Errorf("%w: cannot modify task %d", ErrForbidden, taskID)
+	}
+	return task, nil
+}
+
+// AddEpicTasks adds tasks of any projects the actor may modify to an epic,
+// taking them out of any other one
+func (s *Service) AddEpicTasks(actorID, epicID uint, taskIDs []uint) (*models.Epic, error) {
+	epic, err := s.Repo.GetEpicByID(epicID)
+	if err != nil {
+		return nil, err
+	}
+	var tasks []*models.Task
+	for _, taskID := range taskIDs {
+		task, err := s.epicTaskForEditor(actorID, taskID)
+		if err != nil {
+			return nil, err
+		}
+		if task.EpicID == nil || *task.EpicID != epicID {
+			tasks = append(tasks, task)
+		}
+	}
+	err = s.transaction(func(repo *repository.Repository) error {
+		for _, task := range tasks {
+			if err := repo.SetTasksEpic([]uint{task.ID}, &epic.ID); err != nil {
+				return err
+			}
+			if err := s.recordEvent(repo, Event{
+				Type:      EventTaskUpdated,
+				ProjectID: task.ProjectID,
+				TaskID:    task.ID,
+				ActorID:   actorID,
+				Message:   fmt.Sprintf("added task %q to epic %q", task.Title, epic.Name),
+				Data:      map[string]interface{}{"epic_id": epic.ID},
+			}); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		logrus.WithFields(logrus.Fields{
+			"epicID": epicID,
+			"error":  err,
+		}).Error("Failed to add tasks to epic")
+		return nil, err
+	}
+	return epic, nil
+}
+
+func (s *Service) RemoveEpicTask(actorID, epicID, taskID uint) error {
+	epic, err := s.Repo.GetEpicByID(epicID)
+	if err != nil {
+		return err
+	}
+	task, err := s.epicTaskForEditor(actorID, taskID)
+	if err != nil {
+		return err
+	}
+	if task.EpicID == nil || *task.EpicID != epicID {
+		return invalidInput("task is not part of the epic")
+	}
+	err = s.transaction(func(repo *repository.Repository) error {
+		if err := repo.SetTasksEpic([]uint{taskID}, nil); err != nil {
+			return err
+		}
+		return s.recordEvent(repo, Event{
+			Type:      EventTaskUpdated,
+			ProjectID: task.ProjectID,
+			TaskID:    taskID,
+			ActorID:   actorID,
+			Message:   fmt.Sprintf("removed task %q from epic %q", task.Title, epic.Name),
+			Data:      map[string]interface{}{"epic_id": nil},
+		})
+	})
+	if err != nil {
+		logrus.WithFields(logrus.Fields{
+			"epicID": epicID,
+			"taskID": taskID,
+			"error":  err,
+		}).Error("Failed to remove task from epic")
+		return err
+	}
+	return nil
+}
+
+// epicTaskCategories returns the status category of each task, using the
+// workflow of the task's own project
+func (s *Service) epicTaskCategories(tasks []models.Task) ([]string, error) {
+	projects := map[uint]func(string) string{}
+	categories := make([]string, len(tasks))
+	for i, task := range tasks {
+		category, ok := projects[task.ProjectID]
+		if !ok {
+			var err error
+			if category, err = s.statusCategories(s.Repo, task.ProjectID); err != nil {
+				return nil, err
+			}
+			projects[task.ProjectID] = category
+		}
+		categories[i] = category(task.Status)
+	}
+	return categories, nil
+}
+
+// GetEpicRollup sums up the tasks of an epic that the user can see, overall
+// and per project
+func (s *Service) GetEpicRollup(userID, epicID uint) (*dto.EpicRollup, error) {
+	epic, err := s.Repo.GetEpicByID(epicID)
+	if err != nil {
+		return nil, err
+	}
+	tasks, err := s.GetEpicTasks(userID, epicID, dto.TaskFilter{})
+	if err != nil {
+		return nil, err
+	}
+	categories, err := s.epicTaskCategories(tasks)
+	if err != nil {
+		return nil, err
+	}
+	rollup := &dto.EpicRollup{Epic: *epic, TotalTasks: len(tasks), Projects: []dto.EpicProjectRollup{}}
+	projects := map[uint]*dto.EpicProjectRollup{}
+	for i, task := range tasks {
+		project, ok := projects[task.ProjectID]
+		if !ok {
+			project = &dto.EpicProjectRollup{ProjectID: task.ProjectID, ProjectName: task.Project.Name}
+			projects[task.ProjectID] = project
+		}
+		project.TotalTasks++
+		project.TotalPoints += task.StoryPoints
+		rollup.TotalPoints += task.StoryPoints
+		rollup.OriginalEstimate += task.OriginalEstimate
+		switch categories[i] {
+		case models.CategoryDone:
+			rollup.DoneTasks++
+			rollup.DonePoints += task.StoryPoints
+			project.DoneTasks++
+			project.DonePoints += task.StoryPoints
+			continue
+		case models.CategoryInProgress:
+			rollup.InProgressTasks++
+		default:
+			rollup.ToDoTasks++
+		}
+		rollup.RemainingEstimate += task.RemainingEstimate
+		project.RemainingEstimate += task.RemainingEstimate
+	}
+	switch {
+	case rollup.TotalPoints > 0:
+		rollup.Percent = 100 * float64(rollup.DonePoints) / float64(rollup.TotalPoints)
+	case rollup.TotalTasks > 0:
+		rollup.Percent = 100 * float64(rollup.DoneTasks) / float64(rollup.TotalTasks)
+	}
+	for _, project := range projects {
+		rollup.Projects = append(rollup.Projects, *project)
+	}
+	sort.Slice(rollup.Projects, func(i, j int) bool {
+		return rollup.Projects[i].ProjectID < rollup.Projects[j].ProjectID
+	})
+	return rollup, nil
+}
+
+// GetEpicTimeline lays out the tasks of an epic that the user can see, each
+// from its start date (or creation) to its due date or completion, by start
+func (s *Service) GetEpicTimeline(userID, epicID uint) (*dto.Timeline, error) {
+	epic, err := s.Repo.GetEpicByID(epicID)
+	if err != nil {
+		return nil, err
+	}
+	tasks, err := s.GetEpicTasks(userID, epicID, dto.TaskFilter{})
+	if err != nil {
+		return nil, err
+	}
+	categories, err := s.epicTaskCategories(tasks)
+	if err != nil {
+		return nil, err
+	}
+	timeline := &dto.Timeline{Start: epic.StartDate, End: epic.TargetDate, Items: []dto.TimelineItem{}}
+	extend := func(t time.Time) {
+		if timeline.Start == nil || t.Before(*timeline.Start) {
+			timeline.Start = &t
+		}
+		if timeline.End == nil || t.After(*timeline.End) {
+			timeline.End = &t
+		}
+	}
+	for i, task := range tasks {
+		item := dto.TimelineItem{
+			TaskID:      task.ID,
+			Title:       task.Title,
+			ProjectID:   task.ProjectID,
+			ProjectName: task.Project.Name,
+			Status:      task.Status,
+			Category:    categories[i],
+			Start:       task.CreatedAt,
+		}
+		if task.StartDate != nil {
+			item.Start = *task.StartDate
+		}
+		if !task.DueDate.IsZero() {
+			item.End = &task.DueDate
+		}
+		if task.CompletedAt != nil && (item.End == nil || task.CompletedAt.After(*item.End)) {
+			item.End = task.CompletedAt
+		}
+		extend(item.Start)
+		if item.End != nil {
+			extend(*item.End)
+		}
+		timeline.Items = append(timeline.Items, item)
+	}
+	sort.SliceStable(timeline.Items, func(i, j int) bool {
+		return timeline.Items[i].Start.Before(timeline.Items[j].Start)
+	})
+	return timeline, nil
+}
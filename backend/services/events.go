@@ -0,0 +1,181 @@
+// Domain events recorded by service mutations and published through the outbox
+package services
+
+import (
+	"encoding/json"
+	"time"
+
+	"work-management/models"
+	"work-management/repository"
+
+	"github.com/sirupsen/logrus"
+)
+
+// Event types recorded by the service layer
+const (
+	EventTaskCreated         = "task.created"
+	EventTaskUpdated         = "task.updated"
+	EventTaskDeleted         = "task.deleted" // Moved to the trash
+	EventTaskRestored        = "task.restored"
+	EventTaskAssigned        = "task.assigned"
+	EventTaskUnassigned      = "task.unassigned"
+	EventTaskDueSoon         = "task.due_soon"
+	EventTaskOverdue         = "task.overdue"
+	EventTaskEscalated       = "task.escalated"
+	EventTaskSLABreached     = "task.sla_breached"
+	EventCommentCreated      = "comment.created"
+	EventCommentMentioned    = "comment.mentioned"
+	EventProjectCreated      = "project.created"
+	EventProjectUpdated      = "project.updated"
+	EventProjectDeleted      = "project.deleted" // Moved to the trash
+	EventProjectRestored     = "project.restored"
+	EventProjectArchived     = "project.archived"
+	EventProjectUnarchived   = "project.unarchived"
+	EventProjectOwnerChanged = "project.owner_changed"
+	EventMemberAdded         = "member.added"
+	EventMemberRoleChanged   = "member.role_changed"
+	EventMemberRemoved       = "member.removed"
+	EventSprintStarted       = "sprint.started"
+	EventSprintClosed        = "sprint.closed"
+	EventMilestoneReleased   = "milestone.released"
+	EventAutomationNotice    = "automation.notice" // Sent by the notify action of an automation rule
+)
+
+// EventAutomationTriggered is the event of the deliveries sent by the webhook
+// action of an automation rule; it goes to the chosen webhook only
+const EventAutomationTriggered = "automation.triggered"
+
+// EventTypes lists every event type, e.g. for validating webhook subscriptions
+var EventTypes = []string{
+	EventTaskCreated, EventTaskUpdated, EventTaskDeleted, EventTaskRestored, EventTaskAssigned, EventTaskUnassigned,
+	EventTaskDueSoon, EventTaskOverdue, EventTaskEscalated, EventTaskSLABreached,
+	EventCommentCreated, EventCommentMentioned,
+	EventProjectCreated, EventProjectUpdated, EventProjectDeleted, EventProjectRestored,
+	EventProjectArchived, EventProjectUnarchived, EventProjectOwnerChanged,
+	EventMemberAdded, EventMemberRoleChanged, EventMemberRemoved,
+	EventSprintStarted, EventSprintClosed, EventMilestoneReleased,
+	EventAutomationNotice,
+}
+
+func isEventType(eventType string) bool {
+	for _, t := range EventTypes {
+		if t == eventType {
+			return true
+		}
+	}
+	return false
+}
+
+// Event describes a change made through the service layer. Events are
+// stored in the outbox with the change itself and published to the
+// subscribers afterwards, so they are encoded as JSON in between.
+type Event struct {
+	Type      string `json:"type"`
+	ProjectID uint   `json:"project_id"`
+	TaskID    uint   `json:"task_id"`
+	ActorID   uint   `json:"actor_id"` // 0 when the system raised the event
+	// Message describes the change for the project activity feed, e.g.
+	// `created task "Fix login"`.
+	Message string `json:"message"`
+	// Recipients are the users the event concerns directly (the new assignee,
+	// the mentioned users, ...) and Notice is the text addressed to them,
+	// e.g. `assigned you to "Fix login"`.
+	Recipients []uint                 `json:"recipients"`
+	Notice     string                 `json:"notice"`
+	Data       map[string]interface{} `json:"data"`
+	OccurredAt time.Time              `json:"occurred_at"`
+}
+
+// EventHandler reacts to a published event. An error makes the relay retry
+// the event later, for this handler only, so handlers must tolerate seeing
+// an event more than once.
+type EventHandler func(event Event) error
+
+type subscriber struct {
+	name   string
+	handle EventHandler
+}
+
+// Subscribe registers a handler for every event published from now on. The
+// name identifies the handler in the outbox's delivery bookkeeping and must
+// stay the same across releases. Handlers are added before the job queue is
+// started.
+func (s *Service) Subscribe(name string, handler EventHandler) {
+	s.subscribers = append(s.subscribers, subscriber{name: name, handle: handler})
+}
+
+// SearchIndexer keeps an external search index in step with the changes
+// made through the service layer
+type SearchIndexer interface {
+	IndexEvent(event Event) error
+}
+
+// UseSearchIndexer subscribes a search index to the events; like Subscribe
+// it is called before the job queue is started
+func (s *Service) UseSearchIndexer(indexer SearchIndexer) {
+	s.Subscribe("search", indexer.IndexEvent)
+}
+
+// aggregate returns the entity an event belongs to; events of the same
+// aggregate are published in the order they were recorded
+func (e Event) aggregate() (string, uint) {
+	if e.TaskID != 0 {
+		return "task", e.TaskID
+	}
+	return "project", e.ProjectID
+}
+
+// recordEvent stores an event in the outbox and adds it to the project's
+// activity feed, using repo so that both are part of the transaction making
+// the change. The relay publishes it once the transaction commits.
+func (s *Service) recordEvent(repo *repository.Repository, event Event) error {
+	if event.OccurredAt.IsZero() {
+		event.OccurredAt = time.Now()
+	}
+	if event.ProjectID != 0 && event.ActorID != 0 && event.Message != "" {
+		// Failures are logged by the repository
+		if err := repo.LogActivity(event.ProjectID, event.ActorID, event.Message); err != nil {
+			return err
+		}
+	}
+	payload, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+	aggregateType, aggregateID := event.aggregate()
+	record := &models.OutboxEvent{
+		AggregateType: aggregateType,
+		AggregateID:   aggregateID,
+		Type:          event.Type,
+		Payload:       payload,
+		Status:        models.OutboxPending,
+		CreatedAt:     event.OccurredAt,
+	}
+	if err := repo.CreateOutboxEvent(record); err != nil {
+		logrus.WithFields(logrus.Fields{
+			"event": event.Type,
+			"error": err,
+		}).Error("Failed to record event")
+		return err
+	}
+	logrus.WithFields(logrus.Fields{
+		"outboxID":  record.ID,
+		"event":     event.Type,
+		"projectID": event.ProjectID,
+		"taskID":    event.TaskID,
+		"actorID":   event.ActorID,
+	}).Debug("Event recorded")
+	return nil
+}
+
+// transaction runs fn in a database transaction and, once it has committed,
+// wakes the outbox relay to publish the events fn recorded
+func (s *Service) transaction(fn func(repo *repository.Repository) error) error {
+	if err := s.Repo.Transaction(fn); err != nil {
+		return err
+	}
+	if s.wakeRelay != nil {
+		s.wakeRelay()
+	}
+	return nil
+}
@@ -0,0 +1,129 @@
+// Background job services (job registration and queue administration)
+package services
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"work-management/jobs"
+	"work-management/mailer"
+	"work-management/models"
+	"work-management/repository"
+
+	"github.com/sirupsen/logrus"
+)
+
+// Background job types
+const (
+	JobSendEmail      = "email.send"
+	JobSendDigests    = "email.digests"
+	JobDeliverWebhook = "webhook.deliver"
+	JobCheckDueDates  = "tasks.due_dates"
+	JobCleanupOutbox  = "outbox.cleanup"
+	// JobGenerateOccurrences creates the occurrences of recurring tasks on a
+	// fixed schedule
+	JobGenerateOccurrences = "tasks.recurrences"
+	JobPurgeTrash          = "trash.purge"
+	JobCheckSLAs           = "sla.breaches"
+	JobCleanupAutomation   = "automation.cleanup"
+)
+
+// registerJobs adds the handlers of the service's job types to the queue,
+// along with the periodic jobs and the outbox relay
+func (s *Service) registerJobs() {
+	jobs.Register(s.Jobs, JobSendEmail, "email", func(ctx context.Context, msg mailer.Message) error {
+		return s.Mailer.Send(msg)
+	})
+	jobs.Register(s.Jobs, JobDeliverWebhook, "webhooks", s.runWebhookDelivery)
+	jobs.Register(s.Jobs, JobSendDigests, "scheduled", func(ctx context.Context, _ struct{}) error {
+		return s.SendDueDigests(time.Now())
+	})
+	jobs.Register(s.Jobs, JobCheckDueDates, "scheduled", func(ctx context.Context, _ struct{}) error {
+		return s.CheckDueDates(time.Now())
+	})
+	jobs.Register(s.Jobs, JobGenerateOccurrences, "scheduled", func(ctx context.Context, _ struct{}) error {
+		return s.GenerateScheduledOccurrences(time.Now())
+	})
+	jobs.Register(s.Jobs, JobCleanupOutbox, "scheduled", func(ctx context.Context, _ struct{}) error {
+		return s.CleanupOutbox(time.Now())
+	})
+	jobs.Register(s.Jobs, JobPurgeTrash, "scheduled", func(ctx context.Context, _ struct{}) error {
+		return s.PurgeExpiredTrash(time.Now())
+	})
+	jobs.Register(s.Jobs, JobCheckSLAs, "scheduled", func(ctx context.Context, _ struct{}) error {
+		return s.CheckSLABreaches(time.Now())
+	})
+	jobs.Register(s.Jobs, JobCleanupAutomation, "scheduled", func(ctx context.Context, _ struct{}) error {
+		return s.CleanupAutomationRuns(time.Now())
+	})
+	s.Jobs.SetConcurrency("email", 2)
+	s.Jobs.SetConcurrency("webhooks", 4)
+	s.Jobs.Every(dueDateCheckInterval, JobCheckDueDates)
+	s.Jobs.Every(time.Hour, JobSendDigests)
+	s.Jobs.Every(occurrenceCheckInterval, JobGenerateOccurrences)
+	s.Jobs.Every(24*time.Hour, JobCleanupOutbox)
+	s.Jobs.Every(24*time.Hour, JobPurgeTrash)
+	s.Jobs.Every(slaCheckInterval, JobCheckSLAs)
+	s.Jobs.Every(24*time.Hour, JobCleanupAutomation)
+	s.wakeRelay = s.Jobs.Loop(outboxPollInterval, s.relayOutbox)
+}
+
+// IsSystemAdmin reports whether the user administers the whole installation
+func (s *Service) IsSystemAdmin(userID uint) bool {
+	user, err := s.Repo.GetUserByID(userID)
+	return err == nil && user.IsAdmin
+}
+
+// GetJobs lists the most recent jobs, optionally of one status and queue
+func (s *Service) GetJobs(status, queue string, limit int) ([]models.Job, error) {
+	switch status {
+	case "", models.JobPending, models.JobRunning, models.JobSucceeded, models.JobDead:
+	default:
+		return nil, invalidInput("unknown job status %q", status)
+	}
+	jobList, err := s.Repo.GetJobs(status, queue, limit)
+	if err != nil {
+		logrus.WithFields(logrus.Fields{
+			"status": status,
+			"queue":  queue,
+			"error":  err,
+		}).Error("Failed to retrieve jobs")
+		return nil, err
+	}
+	return jobList, nil
+}
+
+func (s *Service) GetJobStats() ([]repository.JobCount, error) {
+	counts, err := s.Repo.CountJobs()
+	if err != nil {
+		logrus.WithFields(logrus.Fields{
+			"error": err,
+		}).Error("Failed to count jobs")
+		return nil, err
+	}
+	return counts, nil
+}
+
+// RetryJob puts a dead job back in its queue with a fresh set of attempts
+func (s *Service) RetryJob(jobID uint) (*models.Job, error) {
+	job, err := s.Repo.GetJobByID(jobID)
+	if err != nil {
+		return nil, err
+	}
+	if job.Status != models.JobDead {
+		return nil, invalidInput("only dead jobs can be retried, job %d is %s", jobID, job.Status)
+	}
+	if err := s.Repo.RetryDeadJob(jobID, time.Now()); err != nil {
+		logrus.WithFields(logrus.Fields{
+			"jobID": jobID,
+			"error": err,
+		}).Error("Failed to retry job")
+		return nil, fmt.Errorf("retry job %d: %w", jobID, err)
+	}
+	logrus.WithFields(logrus.Fields{
+		"jobID": jobID,
+		"type":  job.Type,
+	}).Info("Dead job queued for retry")
+	return s.Repo.GetJobByID(jobID)
+}
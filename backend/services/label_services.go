@@ -0,0 +1,243 @@
+// Label-related services (CreateLabel, AddLabelToTask, etc.)
+package services
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"work-management/models"
+	"work-management/repository"
+
+	"github.com/sirupsen/logrus"
+	"gorm.io/gorm"
+)
+
+var labelColorPattern = regexp.MustCompile(`^#[0-9a-fA-F]{6}$`)
+
+// defaultLabelColor is used when a label is created without a color
+const defaultLabelColor = "#9b87f6"
+
+func validateLabel(name, color string) error {
+	if strings.TrimSpace(name) == "" {
+		return invalidInput("label name is required")
+	}
+	if !labelColorPattern.MatchString(color) {
+		return invalidInput("label color must be a hex value like #1a2b3c")
+	}
+	return nil
+}
+
+// checkLabelName fails with ErrConflict if another label of the project has
+// the name, ignoring case, as automations look labels up by name
+func (s *Service) checkLabelName(projectID, labelID uint, name string) error {
+	existing, err := s.Repo.GetLabelByName(projectID, strings.TrimSpace(name))
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	if existing.ID != labelID {
+		return labelNameConflict(existing.Name)
+	}
+	return nil
+}
+
+// labelNameConflict is the error for a name clashing with another label's,
+// found before saving or raised by the unique index when saving
+func labelNameConflict(name string) error {
+	return fmt.Errorf("%w: label %q already exists in the project", ErrConflict, name)
+}
+
+func (s *Service) CreateLabel(projectID uint, name, color string) (*models.Label, error) {
+	if color == "" {
+		color = defaultLabelColor
+	}
+	if err := validateLabel(name, color); err != nil {
+		logrus.WithFields(logrus.Fields{
+			"projectID": projectID,
+			"error":     err,
+		}).Warn("Invalid label")
+		return nil, err
+	}
+	if err := s.checkLabelName(projectID, 0, name); err != nil {
+		return nil, err
+	}
+	label := &models.Label{
+		ProjectID: projectID,
+		Name:      strings.TrimSpace(name),
+		Color:     color,
+	}
+	if err := s.Repo.CreateLabel(label); err != nil {
+		if errors.Is(err, gorm.ErrDuplicatedKey) {
+			return nil, labelNameConflict(label.Name)
+		}
+		logrus.WithFields(logrus.Fields{
+			"projectID": projectID,
+			"name":      name,
+			"error":     err,
+		}).Error("Failed to create label")
+		return nil, err
+	}
+	logrus.WithFields(logrus.Fields{
+		"labelID":   label.ID,
+		"projectID": projectID,
+	}).Info("Label created successfully")
+	return label, nil
+}
+
+func (s *Service) GetLabelsByProjectID(projectID uint) ([]models.Label, error) {
+	labels, err := s.Repo.GetLabelsByProjectID(projectID)
+	if err != nil {
+		logrus.WithFields(logrus.Fields{
+			"projectID": projectID,
+			"error":     err,
+		}).Error("Failed to retrieve labels for project")
+		return nil, err
+	}
+	return labels, nil
+}
+
+// GetProjectLabel returns a label only if it belongs to the given project
+func (s *Service) GetProjectLabel(projectID, labelID uint) (*models.Label, error) {
+	label, err := s.Repo.GetLabelByID(labelID)
+	if err != nil {
+		logrus.WithFields(logrus.Fields{
+			"labelID": labelID,
+			"error":   err,
+		}).Warn("Label not found")
+		return nil, err
+	}
+	if label.ProjectID != projectID {
+		logrus.WithFields(logrus.Fields{
+			"labelID":   labelID,
+			"projectID": projectID,
+		}).Warn("Label belongs to another project")
+		return nil, invalidInput("label does not belong to this project")
+	}
+	return label, nil
+}
+
+func (s *Service) UpdateLabel(projectID, labelID uint, name, color string) (*models.Label, error) {
+	label, err := s.GetProjectLabel(projectID, labelID)
+	if err != nil {
+		return nil, err
+	}
+	if color == "" {
+		color = label.Color
+	}
+	if err := validateLabel(name, color); err != nil {
+		logrus.WithFields(logrus.Fields{
+			"labelID": labelID,
+			"error":   err,
+		}).Warn("Invalid label")
+		return nil, err
+	}
+	if err := s.checkLabelName(projectID, labelID, name); err != nil {
+		return nil, err
+	}
+	label.Name = strings.TrimSpace(name)
+	label.Color = color
+	if err := s.Repo.UpdateLabel(label); err != nil {
+		if errors.Is(err, gorm.ErrDuplicatedKey) {
+			return nil, labelNameConflict(label.Name)
+		}
+		logrus.WithFields(logrus.Fields{
+			"labelID": labelID,
+			"error":   err,
+		}).Error("Failed to update label")
+		return nil, err
+	}
+	logrus.WithFields(logrus.Fields{
+		"labelID": labelID,
+	}).Info("Label updated successfully")
+	return label, nil
+}
+
+func (s *Service) DeleteLabel(projectID, labelID uint) error {
+	if _, err := s.GetProjectLabel(projectID, labelID); err != nil {
+		return err
+	}
+	if err := s.Repo.DeleteLabel(labelID); err != nil {
+		logrus.WithFields(logrus.Fields{
+			"labelID": labelID,
+			"error":   err,
+		}).Error("Failed to delete label")
+		return err
+	}
+	logrus.WithFields(logrus.Fields{
+		"labelID": labelID,
+	}).Info("Label deleted successfully")
+	return nil
+}
+
+// AddLabelToTask puts a label of the task's project on the task
+func (s *Service) AddLabelToTask(actorID, taskID, labelID uint) (*models.Task, error) {
+	return s.changeTaskLabel(actorID, taskID, labelID, true)
+}
+
+func (s *Service) RemoveLabelFromTask(actorID, taskID, labelID uint) (*models.Task, error) {
+	return s.changeTaskLabel(actorID, taskID, labelID, false)
+}
+
+// changeTaskLabel adds or removes a label of a task; a change that happened
+// is recorded with the label's name, so that automations can react to it
+func (s *Service) changeTaskLabel(actorID, taskID, labelID uint, add bool) (*models.Task, error) {
+	task, err := s.Repo.GetTaskByID(taskID)
+	if err != nil {
+		logrus.WithFields(logrus.Fields{
+			"taskID": taskID,
+			"error":  err,
+		}).Warn("Task not found for labeling")
+		return nil, err
+	}
+	label, err := s.GetProjectLabel(task.ProjectID, labelID)
+	if err != nil {
+		return nil, err
+	}
+	had := false
+	for _, existing := range task.Labels {
+		had = had || existing.ID == labelID
+	}
+	err = s.transaction(func(repo *repository.Repository) error {
+		if had == add {
+			return nil
+		}
+		message, key := fmt.Sprintf("added label %q to task %q", label.Name, task.Title), "label_added"
+		if add {
+			if err := repo.AddLabelToTask(taskID, labelID); err != nil {
+				return err
+			}
+		} else {
+			if err := repo.RemoveLabelFromTask(taskID, labelID); err != nil {
+				return err
+			}
+			message, key = fmt.Sprintf("removed label %q from task %q", label.Name, task.Title), "label_removed"
+		}
+		return s.recordEvent(repo, Event{
+			Type:      EventTaskUpdated,
+			ProjectID: task.ProjectID,
+			TaskID:    taskID,
+			ActorID:   actorID,
+			Message:   message,
+			Data:      map[string]interface{}{key: label.Name, "label_id": labelID},
+		})
+	})
+	if err != nil {
+		logrus.WithFields(logrus.Fields{
+			"taskID":  taskID,
+			"labelID": labelID,
+			"add":     add,
+			"error":   err,
+		}).Error("Failed to change task label")
+		return nil, err
+	}
+	logrus.WithFields(logrus.Fields{
+		"taskID":  taskID,
+		"labelID": labelID,
+		"add":     add,
+	}).Info("Task label changed successfully")
+	return s.Repo.GetTaskByID(taskID)
+}
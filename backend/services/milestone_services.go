@@ -0,0 +1,376 @@
+// Milestone-related services (milestones, their progress and release notes)
+package services
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"work-management/calendar"
+	"work-management/dto"
+	"work-management/models"
+	"work-management/repository"
+
+	"github.com/sirupsen/logrus"
+	"gorm.io/gorm"
+)
+
+// Milestone health, see dto.MilestoneProgress
+const (
+	HealthCompleted = "completed"
+	HealthOnTrack   = "on_track"
+	HealthAtRisk    = "at_risk"
+	HealthOverdue   = "overdue"
+)
+
+func validateMilestoneInput(input *dto.MilestoneInput) error {
+	input.Name = strings.TrimSpace(input.Name)
+	if input.Name == "" {
+		return invalidInput("milestone name is required")
+	}
+	return nil
+}
+
+// milestoneProgress sums up the tasks of a milestone as of now; category
+// gives the status category of a task and cal the project's working time
+func milestoneProgress(milestone *models.Milestone, tasks []models.Task, category func(string) string, cal *calendar.Calendar, now time.Time) *dto.MilestoneProgress {
+	progress := &dto.MilestoneProgress{Milestone: *milestone, TotalTasks: len(tasks)}
+	assignees := map[uint]bool{}
+	for _, task := range tasks {
+		progress.TotalPoints += task.StoryPoints
+		switch category(task.Status) {
+		case models.CategoryDone:
+			progress.DoneTasks++
+			progress.DonePoints += task.StoryPoints
+			continue
+		case models.CategoryInProgress:
+			progress.InProgressTasks++
+		default:
+			progress.ToDoTasks++
+		}
+		progress.RemainingMinutes += task.RemainingEstimate
+		if task.UserID != 0 {
+			assignees[task.UserID] = true
+		}
+	}
+	switch {
+	case progress.TotalPoints > 0:
+		progress.Percent = 100 * float64(progress.DonePoints) / float64(progress.TotalPoints)
+	case progress.TotalTasks > 0:
+		progress.Percent = 100 * float64(progress.DoneTasks) / float64(progress.TotalTasks)
+	}
+	// Each person working on open tasks works the project's hours on every
+	// working day left, today included
+	people := max(len(assignees), 1)
+	progress.AvailableMinutes = cal.WorkingDaysIn(now, milestone.TargetDate) * (cal.DayEnd - cal.DayStart) * people
+	endOfTargetDay := time.Date(milestone.TargetDate.Year(), milestone.TargetDate.Month(), milestone.TargetDate.Day(), 0, 0, 0, 0, milestone.TargetDate.Location()).AddDate(0, 0, 1)
+	switch {
+	case milestone.ReleasedAt != nil || (progress.TotalTasks > 0 && progress.DoneTasks == progress.TotalTasks):
+		progress.Health = HealthCompleted
+	case !now.Before(endOfTargetDay):
+		progress.Health = HealthOverdue
+	case progress.RemainingMinutes > progress.AvailableMinutes:
+		progress.Health = HealthAtRisk
+	default:
+		progress.Health = HealthOnTrack
+	}
+	return progress
+}
+
+// getMilestoneProgress loads the tasks of a milestone and sums them up
+func (s *Service) getMilestoneProgress(milestone *models.Milestone, category func(string) string, cal *calendar.Calendar) (*dto.MilestoneProgress, error) {
+	tasks, err := s.Repo.GetMilestoneTasks(milestone.ID)
+	if err != nil {
+		logrus.WithFields(logrus.Fields{
+			"milestoneID": milestone.ID,
+			"error":       err,
+		}).Error("Failed to load milestone tasks")
+		return nil, err
+	}
+	return milestoneProgress(milestone, tasks, category, cal, time.Now()), nil
+}
+
+// GetMilestones lists the milestones of a project, by target date, with
+// their progress
+func (s *Service) GetMilestones(projectID uint) ([]dto.MilestoneProgress, error) {
+	milestones, err := s.Repo.GetMilestonesByProjectID(projectID)
+	if err != nil {
+		logrus.WithFields(logrus.Fields{
+			"projectID": projectID,
+			"error":     err,
+		}).Error("Failed to retrieve milestones")
+		return nil, err
+	}
+	category, err := s.statusCategories(s.Repo, projectID)
+	if err != nil {
+		return nil, err
+	}
+	cal, err := s.projectCalendar(s.Repo, projectID)
+	if err != nil {
+		return nil, err
+	}
+	result := make([]dto.MilestoneProgress, 0, len(milestones))
+	for i := range milestones {
+		progress, err := s.getMilestoneProgress(&milestones[i], category, cal)
+		if err != nil {
+			return nil, err
+		}
+		result = append(result, *progress)
+	}
+	return result, nil
+}
+
+func (s *Service) GetMilestone(milestoneID uint) (*models.Milestone, error) {
+	return s.Repo.GetMilestoneByID(milestoneID)
+}
+
+// GetMilestoneProgress returns a milestone with its progress
+func (s *Service) GetMilestoneProgress(milestoneID uint) (*dto.MilestoneProgress, error) {
+	milestone, err := s.Repo.GetMilestoneByID(milestoneID)
+	if err != nil {
+		return nil, err
+	}
+	category, err := s.statusCategories(s.Repo, milestone.ProjectID)
+	if err != nil {
+		return nil, err
+	}
+	cal, err := s.projectCalendar(s.Repo, milestone.ProjectID)
+	if err != nil {
+		return nil, err
+	}
+	return s.getMilestoneProgress(milestone, category, cal)
+}
+
+func (s *Service) CreateMilestone(projectID uint, input dto.MilestoneInput) (*models.Milestone, error) {
+	if err := validateMilestoneInput(&input); err != nil {
+		return nil, err
+	}
+	milestone := &models.Milestone{
+		ProjectID:   projectID,
+		Name:        input.Name,
+		Description: input.Description,
+		TargetDate:  input.TargetDate,
+	}
+	if err := s.Repo.CreateMilestone(milestone); err != nil {
+		logrus.WithFields(logrus.Fields{
+			"projectID": projectID,
+			"error":     err,
+		}).Error("Failed to create milestone")
+		return nil, err
+	}
+	logrus.WithFields(logrus.Fields{
+		"projectID":   projectID,
+		"milestoneID": milestone.ID,
+	}).Info("Milestone created successfully")
+	return milestone, nil
+}
+
+func (s *Service) UpdateMilestone(milestoneID uint, input dto.MilestoneInput) (*models.Milestone, error) {
+	if err := validateMilestoneInput(&input); err != nil {
+		return nil, err
+	}
+	milestone, err := s.Repo.GetMilestoneByID(milestoneID)
+	if err != nil {
+		return nil, err
+	}
+	milestone.Name = input.Name
+	milestone.Description = input.Description
+	milestone.TargetDate = input.TargetDate
+	if err := s.Repo.UpdateMilestone(milestone); err != nil {
+		logrus.WithFields(logrus.Fields{
+			"milestoneID": milestoneID,
+			"error":       err,
+		}).Error("Failed to update milestone")
+		return nil, err
+	}
+	return milestone, nil
+}
+
+func (s *Service) DeleteMilestone(milestoneID uint) error {
+	if err := s.Repo.DeleteMilestone(milestoneID); err != nil {
+		logrus.WithFields(logrus.Fields{
+			"milestoneID": milestoneID,
+			"error":       err,
+		}).Error("Failed to delete milestone")
+		return err
+	}
+	return nil
+}
+
+// ReleaseMilestone marks a milestone as shipped
+func (s *Service) ReleaseMilestone(actorID, milestoneID uint) (*models.Milestone, error) {
+	milestone, err := s.Repo.GetMilestoneByID(milestoneID)
+	if err != nil {
+		return nil, err
+	}
+	if milestone.ReleasedAt != nil {
+		return nil, invalidInput("milestone was already released")
+	}
+	now := time.Now()
+	milestone.ReleasedAt = &now
+	err = s.transaction(func(repo *repository.Repository) error {
+		if err := repo.UpdateMilestone(milestone); err != nil {
+			return err
+		}
+		return s.recordEvent(repo, Event{
+			Type:      EventMilestoneReleased,
+			ProjectID: milestone.ProjectID,
+			ActorID:   actorID,
+			Message:   fmt.Sprintf("released milestone %q", milestone.Name),
+			Data:      map[string]interface{}{"milestone_id": milestone.ID},
+		})
+	})
+	if err != nil {
+		logrus.WithFields(logrus.Fields{
+			"milestoneID": milestoneID,
+			"error":       err,
+		}).Error("Failed to release milestone")
+		return nil, err
+	}
+	return milestone, nil
+}
+
+func (s *Service) GetMilestoneTasks(milestone *models.Milestone, filter dto.TaskFilter) ([]models.Task, error) {
+	filter.MilestoneID = milestone.ID
+	return s.GetTasksByProjectID(milestone.ProjectID, filter)
+}
+
+// AddMilestoneTasks associates tasks of the milestone's project with a
+// milestone, taking them out of any other one
+func (s *Service) AddMilestoneTasks(actorID, milestoneID uint, taskIDs []uint) (*models.Milestone, error) {
+	milestone, err := s.Repo.GetMilestoneByID(milestoneID)
+	if err != nil {
+		return nil, err
+	}
+	var tasks []*models.Task
+	for _, taskID := range taskIDs {
+		task, err := s.Repo.GetTaskByID(taskID)
+		if err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return nil, invalidInput("task %d not found", taskID)
+			}
+			return nil, err
+		}
+		if task.ProjectID != milestone.ProjectID {
+			return nil, invalidInput("task %d belongs to another project", taskID)
+		}
+		if task.MilestoneID == nil || *task.MilestoneID != milestoneID {
+			tasks = append(tasks, task)
+		}
+	}
+	err = s.transaction(func(repo *repository.Repository) error {
+		for _, task := range tasks {
+			if err := repo.SetTasksMilestone([]uint{task.ID}, &milestone.ID); err != nil {
+				return err
+			}
+			if err := s.recordEvent(repo, Event{
+				Type:      EventTaskUpdated,
+				ProjectID: task.ProjectID,
+				TaskID:    task.ID,
+				ActorID:   actorID,
+				Message:   fmt.Sprintf("added task %q to milestone %q", task.Title, milestone.Name),
+				Data:      map[string]interface{}{"milestone_id": milestone.ID},
+			}); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		logrus.WithFields(logrus.Fields{
+			"milestoneID": milestoneID,
+			"error":       err,
+		}).Error("Failed to add tasks to milestone")
+		return nil, err
+	}
+	return milestone, nil
+}
+
+func (s *Service) RemoveMilestoneTask(actorID, milestoneID, taskID uint) error {
+	milestone, err := s.Repo.GetMilestoneByID(milestoneID)
+	if err != nil {
+		return err
+	}
+	task, err := s.Repo.GetTaskByID(taskID)
+	if err != nil {
+		return err
+	}
+	if task.MilestoneID == nil || *task.MilestoneID != milestoneID {
+		return invalidInput("task is not part of the milestone")
+	}
+	err = s.transaction(func(repo *repository.Repository) error {
+		if err := repo.SetTasksMilestone([]uint{taskID}, nil); err != nil {
+			return err
+		}
+		return s.recordEvent(repo, Event{
+			Type:      EventTaskUpdated,
+			ProjectID: task.ProjectID,
+			TaskID:    taskID,
+			ActorID:   actorID,
+			Message:   fmt.Sprintf("removed task %q from milestone %q", task.Title, milestone.Name),
+			Data:      map[string]interface{}{"milestone_id": nil},
+		})
+	})
+	if err != nil {
+		logrus.WithFields(logrus.Fields{
+			"milestoneID": milestoneID,
+			"taskID":      taskID,
+			"error":       err,
+		}).Error("Failed to remove task from milestone")
+		return err
+	}
+	return nil
+}
+
+// GetReleaseNotes lists the completed tasks of a milestone grouped by label,
+// labels in alphabetical order and tasks without labels last
+func (s *Service) GetReleaseNotes(milestoneID uint) (*dto.ReleaseNotes, error) {
+	milestone, err := s.Repo.GetMilestoneByID(milestoneID)
+	if err != nil {
+		return nil, err
+	}
+	tasks, err := s.Repo.GetMilestoneTasks(milestoneID)
+	if err != nil {
+		logrus.WithFields(logrus.Fields{
+			"milestoneID": milestoneID,
+			"error":       err,
+		}).Error("Failed to load milestone tasks")
+		return nil, err
+	}
+	category, err := s.statusCategories(s.Repo, milestone.ProjectID)
+	if err != nil {
+		return nil, err
+	}
+	groups := map[string]*dto.ReleaseNotesGroup{}
+	var unlabeled []models.Task
+	for _, task := range tasks {
+		if category(task.Status) != models.CategoryDone {
+			continue
+		}
+		if len(task.Labels) == 0 {
+			unlabeled = append(unlabeled, task)
+			continue
+		}
+		for _, label := range task.Labels {
+			group, ok := groups[label.Name]
+			if !ok {
+				group = &dto.ReleaseNotesGroup{Label: label.Name, Color: label.Color}
+				groups[label.Name] = group
+			}
+			group.Tasks = append(group.Tasks, task)
+		}
+	}
+	notes := &dto.ReleaseNotes{Milestone: *milestone, Groups: []dto.ReleaseNotesGroup{}}
+	for _, group := range groups {
+		notes.Groups = append(notes.Groups, *group)
+	}
+	sort.Slice(notes.Groups, func(i, j int) bool {
+		return strings.ToLower(notes.Groups[i].Label) < strings.ToLower(notes.Groups[j].Label)
+	})
+	if len(unlabeled) > 0 {
+		notes.Groups = append(notes.Groups, dto.ReleaseNotesGroup{Tasks: unlabeled})
+	}
+	return notes, nil
+}
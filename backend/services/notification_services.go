@@ -0,0 +1,282 @@
+// Notification-related services (inbox and preferences)
+package services
+
+import (
+	"time"
+
+	"work-management/dto"
+	"work-management/models"
+
+	"github.com/sirupsen/logrus"
+)
+
+// NotificationEventTypes are the events that land in a recipient's inbox and
+// can be switched off in the user's preferences
+var NotificationEventTypes = []string{
+	EventTaskAssigned,
+	EventCommentMentioned,
+	EventCommentCreated,
+	EventTaskDueSoon,
+	EventTaskOverdue,
+	EventTaskEscalated,
+	EventTaskSLABreached,
+	EventAutomationNotice,
+	EventMemberAdded,
+	EventMemberRoleChanged,
+	EventProjectOwnerChanged,
+}
+
+// maxNotificationPageSize caps the page_size accepted by GetNotifications
+const maxNotificationPageSize = 100
+
+func isNotificationEventType(eventType string) bool {
+	for _, t := range NotificationEventTypes {
+		if t == eventType {
+			return true
+		}
+	}
+	return false
+}
+
+// notificationPreference returns the user's setting for the given type,
+// falling back to every channel enabled
+func (s *Service) notificationPreference(userID uint, eventType string) models.NotificationPreference {
+	preference := models.NotificationPreference{UserID: userID, EventType: eventType, InApp: true, Email: true}
+	preferences, err := s.Repo.GetNotificationPreferences(userID)
+	if err != nil {
+		logrus.WithFields(logrus.Fields{
+			"userID": userID,
+			"error":  err,
+		}).Error("Failed to load notification preferences")
+		return preference
+	}
+	for _, saved := range preferences {
+		if saved.EventType == eventType {
+			return saved
+		}
+	}
+	return preference
+}
+
+// noticeText is the event's notice as shown to recipients, prefixed with the
+// name of the user who caused it
+func (s *Service) noticeText(event Event) string {
+	if event.ActorID != 0 {
+		if actor, err := s.Repo.GetUserByID(event.ActorID); err == nil {
+			return actor.Name + " " + event.Notice
+		}
+	}
+	return event.Notice
+}
+
+// eventRecipients returns the event's recipients without duplicates and
+// without the user who caused it
+func eventRecipients(event Event) []uint {
+	var recipients []uint
+	seen := map[uint]bool{}
+	for _, userID := range event.Recipients {
+		if userID == 0 || userID == event.ActorID || seen[userID] {
+			continue
+		}
+		seen[userID] = true
+		recipients = append(recipients, userID)
+	}
+	return recipients
+}
+
+// deliverNotifications puts a notification in the inbox of every recipient of
+// the event, except the user who caused it. The notifications are created
+// together, so that an event retried after a failure is not delivered twice
+// to some of its recipients.
+func (s *Service) deliverNotifications(event Event) error {
+	if !isNotificationEventType(event.Type) || event.Notice == "" {
+		return nil
+	}
+	message := s.noticeText(event)
+	var notifications []models.Notification
+	for _, userID := range eventRecipients(event) {
+		if !s.notificationPreference(userID, event.Type).InApp {
+			continue
+		}
+		notifications = append(notifications, models.Notification{
+			UserID:    userID,
+			Type:      event.Type,
+			ProjectID: event.ProjectID,
+			TaskID:    event.TaskID,
+			ActorID:   event.ActorID,
+			Message:   message,
+		})
+	}
+	if len(notifications) == 0 {
+		return nil
+	}
+	if err := s.Repo.CreateNotifications(notifications); err != nil {
+		logrus.WithFields(logrus.Fields{
+			"event": event.Type,
+			"error": err,
+		}).Error("Failed to create notifications")
+		return err
+	}
+	logrus.WithFields(logrus.Fields{
+		"recipientCount": len(notifications),
+		"event":          event.Type,
+	}).Debug("Notifications created")
+	return nil
+}
+
+// GetNotifications returns a page of the user's inbox. Pages start at 1.
+func (s *Service) GetNotifications(userID uint, unreadOnly bool, page, pageSize int) (*dto.NotificationPage, error) {
+	if page < 1 {
+		page = 1
+	}
+	if pageSize < 1 || pageSize > maxNotificationPageSize {
+		return nil, invalidInput("page_size must be between 1 and %d", maxNotificationPageSize)
+	}
+	notifications, total, err := s.Repo.GetNotifications(userID, unreadOnly, (page-1)*pageSize, pageSize)
+	if err != nil {
+		logrus.WithFields(logrus.Fields{
+			"userID": userID,
+			"error":  err,
+		}).Error("Failed to retrieve notifications")
+		return nil, err
+	}
+	unread, err := s.Repo.CountUnreadNotifications(userID)
+	if err != nil {
+		logrus.WithFields(logrus.Fields{
+			"userID": userID,
+			"error":  err,
+		}).Error("Failed to count unread notifications")
+		return nil, err
+	}
+	logrus.WithFields(logrus.Fields{
+		"userID": userID,
+		"page":   page,
+		"count":  len(notifications),
+	}).Info("Notifications retrieved successfully")
+	return &dto.NotificationPage{
+		Notifications: notifications,
+		Total:         total,
+		Unread:        unread,
+		Page:          page,
+		PageSize:      pageSize,
+	}, nil
+}
+
+func (s *Service) CountUnreadNotifications(userID uint) (int64, error) {
+	count, err := s.Repo.CountUnreadNotifications(userID)
+	if err != nil {
+		logrus.WithFields(logrus.Fields{
+			"userID": userID,
+			"error":  err,
+		}).Error("Failed to count unread notifications")
+	}
+	return count, err
+}
+
+func (s *Service) MarkNotificationRead(userID, notificationID uint) error {
+	if err := s.Repo.MarkNotificationRead(userID, notificationID, time.Now()); err != nil {
+		logrus.WithFields(logrus.Fields{
+			"userID":         userID,
+			"notificationID": notificationID,
+			"error":          err,
+		}).Warn("Failed to mark notification as read")
+		return err
+	}
+	logrus.WithFields(logrus.Fields{
+		"userID":         userID,
+		"notificationID": notificationID,
+	}).Info("Notification marked as read")
+	return nil
+}
+
+// MarkAllNotificationsRead marks the whole inbox as read and returns how many
+// notifications were unread
+func (s *Service) MarkAllNotificationsRead(userID uint) (int64, error) {
+	count, err := s.Repo.MarkAllNotificationsRead(userID, time.Now())
+	if err != nil {
+		logrus.WithFields(logrus.Fields{
+			"userID": userID,
+			"error":  err,
+		}).Error("Failed to mark notifications as read")
+		return 0, err
+	}
+	logrus.WithFields(logrus.Fields{
+		"userID": userID,
+		"count":  count,
+	}).Info("All notifications marked as read")
+	return count, nil
+}
+
+// GetNotificationPreferences returns the user's setting for every
+// notification type, defaults included
+func (s *Service) GetNotificationPreferences(userID uint) ([]models.NotificationPreference, error) {
+	saved, err := s.Repo.GetNotificationPreferences(userID)
+	if err != nil {
+		logrus.WithFields(logrus.Fields{
+			"userID": userID,
+			"error":  err,
+		}).Error("Failed to retrieve notification preferences")
+		return nil, err
+	}
+	byType := map[string]models.NotificationPreference{}
+	for _, preference := range saved {
+		byType[preference.EventType] = preference
+	}
+	preferences := make([]models.NotificationPreference, 0, len(NotificationEventTypes))
+	for _, eventType := range NotificationEventTypes {
+		preference, ok := byType[eventType]
+		if !ok {
+			preference = models.NotificationPreference{UserID: userID, EventType: eventType, InApp: true, Email: true}
+		}
+		preferences = append(preferences, preference)
+	}
+	return preferences, nil
+}
+
+// UpdateNotificationPreferences changes the channels given for each type;
+// types and channels left out keep their current setting
+func (s *Service) UpdateNotificationPreferences(userID uint, input []dto.NotificationPreferenceInput) ([]models.NotificationPreference, error) {
+	current, err := s.GetNotificationPreferences(userID)
+	if err != nil {
+		return nil, err
+	}
+	byType := map[string]*models.NotificationPreference{}
+	for i := range current {
+		byType[current[i].EventType] = &current[i]
+	}
+	var changed []models.NotificationPreference
+	seen := map[string]bool{}
+	for _, change := range input {
+		preference, ok := byType[change.EventType]
+		if !ok {
+			return nil, invalidInput("unknown notification type %q", change.EventType)
+		}
+		if seen[change.EventType] {
+			return nil, invalidInput("notification type %q is listed twice", change.EventType)
+		}
+		seen[change.EventType] = true
+		if change.InApp != nil {
+			preference.InApp = *change.InApp
+		}
+		if change.Email != nil {
+			preference.Email = *change.Email
+		}
+		changed = append(changed, models.NotificationPreference{
+			UserID:    userID,
+			EventType: preference.EventType,
+			InApp:     preference.InApp,
+			Email:     preference.Email,
+		})
+	}
+	if err := s.Repo.SaveNotificationPreferences(changed); err != nil {
+		logrus.WithFields(logrus.Fields{
+			"userID": userID,
+			"error":  err,
+		}).Error("Failed to save notification preferences")
+		return nil, err
+	}
+	logrus.WithFields(logrus.Fields{
+		"userID": userID,
+	}).Info("Notification preferences updated successfully")
+	return current, nil
+}
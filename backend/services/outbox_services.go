@@ -0,0 +1,175 @@
+// Outbox-related services (publishing recorded events to subscribers)
+package services
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"work-management/models"
+	"work-management/repository"
+
+	"github.com/sirupsen/logrus"
+)
+
+// Outbox relay settings
+const (
+	// outboxPollInterval is how often the relay looks for events recorded by
+	// other servers or left over after a crash
+	outboxPollInterval = 2 * time.Second
+	outboxBatchSize    = 100
+	// outboxMaxAttempts is how often an event is published before it is
+	// marked as failed and stops holding up its aggregate
+	outboxMaxAttempts = 10
+	// outboxBackoff is the wait before the first retry; it doubles with each
+	// further attempt up to maxOutboxBackoff
+	outboxBackoff    = 5 * time.Second
+	maxOutboxBackoff = 10 * time.Minute
+	// keepPublishedEvents is how long published events are kept before
+	// cleanup; failed events are kept for inspection
+	keepPublishedEvents = 7 * 24 * time.Hour
+)
+
+// relayOutbox publishes a batch of pending events in the order they were
+// recorded. An event that cannot be published yet holds back the later
+// events of its aggregate. The batch runs in a transaction holding an
+// advisory lock, so only one server relays at a time; if the server dies
+// halfway, the batch is published again (at-least-once).
+func (s *Service) relayOutbox() {
+	published := 0
+	err := s.Repo.Transaction(func(repo *repository.Repository) error {
+		locked, err := repo.TryOutboxLock()
+		if err != nil || !locked {
+			return err
+		}
+		now := time.Now()
+		records, err := repo.GetPendingOutboxEvents(now, outboxBatchSize)
+		if err != nil {
+			return err
+		}
+		blocked := map[string]bool{}
+		for i := range records {
+			record := &records[i]
+			aggregate := fmt.Sprintf("%s:%d", record.AggregateType, record.AggregateID)
+			if blocked[aggregate] {
+				continue
+			}
+			if record.NextAttemptAt != nil && record.NextAttemptAt.After(now) {
+				blocked[aggregate] = true
+				continue
+			}
+			s.publishOutboxEvent(record, now)
+			if record.Status == models.OutboxPending {
+				blocked[aggregate] = true
+			} else {
+				published++
+			}
+			if err := repo.UpdateOutboxEvent(record); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		logrus.WithFields(logrus.Fields{
+			"error": err,
+		}).Error("Failed to relay outbox events")
+		return
+	}
+	if published > 0 {
+		logrus.WithFields(logrus.Fields{
+			"count": published,
+		}).Debug("Outbox events published")
+	}
+}
+
+// publishOutboxEvent hands an event to the subscribers that have not handled
+// it yet and updates the record with the outcome
+func (s *Service) publishOutboxEvent(record *models.OutboxEvent, now time.Time) {
+	fields := logrus.Fields{
+		"outboxID": record.ID,
+		"event":    record.Type,
+	}
+	record.Attempts++
+	var event Event
+	if err := json.Unmarshal(record.Payload, &event); err != nil {
+		logrus.WithFields(fields).WithField("error", err).Error("Failed to decode outbox event")
+		record.Status = models.OutboxFailed
+		record.LastError = err.Error()
+		return
+	}
+	delivered := map[string]bool{}
+	for _, name := range record.Delivered {
+		delivered[name] = true
+	}
+	var failures []string
+	for _, sub := range s.subscribers {
+		if delivered[sub.name] {
+			continue
+		}
+		if err := safeHandle(sub.handle, event); err != nil {
+			logrus.WithFields(fields).WithFields(logrus.Fields{
+				"subscriber": sub.name,
+				"attempt":    record.Attempts,
+				"error":      err,
+			}).Warn("Event subscriber failed")
+			failures = append(failures, sub.name+": "+err.Error())
+			continue
+		}
+		record.Delivered = append(record.Delivered, sub.name)
+	}
+	switch {
+	case len(failures) == 0:
+		record.Status = models.OutboxPublished
+		record.PublishedAt = &now
+		record.NextAttemptAt = nil
+		record.LastError = ""
+	case record.Attempts >= outboxMaxAttempts:
+		record.Status = models.OutboxFailed
+		record.LastError = strings.Join(failures, "; ")
+		logrus.WithFields(fields).WithField("error", record.LastError).Error("Giving up on outbox event")
+	default:
+		next := now.Add(outboxRetryWait(record.Attempts))
+		record.NextAttemptAt = &next
+		record.LastError = strings.Join(failures, "; ")
+	}
+}
+
+// safeHandle calls an event handler, turning a panic into an error
+func safeHandle(handle EventHandler, event Event) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("panic: %v", r)
+		}
+	}()
+	return handle(event)
+}
+
+// outboxRetryWait is the wait before publishing an event again after its
+// nth attempt failed
+func outboxRetryWait(attempt int) time.Duration {
+	wait := outboxBackoff
+	for i := 1; i < attempt && wait < maxOutboxBackoff; i++ {
+		wait *= 2
+	}
+	if wait > maxOutboxBackoff {
+		wait = maxOutboxBackoff
+	}
+	return wait
+}
+
+// CleanupOutbox deletes events published more than keepPublishedEvents ago
+func (s *Service) CleanupOutbox(now time.Time) error {
+	count, err := s.Repo.DeletePublishedOutboxEvents(now.Add(-keepPublishedEvents))
+	if err != nil {
+		logrus.WithFields(logrus.Fields{
+			"error": err,
+		}).Error("Failed to clean up outbox")
+		return err
+	}
+	logrus.WithFields(logrus.Fields{
+		"count": count,
+	}).Info("Outbox cleaned up")
+	return nil
+}
@@ -2,8 +2,11 @@ package services
 
 import (
 	"errors"
+	"fmt"
+	"time"
 
 	"work-management/models"
+	"work-management/repository"
 
 	"github.com/sirupsen/logrus"
 )
@@ -14,20 +17,8 @@ func (s *Service) CreateProject(name, description, category, status string, crea
 		"name":      name,
 	}).Debug("Starting CreateProject")
 
-	// Start a transaction
-	tx := s.Repo.DB.Begin()
-	if tx.Error != nil {
-		logrus.WithFields(logrus.Fields{
-			"creatorID": creatorID,
-			"error":     tx.Error,
-		}).Error("Failed to start transaction")
-		return nil, tx.Error
-	}
-	logrus.WithFields(logrus.Fields{
-		"creatorID": creatorID,
-	}).Debug("Transaction started")
-
-	// Create the project
+	// Create the project, add the creator as an admin in the user_roles table
+	// and record the event in one transaction
 	project := models.Project{
 		Name:        name,
 		Description: description,
@@ -35,58 +26,68 @@ func (s *Service) CreateProject(name, description, category, status string, crea
 		Status:      status,
 		CreatorID:   creatorID,
 	}
-	if err := tx.Create(&project).Error; err != nil {
-		tx.Rollback()
+	err := s.transaction(func(repo *repository.Repository) error {
+		if err := insertProject(repo, &project); err != nil {
+			return err
+		}
+		return s.recordEvent(repo, Event{
+			Type:      EventProjectCreated,
+			ProjectID: project.ID,
+			ActorID:   creatorID,
+			Message:   fmt.Sprintf("created project %q", project.Name),
+		})
+	})
+	if err != nil {
 		logrus.WithFields(logrus.Fields{
 			"creatorID": creatorID,
 			"error":     err,
-		}).Error("Failed to create project in database")
+		}).Error("Failed to commit transaction")
 		return nil, err
 	}
 	logrus.WithFields(logrus.Fields{
 		"creatorID": creatorID,
 		"projectID": project.ID,
+	}).Info("Creator added to user_roles successfully")
+	return &project, nil
+}
+
+// insertProject creates a project with its creator as admin
+func insertProject(repo *repository.Repository, project *models.Project) error {
+	if err := repo.DB.Create(project).Error; err != nil {
+		logrus.WithFields(logrus.Fields{
+			"creatorID": project.CreatorID,
+			"error":     err,
+		}).Error("Failed to create project in database")
+		return err
+	}
+	logrus.WithFields(logrus.Fields{
+		"creatorID": project.CreatorID,
+		"projectID": project.ID,
 	}).Debug("Project created in database")
 
-	// Add the creator as an admin in the user_roles table
 	userRole := models.UserRole{
-		UserID:    creatorID,
+		UserID:    project.CreatorID,
 		ProjectID: project.ID,
 		Role:      "admin",
 	}
-	if err := tx.Create(&userRole).Error; err != nil {
-		tx.Rollback()
+	if err := repo.DB.Create(&userRole).Error; err != nil {
 		logrus.WithFields(logrus.Fields{
-			"creatorID": creatorID,
+			"creatorID": project.CreatorID,
 			"projectID": project.ID,
 			"error":     err,
 		}).Error("Failed to add creator to user_roles")
-		return nil, err
+		return err
 	}
 	logrus.WithFields(logrus.Fields{
-		"creatorID": creatorID,
+		"creatorID": project.CreatorID,
 		"projectID": project.ID,
 	}).Debug("Creator added to user_roles")
-
-	// Commit the transaction
-	if err := tx.Commit().Error; err != nil {
-		tx.Rollback()
-		logrus.WithFields(logrus.Fields{
-			"creatorID": creatorID,
-			"projectID": project.ID,
-			"error":     err,
-		}).Error("Failed to commit transaction")
-		return nil, err
-	}
-	logrus.WithFields(logrus.Fields{
-		"creatorID": creatorID,
-		"projectID": project.ID,
-	}).Info("Creator added to user_roles successfully")
-	return &project, nil
+	return nil
 }
 
-func (s *Service) GetProjects(userID uint) ([]models.Project, error) {
-	projects, err := s.Repo.GetProjects(userID)
+// GetProjects lists the active projects of a user, or the archived ones
+func (s *Service) GetProjects(userID uint, archived bool) ([]models.Project, error) {
+	projects, err := s.Repo.GetProjects(userID, archived)
 	if err != nil {
 		logrus.WithFields(logrus.Fields{
 			"userID": userID,
@@ -115,7 +116,7 @@ func (s *Service) GetProjectByID(projectID uint) (*models.Project, error) {
 	return project, nil
 }
 
-func (s *Service) UpdateProject(projectID uint, name, description, category, status string) (*models.Project, error) {
+func (s *Service) UpdateProject(actorID, projectID uint, name, description, category, status string) (*models.Project, error) {
 	project, err := s.Repo.GetProjectByID(projectID)
 	if err != nil {
 		logrus.WithFields(logrus.Fields{
@@ -128,7 +129,18 @@ func (s *Service) UpdateProject(projectID uint, name, description, category, sta
 	project.Description = description
 	project.Category = category
 	project.Status = status
-	if err := s.Repo.UpdateProject(project); err != nil {
+	err = s.transaction(func(repo *repository.Repository) error {
+		if err := repo.UpdateProject(project); err != nil {
+			return err
+		}
+		return s.recordEvent(repo, Event{
+			Type:      EventProjectUpdated,
+			ProjectID: projectID,
+			ActorID:   actorID,
+			Message:   fmt.Sprintf("updated project %q", project.Name),
+		})
+	})
+	if err != nil {
 		logrus.WithFields(logrus.Fields{
 			"projectID": projectID,
 			"error":     err,
@@ -141,8 +153,39 @@ func (s *Service) UpdateProject(projectID uint, name, description, category, sta
 	return project, nil
 }
 
-func (s *Service) DeleteProject(projectID uint) error {
-	if err := s.Repo.DeleteProject(projectID); err != nil {
+// DeleteProject moves a project to the trash, from which it can be restored
+// until it is purged
+func (s *Service) DeleteProject(actorID, projectID uint) error {
+	project, err := s.Repo.GetProjectByID(projectID)
+	if err != nil {
+		logrus.WithFields(logrus.Fields{
+			"projectID": projectID,
+			"error":     err,
+		}).Warn("Project not found for deletion")
+		return err
+	}
+	err = s.transaction(func(repo *repository.Repository) error {
+		if err := repo.DeleteProject(projectID, time.Now()); err != nil {
+			return err
+		}
+		if err := s.audit(repo, models.AuditLog{
+			ActorID:    actorID,
+			Action:     AuditProjectTrashed,
+			EntityType: "project",
+			EntityID:   projectID,
+			ProjectID:  projectID,
+			Details:    map[string]interface{}{"name": project.Name},
+		}); err != nil {
+			return err
+		}
+		return s.recordEvent(repo, Event{
+			Type:      EventProjectDeleted,
+			ProjectID: projectID,
+			ActorID:   actorID,
+			Message:   fmt.Sprintf("moved project %q to the trash", project.Name),
+		})
+	})
+	if err != nil {
 		logrus.WithFields(logrus.Fields{
 			"projectID": projectID,
 			"error":     err,
@@ -151,7 +194,7 @@ func (s *Service) DeleteProject(projectID uint) error {
 	}
 	logrus.WithFields(logrus.Fields{
 		"projectID": projectID,
-	}).Info("Project deleted successfully")
+	}).Info("Project moved to the trash")
 	return nil
 }
 
@@ -179,7 +222,23 @@ func (s *Service) ToggleFavorite(projectID uint, isFavorite bool) (*models.Proje
 	return project, nil
 }
 
-func (s *Service) AddUserToProject(userID, projectID uint, role string) error {
+// projectName returns the name of a project for event messages
+func (s *Service) projectName(projectID uint) string {
+	var name string
+	s.Repo.DB.Model(&models.Project{}).Select("name").Where("id = ?", projectID).Scan(&name)
+	return name
+}
+
+// userName returns the name of a user for event messages
+func (s *Service) userName(userID uint) string {
+	user, err := s.Repo.GetUserByID(userID)
+	if err != nil {
+		return fmt.Sprintf("user %d", userID)
+	}
+	return user.Name
+}
+
+func (s *Service) AddUserToProject(actorID, userID, projectID uint, role string) error {
 	validRoles := map[string]bool{"admin": true, "editor": true, "viewer": true}
 	if !validRoles[role] {
 		logrus.WithFields(logrus.Fields{
@@ -187,7 +246,22 @@ func (s *Service) AddUserToProject(userID, projectID uint, role string) error {
 		}).Warn("Invalid role provided")
 		return errors.New("invalid role")
 	}
-	if err := s.Repo.AddUserToProject(userID, projectID, role); err != nil {
+	name := s.projectName(projectID)
+	err := s.transaction(func(repo *repository.Repository) error {
+		if err := repo.AddUserToProject(userID, projectID, role); err != nil {
+			return err
+		}
+		return s.recordEvent(repo, Event{
+			Type:       EventMemberAdded,
+			ProjectID:  projectID,
+			ActorID:    actorID,
+			Message:    fmt.Sprintf("added %s as %s", s.userName(userID), role),
+			Recipients: []uint{userID},
+			Notice:     fmt.Sprintf("added you to %q as %s", name, role),
+			Data:       map[string]interface{}{"user_id": userID, "role": role},
+		})
+	})
+	if err != nil {
 		logrus.WithFields(logrus.Fields{
 			"projectID": projectID,
 			"userID":    userID,
@@ -204,7 +278,7 @@ func (s *Service) AddUserToProject(userID, projectID uint, role string) error {
 	return nil
 }
 
-func (s *Service) UpdateUserRole(userID, projectID uint, role string) error {
+func (s *Service) UpdateUserRole(actorID, userID, projectID uint, role string) error {
 	validRoles := map[string]bool{"admin": true, "editor": true, "viewer": true}
 	if !validRoles[role] {
 		logrus.WithFields(logrus.Fields{
@@ -212,7 +286,22 @@ func (s *Service) UpdateUserRole(userID, projectID uint, role string) error {
 		}).Warn("Invalid role provided")
 		return errors.New("invalid role")
 	}
-	if err := s.Repo.UpdateUserRole(userID, projectID, role); err != nil {
+	name := s.projectName(projectID)
+	err := s.transaction(func(repo *repository.Repository) error {
+		if err := repo.UpdateUserRole(userID, projectID, role); err != nil {
+			return err
+		}
+		return s.recordEvent(repo, Event{
+			Type:       EventMemberRoleChanged,
+			ProjectID:  projectID,
+			ActorID:    actorID,
+			Message:    fmt.Sprintf("changed the role of %s to %s", s.userName(userID), role),
+			Recipients: []uint{userID},
+			Notice:     fmt.Sprintf("changed your role in %q to %s", name, role),
+			Data:       map[string]interface{}{"user_id": userID, "role": role},
+		})
+	})
+	if err != nil {
 		logrus.WithFields(logrus.Fields{
 			"projectID": projectID,
 			"userID":    userID,
@@ -229,8 +318,20 @@ func (s *Service) UpdateUserRole(userID, projectID uint, role string) error {
 	return nil
 }
 
-func (s *Service) RemoveUserFromProject(userID, projectID uint) error {
-	if err := s.Repo.RemoveUserFromProject(userID, projectID); err != nil {
+func (s *Service) RemoveUserFromProject(actorID, userID, projectID uint) error {
+	err := s.transaction(func(repo *repository.Repository) error {
+		if err := repo.RemoveUserFromProject(userID, projectID); err != nil {
+			return err
+		}
+		return s.recordEvent(repo, Event{
+			Type:      EventMemberRemoved,
+			ProjectID: projectID,
+			ActorID:   actorID,
+			Message:   fmt.Sprintf("removed %s from the project", s.userName(userID)),
+			Data:      map[string]interface{}{"user_id": userID},
+		})
+	})
+	if err != nil {
 		logrus.WithFields(logrus.Fields{
 			"projectID": projectID,
 			"userID":    userID,
@@ -262,6 +363,11 @@ func (s *Service) CanModifyProject(userID, projectID uint) (bool, error) {
 		"role":      role,
 		"canModify": canModify,
 	}).Info("Checked user modification permission")
+	if canModify {
+		if err := s.ensureNotArchived(projectID); err != nil {
+			return false, err
+		}
+	}
 	return canModify, nil
 }
 
@@ -284,7 +390,20 @@ func (s *Service) AdminOnly(userID, projectID uint) (bool, error) {
 	}).Info("Checked admin-only permission")
 	return isAdmin, nil
 }
-func (s *Service) ChangeProjectOwner(projectID, newOwnerID uint) (*models.Project, error) {
+
+// CanAdministerProject checks if the user may change the settings of a
+// project, which nobody can while it is archived
+func (s *Service) CanAdministerProject(userID, projectID uint) (bool, error) {
+	isAdmin, err := s.AdminOnly(userID, projectID)
+	if err != nil || !isAdmin {
+		return false, err
+	}
+	if err := s.ensureNotArchived(projectID); err != nil {
+		return false, err
+	}
+	return true, nil
+}
+func (s *Service) ChangeProjectOwner(actorID, projectID, newOwnerID uint) (*models.Project, error) {
 	project, err := s.Repo.GetProjectByID(projectID)
 	if err != nil {
 		logrus.WithFields(logrus.Fields{
@@ -305,27 +424,41 @@ func (s *Service) ChangeProjectOwner(projectID, newOwnerID uint) (*models.Projec
 		return nil, errors.New("new owner must be a member of the project")
 	}
 
-	// Update the creator_id
+	// Update the creator_id, make sure the new owner has the admin role and
+	// record the event in one transaction
+	previousOwnerID := project.CreatorID
 	project.CreatorID = newOwnerID
-	if err := s.Repo.UpdateProject(project); err != nil {
-		logrus.WithFields(logrus.Fields{
-			"projectID":  projectID,
-			"newOwnerID": newOwnerID,
-			"error":      err,
-		}).Error("Failed to update project owner")
-		return nil, err
-	}
-
-	// Ensure the new owner has admin role
-	if role != "admin" {
-		if err := s.Repo.UpdateUserRole(newOwnerID, projectID, "admin"); err != nil {
+	err = s.transaction(func(repo *repository.Repository) error {
+		if err := repo.UpdateProject(project); err != nil {
 			logrus.WithFields(logrus.Fields{
 				"projectID":  projectID,
 				"newOwnerID": newOwnerID,
 				"error":      err,
-			}).Error("Failed to update new owner's role to admin")
-			return nil, err
+			}).Error("Failed to update project owner")
+			return err
+		}
+		if role != "admin" {
+			if err := repo.UpdateUserRole(newOwnerID, projectID, "admin"); err != nil {
+				logrus.WithFields(logrus.Fields{
+					"projectID":  projectID,
+					"newOwnerID": newOwnerID,
+					"error":      err,
+				}).Error("Failed to update new owner's role to admin")
+				return err
+			}
 		}
+		return s.recordEvent(repo, Event{
+			Type:       EventProjectOwnerChanged,
+			ProjectID:  projectID,
+			ActorID:    actorID,
+			Message:    fmt.Sprintf("transferred ownership of %q to %s", project.Name, s.userName(newOwnerID)),
+			Recipients: []uint{newOwnerID, previousOwnerID},
+			Notice:     fmt.Sprintf("transferred ownership of %q to %s", project.Name, s.userName(newOwnerID)),
+			Data:       map[string]interface{}{"owner_id": newOwnerID, "previous_owner_id": previousOwnerID},
+		})
+	})
+	if err != nil {
+		return nil, err
 	}
 
 	logrus.WithFields(logrus.Fields{
@@ -353,68 +486,3 @@ func (s *Service) GetActivitiesByProjectID(projectID uint) ([]models.Activity, e
 	}).Info("Activities retrieved for project successfully")
 	return activities, nil
 }
-
-func (s *Service) GetProjectAnalytics(projectID uint) (map[string]interface{}, error) {
-	// Mock analytics data for now
-	analytics := map[string]interface{}{
-		"cycleTime": map[string]interface{}{
-			"labels": []string{"Task 1", "Task 2", "Task 3", "Task 4", "Task 5"},
-			"datasets": []map[string]interface{}{
-				{
-					"label":           "Cycle Time (Days)",
-					"data":            []int{5, 7, 3, 6, 4},
-					"backgroundColor": "#9b87f6",
-				},
-			},
-		},
-		"velocity": map[string]interface{}{
-			"labels": []string{"Sprint 1", "Sprint 2", "Sprint 3", "Sprint 4"},
-			"datasets": []map[string]interface{}{
-				{
-					"label":           "Velocity (Story Points)",
-					"data":            []int{18, 27, 36, 30},
-					"backgroundColor": "#2ecc71",
-				},
-			},
-		},
-		"burndown": map[string]interface{}{
-			"labels": []string{"Day 1", "Day 2", "Day 3", "Day 4", "Day 5", "Day 6", "Day 7"},
-			"datasets": []map[string]interface{}{
-				{
-					"label":       "Burndown",
-					"data":        []int{12, 10, 8, 6, 4, 2, 0},
-					"fill":        false,
-					"borderColor": "#9b87f6",
-					"tension":     0.1,
-				},
-			},
-		},
-		"cumulativeFlow": map[string]interface{}{
-			"labels": []string{"Week 1", "Week 2", "Week 3", "Week 4", "Week 5"},
-			"datasets": []map[string]interface{}{
-				{
-					"label":           "To Do",
-					"data":            []int{15, 12, 10, 8, 5},
-					"backgroundColor": "#e74c3c",
-				},
-				{
-					"label":           "In Progress",
-					"data":            []int{0, 3, 5, 7, 8},
-					"backgroundColor": "#f1c40f",
-				},
-				{
-					"label":           "Completed",
-					"data":            []int{0, 0, 0, 0, 2},
-					"backgroundColor": "#2ecc71",
-				},
-			},
-		},
-		"metrics": map[string]interface{}{
-			"cycleTime":    "4.2 days",
-			"velocity":     "28 points",
-			"defects":      3,
-			"codeCoverage": "87%",
-		},
-	}
-	return analytics, nil
-}
@@ -0,0 +1,38 @@
+// Realtime services (pushing events to connected WebSocket clients)
+package services
+
+import (
+	"github.com/sirupsen/logrus"
+)
+
+// pushRealtimeEvent sends an event to the connected members of its project
+// and to its recipients. Clients that are not connected simply miss it, so
+// failures are logged rather than retried.
+func (s *Service) pushRealtimeEvent(event Event) error {
+	userIDs := append([]uint{event.ActorID}, event.Recipients...)
+	if event.ProjectID != 0 {
+		members, err := s.Repo.GetProjectMemberIDs(event.ProjectID)
+		if err != nil {
+			logrus.WithFields(logrus.Fields{
+				"projectID": event.ProjectID,
+				"error":     err,
+			}).Error("Failed to retrieve project members for realtime event")
+		}
+		userIDs = append(userIDs, members...)
+	}
+	seen := map[uint]bool{}
+	var targets []uint
+	for _, userID := range userIDs {
+		if userID != 0 && !seen[userID] {
+			seen[userID] = true
+			targets = append(targets, userID)
+		}
+	}
+	if err := s.Realtime.Send(targets, newEventPayload(event)); err != nil {
+		logrus.WithFields(logrus.Fields{
+			"event": event.Type,
+			"error": err,
+		}).Error("Failed to push realtime event")
+	}
+	return nil
+}
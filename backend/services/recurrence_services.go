@@ -0,0 +1,446 @@
+// Recurrence-related services (recurring tasks and their occurrences)
+package services
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"work-management/calendar"
+	"work-management/dto"
+	"work-management/models"
+	"work-management/recurrence"
+	"work-management/repository"
+
+	"github.com/sirupsen/logrus"
+	"gorm.io/gorm"
+)
+
+// Recurrence settings
+const (
+	// occurrenceCheckInterval is how often series on a fixed schedule are
+	// checked for a due occurrence
+	occurrenceCheckInterval = 15 * time.Minute
+	// maxSkippedOccurrences bounds how many missed occurrences a series skips
+	// to catch up with the present
+	maxSkippedOccurrences = 1000
+)
+
+// validateSeriesLabels checks that the labels belong to the project
+func (s *Service) validateSeriesLabels(projectID uint, labelIDs []uint) error {
+	for _, labelID := range labelIDs {
+		if _, err := s.GetProjectLabel(projectID, labelID); err != nil {
+			return invalidInput("label %d does not belong to the project", labelID)
+		}
+	}
+	return nil
+}
+
+// GetTaskRecurrence returns the series of a recurring task
+func (s *Service) GetTaskRecurrence(taskID uint) (*models.Recurrence, error) {
+	task, err := s.Repo.GetTaskByID(taskID)
+	if err != nil {
+		return nil, err
+	}
+	if task.RecurrenceID == nil {
+		return nil, gorm.ErrRecordNotFound
+	}
+	return s.Repo.GetRecurrenceByID(*task.RecurrenceID)
+}
+
+// SetTaskRecurrence starts a series with the task as its first occurrence,
+// or edits the series the task belongs to. A new series copies its template
+// from the task; the task needs a due date, which anchors the schedule.
+func (s *Service) SetTaskRecurrence(actorID, taskID uint, input dto.RecurrenceInput) (*models.Recurrence, error) {
+	task, err := s.Repo.GetTaskByID(taskID)
+	if err != nil {
+		logrus.WithFields(logrus.Fields{
+			"taskID": taskID,
+			"error":  err,
+		}).Warn("Task not found for recurrence")
+		return nil, err
+	}
+	var series *models.Recurrence
+	if task.RecurrenceID != nil {
+		if series, err = s.Repo.GetRecurrenceByID(*task.RecurrenceID); err != nil {
+			return nil, err
+		}
+		if series.EndedAt != nil {
+			// A stopped series is replaced by a new one
+			series = nil
+		}
+	}
+	if series == nil {
+		return s.startRecurrence(actorID, task, input)
+	}
+	return s.updateRecurrence(actorID, task, series, input)
+}
+
+func (s *Service) startRecurrence(actorID uint, task *models.Task, input dto.RecurrenceInput) (*models.Recurrence, error) {
+	if input.Rule == "" {
+		return nil, invalidInput("rule is required")
+	}
+	if task.DueDate.IsZero() {
+		return nil, invalidInput("a recurring task needs a due date")
+	}
+	if task.CompletedAt != nil {
+		return nil, invalidInput("a completed task cannot start a series")
+	}
+	rule, err := recurrence.Parse(input.Rule)
+	if err != nil {
+		return nil, invalidInput("%v", err)
+	}
+	rule.Anchor(task.DueDate)
+	if rule.Ends(task.DueDate, 1) {
+		return nil, invalidInput("UNTIL is before the task's due date")
+	}
+	series := &models.Recurrence{
+		ProjectID:       task.ProjectID,
+		Rule:            rule.String(),
+		Title:           task.Title,
+		Description:     task.Description,
+		Priority:        task.Priority,
+		StoryPoints:     task.StoryPoints,
+		LabelIDs:        []uint{},
+		AssigneeIDs:     userIDs(task.Assignees),
+		AfterCompletion: rule.AfterCompletion,
+		DueDateOnly:     task.DueDateOnly,
+		ReporterID:      task.ReporterID,
+		LastDueDate:     task.DueDate,
+		Occurrences:     1,
+	}
+	for _, label := range task.Labels {
+		series.LabelIDs = append(series.LabelIDs, label.ID)
+	}
+	if err := s.applySeriesTemplate(series, input); err != nil {
+		return nil, err
+	}
+	err = s.transaction(func(repo *repository.Repository) error {
+		if err := repo.CreateRecurrence(series); err != nil {
+			return err
+		}
+		if err := repo.SetTaskRecurrence(task.ID, &series.ID); err != nil {
+			return err
+		}
+		if input.ApplyToOpen {
+			if err := s.applyTemplateToTask(repo, series, task); err != nil {
+				return err
+			}
+		}
+		return s.recordEvent(repo, Event{
+			Type:      EventTaskUpdated,
+			ProjectID: task.ProjectID,
+			TaskID:    task.ID,
+			ActorID:   actorID,
+			Message:   fmt.Sprintf("made task %q recurring (%s)", task.Title, series.Rule),
+			Data:      map[string]interface{}{"recurrence_id": series.ID, "rule": series.Rule},
+		})
+	})
+	if err != nil {
+		logrus.WithFields(logrus.Fields{
+			"taskID": task.ID,
+			"error":  err,
+		}).Error("Failed to start recurrence")
+		return nil, err
+	}
+	logrus.WithFields(logrus.Fields{
+		"taskID":       task.ID,
+		"recurrenceID": series.ID,
+		"rule":         series.Rule,
+	}).Info("Recurrence started successfully")
+	return series, nil
+}
+
+// updateRecurrence edits the rule and template of a running series, and its
+// open occurrences too if asked to
+func (s *Service) updateRecurrence(actorID uint, task *models.Task, series *models.Recurrence, input dto.RecurrenceInput) (*models.Recurrence, error) {
+	if input.Rule != "" {
+		rule, err := recurrence.Parse(input.Rule)
+		if err != nil {
+			return nil, invalidInput("%v", err)
+		}
+		rule.Anchor(series.LastDueDate)
+		series.Rule = rule.String()
+		series.AfterCompletion = rule.AfterCompletion
+	}
+	if err := s.applySeriesTemplate(series, input); err != nil {
+		return nil, err
+	}
+	err := s.transaction(func(repo *repository.Repository) error {
+		if err := repo.UpdateRecurrence(series); err != nil {
+			return err
+		}
+		if input.ApplyToOpen {
+			occurrences, err := repo.GetOpenOccurrences(series.ID)
+			if err != nil {
+				return err
+			}
+			for i := range occurrences {
+				if err := s.applyTemplateToTask(repo, series, &occurrences[i]); err != nil {
+					return err
+				}
+			}
+		}
+		return s.recordEvent(repo, Event{
+			Type:      EventTaskUpdated,
+			ProjectID: task.ProjectID,
+			TaskID:    task.ID,
+			ActorID:   actorID,
+			Message:   fmt.Sprintf("updated the series of task %q", task.Title),
+			Data:      map[string]interface{}{"recurrence_id": series.ID, "rule": series.Rule},
+		})
+	})
+	if err != nil {
+		logrus.WithFields(logrus.Fields{
+			"recurrenceID": series.ID,
+			"error":        err,
+		}).Error("Failed to update recurrence")
+		return nil, err
+	}
+	logrus.WithFields(logrus.Fields{
+		"recurrenceID": series.ID,
+		"rule":         series.Rule,
+	}).Info("Recurrence updated successfully")
+	return series, nil
+}
+
+// applySeriesTemplate validates the template fields given in the input and
+// copies them onto the series
+func (s *Service) applySeriesTemplate(series *models.Recurrence, input dto.RecurrenceInput) error {
+	if input.Title != nil {
+		if *input.Title == "" {
+			return invalidInput("title cannot be empty")
+		}
+		series.Title = *input.Title
+	}
+	if input.Description != nil {
+		series.Description = *input.Description
+	}
+	if input.LabelIDs != nil {
+		if err := s.validateSeriesLabels(series.ProjectID, *input.LabelIDs); err != nil {
+			return err
+		}
+		series.LabelIDs = append([]uint{}, *input.LabelIDs...)
+	}
+	if input.AssigneeIDs != nil {
+		assigneeIDs, err := s.validateAssignees(series.ProjectID, 0, *input.AssigneeIDs)
+		if err != nil {
+			return err
+		}
+		series.AssigneeIDs = assigneeIDs
+	}
+	return nil
+}
+
+// applyTemplateToTask overwrites an occurrence's title, description, labels
+// and assignees with the series template
+func (s *Service) applyTemplateToTask(repo *repository.Repository, series *models.Recurrence, task *models.Task) error {
+	task.Title = series.Title
+	task.Description = series.Description
+	task.UserID = 0
+	if len(series.AssigneeIDs) > 0 {
+		task.UserID = series.AssigneeIDs[0]
+	}
+	return repo.SaveTask(task, repository.TaskUpdate{
+		AssigneeIDs: append([]uint{}, series.AssigneeIDs...),
+		LabelIDs:    append([]uint{}, series.LabelIDs...),
+	})
+}
+
+// StopTaskRecurrence ends the series of a recurring task. Its existing
+// occurrences are kept.
+func (s *Service) StopTaskRecurrence(actorID, taskID uint) error {
+	task, err := s.Repo.GetTaskByID(taskID)
+	if err != nil {
+		return err
+	}
+	series, err := s.GetTaskRecurrence(taskID)
+	if err != nil {
+		return err
+	}
+	if series.EndedAt != nil {
+		return nil
+	}
+	now := time.Now()
+	err = s.transaction(func(repo *repository.Repository) error {
+		stopped, err := repo.AdvanceRecurrence(series.ID, series.LastDueDate, series.LastDueDate, series.Occurrences, &now)
+		if err != nil || !stopped {
+			return err
+		}
+		return s.recordEvent(repo, Event{
+			Type:      EventTaskUpdated,
+			ProjectID: task.ProjectID,
+			TaskID:    task.ID,
+			ActorID:   actorID,
+			Message:   fmt.Sprintf("stopped the series of task %q", task.Title),
+			Data:      map[string]interface{}{"recurrence_id": series.ID},
+		})
+	})
+	if err != nil {
+		logrus.WithFields(logrus.Fields{
+			"recurrenceID": series.ID,
+			"error":        err,
+		}).Error("Failed to stop recurrence")
+		return err
+	}
+	logrus.WithFields(logrus.Fields{
+		"recurrenceID": series.ID,
+	}).Info("Recurrence stopped successfully")
+	return nil
+}
+
+// continueRecurrence generates the next occurrence of the series of a task
+// that has just been completed. Only the latest occurrence continues the
+// series; completing an older one, e.g. after the scheduler already moved on,
+// changes nothing.
+func (s *Service) continueRecurrence(repo *repository.Repository, actorID uint, task *models.Task) error {
+	series, err := repo.GetRecurrenceByID(*task.RecurrenceID)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	if series.EndedAt != nil || !task.DueDate.Equal(series.LastDueDate) {
+		return nil
+	}
+	previous := series.LastDueDate
+	if series.AfterCompletion && task.CompletedAt != nil {
+		previous = *task.CompletedAt
+	}
+	return s.spawnOccurrence(repo, actorID, series, previous, time.Now())
+}
+
+// spawnOccurrence creates the occurrence following previous, which is the
+// due date of the latest occurrence, or its completion time for series
+// anchored on completion. Occurrences of a fixed schedule that are already in
+// the past are skipped. Claiming the step on the series keeps two servers
+// from generating the same occurrence.
+func (s *Service) spawnOccurrence(repo *repository.Repository, actorID uint, series *models.Recurrence, previous, now time.Time) error {
+	rule, err := recurrence.Parse(series.Rule)
+	if err != nil {
+		return fmt.Errorf("recurrence %d: %w", series.ID, err)
+	}
+	number := series.Occurrences + 1
+	next := rule.Next(previous)
+	for skipped := 0; !rule.AfterCompletion && !next.After(now) && skipped < maxSkippedOccurrences; skipped++ {
+		next = rule.Next(next)
+		number++
+	}
+	if series.DueDateOnly {
+		// Completion-anchored occurrences keep the day they land on
+		next = calendar.Date(next)
+	}
+	if rule.Ends(next, number) {
+		ended, err := repo.AdvanceRecurrence(series.ID, series.LastDueDate, series.LastDueDate, series.Occurrences, &now)
+		if err == nil && ended {
+			logrus.WithFields(logrus.Fields{
+				"recurrenceID": series.ID,
+			}).Info("Recurrence ended")
+		}
+		return err
+	}
+	claimed, err := repo.AdvanceRecurrence(series.ID, series.LastDueDate, next, number, nil)
+	if err != nil || !claimed {
+		return err
+	}
+
+	task := &models.Task{
+		Title:        series.Title,
+		Description:  series.Description,
+		ProjectID:    series.ProjectID,
+		DueDate:      next,
+		DueDateOnly:  series.DueDateOnly,
+		Priority:     series.Priority,
+		StoryPoints:  series.StoryPoints,
+		ReporterID:   series.ReporterID,
+		RecurrenceID: &series.ID,
+	}
+	if task.Priority == "" {
+		task.Priority = models.PriorityMedium
+	}
+	status, err := s.initialStatus(repo, series.ProjectID)
+	if err != nil {
+		return err
+	}
+	setTaskStatus(task, status, false)
+	// Members or labels removed since the template was saved are left out
+	var assigneeIDs []uint
+	for _, userID := range series.AssigneeIDs {
+		if s.IsProjectMember(userID, series.ProjectID) {
+			assigneeIDs = append(assigneeIDs, userID)
+			task.Assignees = append(task.Assignees, models.User{Model: gorm.Model{ID: userID}})
+		}
+	}
+	if len(assigneeIDs) > 0 {
+		task.UserID = assigneeIDs[0]
+	}
+	for _, labelID := range series.LabelIDs {
+		if _, err := s.GetProjectLabel(series.ProjectID, labelID); err == nil {
+			task.Labels = append(task.Labels, models.Label{Model: gorm.Model{ID: labelID}})
+		}
+	}
+	if customFields, _, err := s.resolveTaskCustomFields(series.ProjectID, nil, true); err == nil {
+		task.CustomFields = customFields
+	} else {
+		logrus.WithFields(logrus.Fields{
+			"recurrenceID": series.ID,
+			"error":        err,
+		}).Warn("Occurrence created without custom field defaults")
+	}
+	if err := repo.CreateTask(task); err != nil {
+		return err
+	}
+	logrus.WithFields(logrus.Fields{
+		"recurrenceID": series.ID,
+		"taskID":       task.ID,
+		"dueDate":      next,
+	}).Info("Recurring task occurrence created")
+	if err := s.recordEvent(repo, Event{
+		Type:      EventTaskCreated,
+		ProjectID: task.ProjectID,
+		TaskID:    task.ID,
+		ActorID:   actorID,
+		Message:   fmt.Sprintf("created task %q", task.Title),
+		Data:      map[string]interface{}{"recurrence_id": series.ID},
+	}); err != nil {
+		return err
+	}
+	return s.recordEvent(repo, Event{
+		Type:       EventTaskAssigned,
+		ProjectID:  task.ProjectID,
+		TaskID:     task.ID,
+		ActorID:    actorID,
+		Recipients: assigneeIDs,
+		Notice:     fmt.Sprintf("assigned you to %q", task.Title),
+	})
+}
+
+// GenerateScheduledOccurrences creates the next occurrence of every series on
+// a fixed schedule whose latest occurrence is due, done or not
+func (s *Service) GenerateScheduledOccurrences(now time.Time) error {
+	due, err := s.Repo.GetScheduledRecurrencesDue(now)
+	if err != nil {
+		logrus.WithFields(logrus.Fields{
+			"error": err,
+		}).Error("Failed to retrieve recurrences due")
+		return err
+	}
+	for i := range due {
+		series := &due[i]
+		err := s.transaction(func(repo *repository.Repository) error {
+			return s.spawnOccurrence(repo, 0, series, series.LastDueDate, now)
+		})
+		if err != nil {
+			logrus.WithFields(logrus.Fields{
+				"recurrenceID": series.ID,
+				"error":        err,
+			}).Error("Failed to generate recurring task occurrence")
+			return err
+		}
+	}
+	logrus.WithFields(logrus.Fields{
+		"recurrenceCount": len(due),
+	}).Info("Scheduled occurrences generated")
+	return nil
+}
@@ -0,0 +1,359 @@
+// Reminder-related services (due-date reminders, overdue tasks, escalation)
+package services
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"time"
+
+	"work-management/calendar"
+	"work-management/dto"
+	"work-management/models"
+	"work-management/repository"
+
+	"github.com/sirupsen/logrus"
+	"gorm.io/gorm"
+)
+
+// Reminder settings
+const (
+	// dueDateCheckInterval is how often due dates are checked; reminders
+	// fire up to this long after their offset
+	dueDateCheckInterval = 5 * time.Minute
+	// defaultEscalateAfter is the grace period, in minutes, of projects
+	// without a reminder policy
+	defaultEscalateAfter = 24 * 60
+	maxReminderOffsets   = 5
+	// maxReminderMinutes bounds offsets and grace periods to 30 days
+	maxReminderMinutes = 30 * 24 * 60
+)
+
+// defaultReminderOffsets are the reminder offsets, in minutes before the due
+// date, of projects without a reminder policy
+var defaultReminderOffsets = []int{24 * 60, 60}
+
+func defaultReminderPolicy(projectID uint) *models.ReminderPolicy {
+	return &models.ReminderPolicy{
+		ProjectID:     projectID,
+		Offsets:       append([]int(nil), defaultReminderOffsets...),
+		EscalateAfter: defaultEscalateAfter,
+	}
+}
+
+// GetReminderPolicy returns the project's reminder policy, or the defaults if
+// it has none
+func (s *Service) GetReminderPolicy(projectID uint) (*models.ReminderPolicy, error) {
+	policy, err := s.Repo.GetReminderPolicy(projectID)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return defaultReminderPolicy(projectID), nil
+	}
+	if err != nil {
+		logrus.WithFields(logrus.Fields{
+			"projectID": projectID,
+			"error":     err,
+		}).Error("Failed to retrieve reminder policy")
+		return nil, err
+	}
+	return policy, nil
+}
+
+// UpdateReminderPolicy replaces the project's reminder offsets and, when
+// given, its escalation grace period
+func (s *Service) UpdateReminderPolicy(projectID uint, input dto.ReminderPolicyInput) (*models.ReminderPolicy, error) {
+	if len(input.Offsets) > maxReminderOffsets {
+		return nil, invalidInput("at most %d reminder offsets are allowed", maxReminderOffsets)
+	}
+	offsets := []int{}
+	seen := map[int]bool{}
+	for _, offset := range input.Offsets {
+		if offset < 1 || offset > maxReminderMinutes {
+			return nil, invalidInput("reminder offsets must be between 1 and %d minutes", maxReminderMinutes)
+		}
+		if !seen[offset] {
+			seen[offset] = true
+			offsets = append(offsets, offset)
+		}
+	}
+	sort.Sort(sort.Reverse(sort.IntSlice(offsets)))
+	policy, err := s.GetReminderPolicy(projectID)
+	if err != nil {
+		return nil, err
+	}
+	policy.Offsets = offsets
+	if input.EscalateAfter != nil {
+		if *input.EscalateAfter < 0 || *input.EscalateAfter > maxReminderMinutes {
+			return nil, invalidInput("escalate_after must be between 0 and %d minutes", maxReminderMinutes)
+		}
+		policy.EscalateAfter = *input.EscalateAfter
+	}
+	if input.WorkingTime != nil {
+		policy.WorkingTime = *input.WorkingTime
+	}
+	if err := s.Repo.SaveReminderPolicy(policy); err != nil {
+		logrus.WithFields(logrus.Fields{
+			"projectID": projectID,
+			"error":     err,
+		}).Error("Failed to save reminder policy")
+		return nil, err
+	}
+	logrus.WithFields(logrus.Fields{
+		"projectID": projectID,
+		"offsets":   policy.Offsets,
+	}).Info("Reminder policy updated successfully")
+	return policy, nil
+}
+
+// reminderPolicies returns the saved policies by project; projects missing
+// from the map use the defaults
+func (s *Service) reminderPolicies() (map[uint]*models.ReminderPolicy, error) {
+	saved, err := s.Repo.GetReminderPolicies()
+	if err != nil {
+		return nil, err
+	}
+	policies := map[uint]*models.ReminderPolicy{}
+	for i := range saved {
+		policies[saved[i].ProjectID] = &saved[i]
+	}
+	return policies, nil
+}
+
+func policyFor(policies map[uint]*models.ReminderPolicy, projectID uint) *models.ReminderPolicy {
+	if policy, ok := policies[projectID]; ok {
+		return policy
+	}
+	return defaultReminderPolicy(projectID)
+}
+
+// CheckDueDates sends the due-date reminders that are due, marks tasks whose
+// due date has passed as overdue and escalates overdue tasks to the project
+// admins after the grace period. Every step is claimed in the database, so
+// running it again, after a restart or on another server, does not fire
+// anything twice. Moving a task's due date re-arms all three steps. Tasks
+// fall due at their deadline on their project's calendar, and policies
+// counting working time skip what lies outside its working hours.
+func (s *Service) CheckDueDates(now time.Time) error {
+	policies, err := s.reminderPolicies()
+	if err != nil {
+		logrus.WithFields(logrus.Fields{
+			"error": err,
+		}).Error("Failed to retrieve reminder policies")
+		return err
+	}
+	calendars := s.calendarLookup()
+	if err := s.sendDueReminders(now, policies, calendars); err != nil {
+		return err
+	}
+	if err := s.markOverdueTasks(now, calendars); err != nil {
+		return err
+	}
+	return s.escalateOverdueTasks(now, policies, calendars)
+}
+
+// policyDuration measures the time from from to to the way a reminder
+// policy counts it: around the clock, or only in working hours
+func policyDuration(policy *models.ReminderPolicy, cal *calendar.Calendar, from, to time.Time) time.Duration {
+	if policy.WorkingTime {
+		return time.Duration(cal.WorkingMinutes(from, to)) * time.Minute
+	}
+	return to.Sub(from)
+}
+
+// sendDueReminders reminds the assignees of open tasks whose deadline is
+// within one of their project's reminder offsets. A task that is first seen
+// within several offsets gets a single reminder.
+func (s *Service) sendDueReminders(now time.Time, policies map[uint]*models.ReminderPolicy, calendars func(uint) *calendar.Calendar) error {
+	horizon := 0
+	for _, offset := range defaultReminderOffsets {
+		horizon = max(horizon, offset)
+	}
+	until := now.Add(time.Duration(horizon) * time.Minute)
+	for _, policy := range policies {
+		for _, offset := range policy.Offsets {
+			end := now.Add(time.Duration(offset) * time.Minute)
+			if policy.WorkingTime {
+				// Working time stretches over nights and weekends
+				end = calendars(policy.ProjectID).AddWorkingMinutes(now, offset)
+			}
+			if end.After(until) {
+				until = end
+			}
+		}
+	}
+	tasks, err := s.Repo.GetTasksDueBetween(now, until)
+	if err != nil {
+		logrus.WithFields(logrus.Fields{
+			"error": err,
+		}).Error("Failed to retrieve tasks due soon")
+		return err
+	}
+	reminded := 0
+	for _, task := range tasks {
+		policy, cal := policyFor(policies, task.ProjectID), calendars(task.ProjectID)
+		left := policyDuration(policy, cal, now, taskDeadline(&task, cal))
+		var offsets []int
+		for _, offset := range policy.Offsets {
+			if left <= time.Duration(offset)*time.Minute {
+				offsets = append(offsets, offset)
+			}
+		}
+		if len(offsets) == 0 || len(task.Assignees) == 0 {
+			continue
+		}
+		err := s.transaction(func(repo *repository.Repository) error {
+			var recipients []uint
+			for _, assignee := range task.Assignees {
+				claimed := false
+				for _, offset := range offsets {
+					ok, err := repo.ClaimTaskReminder(task.ID, assignee.ID, task.DueDate, offset)
+					if err != nil {
+						return err
+					}
+					claimed = claimed || ok
+				}
+				if claimed {
+					recipients = append(recipients, assignee.ID)
+				}
+			}
+			if len(recipients) == 0 {
+				return nil
+			}
+			reminded++
+			return s.recordEvent(repo, Event{
+				Type:       EventTaskDueSoon,
+				ProjectID:  task.ProjectID,
+				TaskID:     task.ID,
+				Recipients: recipients,
+				Notice:     fmt.Sprintf("Task %q is due %s", task.Title, dueText(&task, cal)),
+				Data:       map[string]interface{}{"due_date": task.DueDate, "due_date_only": task.DueDateOnly},
+			})
+		})
+		if err != nil {
+			logrus.WithFields(logrus.Fields{
+				"taskID": task.ID,
+				"error":  err,
+			}).Error("Failed to send due-date reminders")
+			return err
+		}
+	}
+	logrus.WithFields(logrus.Fields{
+		"taskCount": len(tasks),
+		"reminded":  reminded,
+	}).Info("Due-date reminders checked")
+	return nil
+}
+
+// markOverdueTasks marks open tasks past their deadline as overdue and tells
+// their assignees
+func (s *Service) markOverdueTasks(now time.Time, calendars func(uint) *calendar.Calendar) error {
+	tasks, err := s.Repo.GetTasksToMarkOverdue(now)
+	if err != nil {
+		logrus.WithFields(logrus.Fields{
+			"error": err,
+		}).Error("Failed to retrieve overdue tasks")
+		return err
+	}
+	for _, task := range tasks {
+		err := s.transaction(func(repo *repository.Repository) error {
+			marked, err := repo.MarkTaskOverdue(task.ID, task.DueDate, now)
+			if err != nil || !marked {
+				return err
+			}
+			return s.recordEvent(repo, Event{
+				Type:       EventTaskOverdue,
+				ProjectID:  task.ProjectID,
+				TaskID:     task.ID,
+				Recipients: userIDs(task.Assignees),
+				Notice:     fmt.Sprintf("Task %q is overdue; it was due %s", task.Title, dueText(&task, calendars(task.ProjectID))),
+				Data:       map[string]interface{}{"due_date": task.DueDate, "due_date_only": task.DueDateOnly},
+			})
+		})
+		if err != nil {
+			logrus.WithFields(logrus.Fields{
+				"taskID": task.ID,
+				"error":  err,
+			}).Error("Failed to mark task overdue")
+			return err
+		}
+	}
+	if len(tasks) > 0 {
+		logrus.WithFields(logrus.Fields{
+			"taskCount": len(tasks),
+		}).Info("Tasks marked overdue")
+	}
+	return nil
+}
+
+// escalateOverdueTasks tells the project admins about tasks still open once
+// the grace period after their deadline has passed
+func (s *Service) escalateOverdueTasks(now time.Time, policies map[uint]*models.ReminderPolicy, calendars func(uint) *calendar.Calendar) error {
+	tasks, err := s.Repo.GetTasksToEscalate()
+	if err != nil {
+		logrus.WithFields(logrus.Fields{
+			"error": err,
+		}).Error("Failed to retrieve tasks to escalate")
+		return err
+	}
+	escalated := 0
+	for _, task := range tasks {
+		policy, cal := policyFor(policies, task.ProjectID), calendars(task.ProjectID)
+		grace := time.Duration(policy.EscalateAfter) * time.Minute
+		if grace == 0 || policyDuration(policy, cal, taskDeadline(&task, cal), now) < grace {
+			continue
+		}
+		err := s.transaction(func(repo *repository.Repository) error {
+			marked, err := repo.MarkTaskEscalated(task.ID, task.DueDate, now)
+			if err != nil || !marked {
+				return err
+			}
+			admins, err := repo.GetProjectAdminIDs(task.ProjectID)
+			if err != nil {
+				return err
+			}
+			escalated++
+			return s.recordEvent(repo, Event{
+				Type:       EventTaskEscalated,
+				ProjectID:  task.ProjectID,
+				TaskID:     task.ID,
+				Recipients: admins,
+				Notice:     fmt.Sprintf("Task %q is still open; it was due %s", task.Title, dueText(&task, cal)),
+				Data:       map[string]interface{}{"due_date": task.DueDate, "due_date_only": task.DueDateOnly},
+			})
+		})
+		if err != nil {
+			logrus.WithFields(logrus.Fields{
+				"taskID": task.ID,
+				"error":  err,
+			}).Error("Failed to escalate overdue task")
+			return err
+		}
+	}
+	if escalated > 0 {
+		logrus.WithFields(logrus.Fields{
+			"taskCount": escalated,
+		}).Info("Overdue tasks escalated")
+	}
+	return nil
+}
+
+// GetOverdueTasks lists the project's open tasks whose due date has passed,
+// oldest due date first unless the filter sorts otherwise
+func (s *Service) GetOverdueTasks(projectID uint, filter dto.TaskFilter) ([]models.Task, error) {
+	filter.Overdue = true
+	if filter.Sort == "" {
+		filter.Sort = "due_date"
+	}
+	return s.GetTasksByProjectID(projectID, filter)
+}
+
+// GetMyOverdueTasks lists the overdue tasks assigned to the user across the
+// projects they belong to
+func (s *Service) GetMyOverdueTasks(userID uint, filter dto.TaskFilter) ([]models.Task, error) {
+	filter.Overdue = true
+	filter.AssigneeID = userID
+	filter.VisibleToUserID = userID
+	if filter.Sort == "" {
+		filter.Sort = "due_date"
+	}
+	return s.GetTasks(filter)
+}
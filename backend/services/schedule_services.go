@@ -0,0 +1,476 @@
+// Schedule-related services (start dates, dependencies, Gantt timelines and
+// auto-scheduling)
+package services
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"time"
+
+	"work-management/calendar"
+	"work-management/dto"
+	"work-management/models"
+	"work-management/repository"
+
+	"github.com/sirupsen/logrus"
+	"gorm.io/gorm"
+)
+
+// planTaskDates checks a task starts no later than it is due and works out
+// its duration in working days of its project's calendar
+func planTaskDates(task *models.Task, cal *calendar.Calendar) error {
+	task.Duration = 0
+	if task.StartDate == nil {
+		return nil
+	}
+	if task.StartDate.After(taskDeadline(task, cal)) {
+		return invalidInput("start_date is after due_date")
+	}
+	task.Duration = max(cal.DaysBetween(*task.StartDate, dueDay(task, cal)), 0)
+	return nil
+}
+
+// dueDay returns a moment on the day a task is due, for arithmetic on days
+func dueDay(task *models.Task, cal *calendar.Calendar) time.Time {
+	if task.DueDateOnly {
+		return cal.OnDate(task.DueDate)
+	}
+	return task.DueDate
+}
+
+// setDueDay moves a task's due date to a moment found by arithmetic on
+// dueDay, keeping only its day for date-only due dates
+func setDueDay(task *models.Task, due time.Time, cal *calendar.Calendar) {
+	if task.DueDateOnly {
+		due = calendar.Date(due.In(cal.Location))
+	}
+	task.DueDate = due
+}
+
+// plannedStart is when a task starts on a timeline: its start date, or its
+// due date when it has none
+func plannedStart(task *models.Task) time.Time {
+	if task.StartDate != nil {
+		return *task.StartDate
+	}
+	return task.DueDate
+}
+
+// shiftTask moves a task's planned dates by a number of working days
+func shiftTask(task *models.Task, days int, cal *calendar.Calendar) {
+	if task.StartDate != nil {
+		start := cal.AddWorkingDays(*task.StartDate, days)
+		task.StartDate = &start
+	}
+	setDueDay(task, cal.AddWorkingDays(dueDay(task, cal), days), cal)
+	task.OverdueAt = nil
+	task.EscalatedAt = nil
+}
+
+// cascadeDates pushes back the tasks waiting for root, directly or not, that
+// would start before what they wait for is due (plus the lag, in working
+// days). Tasks are only ever pushed later, by working days of the project's
+// calendar, keeping their duration; completed tasks and tasks without dates
+// stay put. It returns the tasks it moved, in the order they were first
+// moved.
+func cascadeDates(root *models.Task, tasks map[uint]*models.Task, dependents map[uint][]models.TaskDependency, cal *calendar.Calendar) []*models.Task {
+	var moved []*models.Task
+	seen := map[uint]bool{}
+	queue := []*models.Task{root}
+	for len(queue) > 0 {
+		current := queue[0]
+		queue = queue[1:]
+		if current.DueDate.IsZero() {
+			continue
+		}
+		for _, dependency := range dependents[current.ID] {
+			next, ok := tasks[dependency.TaskID]
+			if !ok || next.ID == root.ID || next.CompletedAt != nil || next.DueDate.IsZero() {
+				continue
+			}
+			// Work starts on a working day, at the earliest the one the task
+			// waited for is due on
+			earliest := cal.NextWorkingDay(cal.AddWorkingDays(dueDay(current, cal), dependency.LagDays))
+			start := dueDay(next, cal)
+			if next.StartDate != nil {
+				start = *next.StartDate
+			}
+			days := cal.DaysBetween(start, earliest)
+			if days <= 0 {
+				continue
+			}
+			shiftTask(next, days, cal)
+			if !seen[next.ID] {
+				seen[next.ID] = true
+				moved = append(moved, next)
+			}
+			queue = append(queue, next)
+		}
+	}
+	return moved
+}
+
+// projectSchedule loads the live tasks of a project by ID, with the
+// dependencies of each task on the others keyed by what they wait for
+func projectSchedule(repo *repository.Repository, projectID uint) (map[uint]*models.Task, map[uint][]models.TaskDependency, error) {
+	list, err := repo.GetTasksByProjectID(projectID, dto.TaskFilter{})
+	if err != nil {
+		return nil, nil, err
+	}
+	tasks := make(map[uint]*models.Task, len(list))
+	for i := range list {
+		tasks[list[i].ID] = &list[i]
+	}
+	dependencies, err := repo.GetProjectDependencies(projectID)
+	if err != nil {
+		return nil, nil, err
+	}
+	dependents := map[uint][]models.TaskDependency{}
+	for _, dependency := range dependencies {
+		dependents[dependency.DependsOnID] = append(dependents[dependency.DependsOnID], dependency)
+	}
+	return tasks, dependents, nil
+}
+
+// rescheduleDependents pushes back the tasks waiting for a task whose dates
+// were just saved, and records an event for each of them
+func (s *Service) rescheduleDependents(repo *repository.Repository, actorID uint, task *models.Task) error {
+	tasks, dependents, err := projectSchedule(repo, task.ProjectID)
+	if err != nil {
+		return err
+	}
+	cal, err := s.projectCalendar(repo, task.ProjectID)
+	if err != nil {
+		return err
+	}
+	root := *task
+	tasks[task.ID] = &root
+	for _, moved := range cascadeDates(&root, tasks, dependents, cal) {
+		if err := repo.SetTaskDates(moved); err != nil {
+			return err
+		}
+		if err := s.recordEvent(repo, Event{
+			Type:      EventTaskUpdated,
+			ProjectID: moved.ProjectID,
+			TaskID:    moved.ID,
+			ActorID:   actorID,
+			Message:   fmt.Sprintf("rescheduled task %q after %q", moved.Title, task.Title),
+			Data: map[string]interface{}{
+				"start_date": moved.StartDate,
+				"due_date":   moved.DueDate,
+			},
+		}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// ScheduleTask moves a task's dates and pushes back the tasks waiting for it.
+// A dry run returns the same changes without saving them.
+func (s *Service) ScheduleTask(actorID, taskID uint, input dto.ScheduleInput) (*dto.ScheduleResult, error) {
+	task, err := s.Repo.GetTaskByID(taskID)
+	if err != nil {
+		return nil, err
+	}
+	result := &dto.ScheduleResult{DryRun: input.DryRun, Changes: []dto.ScheduleChange{}}
+	schedule := func(repo *repository.Repository) error {
+		tasks, dependents, err := projectSchedule(repo, task.ProjectID)
+		if err != nil {
+			return err
+		}
+		root, ok := tasks[taskID]
+		if !ok {
+			return gorm.ErrRecordNotFound
+		}
+		cal, err := s.projectCalendar(repo, task.ProjectID)
+		if err != nil {
+			return err
+		}
+		before := map[uint]models.Task{}
+		for id, t := range tasks {
+			before[id] = *t
+		}
+		start := input.StartDate
+		root.StartDate = &start
+		if input.DueDate != nil {
+			root.DueDate = *input.DueDate
+			normalizeDueDate(root)
+		} else {
+			setDueDay(root, cal.AddWorkingDays(start, root.Duration), cal)
+		}
+		if err := planTaskDates(root, cal); err != nil {
+			return err
+		}
+		root.OverdueAt = nil
+		root.EscalatedAt = nil
+		changed := append([]*models.Task{root}, cascadeDates(root, tasks, dependents, cal)...)
+		for _, t := range changed {
+			old := before[t.ID]
+			result.Changes = append(result.Changes, dto.ScheduleChange{
+				TaskID:       t.ID,
+				Title:        t.Title,
+				OldStartDate: old.StartDate,
+				OldDueDate:   old.DueDate,
+				StartDate:    t.StartDate,
+				DueDate:      t.DueDate,
+			})
+		}
+		if input.DryRun {
+			return nil
+		}
+		for _, t := range changed {
+			if err := repo.SetTaskDates(t); err != nil {
+				return err
+			}
+		}
+		return s.recordEvent(repo, Event{
+			Type:      EventTaskUpdated,
+			ProjectID: root.ProjectID,
+			TaskID:    root.ID,
+			ActorID:   actorID,
+			Message:   fmt.Sprintf("rescheduled task %q, moving %d tasks waiting for it", root.Title, len(changed)-1),
+			Data: map[string]interface{}{
+				"start_date": root.StartDate,
+				"due_date":   root.DueDate,
+			},
+		})
+	}
+	if input.DryRun {
+		err = schedule(s.Repo)
+	} else {
+		err = s.transaction(func(repo *repository.Repository) error {
+			// Concurrent reschedulings of the project would push from stale dates
+			if err := repo.LockProject(task.ProjectID); err != nil {
+				return err
+			}
+			return schedule(repo)
+		})
+	}
+	if err != nil {
+		logrus.WithFields(logrus.Fields{
+			"taskID": taskID,
+			"dryRun": input.DryRun,
+			"error":  err,
+		}).Error("Failed to reschedule task")
+		return nil, err
+	}
+	return result, nil
+}
+
+func (s *Service) GetTaskDependencies(taskID uint) (*dto.TaskDependencies, error) {
+	dependsOn, err := s.Repo.GetDependencies(taskID)
+	if err != nil {
+		return nil, err
+	}
+	dependents, err := s.Repo.GetDependents(taskID)
+	if err != nil {
+		return nil, err
+	}
+	return &dto.TaskDependencies{DependsOn: dependsOn, Dependents: dependents}, nil
+}
+
+// AddDependency makes a task wait for another task of its project, refusing
+// dependencies that would go round in a circle
+func (s *Service) AddDependency(actorID, taskID uint, input dto.DependencyInput) (*models.TaskDependency, error) {
+	if input.DependsOnID == taskID {
+		return nil, invalidInput("a task cannot depend on itself")
+	}
+	if input.LagDays < 0 {
+		return nil, invalidInput("lag_days cannot be negative")
+	}
+	task, err := s.Repo.GetTaskByID(taskID)
+	if err != nil {
+		return nil, err
+	}
+	dependsOn, err := s.Repo.GetTaskByID(input.DependsOnID)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, invalidInput("task %d not found", input.DependsOnID)
+		}
+		return nil, err
+	}
+	if dependsOn.ProjectID != task.ProjectID {
+		return nil, invalidInput("task %d belongs to another project", input.DependsOnID)
+	}
+	dependency := &models.TaskDependency{TaskID: taskID, DependsOnID: input.DependsOnID, LagDays: input.LagDays}
+	err = s.transaction(func(repo *repository.Repository) error {
+		if err := repo.LockProject(task.ProjectID); err != nil {
+			return err
+		}
+		dependencies, err := repo.GetProjectDependencies(task.ProjectID)
+		if err != nil {
+			return err
+		}
+		// Walk what the new predecessor waits for; reaching the task is a cycle
+		waitsFor := map[uint][]uint{}
+		for _, existing := range dependencies {
+			if existing.TaskID == taskID && existing.DependsOnID == input.DependsOnID {
+				return invalidInput("task already depends on task %d", input.DependsOnID)
+			}
+			waitsFor[existing.TaskID] = append(waitsFor[existing.TaskID], existing.DependsOnID)
+		}
+		seen := map[uint]bool{}
+		pending := []uint{input.DependsOnID}
+		for len(pending) > 0 {
+			id := pending[len(pending)-1]
+			pending = pending[:len(pending)-1]
+			if id == taskID {
+				return invalidInput("task %d already waits for this task", input.DependsOnID)
+			}
+			if !seen[id] {
+				seen[id] = true
+				pending = append(pending, waitsFor[id]...)
+			}
+		}
+		if err := repo.CreateDependency(dependency); err != nil {
+			return err
+		}
+		return s.recordEvent(repo, Event{
+			Type:      EventTaskUpdated,
+			ProjectID: task.ProjectID,
+			TaskID:    taskID,
+			ActorID:   actorID,
+			Message:   fmt.Sprintf("made task %q wait for %q", task.Title, dependsOn.Title),
+			Data:      map[string]interface{}{"depends_on_id": input.DependsOnID},
+		})
+	})
+	if err != nil {
+		logrus.WithFields(logrus.Fields{
+			"taskID":      taskID,
+			"dependsOnID": input.DependsOnID,
+			"error":       err,
+		}).Error("Failed to add task dependency")
+		return nil, err
+	}
+	return dependency, nil
+}
+
+func (s *Service) RemoveDependency(actorID, taskID, dependsOnID uint) error {
+	task, err := s.Repo.GetTaskByID(taskID)
+	if err != nil {
+		return err
+	}
+	err = s.transaction(func(repo *repository.Repository) error {
+		if err := repo.DeleteDependency(taskID, dependsOnID); err != nil {
+			return err
+		}
+		return s.recordEvent(repo, Event{
+			Type:      EventTaskUpdated,
+			ProjectID: task.ProjectID,
+			TaskID:    taskID,
+			ActorID:   actorID,
+			Message:   fmt.Sprintf("stopped task %q waiting for task %d", task.Title, dependsOnID),
+			Data:      map[string]interface{}{"depends_on_id": nil},
+		})
+	})
+	if err != nil {
+		logrus.WithFields(logrus.Fields{
+			"taskID":      taskID,
+			"dependsOnID": dependsOnID,
+			"error":       err,
+		}).Error("Failed to remove task dependency")
+		return err
+	}
+	return nil
+}
+
+// GetGantt lays out a project's dated tasks, the dependencies between them
+// and its milestones, tasks ordered by start
+func (s *Service) GetGantt(projectID uint) (*dto.Gantt, error) {
+	tasks, err := s.Repo.GetTasksByProjectID(projectID, dto.TaskFilter{})
+	if err != nil {
+		logrus.WithFields(logrus.Fields{
+			"projectID": projectID,
+			"error":     err,
+		}).Error("Failed to load project tasks")
+		return nil, err
+	}
+	dependencies, err := s.Repo.GetProjectDependencies(projectID)
+	if err != nil {
+		return nil, err
+	}
+	milestones, err := s.Repo.GetMilestonesByProjectID(projectID)
+	if err != nil {
+		return nil, err
+	}
+	category, err := s.statusCategories(s.Repo, projectID)
+	if err != nil {
+		return nil, err
+	}
+	gantt := &dto.Gantt{
+		Tasks:        []dto.GanttTask{},
+		Dependencies: []models.TaskDependency{},
+		Milestones:   []dto.GanttMilestone{},
+	}
+	extend := func(t time.Time) {
+		if gantt.Start == nil || t.Before(*gantt.Start) {
+			gantt.Start = &t
+		}
+		if gantt.End == nil || t.After(*gantt.End) {
+			gantt.End = &t
+		}
+	}
+	waitsFor := map[uint][]uint{}
+	for _, dependency := range dependencies {
+		waitsFor[dependency.TaskID] = append(waitsFor[dependency.TaskID], dependency.DependsOnID)
+	}
+	dated := map[uint]bool{}
+	for _, task := range tasks {
+		if task.DueDate.IsZero() {
+			continue
+		}
+		dated[task.ID] = true
+		item := dto.GanttTask{
+			ID:          task.ID,
+			Title:       task.Title,
+			ParentID:    task.ParentID,
+			MilestoneID: task.MilestoneID,
+			AssigneeID:  task.UserID,
+			Status:      task.Status,
+			Category:    category(task.Status),
+			Start:       plannedStart(&task),
+			End:         task.DueDate,
+			Duration:    task.Duration,
+			DependsOn:   []uint{},
+		}
+		switch {
+		case item.Category == models.CategoryDone:
+			item.Progress = 100
+		case task.OriginalEstimate > 0 && task.RemainingEstimate < task.OriginalEstimate:
+			item.Progress = 100 * float64(task.OriginalEstimate-task.RemainingEstimate) / float64(task.OriginalEstimate)
+		}
+		extend(item.Start)
+		extend(item.End)
+		gantt.Tasks = append(gantt.Tasks, item)
+	}
+	for i := range gantt.Tasks {
+		for _, id := range waitsFor[gantt.Tasks[i].ID] {
+			if dated[id] {
+				gantt.Tasks[i].DependsOn = append(gantt.Tasks[i].DependsOn, id)
+			}
+		}
+	}
+	for _, dependency := range dependencies {
+		if dated[dependency.TaskID] && dated[dependency.DependsOnID] {
+			gantt.Dependencies = append(gantt.Dependencies, dependency)
+		}
+	}
+	sort.SliceStable(gantt.Tasks, func(i, j int) bool {
+		if !gantt.Tasks[i].Start.Equal(gantt.Tasks[j].Start) {
+			return gantt.Tasks[i].Start.Before(gantt.Tasks[j].Start)
+		}
+		return gantt.Tasks[i].ID < gantt.Tasks[j].ID
+	})
+	for _, milestone := range milestones {
+		extend(milestone.TargetDate)
+		gantt.Milestones = append(gantt.Milestones, dto.GanttMilestone{
+			ID:       milestone.ID,
+			Name:     milestone.Name,
+			Date:     milestone.TargetDate,
+			Released: milestone.ReleasedAt,
+		})
+	}
+	return gantt, nil
+}
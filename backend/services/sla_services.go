@@ -0,0 +1,388 @@
+// SLA-related services (SLA policies, task clocks and breaches)
+package services
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"work-management/calendar"
+	"work-management/dto"
+	"work-management/models"
+	"work-management/repository"
+
+	"github.com/sirupsen/logrus"
+	"gorm.io/gorm"
+)
+
+// SLA settings
+const (
+	// slaCheckInterval is how often running clocks are checked for breaches;
+	// a breach is reported up to this long after it happens
+	slaCheckInterval = time.Minute
+	// maxSLAMinutes bounds targets to 90 days
+	maxSLAMinutes = 90 * 24 * 60
+)
+
+// GetSLAPolicy returns the project's SLA policy; projects without one have
+// no SLA clocks
+func (s *Service) GetSLAPolicy(projectID uint) (*models.SLAPolicy, error) {
+	policy, err := s.Repo.GetSLAPolicy(projectID)
+	if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+		logrus.WithFields(logrus.Fields{
+			"projectID": projectID,
+			"error":     err,
+		}).Error("Failed to retrieve SLA policy")
+	}
+	return policy, err
+}
+
+// UpdateSLAPolicy replaces the project's SLA policy and brings the clocks of
+// its open tasks in line with it. Tasks that had no clock yet start theirs
+// now.
+func (s *Service) UpdateSLAPolicy(projectID uint, input dto.SLAPolicyInput) (*models.SLAPolicy, error) {
+	targets := []models.SLATarget{}
+	seen := map[string]bool{}
+	for _, target := range input.Targets {
+		if _, ok := models.PriorityRank[target.Priority]; !ok {
+			return nil, invalidInput("unknown priority %q", target.Priority)
+		}
+		if seen[target.Priority] {
+			return nil, invalidInput("priority %q is listed twice", target.Priority)
+		}
+		seen[target.Priority] = true
+		if target.Response < 0 || target.Response > maxSLAMinutes || target.Resolution < 0 || target.Resolution > maxSLAMinutes {
+			return nil, invalidInput("SLA targets must be between 0 and %d minutes", maxSLAMinutes)
+		}
+		targets = append(targets, target)
+	}
+	statuses, err := s.GetWorkflow(projectID)
+	if err != nil {
+		return nil, err
+	}
+	known := map[string]bool{}
+	for _, status := range statuses {
+		known[status.Name] = true
+	}
+	paused := []string{}
+	for _, name := range input.PausedStatuses {
+		name = strings.TrimSpace(name)
+		if !known[name] {
+			return nil, invalidInput("status %q is not part of the project's workflow", name)
+		}
+		paused = append(paused, name)
+	}
+	policy := &models.SLAPolicy{
+		ProjectID:      projectID,
+		Targets:        targets,
+		PausedStatuses: paused,
+		WorkingTime:    input.WorkingTime,
+	}
+	now := time.Now()
+	err = s.transaction(func(repo *repository.Repository) error {
+		if err := repo.SaveSLAPolicy(policy); err != nil {
+			return err
+		}
+		taskIDs, err := repo.GetOpenTaskIDs(projectID)
+		if err != nil {
+			return err
+		}
+		tracker, err := s.slaTracker(repo, policy)
+		if err != nil {
+			return err
+		}
+		for _, taskID := range taskIDs {
+			task, err := repo.GetTaskByID(taskID)
+			if err != nil {
+				return err
+			}
+			if err := tracker.sync(task, now); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		logrus.WithFields(logrus.Fields{
+			"projectID": projectID,
+			"error":     err,
+		}).Error("Failed to save SLA policy")
+		return nil, err
+	}
+	logrus.WithFields(logrus.Fields{
+		"projectID": projectID,
+		"targets":   len(policy.Targets),
+	}).Info("SLA policy updated successfully")
+	return s.GetSLAPolicy(projectID)
+}
+
+// DeleteSLAPolicy removes the project's SLA policy along with the clocks of
+// its tasks
+func (s *Service) DeleteSLAPolicy(projectID uint) error {
+	deleted, err := s.Repo.DeleteSLAPolicy(projectID)
+	if err != nil {
+		logrus.WithFields(logrus.Fields{
+			"projectID": projectID,
+			"error":     err,
+		}).Error("Failed to delete SLA policy")
+		return err
+	}
+	if !deleted {
+		return gorm.ErrRecordNotFound
+	}
+	logrus.WithFields(logrus.Fields{
+		"projectID": projectID,
+	}).Info("SLA policy deleted successfully")
+	return nil
+}
+
+// slaTracker keeps the SLA clocks of one project's tasks in step with them
+type slaTracker struct {
+	s        *Service
+	repo     *repository.Repository
+	policy   *models.SLAPolicy
+	cal      *calendar.Calendar
+	category func(status string) string
+}
+
+func (s *Service) slaTracker(repo *repository.Repository, policy *models.SLAPolicy) (*slaTracker, error) {
+	cal, err := s.projectCalendar(repo, policy.ProjectID)
+	if err != nil {
+		return nil, err
+	}
+	category, err := s.statusCategories(repo, policy.ProjectID)
+	if err != nil {
+		return nil, err
+	}
+	return &slaTracker{s: s, repo: repo, policy: policy, cal: cal, category: category}, nil
+}
+
+// minutes measures the time from from to to the way the policy counts it:
+// around the clock, or only in working hours
+func (t *slaTracker) minutes(from, to time.Time) int {
+	if t.policy.WorkingTime {
+		return t.cal.WorkingMinutes(from, to)
+	}
+	return int(to.Sub(from).Round(time.Minute) / time.Minute)
+}
+
+// add returns when a clock started at from reaches the given minutes
+func (t *slaTracker) add(from time.Time, minutes int) time.Time {
+	if t.policy.WorkingTime {
+		return t.cal.AddWorkingMinutes(from, minutes)
+	}
+	return from.Add(time.Duration(minutes) * time.Minute)
+}
+
+func (t *slaTracker) paused(status string) bool {
+	for _, name := range t.policy.PausedStatuses {
+		if name == status {
+			return true
+		}
+	}
+	return false
+}
+
+// reached reports whether the task has met a metric as of at: a response
+// is a first comment or leaving the to-do statuses, a resolution is
+// completing the task
+func (t *slaTracker) reached(task *models.Task, metric string, at time.Time) (bool, error) {
+	if metric == models.SLAResolution {
+		return task.CompletedAt != nil, nil
+	}
+	if t.category(task.Status) != models.CategoryToDo {
+		return true, nil
+	}
+	return t.repo.HasTaskComments(task.ID, at)
+}
+
+// sync brings the task's clocks to its state as of at. Clocks count from
+// their last sync until at, then start, pause, resume or stop depending on
+// where the task is now, and their targets follow the task's priority. A
+// clock that went over its target in between is marked breached. Changes
+// older than a clock's last sync are ignored, as events can be replayed.
+func (t *slaTracker) sync(task *models.Task, at time.Time) error {
+	clocks, err := t.repo.LockSLAClocks(task.ID)
+	if err != nil {
+		return err
+	}
+	byMetric := map[string]*models.SLAClock{}
+	for i := range clocks {
+		byMetric[clocks[i].Metric] = &clocks[i]
+	}
+	for _, metric := range models.SLAMetrics {
+		clock := byMetric[metric]
+		if clock != nil && clock.SyncedAt.After(at) {
+			continue
+		}
+		reached, err := t.reached(task, metric, at)
+		if err != nil {
+			return err
+		}
+		target := t.policy.Target(task.Priority, metric)
+		if clock == nil {
+			if reached || target == 0 {
+				continue
+			}
+			clock = &models.SLAClock{TaskID: task.ID, ProjectID: task.ProjectID, Metric: metric}
+		}
+		if clock.RunningSince != nil {
+			if err := t.checkBreach(task, clock, at); err != nil {
+				return err
+			}
+			clock.Elapsed += t.minutes(*clock.RunningSince, at)
+			clock.RunningSince, clock.DueAt = nil, nil
+		}
+		switch {
+		case reached:
+			if clock.StoppedAt == nil {
+				clock.StoppedAt = &at
+			}
+		case clock.StoppedAt != nil && metric == models.SLAResponse:
+			// A response cannot be taken back
+		case target == 0:
+			if clock.StoppedAt == nil && clock.ID != 0 {
+				if err := t.repo.DeleteSLAClock(clock.ID); err != nil {
+					return err
+				}
+			}
+			continue
+		default:
+			// Reopened tasks carry on from the time already counted
+			clock.StoppedAt = nil
+			clock.Target = target
+			if !t.paused(task.Status) {
+				since, due := at, t.add(at, max(target-clock.Elapsed, 0))
+				clock.RunningSince, clock.DueAt = &since, &due
+			}
+		}
+		clock.SyncedAt = at
+		if err := t.repo.SaveSLAClock(clock); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// checkBreach marks a running clock breached, and tells the task's
+// assignees and the project admins, if it reached its target by at
+func (t *slaTracker) checkBreach(task *models.Task, clock *models.SLAClock, at time.Time) error {
+	if clock.BreachedAt != nil || clock.DueAt == nil || clock.DueAt.After(at) {
+		return nil
+	}
+	breachedAt := *clock.DueAt
+	clock.BreachedAt = &breachedAt
+	return t.s.recordSLABreach(t.repo, task, clock)
+}
+
+func (s *Service) recordSLABreach(repo *repository.Repository, task *models.Task, clock *models.SLAClock) error {
+	admins, err := repo.GetProjectAdminIDs(task.ProjectID)
+	if err != nil {
+		return err
+	}
+	return s.recordEvent(repo, Event{
+		Type:       EventTaskSLABreached,
+		ProjectID:  task.ProjectID,
+		TaskID:     task.ID,
+		Recipients: append(userIDs(task.Assignees), admins...),
+		Notice:     fmt.Sprintf("Task %q breached its %s SLA", task.Title, clock.Metric),
+		Data: map[string]interface{}{
+			"metric":      clock.Metric,
+			"target":      clock.Target,
+			"breached_at": clock.BreachedAt,
+		},
+	})
+}
+
+// trackSLAs updates the SLA clocks of the task an event is about. It
+// applies the task as it is now at the time of the event; since the relay
+// publishes events within moments, a change undone in between is not seen.
+func (s *Service) trackSLAs(event Event) error {
+	switch event.Type {
+	case EventTaskCreated, EventTaskUpdated, EventTaskRestored, EventCommentCreated:
+	default:
+		return nil
+	}
+	if event.TaskID == 0 {
+		return nil
+	}
+	policy, err := s.Repo.GetSLAPolicy(event.ProjectID)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	err = s.transaction(func(repo *repository.Repository) error {
+		task, err := repo.GetTaskByID(event.TaskID)
+		if err != nil {
+			return err
+		}
+		if task.ProjectID != policy.ProjectID {
+			// Moved to another project, whose events take over
+			return nil
+		}
+		tracker, err := s.slaTracker(repo, policy)
+		if err != nil {
+			return err
+		}
+		return tracker.sync(task, event.OccurredAt)
+	})
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		// The task was deleted since
+		return nil
+	}
+	if err != nil {
+		logrus.WithFields(logrus.Fields{
+			"taskID": event.TaskID,
+			"event":  event.Type,
+			"error":  err,
+		}).Error("Failed to update SLA clocks")
+	}
+	return err
+}
+
+// CheckSLABreaches marks running clocks that reached their target as
+// breached and tells the task's assignees and the project admins. Breaches
+// are claimed in the database, so each is reported once.
+func (s *Service) CheckSLABreaches(now time.Time) error {
+	clocks, err := s.Repo.GetSLAClocksToBreach(now)
+	if err != nil {
+		logrus.WithFields(logrus.Fields{
+			"error": err,
+		}).Error("Failed to retrieve SLA clocks to check")
+		return err
+	}
+	breached := 0
+	for _, clock := range clocks {
+		err := s.transaction(func(repo *repository.Repository) error {
+			marked, err := repo.MarkSLABreached(clock.ID, *clock.DueAt)
+			if err != nil || !marked {
+				return err
+			}
+			task, err := repo.GetTaskByID(clock.TaskID)
+			if err != nil {
+				return err
+			}
+			breachedAt := *clock.DueAt
+			clock.BreachedAt = &breachedAt
+			breached++
+			return s.recordSLABreach(repo, task, &clock)
+		})
+		if err != nil {
+			logrus.WithFields(logrus.Fields{
+				"taskID": clock.TaskID,
+				"metric": clock.Metric,
+				"error":  err,
+			}).Error("Failed to mark SLA breach")
+			return err
+		}
+	}
+	if breached > 0 {
+		logrus.WithFields(logrus.Fields{
+			"clockCount": breached,
+		}).Info("SLA breaches recorded")
+	}
+	return nil
+}
@@ -0,0 +1,499 @@
+// Sprint-related services (sprint planning, start and close-out, reports)
+package services
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"work-management/dto"
+	"work-management/models"
+	"work-management/repository"
+
+	"github.com/sirupsen/logrus"
+	"gorm.io/gorm"
+)
+
+func validateSprintInput(input *dto.SprintInput) error {
+	input.Name = strings.TrimSpace(input.Name)
+	if input.Name == "" {
+		return invalidInput("sprint name is required")
+	}
+	if !input.EndDate.After(input.StartDate) {
+		return invalidInput("sprint must end after it starts")
+	}
+	return nil
+}
+
+func (s *Service) GetSprints(projectID uint) ([]models.Sprint, error) {
+	sprints, err := s.Repo.GetSprintsByProjectID(projectID)
+	if err != nil {
+		logrus.WithFields(logrus.Fields{
+			"projectID": projectID,
+			"error":     err,
+		}).Error("Failed to retrieve sprints")
+		return nil, err
+	}
+	return sprints, nil
+}
+
+func (s *Service) GetSprint(sprintID uint) (*models.Sprint, error) {
+	return s.Repo.GetSprintByID(sprintID)
+}
+
+func (s *Service) CreateSprint(projectID uint, input dto.SprintInput) (*models.Sprint, error) {
+	if err := validateSprintInput(&input); err != nil {
+		return nil, err
+	}
+	sprint := &models.Sprint{
+		ProjectID: projectID,
+		Name:      input.Name,
+		Goal:      input.Goal,
+		StartDate: input.StartDate,
+		EndDate:   input.EndDate,
+		State:     models.SprintPlanned,
+	}
+	if err := s.Repo.CreateSprint(sprint); err != nil {
+		logrus.WithFields(logrus.Fields{
+			"projectID": projectID,
+			"error":     err,
+		}).Error("Failed to create sprint")
+		return nil, err
+	}
+	logrus.WithFields(logrus.Fields{
+		"projectID": projectID,
+		"sprintID":  sprint.ID,
+	}).Info("Sprint created successfully")
+	return sprint, nil
+}
+
+// UpdateSprint changes the details of a sprint that is not closed
+func (s *Service) UpdateSprint(sprintID uint, input dto.SprintInput) (*models.Sprint, error) {
+	if err := validateSprintInput(&input); err != nil {
+		return nil, err
+	}
+	sprint, err := s.Repo.GetSprintByID(sprintID)
+	if err != nil {
+		return nil, err
+	}
+	if sprint.State == models.SprintClosed {
+		return nil, invalidInput("closed sprints cannot be changed")
+	}
+	sprint.Name = input.Name
+	sprint.Goal = input.Goal
+	sprint.StartDate = input.StartDate
+	sprint.EndDate = input.EndDate
+	if err := s.Repo.UpdateSprint(sprint); err != nil {
+		logrus.WithFields(logrus.Fields{
+			"sprintID": sprintID,
+			"error":    err,
+		}).Error("Failed to update sprint")
+		return nil, err
+	}
+	return sprint, nil
+}
+
+// DeleteSprint deletes a planned sprint; its tasks go back to the backlog
+func (s *Service) DeleteSprint(sprintID uint) error {
+	sprint, err := s.Repo.GetSprintByID(sprintID)
+	if err != nil {
+		return err
+	}
+	if sprint.State != models.SprintPlanned {
+		return invalidInput("only planned sprints can be deleted")
+	}
+	if err := s.Repo.DeleteSprint(sprintID); err != nil {
+		logrus.WithFields(logrus.Fields{
+			"sprintID": sprintID,
+			"error":    err,
+		}).Error("Failed to delete sprint")
+		return err
+	}
+	logrus.WithFields(logrus.Fields{
+		"sprintID": sprintID,
+	}).Info("Sprint deleted successfully")
+	return nil
+}
+
+// GetBacklog lists the open tasks of a project that are not planned into a
+// sprint
+func (s *Service) GetBacklog(projectID uint, filter dto.TaskFilter) ([]models.Task, error) {
+	filter.Backlog = true
+	return s.GetTasksByProjectID(projectID, filter)
+}
+
+func (s *Service) GetSprintTasks(sprint *models.Sprint, filter dto.TaskFilter) ([]models.Task, error) {
+	filter.SprintID = sprint.ID
+	return s.GetTasksByProjectID(sprint.ProjectID, filter)
+}
+
+// recordScopeChange records a change to the scope of a sprint if the sprint
+// is active; planned sprints have no commitment to change yet
+func (s *Service) recordScopeChange(repo *repository.Repository, sprintID uint, change models.SprintChange) error {
+	sprint, err := repo.GetSprintByID(sprintID)
+	if err != nil {
+		return err
+	}
+	if sprint.State != models.SprintActive {
+		return nil
+	}
+	change.SprintID = sprintID
+	change.CreatedAt = time.Now()
+	if err := repo.CreateSprintChange(&change); err != nil {
+		logrus.WithFields(logrus.Fields{
+			"sprintID": sprintID,
+			"taskID":   change.TaskID,
+			"error":    err,
+		}).Error("Failed to record sprint scope change")
+		return err
+	}
+	return nil
+}
+
+// trackSprintScope records how an update of a task changed the scope of the
+// sprint it was planned into, which it leaves when moved to another project
+func (s *Service) trackSprintScope(repo *repository.Repository, actorID uint, sprintID *uint, previousPoints int, task *models.Task) error {
+	if sprintID == nil {
+		return nil
+	}
+	change := models.SprintChange{TaskID: task.ID, ActorID: actorID}
+	switch {
+	case task.SprintID == nil:
+		change.Kind, change.Points = models.ScopeRemoved, -previousPoints
+	case task.StoryPoints != previousPoints:
+		change.Kind, change.Points = models.ScopeEstimate, task.StoryPoints-previousPoints
+	default:
+		return nil
+	}
+	return s.recordScopeChange(repo, *sprintID, change)
+}
+
+// AddSprintTasks plans tasks of the sprint's project into a sprint that is
+// not closed, taking them out of any other sprint
+func (s *Service) AddSprintTasks(actorID, sprintID uint, taskIDs []uint) (*models.Sprint, error) {
+	sprint, err := s.Repo.GetSprintByID(sprintID)
+	if err != nil {
+		return nil, err
+	}
+	if sprint.State == models.SprintClosed {
+		return nil, invalidInput("tasks cannot be added to a closed sprint")
+	}
+	var tasks []*models.Task
+	for _, taskID := range taskIDs {
+		task, err := s.Repo.GetTaskByID(taskID)
+		if err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return nil, invalidInput("task %d not found", taskID)
+			}
+			return nil, err
+		}
+		if task.ProjectID != sprint.ProjectID {
+			return nil, invalidInput("task %d belongs to another project", taskID)
+		}
+		if task.SprintID == nil || *task.SprintID != sprintID {
+			tasks = append(tasks, task)
+		}
+	}
+	err = s.transaction(func(repo *repository.Repository) error {
+		for _, task := range tasks {
+			if err := repo.SetTasksSprint([]uint{task.ID}, &sprint.ID); err != nil {
+				return err
+			}
+			if task.SprintID != nil {
+				if err := s.recordScopeChange(repo, *task.SprintID, models.SprintChange{
+					TaskID: task.ID, Kind: models.ScopeRemoved, Points: -task.StoryPoints, ActorID: actorID,
+				}); err != nil {
+					return err
+				}
+			}
+			if err := s.recordScopeChange(repo, sprint.ID, models.SprintChange{
+				TaskID: task.ID, Kind: models.ScopeAdded, Points: task.StoryPoints, ActorID: actorID,
+			}); err != nil {
+				return err
+			}
+			if err := s.recordEvent(repo, Event{
+				Type:      EventTaskUpdated,
+				ProjectID: task.ProjectID,
+				TaskID:    task.ID,
+				ActorID:   actorID,
+				Message:   fmt.Sprintf("planned task %q into sprint %q", task.Title, sprint.Name),
+				Data:      map[string]interface{}{"sprint_id": sprint.ID},
+			}); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		logrus.WithFields(logrus.Fields{
+			"sprintID": sprintID,
+			"error":    err,
+		}).Error("Failed to add tasks to sprint")
+		return nil, err
+	}
+	logrus.WithFields(logrus.Fields{
+		"sprintID":  sprintID,
+		"taskCount": len(tasks),
+	}).Info("Tasks added to sprint")
+	return sprint, nil
+}
+
+// RemoveSprintTask moves a task of a sprint that is not closed back to the
+// backlog
+func (s *Service) RemoveSprintTask(actorID, sprintID, taskID uint) error {
+	sprint, err := s.Repo.GetSprintByID(sprintID)
+	if err != nil {
+		return err
+	}
+	if sprint.State == models.SprintClosed {
+		return invalidInput("tasks cannot be removed from a closed sprint")
+	}
+	task, err := s.Repo.GetTaskByID(taskID)
+	if err != nil {
+		return err
+	}
+	if task.SprintID == nil || *task.SprintID != sprintID {
+		return invalidInput("task is not in the sprint")
+	}
+	err = s.transaction(func(repo *repository.Repository) error {
+		if err := repo.SetTasksSprint([]uint{taskID}, nil); err != nil {
+			return err
+		}
+		if err := s.recordScopeChange(repo, sprintID, models.SprintChange{
+			TaskID: taskID, Kind: models.ScopeRemoved, Points: -task.StoryPoints, ActorID: actorID,
+		}); err != nil {
+			return err
+		}
+		return s.recordEvent(repo, Event{
+			Type:      EventTaskUpdated,
+			ProjectID: task.ProjectID,
+			TaskID:    taskID,
+			ActorID:   actorID,
+			Message:   fmt.Sprintf("moved task %q from sprint %q to the backlog", task.Title, sprint.Name),
+			Data:      map[string]interface{}{"sprint_id": nil},
+		})
+	})
+	if err != nil {
+		logrus.WithFields(logrus.Fields{
+			"sprintID": sprintID,
+			"taskID":   taskID,
+			"error":    err,
+		}).Error("Failed to remove task from sprint")
+		return err
+	}
+	return nil
+}
+
+// StartSprint activates a planned sprint, committing to the tasks planned
+// into it. Only one sprint of a project can be active at a time.
+func (s *Service) StartSprint(actorID, sprintID uint) (*models.Sprint, error) {
+	sprint, err := s.Repo.GetSprintByID(sprintID)
+	if err != nil {
+		return nil, err
+	}
+	if sprint.State != models.SprintPlanned {
+		return nil, invalidInput("only planned sprints can be started")
+	}
+	err = s.transaction(func(repo *repository.Repository) error {
+		started, err := repo.StartSprint(sprint, time.Now())
+		if err != nil {
+			return err
+		}
+		if !started {
+			return invalidInput("another sprint of the project is active")
+		}
+		return s.recordEvent(repo, Event{
+			Type:      EventSprintStarted,
+			ProjectID: sprint.ProjectID,
+			ActorID:   actorID,
+			Message:   fmt.Sprintf("started sprint %q", sprint.Name),
+			Data:      map[string]interface{}{"sprint_id": sprint.ID},
+		})
+	})
+	if err != nil {
+		logrus.WithFields(logrus.Fields{
+			"sprintID": sprintID,
+			"error":    err,
+		}).Warn("Failed to start sprint")
+		return nil, err
+	}
+	logrus.WithFields(logrus.Fields{
+		"sprintID": sprintID,
+	}).Info("Sprint started")
+	return s.Repo.GetSprintByID(sprintID)
+}
+
+// carryOverTarget resolves where the unfinished tasks of a closing sprint go
+// (see dto.CloseSprintInput); nil stands for the backlog
+func (s *Service) carryOverTarget(sprint *models.Sprint, carryOverTo *uint) (*models.Sprint, error) {
+	if carryOverTo == nil {
+		next, err := s.Repo.GetNextPlannedSprint(sprint.ProjectID, sprint.ID)
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		return next, err
+	}
+	if *carryOverTo == 0 {
+		return nil, nil
+	}
+	target, err := s.Repo.GetSprintByID(*carryOverTo)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, invalidInput("sprint %d not found", *carryOverTo)
+		}
+		return nil, err
+	}
+	if target.ProjectID != sprint.ProjectID || target.State != models.SprintPlanned {
+		return nil, invalidInput("unfinished tasks can only move to a planned sprint of the project")
+	}
+	return target, nil
+}
+
+// CloseSprint closes an active sprint, recording what it completed, and
+// carries its unfinished tasks over to another sprint or the backlog
+func (s *Service) CloseSprint(actorID, sprintID uint, input dto.CloseSprintInput) (*models.Sprint, error) {
+	sprint, err := s.Repo.GetSprintByID(sprintID)
+	if err != nil {
+		return nil, err
+	}
+	if sprint.State != models.SprintActive {
+		return nil, invalidInput("only active sprints can be closed")
+	}
+	target, err := s.carryOverTarget(sprint, input.CarryOverTo)
+	if err != nil {
+		return nil, err
+	}
+	var targetID *uint
+	destination := "the backlog"
+	if target != nil {
+		targetID = &target.ID
+		destination = fmt.Sprintf("sprint %q", target.Name)
+	}
+	err = s.transaction(func(repo *repository.Repository) error {
+		closed, err := repo.CloseSprint(sprint, targetID, time.Now())
+		if err != nil {
+			return err
+		}
+		if !closed {
+			return invalidInput("only active sprints can be closed")
+		}
+		return s.recordEvent(repo, Event{
+			Type:      EventSprintClosed,
+			ProjectID: sprint.ProjectID,
+			ActorID:   actorID,
+			Message:   fmt.Sprintf("closed sprint %q, moving unfinished tasks to %s", sprint.Name, destination),
+			Data: map[string]interface{}{
+				"sprint_id":       sprint.ID,
+				"carried_over_to": targetID,
+			},
+		})
+	})
+	if err != nil {
+		logrus.WithFields(logrus.Fields{
+			"sprintID": sprintID,
+			"error":    err,
+		}).Warn("Failed to close sprint")
+		return nil, err
+	}
+	logrus.WithFields(logrus.Fields{
+		"sprintID":      sprintID,
+		"carriedOverTo": targetID,
+	}).Info("Sprint closed")
+	return s.Repo.GetSprintByID(sprintID)
+}
+
+// GetSprintReport compares a sprint's commitment with what it completed and
+// how its scope changed along the way
+func (s *Service) GetSprintReport(sprintID uint) (*dto.SprintReport, error) {
+	sprint, err := s.Repo.GetSprintByID(sprintID)
+	if err != nil {
+		return nil, err
+	}
+	tasks, err := s.Repo.GetSprintTasks(sprintID, sprint.CarriedOver)
+	if err != nil {
+		logrus.WithFields(logrus.Fields{
+			"sprintID": sprintID,
+			"error":    err,
+		}).Error("Failed to load sprint tasks")
+		return nil, err
+	}
+	changes, err := s.Repo.GetSprintChanges(sprintID)
+	if err != nil {
+		logrus.WithFields(logrus.Fields{
+			"sprintID": sprintID,
+			"error":    err,
+		}).Error("Failed to load sprint scope changes")
+		return nil, err
+	}
+	report := &dto.SprintReport{
+		Sprint:          *sprint,
+		CommittedPoints: sprint.CommittedPoints,
+		CommittedTasks:  sprint.CommittedTasks,
+		Changes:         changes,
+	}
+	for _, change := range changes {
+		switch change.Kind {
+		case models.ScopeAdded:
+			report.AddedPoints += change.Points
+		case models.ScopeRemoved:
+			report.RemovedPoints -= change.Points
+		case models.ScopeEstimate:
+			report.EstimateChange += change.Points
+		}
+		report.ScopeChange += change.Points
+	}
+	now := time.Now()
+	if sprint.State == models.SprintClosed {
+		report.CompletedPoints = sprint.CompletedPoints
+		report.CompletedTasks = sprint.CompletedTasks
+		report.RemainingTasks = len(sprint.CarriedOver)
+		now = *sprint.ClosedAt
+	}
+	for _, task := range tasks {
+		if sprint.State == models.SprintClosed {
+			if task.CompletedAt == nil || task.CompletedAt.After(now) {
+				report.RemainingPoints += task.StoryPoints
+			}
+			continue
+		}
+		if task.CompletedAt != nil {
+			report.CompletedPoints += task.StoryPoints
+			report.CompletedTasks++
+		} else {
+			report.RemainingPoints += task.StoryPoints
+			report.RemainingTasks++
+		}
+	}
+	if sprint.StartedAt != nil {
+		report.Burndown = sprintBurndown(sprint, tasks, now)
+	}
+	return report, nil
+}
+
+// sprintBurndown gives the story points of a sprint's tasks left open at
+// the end of each day from its start date to its end date or until, along
+// with the ideal line from the committed points down to zero
+func sprintBurndown(sprint *models.Sprint, tasks []models.Task, until time.Time) []dto.BurndownPoint {
+	start := time.Date(sprint.StartDate.Year(), sprint.StartDate.Month(), sprint.StartDate.Day(), 0, 0, 0, 0, sprint.StartDate.Location())
+	days := int(sprint.EndDate.Sub(start).Hours()/24) + 1
+	var points []dto.BurndownPoint
+	for i := 0; i < days; i++ {
+		day := start.AddDate(0, 0, i)
+		if day.After(until) {
+			break
+		}
+		endOfDay := day.AddDate(0, 0, 1)
+		point := dto.BurndownPoint{
+			Date:  day.Format("2006-01-02"),
+			Ideal: float64(sprint.CommittedPoints) * float64(days-1-i) / float64(max(days-1, 1)),
+		}
+		for _, task := range tasks {
+			if task.CompletedAt == nil || !task.CompletedAt.Before(endOfDay) {
+				point.Remaining += task.StoryPoints
+			}
+		}
+		points = append(points, point)
+	}
+	return points
+}
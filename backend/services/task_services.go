@@ -2,41 +2,251 @@
 package services
 
 import (
+	"fmt"
 	"time"
+	"work-management/calendar"
+	"work-management/dto"
 	"work-management/models"
+	"work-management/repository"
 
 	"github.com/sirupsen/logrus"
+	"gorm.io/gorm"
 )
 
-func (s *Service) CreateTask(title, description string, projectID, userID uint, status string, dueDate time.Time) (*models.Task, error) {
+// validateTaskInput checks the task fields that have a constrained range and
+// fills in defaults for the ones left empty.
+func validateTaskInput(input *dto.TaskInput) error {
+	if input.Priority != nil {
+		if _, ok := models.PriorityRank[*input.Priority]; !ok {
+			return invalidInput("priority must be one of low, medium, high, urgent")
+		}
+	}
+	if input.StoryPoints != nil && (*input.StoryPoints < 0 || *input.StoryPoints > 100) {
+		return invalidInput("story_points must be between 0 and 100")
+	}
+	if input.OriginalEstimate != nil && *input.OriginalEstimate < 0 {
+		return invalidInput("original_estimate cannot be negative")
+	}
+	if input.RemainingEstimate != nil && *input.RemainingEstimate < 0 {
+		return invalidInput("remaining_estimate cannot be negative")
+	}
+	return nil
+}
+
+// prepareTaskFilter rejects listing filters the repository cannot apply and
+// resolves custom field filters against their field definitions.
+func (s *Service) prepareTaskFilter(filter *dto.TaskFilter) error {
+	for _, priority := range filter.Priorities {
+		if _, ok := models.PriorityRank[priority]; !ok {
+			return invalidInput("unknown priority %q", priority)
+		}
+	}
+	if _, isCustom := repository.CustomFieldSortID(filter.Sort); filter.Sort != "" && !isCustom && !repository.IsSortableTaskField(filter.Sort) {
+		return invalidInput("cannot sort tasks by %q", filter.Sort)
+	}
+	return s.resolveCustomFieldFilters(filter)
+}
+
+// applyTaskPlanning sets the priority, story points and original estimate
+// given in the input, keeping those that were omitted
+func applyTaskPlanning(task *models.Task, input dto.TaskInput) {
+	if input.Priority != nil {
+		task.Priority = *input.Priority
+	}
+	if input.StoryPoints != nil {
+		task.StoryPoints = *input.StoryPoints
+	}
+	if input.OriginalEstimate != nil {
+		task.OriginalEstimate = *input.OriginalEstimate
+	}
+}
+
+// setTaskStatus updates the status and keeps CompletedAt in step with it;
+// completed tells whether the status completes tasks, see
+// Service.isCompletedStatus
+func setTaskStatus(task *models.Task, status string, completed bool) {
+	wasCompleted := task.CompletedAt != nil
+	task.Status = status
+	switch {
+	case completed && !wasCompleted:
+		now := time.Now()
+		task.CompletedAt = &now
+		task.RemainingEstimate = 0
+	case !completed:
+		task.CompletedAt = nil
+	}
+}
+
+// userIDs returns the IDs of the given users
+func userIDs(users []models.User) []uint {
+	ids := make([]uint, 0, len(users))
+	for _, user := range users {
+		ids = append(ids, user.ID)
+	}
+	return ids
+}
+
+// newUserIDs returns the IDs in after that are not in before
+func newUserIDs(before, after []uint) []uint {
+	existing := map[uint]bool{}
+	for _, id := range before {
+		existing[id] = true
+	}
+	var added []uint
+	for _, id := range after {
+		if !existing[id] {
+			added = append(added, id)
+		}
+	}
+	return added
+}
+
+// maxSubtaskDepth bounds how deeply subtasks nest
+const maxSubtaskDepth = 10
+
+// validateParent checks that a task can become a subtask of parentID: the
+// parent belongs to the same project and is not the task or one of its
+// subtasks. taskID is 0 for a task being created.
+func (s *Service) validateParent(projectID, taskID, parentID uint) error {
+	depth := 0
+	for id := parentID; id != 0; depth++ {
+		if id == taskID {
+			return invalidInput("a task cannot be a subtask of itself or of its own subtasks")
+		}
+		if depth >= maxSubtaskDepth {
+			return invalidInput("subtasks nest at most %d levels deep", maxSubtaskDepth)
+		}
+		ancestor, err := s.Repo.GetTaskByID(id)
+		if err != nil {
+			return invalidInput("parent task %d not found", id)
+		}
+		if ancestor.ProjectID != projectID {
+			return invalidInput("parent task %d belongs to another project", id)
+		}
+		id = 0
+		if ancestor.ParentID != nil {
+			id = *ancestor.ParentID
+		}
+	}
+	return nil
+}
+
+func (s *Service) CreateTask(actorID uint, input dto.TaskInput) (*models.Task, error) {
+	if err := validateTaskInput(&input); err != nil {
+		logrus.WithFields(logrus.Fields{
+			"projectID": input.ProjectID,
+			"error":     err,
+		}).Warn("Invalid task input")
+		return nil, err
+	}
 	task := &models.Task{
-		Title:       title,
-		Description: description,
-		ProjectID:   projectID,
-		UserID:      userID,
-		Status:      status,
-		DueDate:     dueDate,
+		Title:       input.Title,
+		Description: input.Description,
+		ProjectID:   input.ProjectID,
+		UserID:      input.UserID,
+		ReporterID:  actorID,
+		DueDate:     input.DueDate,
+		Priority:    models.PriorityMedium,
+		StartDate:   input.StartDate,
+		DueDateOnly: input.DueDateOnly,
+	}
+	applyTaskPlanning(task, input)
+	task.RemainingEstimate = task.OriginalEstimate
+	if input.RemainingEstimate != nil {
+		task.RemainingEstimate = *input.RemainingEstimate
+	}
+	normalizeDueDate(task)
+	cal, err := s.projectCalendar(s.Repo, input.ProjectID)
+	if err != nil {
+		return nil, err
+	}
+	if err := planTaskDates(task, cal); err != nil {
+		return nil, err
 	}
-	if err := s.Repo.CreateTask(task); err != nil {
+	completed, err := s.isCompletedStatus(s.Repo, input.ProjectID, input.Status)
+	if err != nil {
 		logrus.WithFields(logrus.Fields{
-			"projectID": projectID,
-			"userID":    userID,
+			"projectID": input.ProjectID,
+			"error":     err,
+		}).Warn("Invalid task status")
+		return nil, err
+	}
+	setTaskStatus(task, input.Status, completed)
+	if input.ParentID != nil && *input.ParentID != 0 {
+		if err := s.validateParent(input.ProjectID, 0, *input.ParentID); err != nil {
+			return nil, err
+		}
+		task.ParentID = input.ParentID
+	}
+	customFields, _, err := s.resolveTaskCustomFields(input.ProjectID, input.CustomFields, true)
+	if err != nil {
+		logrus.WithFields(logrus.Fields{
+			"projectID": input.ProjectID,
+			"error":     err,
+		}).Warn("Invalid custom field values")
+		return nil, err
+	}
+	task.CustomFields = customFields
+	assigneeIDs, err := s.validateAssignees(input.ProjectID, input.UserID, input.AssigneeIDs)
+	if err != nil {
+		logrus.WithFields(logrus.Fields{
+			"projectID": input.ProjectID,
+			"error":     err,
+		}).Warn("Invalid task assignees")
+		return nil, err
+	}
+	if task.UserID == 0 && len(assigneeIDs) > 0 {
+		task.UserID = assigneeIDs[0]
+	}
+	for _, assigneeID := range assigneeIDs {
+		task.Assignees = append(task.Assignees, models.User{Model: gorm.Model{ID: assigneeID}})
+	}
+	err = s.transaction(func(repo *repository.Repository) error {
+		if _, err := s.checkWIPLimit(repo, task.ProjectID, task.Status, 0); err != nil {
+			return err
+		}
+		if err := repo.CreateTask(task); err != nil {
+			return err
+		}
+		if err := s.recordEvent(repo, Event{
+			Type:      EventTaskCreated,
+			ProjectID: task.ProjectID,
+			TaskID:    task.ID,
+			ActorID:   actorID,
+			Message:   fmt.Sprintf("created task %q", task.Title),
+		}); err != nil {
+			return err
+		}
+		return s.recordEvent(repo, Event{
+			Type:       EventTaskAssigned,
+			ProjectID:  task.ProjectID,
+			TaskID:     task.ID,
+			ActorID:    actorID,
+			Recipients: assigneeIDs,
+			Notice:     fmt.Sprintf("assigned you to %q", task.Title),
+		})
+	})
+	if err != nil {
+		logrus.WithFields(logrus.Fields{
+			"projectID": input.ProjectID,
+			"userID":    input.UserID,
 			"error":     err,
 		}).Error("Failed to create task")
 		return nil, err
 	}
 	logrus.WithFields(logrus.Fields{
 		"taskID":    task.ID,
-		"projectID": projectID,
-		"userID":    userID,
+		"projectID": input.ProjectID,
+		"userID":    input.UserID,
 	}).Info("Task created successfully")
-	// Enhancement: Emit a WebSocket event for real-time updates
-	// s.notifyClients("task_created", task)
 	return task, nil
 }
 
-func (s *Service) GetTasks() ([]models.Task, error) {
-	tasks, err := s.Repo.GetTasks()
+func (s *Service) GetTasks(filter dto.TaskFilter) ([]models.Task, error) {
+	if err := s.prepareTaskFilter(&filter); err != nil {
+		return nil, err
+	}
+	tasks, err := s.Repo.GetTasks(filter)
 	if err != nil {
 		logrus.WithFields(logrus.Fields{
 			"error": err,
@@ -62,7 +272,30 @@ func (s *Service) GetTaskByID(taskID uint) (*models.Task, error) {
 	return task, nil
 }
 
-func (s *Service) UpdateTask(taskID uint, title, description string, projectID, userID uint, status string, dueDate time.Time) (*models.Task, error) {
+// GetSubtasks lists the direct subtasks of a task
+func (s *Service) GetSubtasks(taskID uint) ([]models.Task, error) {
+	if _, err := s.Repo.GetTaskByID(taskID); err != nil {
+		return nil, err
+	}
+	tasks, err := s.Repo.GetSubtasks(taskID)
+	if err != nil {
+		logrus.WithFields(logrus.Fields{
+			"taskID": taskID,
+			"error":  err,
+		}).Error("Failed to retrieve subtasks")
+		return nil, err
+	}
+	return tasks, nil
+}
+
+func (s *Service) UpdateTask(actorID, taskID uint, input dto.TaskInput) (*models.Task, error) {
+	if err := validateTaskInput(&input); err != nil {
+		logrus.WithFields(logrus.Fields{
+			"taskID": taskID,
+			"error":  err,
+		}).Warn("Invalid task input")
+		return nil, err
+	}
 	task, err := s.Repo.GetTaskByID(taskID)
 	if err != nil {
 		logrus.WithFields(logrus.Fields{
@@ -71,13 +304,182 @@ func (s *Service) UpdateTask(taskID uint, title, description string, projectID,
 		}).Warn("Task not found for update")
 		return nil, err
 	}
-	task.Title = title
-	task.Description = description
-	task.ProjectID = projectID
-	task.UserID = userID
-	task.Status = status
-	task.DueDate = dueDate
-	if err := s.Repo.UpdateTask(task); err != nil {
+	// Moving a task to another project drops values of the old project's
+	// fields and applies the new project's defaults and required fields.
+	movedProject := task.ProjectID != input.ProjectID
+	if movedProject {
+		if err := s.ensureNotArchived(task.ProjectID); err != nil {
+			return nil, err
+		}
+	}
+	customFields, cleared, err := s.resolveTaskCustomFields(input.ProjectID, input.CustomFields, movedProject)
+	if err != nil {
+		logrus.WithFields(logrus.Fields{
+			"taskID": taskID,
+			"error":  err,
+		}).Warn("Invalid custom field values")
+		return nil, err
+	}
+	if movedProject {
+		for _, value := range task.CustomFields {
+			cleared = append(cleared, value.FieldID)
+		}
+	}
+	assigneeIDs, err := s.validateAssignees(input.ProjectID, input.UserID, input.AssigneeIDs)
+	if err != nil {
+		logrus.WithFields(logrus.Fields{
+			"taskID": taskID,
+			"error":  err,
+		}).Warn("Invalid task assignees")
+		return nil, err
+	}
+	if input.AssigneeIDs == nil {
+		// Keep the current assignees; SaveTask still adds the primary one
+		assigneeIDs = nil
+		switch {
+		case movedProject:
+			// Assignees of the old project may not belong to the new one
+			assigneeIDs = []uint{}
+			if input.UserID != 0 {
+				assigneeIDs = append(assigneeIDs, input.UserID)
+			}
+		case input.UserID != task.UserID:
+			// A new primary assignee takes the place of the old one
+			assigneeIDs = []uint{}
+			for _, assigneeID := range userIDs(task.Assignees) {
+				if assigneeID != task.UserID {
+					assigneeIDs = append(assigneeIDs, assigneeID)
+				}
+			}
+			if input.UserID != 0 {
+				assigneeIDs = append(assigneeIDs, input.UserID)
+			}
+		}
+	}
+	completed, err := s.isCompletedStatus(s.Repo, input.ProjectID, input.Status)
+	if err != nil {
+		logrus.WithFields(logrus.Fields{
+			"taskID": taskID,
+			"error":  err,
+		}).Warn("Invalid task status")
+		return nil, err
+	}
+	switch {
+	case input.ParentID != nil && *input.ParentID != 0:
+		if err := s.validateParent(input.ProjectID, taskID, *input.ParentID); err != nil {
+			return nil, err
+		}
+		task.ParentID = input.ParentID
+	case input.ParentID != nil || movedProject:
+		// A task moved on its own leaves its parent behind
+		task.ParentID = nil
+	}
+	previousAssignees := userIDs(task.Assignees)
+	previousStatus := task.Status
+	previousSprint, previousPoints := task.SprintID, task.StoryPoints
+	if movedProject {
+		// Sprints and milestones belong to a project, so the task goes to
+		// the new backlog
+		task.SprintID = nil
+		task.MilestoneID = nil
+	}
+	wasCompleted := task.CompletedAt != nil
+	previousStart, previousDue, previousDateOnly := plannedStart(task), task.DueDate, task.DueDateOnly
+	task.DueDateOnly = input.DueDateOnly
+	if input.DueDateOnly {
+		input.DueDate = calendar.Date(input.DueDate)
+	}
+	if !task.DueDate.Equal(input.DueDate) || task.DueDateOnly != previousDateOnly {
+		// Re-arm the overdue marking and escalation for the new due date
+		task.OverdueAt = nil
+		task.EscalatedAt = nil
+	}
+	if input.StartDate != nil {
+		task.StartDate = input.StartDate
+	}
+	task.Title = input.Title
+	task.Description = input.Description
+	task.ProjectID = input.ProjectID
+	task.UserID = input.UserID
+	task.DueDate = input.DueDate
+	applyTaskPlanning(task, input)
+	if input.RemainingEstimate != nil {
+		task.RemainingEstimate = *input.RemainingEstimate
+	}
+	cal, err := s.projectCalendar(s.Repo, task.ProjectID)
+	if err != nil {
+		return nil, err
+	}
+	if err := planTaskDates(task, cal); err != nil {
+		return nil, err
+	}
+	rescheduled := !movedProject && (!plannedStart(task).Equal(previousStart) || !task.DueDate.Equal(previousDue) ||
+		task.DueDateOnly != previousDateOnly)
+	setTaskStatus(task, input.Status, completed)
+	err = s.transaction(func(repo *repository.Repository) error {
+		if movedProject || task.Status != previousStatus {
+			if _, err := s.checkWIPLimit(repo, task.ProjectID, task.Status, taskID); err != nil {
+				return err
+			}
+		}
+		if err := repo.SaveTask(task, repository.TaskUpdate{
+			CustomFields:  customFields,
+			ClearedFields: cleared,
+			AssigneeIDs:   assigneeIDs,
+		}); err != nil {
+			return err
+		}
+		if movedProject {
+			// Its subtasks stay in the old project as top-level tasks, and
+			// dependencies do not cross projects
+			if err := repo.DetachSubtasks(taskID); err != nil {
+				return err
+			}
+			if err := repo.DeleteTaskDependencies(taskID); err != nil {
+				return err
+			}
+		}
+		if rescheduled {
+			if err := s.rescheduleDependents(repo, actorID, task); err != nil {
+				return err
+			}
+		}
+		if err := s.trackSprintScope(repo, actorID, previousSprint, previousPoints, task); err != nil {
+			return err
+		}
+		saved, err := repo.GetTaskByID(taskID)
+		if err != nil {
+			return err
+		}
+		if err := s.recordEvent(repo, Event{
+			Type:      EventTaskUpdated,
+			ProjectID: saved.ProjectID,
+			TaskID:    saved.ID,
+			ActorID:   actorID,
+			Message:   fmt.Sprintf("updated task %q", saved.Title),
+			Data: map[string]interface{}{
+				"status":          saved.Status,
+				"previous_status": previousStatus,
+			},
+		}); err != nil {
+			return err
+		}
+		if err := s.recordEvent(repo, Event{
+			Type:       EventTaskAssigned,
+			ProjectID:  saved.ProjectID,
+			TaskID:     saved.ID,
+			ActorID:    actorID,
+			Recipients: newUserIDs(previousAssignees, userIDs(saved.Assignees)),
+			Notice:     fmt.Sprintf("assigned you to %q", saved.Title),
+		}); err != nil {
+			return err
+		}
+		if saved.RecurrenceID != nil && saved.CompletedAt != nil && !wasCompleted {
+			return s.continueRecurrence(repo, actorID, saved)
+		}
+		return nil
+	})
+	if err != nil {
 		logrus.WithFields(logrus.Fields{
 			"taskID": taskID,
 			"error":  err,
@@ -87,45 +489,59 @@ func (s *Service) UpdateTask(taskID uint, title, description string, projectID,
 	logrus.WithFields(logrus.Fields{
 		"taskID": taskID,
 	}).Info("Task updated successfully")
-	// Enhancement: Emit a WebSocket event for real-time updates
-	// s.notifyClients("task_updated", task)
-	return task, nil
+	return s.Repo.GetTaskByID(taskID)
 }
 
-func (s *Service) DeleteTask(taskID uint) error {
-	if err := s.Repo.DeleteTask(taskID); err != nil {
+// DeleteTask moves a task and its subtasks to the trash
+func (s *Service) DeleteTask(actorID, taskID uint) error {
+	task, err := s.Repo.GetTaskByID(taskID)
+	if err != nil {
 		logrus.WithFields(logrus.Fields{
 			"taskID": taskID,
 			"error":  err,
-		}).Error("Failed to delete task")
+		}).Warn("Task not found for deletion")
 		return err
 	}
-	logrus.WithFields(logrus.Fields{
-		"taskID": taskID,
-	}).Info("Task deleted successfully")
-	// Enhancement: Emit a WebSocket event for real-time updates
-	// s.notifyClients("task_deleted", taskID)
-	return nil
-}
-
-func (s *Service) AssignTaskToUser(taskID, userID uint) error {
-	if err := s.Repo.AssignTaskToUser(taskID, userID); err != nil {
+	err = s.transaction(func(repo *repository.Repository) error {
+		if err := repo.DeleteTask(taskID, time.Now()); err != nil {
+			return err
+		}
+		if err := s.audit(repo, models.AuditLog{
+			ActorID:    actorID,
+			Action:     AuditTaskTrashed,
+			EntityType: "task",
+			EntityID:   task.ID,
+			ProjectID:  task.ProjectID,
+			Details:    map[string]interface{}{"title": task.Title},
+		}); err != nil {
+			return err
+		}
+		return s.recordEvent(repo, Event{
+			Type:      EventTaskDeleted,
+			ProjectID: task.ProjectID,
+			TaskID:    task.ID,
+			ActorID:   actorID,
+			Message:   fmt.Sprintf("moved task %q to the trash", task.Title),
+		})
+	})
+	if err != nil {
 		logrus.WithFields(logrus.Fields{
 			"taskID": taskID,
-			"userID": userID,
 			"error":  err,
-		}).Error("Failed to assign task to user")
+		}).Error("Failed to delete task")
 		return err
 	}
 	logrus.WithFields(logrus.Fields{
 		"taskID": taskID,
-		"userID": userID,
-	}).Info("Task assigned to user successfully")
+	}).Info("Task deleted successfully")
 	return nil
 }
 
-func (s *Service) GetTasksByProjectID(projectID uint) ([]models.Task, error) {
-	tasks, err := s.Repo.GetTasksByProjectID(projectID)
+func (s *Service) GetTasksByProjectID(projectID uint, filter dto.TaskFilter) ([]models.Task, error) {
+	if err := s.prepareTaskFilter(&filter); err != nil {
+		return nil, err
+	}
+	tasks, err := s.Repo.GetTasksByProjectID(projectID, filter)
 	if err != nil {
 		logrus.WithFields(logrus.Fields{
 			"projectID": projectID,
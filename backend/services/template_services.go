@@ -0,0 +1,951 @@
+// Template-related services (project and task templates)
+package services
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"work-management/dto"
+	"work-management/models"
+	"work-management/repository"
+
+	"github.com/sirupsen/logrus"
+	"gorm.io/gorm"
+)
+
+// Template limits
+const (
+	maxTemplateTasks = 500
+	// maxDueOffset bounds due offsets to ten years
+	maxDueOffset = 3650
+)
+
+var templateRoles = map[string]bool{"admin": true, "editor": true, "viewer": true}
+
+// templateScope is what the tasks of a template may refer to
+type templateScope struct {
+	statuses map[string]bool // nil when any status goes
+	labels   map[string]bool // By lowercase name
+	fields   map[string]*models.CustomField
+	count    int
+}
+
+// fieldDefinition turns a template field into the custom field it creates
+func fieldDefinition(field models.TemplateField) *models.CustomField {
+	return &models.CustomField{
+		Name:         strings.TrimSpace(field.Name),
+		Type:         field.Type,
+		Required:     field.Required,
+		Options:      field.Options,
+		DefaultValue: field.DefaultValue,
+	}
+}
+
+// validateTemplateTasks checks a task tree against the scope and normalizes
+// its priorities and custom field values
+func (s *Service) validateTemplateTasks(tasks []models.TemplateTask, scope *templateScope, depth int) error {
+	if len(tasks) > 0 && depth > maxSubtaskDepth {
+		return invalidInput("subtasks nest at most %d levels deep", maxSubtaskDepth)
+	}
+	for i := range tasks {
+		task := &tasks[i]
+		scope.count++
+		if scope.count > maxTemplateTasks {
+			return invalidInput("a template holds at most %d tasks", maxTemplateTasks)
+		}
+		task.Title = strings.TrimSpace(task.Title)
+		if task.Title == "" {
+			return invalidInput("template task title is required")
+		}
+		if task.Priority == "" {
+			task.Priority = models.PriorityMedium
+		}
+		if _, ok := models.PriorityRank[task.Priority]; !ok {
+			return invalidInput("task %q: priority must be one of low, medium, high, urgent", task.Title)
+		}
+		if task.StoryPoints < 0 || task.StoryPoints > 100 {
+			return invalidInput("task %q: story_points must be between 0 and 100", task.Title)
+		}
+		if task.OriginalEstimate < 0 {
+			return invalidInput("task %q: original_estimate cannot be negative", task.Title)
+		}
+		if task.DueOffset != nil && (*task.DueOffset < 0 || *task.DueOffset > maxDueOffset) {
+			return invalidInput("task %q: due_offset must be between 0 and %d days", task.Title, maxDueOffset)
+		}
+		if task.Status != "" && scope.statuses != nil && !scope.statuses[task.Status] {
+			return invalidInput("task %q: status %q is not part of the workflow", task.Title, task.Status)
+		}
+		for _, label := range task.Labels {
+			if !scope.labels[strings.ToLower(label)] {
+				return invalidInput("task %q: unknown label %q", task.Title, label)
+			}
+		}
+		for name, raw := range task.CustomFields {
+			field, ok := scope.fields[name]
+			if !ok {
+				return invalidInput("task %q: unknown custom field %q", task.Title, name)
+			}
+			if raw == nil {
+				delete(task.CustomFields, name)
+				continue
+			}
+			value, err := s.normalizeFieldValue(field, raw)
+			if err != nil {
+				return invalidInput("task %q: %v", task.Title, err)
+			}
+			task.CustomFields[name] = value.Value
+		}
+		for name, field := range scope.fields {
+			if _, ok := task.CustomFields[name]; !ok && field.Required && field.DefaultValue == nil {
+				return invalidInput("task %q: custom field %s is required", task.Title, name)
+			}
+		}
+		if err := s.validateTemplateTasks(task.Subtasks, scope, depth+1); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// validateProjectTemplate checks a project template's parts and that its
+// tasks only refer to the statuses, labels and fields it defines
+func (s *Service) validateProjectTemplate(template *models.ProjectTemplate) error {
+	template.Name = strings.TrimSpace(template.Name)
+	if template.Name == "" {
+		return invalidInput("template name is required")
+	}
+	scope := &templateScope{labels: map[string]bool{}, fields: map[string]*models.CustomField{}}
+	if len(template.Workflow) > 0 {
+		statuses := make([]models.WorkflowStatus, 0, len(template.Workflow))
+		for _, status := range template.Workflow {
+			statuses = append(statuses, models.WorkflowStatus{Name: status.Name, Category: status.Category})
+		}
+		if err := validateWorkflow(statuses); err != nil {
+			return err
+		}
+		scope.statuses = map[string]bool{}
+		for i, status := range statuses {
+			template.Workflow[i].Name = status.Name
+			scope.statuses[status.Name] = true
+		}
+	}
+	for i := range template.Labels {
+		label := &template.Labels[i]
+		label.Name = strings.TrimSpace(label.Name)
+		if label.Color == "" {
+			label.Color = defaultLabelColor
+		}
+		if err := validateLabel(label.Name, label.Color); err != nil {
+			return err
+		}
+		if scope.labels[strings.ToLower(label.Name)] {
+			return invalidInput("label %q is listed twice", label.Name)
+		}
+		scope.labels[strings.ToLower(label.Name)] = true
+	}
+	for i := range template.CustomFields {
+		field := fieldDefinition(template.CustomFields[i])
+		if err := s.validateFieldDefinition(field); err != nil {
+			return err
+		}
+		if _, ok := scope.fields[field.Name]; ok {
+			return invalidInput("custom field %q is listed twice", field.Name)
+		}
+		scope.fields[field.Name] = field
+		template.CustomFields[i] = models.TemplateField{
+			Name:         field.Name,
+			Type:         field.Type,
+			Required:     field.Required,
+			Options:      field.Options,
+			DefaultValue: field.DefaultValue,
+		}
+	}
+	seen := map[uint]bool{}
+	for _, member := range template.Members {
+		if !templateRoles[member.Role] {
+			return invalidInput("member %d: role must be one of admin, editor, viewer", member.UserID)
+		}
+		if seen[member.UserID] {
+			return invalidInput("member %d is listed twice", member.UserID)
+		}
+		seen[member.UserID] = true
+		if _, err := s.Repo.GetUserByID(member.UserID); err != nil {
+			return invalidInput("user %d not found", member.UserID)
+		}
+	}
+	return s.validateTemplateTasks(template.Tasks, scope, 1)
+}
+
+func projectTemplateFromInput(input dto.ProjectTemplateInput) models.ProjectTemplate {
+	return models.ProjectTemplate{
+		Name:         input.Name,
+		Description:  input.Description,
+		Category:     input.Category,
+		Workflow:     input.Workflow,
+		Labels:       input.Labels,
+		CustomFields: input.CustomFields,
+		Tasks:        input.Tasks,
+		Members:      input.Members,
+	}
+}
+
+func (s *Service) GetProjectTemplates() ([]models.ProjectTemplate, error) {
+	templates, err := s.Repo.GetProjectTemplates()
+	if err != nil {
+		logrus.WithFields(logrus.Fields{
+			"error": err,
+		}).Error("Failed to retrieve project templates")
+		return nil, err
+	}
+	return templates, nil
+}
+
+func (s *Service) GetProjectTemplate(templateID uint) (*models.ProjectTemplate, error) {
+	template, err := s.Repo.GetProjectTemplateByID(templateID)
+	if err != nil {
+		logrus.WithFields(logrus.Fields{
+			"templateID": templateID,
+			"error":      err,
+		}).Warn("Project template not found")
+		return nil, err
+	}
+	return template, nil
+}
+
+func (s *Service) CreateProjectTemplate(actorID uint, input dto.ProjectTemplateInput) (*models.ProjectTemplate, error) {
+	template := projectTemplateFromInput(input)
+	template.CreatorID = actorID
+	if err := s.validateProjectTemplate(&template); err != nil {
+		logrus.WithFields(logrus.Fields{
+			"userID": actorID,
+			"error":  err,
+		}).Warn("Invalid project template")
+		return nil, err
+	}
+	if err := s.Repo.CreateProjectTemplate(&template); err != nil {
+		logrus.WithFields(logrus.Fields{
+			"userID": actorID,
+			"error":  err,
+		}).Error("Failed to create project template")
+		return nil, err
+	}
+	logrus.WithFields(logrus.Fields{
+		"templateID": template.ID,
+		"userID":     actorID,
+	}).Info("Project template created successfully")
+	return &template, nil
+}
+
+// templateForOwner loads a project template the actor may change: their own,
+// or any for system administrators
+func (s *Service) templateForOwner(actorID, templateID uint) (*models.ProjectTemplate, error) {
+	template, err := s.GetProjectTemplate(templateID)
+	if err != nil {
+		return nil, err
+	}
+	if template.CreatorID != actorID && !s.IsSystemAdmin(actorID) {
+		return nil, fmt.Errorf("%w: only the creator can change a template", ErrForbidden)
+	}
+	return template, nil
+}
+
+// UpdateProjectTemplate replaces a template's contents
+func (s *Service) UpdateProjectTemplate(actorID, templateID uint, input dto.ProjectTemplateInput) (*models.ProjectTemplate, error) {
+	existing, err := s.templateForOwner(actorID, templateID)
+	if err != nil {
+		return nil, err
+	}
+	template := projectTemplateFromInput(input)
+	template.Model = existing.Model
+	template.CreatorID = existing.CreatorID
+	if err := s.validateProjectTemplate(&template); err != nil {
+		logrus.WithFields(logrus.Fields{
+			"templateID": templateID,
+			"error":      err,
+		}).Warn("Invalid project template")
+		return nil, err
+	}
+	if err := s.Repo.UpdateProjectTemplate(&template); err != nil {
+		logrus.WithFields(logrus.Fields{
+			"templateID": templateID,
+			"error":      err,
+		}).Error("Failed to update project template")
+		return nil, err
+	}
+	logrus.WithFields(logrus.Fields{
+		"templateID": templateID,
+	}).Info("Project template updated successfully")
+	return &template, nil
+}
+
+func (s *Service) DeleteProjectTemplate(actorID, templateID uint) error {
+	if _, err := s.templateForOwner(actorID, templateID); err != nil {
+		return err
+	}
+	if err := s.Repo.DeleteProjectTemplate(templateID); err != nil {
+		logrus.WithFields(logrus.Fields{
+			"templateID": templateID,
+			"error":      err,
+		}).Error("Failed to delete project template")
+		return err
+	}
+	logrus.WithFields(logrus.Fields{
+		"templateID": templateID,
+	}).Info("Project template deleted successfully")
+	return nil
+}
+
+// startDate returns the date due offsets count from: the given one, or today
+func startDate(date *time.Time) time.Time {
+	if date != nil && !date.IsZero() {
+		return *date
+	}
+	return time.Now().Truncate(24 * time.Hour)
+}
+
+// templateTarget is the project template tasks are created in, with its
+// statuses, labels and fields resolved by name
+type templateTarget struct {
+	projectID uint
+	start     time.Time
+	// categories maps the statuses of a saved workflow to their category;
+	// it is empty for the default workflow
+	categories map[string]string
+	initial    string
+	labels     map[string]uint // By lowercase name, see templateTarget.label
+	fields     map[string]*models.CustomField
+	created    []*models.Task
+	reporterID uint // User the created tasks are reported by
+}
+
+func newTemplateTarget(projectID uint, start time.Time) *templateTarget {
+	return &templateTarget{
+		projectID:  projectID,
+		start:      start,
+		categories: map[string]string{},
+		initial:    models.StatusToDo,
+		labels:     map[string]uint{},
+		fields:     map[string]*models.CustomField{},
+	}
+}
+
+// label returns the ID of the target's label with the given name, which is
+// unique ignoring case
+func (t *templateTarget) label(name string) (uint, bool) {
+	labelID, ok := t.labels[strings.ToLower(name)]
+	return labelID, ok
+}
+
+func (t *templateTarget) addLabel(name string, labelID uint) {
+	t.labels[strings.ToLower(name)] = labelID
+}
+
+// loadTemplateTarget resolves the existing statuses, labels and fields of a
+// project, for task templates
+func loadTemplateTarget(repo *repository.Repository, projectID uint, start time.Time) (*templateTarget, error) {
+	target := newTemplateTarget(projectID, start)
+	statuses, err := repo.GetWorkflow(projectID)
+	if err != nil {
+		return nil, err
+	}
+	target.setWorkflow(statuses)
+	labels, err := repo.GetLabelsByProjectID(projectID)
+	if err != nil {
+		return nil, err
+	}
+	for _, label := range labels {
+		target.addLabel(label.Name, label.ID)
+	}
+	fields, err := repo.GetCustomFieldsByProjectID(projectID)
+	if err != nil {
+		return nil, err
+	}
+	for i := range fields {
+		target.fields[fields[i].Name] = &fields[i]
+	}
+	return target, nil
+}
+
+func (t *templateTarget) setWorkflow(statuses []models.WorkflowStatus) {
+	for _, status := range statuses {
+		t.categories[status.Name] = status.Category
+		if t.initial == models.StatusToDo && status.Category == models.CategoryToDo {
+			t.initial = status.Name
+		}
+	}
+}
+
+// status returns the status a template task starts in and whether it
+// completes the task. Statuses the workflow lacks fall back to the initial
+// status.
+func (t *templateTarget) status(status string) (string, bool) {
+	if len(t.categories) == 0 {
+		if status == "" {
+			status = t.initial
+		}
+		return status, models.IsCompletedStatus(status)
+	}
+	category, ok := t.categories[status]
+	if !ok {
+		return t.initial, false
+	}
+	return status, category == models.CategoryDone
+}
+
+// fieldValues resolves custom field values given by field name against the
+// target project's fields. Fields without a value get their default;
+// required fields need one or the other.
+func (s *Service) fieldValues(target *templateTarget, title string, given map[string]interface{}) ([]models.CustomFieldValue, error) {
+	names := make([]string, 0, len(target.fields))
+	for name := range target.fields {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	var values []models.CustomFieldValue
+	for _, name := range names {
+		field := target.fields[name]
+		raw, ok := given[name]
+		if !ok || raw == nil {
+			raw = field.DefaultValue
+		}
+		if raw == nil {
+			if field.Required {
+				return nil, invalidInput("task %q: custom field %s is required", title, name)
+			}
+			continue
+		}
+		// User fields are not checked for membership here: the values come
+		// from a template or task that was checked, and a project being
+		// created is not committed yet
+		definition := *field
+		definition.ProjectID = 0
+		value, err := s.normalizeFieldValue(&definition, raw)
+		if err != nil {
+			return nil, invalidInput("task %q: %v", title, err)
+		}
+		values = append(values, value)
+	}
+	return values, nil
+}
+
+// createTemplateTasks creates a tree of template tasks. Labels and custom
+// fields the target project lacks are left out; its required fields need a
+// value or a default.
+func (s *Service) createTemplateTasks(repo *repository.Repository, target *templateTarget, tasks []models.TemplateTask, parentID *uint) error {
+	for _, item := range tasks {
+		task := &models.Task{
+			Title:             item.Title,
+			Description:       item.Description,
+			ProjectID:         target.projectID,
+			Priority:          item.Priority,
+			StoryPoints:       item.StoryPoints,
+			OriginalEstimate:  item.OriginalEstimate,
+			RemainingEstimate: item.OriginalEstimate,
+			ReporterID:        target.reporterID,
+			ParentID:          parentID,
+		}
+		if task.Priority == "" {
+			task.Priority = models.PriorityMedium
+		}
+		if item.DueOffset != nil {
+			task.DueDate = target.start.AddDate(0, 0, *item.DueOffset)
+		}
+		status, completed := target.status(item.Status)
+		setTaskStatus(task, status, completed)
+		for _, name := range item.Labels {
+			if labelID, ok := target.label(name); ok {
+				task.Labels = append(task.Labels, models.Label{Model: gorm.Model{ID: labelID}})
+			}
+		}
+		values, err := s.fieldValues(target, item.Title, item.CustomFields)
+		if err != nil {
+			return err
+		}
+		task.CustomFields = values
+		if err := repo.CreateTask(task); err != nil {
+			return err
+		}
+		target.created = append(target.created, task)
+		if err := s.createTemplateTasks(repo, target, item.Subtasks, &task.ID); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// CreateProjectFromTemplate creates a project with the template's workflow,
+// labels, custom fields, members and tasks in one transaction. The actor
+// becomes its admin; template members that no longer exist are skipped.
+func (s *Service) CreateProjectFromTemplate(actorID, templateID uint, input dto.FromTemplateInput) (*models.Project, error) {
+	template, err := s.GetProjectTemplate(templateID)
+	if err != nil {
+		return nil, err
+	}
+	name := strings.TrimSpace(input.Name)
+	if name == "" {
+		return nil, invalidInput("project name is required")
+	}
+	project := models.Project{
+		Name:        name,
+		Description: input.Description,
+		Category:    template.Category,
+		CreatorID:   actorID,
+	}
+	if project.Description == "" {
+		project.Description = template.Description
+	}
+	var target *templateTarget
+	err = s.transaction(func(repo *repository.Repository) error {
+		if err := insertProject(repo, &project); err != nil {
+			return err
+		}
+		target = newTemplateTarget(project.ID, startDate(input.StartDate))
+		target.reporterID = actorID
+		if len(template.Workflow) > 0 {
+			statuses := make([]models.WorkflowStatus, 0, len(template.Workflow))
+			for i, status := range template.Workflow {
+				statuses = append(statuses, models.WorkflowStatus{Name: status.Name, Category: status.Category, Position: i})
+			}
+			if err := repo.ReplaceWorkflow(project.ID, statuses); err != nil {
+				return err
+			}
+			target.setWorkflow(statuses)
+		}
+		for _, item := range template.Labels {
+			label := &models.Label{ProjectID: project.ID, Name: item.Name, Color: item.Color}
+			if err := repo.CreateLabel(label); err != nil {
+				return err
+			}
+			target.addLabel(label.Name, label.ID)
+		}
+		for _, item := range template.CustomFields {
+			field := fieldDefinition(item)
+			field.ProjectID = project.ID
+			if err := repo.CreateCustomField(field); err != nil {
+				return err
+			}
+			target.fields[field.Name] = field
+		}
+		if err := s.createTemplateTasks(repo, target, template.Tasks, nil); err != nil {
+			return err
+		}
+		if err := s.recordEvent(repo, Event{
+			Type:      EventProjectCreated,
+			ProjectID: project.ID,
+			ActorID:   actorID,
+			Message:   fmt.Sprintf("created project %q from template %q", project.Name, template.Name),
+			Data:      map[string]interface{}{"template_id": template.ID, "task_count": len(target.created)},
+		}); err != nil {
+			return err
+		}
+		for _, member := range template.Members {
+			if member.UserID == actorID {
+				continue
+			}
+			if _, err := repo.GetUserByID(member.UserID); err != nil {
+				logrus.WithFields(logrus.Fields{
+					"templateID": template.ID,
+					"userID":     member.UserID,
+				}).Warn("Skipping missing template member")
+				continue
+			}
+			if err := repo.AddUserToProject(member.UserID, project.ID, member.Role); err != nil {
+				return err
+			}
+			if err := s.recordEvent(repo, Event{
+				Type:       EventMemberAdded,
+				ProjectID:  project.ID,
+				ActorID:    actorID,
+				Message:    fmt.Sprintf("added %s as %s", s.userName(member.UserID), member.Role),
+				Recipients: []uint{member.UserID},
+				Notice:     fmt.Sprintf("added you to %q as %s", project.Name, member.Role),
+				Data:       map[string]interface{}{"user_id": member.UserID, "role": member.Role},
+			}); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		logrus.WithFields(logrus.Fields{
+			"templateID": templateID,
+			"userID":     actorID,
+			"error":      err,
+		}).Error("Failed to create project from template")
+		return nil, err
+	}
+	logrus.WithFields(logrus.Fields{
+		"templateID": templateID,
+		"projectID":  project.ID,
+		"taskCount":  len(target.created),
+	}).Info("Project created from template successfully")
+	return s.Repo.GetProjectByID(project.ID)
+}
+
+// dueOffset returns the whole days from base to a due date, or nil for tasks
+// without one
+func dueOffset(base, due time.Time) *int {
+	if due.IsZero() {
+		return nil
+	}
+	days := int(due.Truncate(24*time.Hour).Sub(base) / (24 * time.Hour))
+	days = max(0, min(days, maxDueOffset))
+	return &days
+}
+
+// templateTaskFrom turns a task and its subtasks into a template task. The
+// template starts over, so statuses are left for the workflow to fill in.
+func templateTaskFrom(task *models.Task, children map[uint][]*models.Task, fields map[uint]string, base time.Time, depth int) models.TemplateTask {
+	item := models.TemplateTask{
+		Title:            task.Title,
+		Description:      task.Description,
+		Priority:         task.Priority,
+		StoryPoints:      task.StoryPoints,
+		OriginalEstimate: task.OriginalEstimate,
+		DueOffset:        dueOffset(base, task.DueDate),
+		Labels:           []string{},
+		CustomFields:     map[string]interface{}{},
+	}
+	for _, label := range task.Labels {
+		item.Labels = append(item.Labels, label.Name)
+	}
+	for _, value := range task.CustomFields {
+		if name, ok := fields[value.FieldID]; ok {
+			item.CustomFields[name] = value.Value
+		}
+	}
+	if depth < maxSubtaskDepth {
+		for _, child := range children[task.ID] {
+			item.Subtasks = append(item.Subtasks, templateTaskFrom(child, children, fields, base, depth+1))
+		}
+	}
+	return item
+}
+
+// earliestDue returns the day of the earliest due date among the tasks, the
+// base of their due offsets
+func earliestDue(tasks []*models.Task) time.Time {
+	var base time.Time
+	for _, task := range tasks {
+		if !task.DueDate.IsZero() && (base.IsZero() || task.DueDate.Before(base)) {
+			base = task.DueDate
+		}
+	}
+	return base.Truncate(24 * time.Hour)
+}
+
+// taskTree indexes tasks by parent and returns the top-level ones; tasks
+// whose parent is not among them count as top-level
+func taskTree(tasks []*models.Task) ([]*models.Task, map[uint][]*models.Task) {
+	present := map[uint]bool{}
+	for _, task := range tasks {
+		present[task.ID] = true
+	}
+	var roots []*models.Task
+	children := map[uint][]*models.Task{}
+	for _, task := range tasks {
+		if task.ParentID != nil && present[*task.ParentID] {
+			children[*task.ParentID] = append(children[*task.ParentID], task)
+		} else {
+			roots = append(roots, task)
+		}
+	}
+	return roots, children
+}
+
+// SaveProjectAsTemplate saves a project's workflow, labels, custom fields and
+// tasks, and optionally its members, as a new template. Due offsets count
+// from the earliest due date in the project.
+func (s *Service) SaveProjectAsTemplate(actorID, projectID uint, input dto.SaveAsTemplateInput) (*models.ProjectTemplate, error) {
+	project, err := s.Repo.GetProjectByID(projectID)
+	if err != nil {
+		return nil, err
+	}
+	statuses, err := s.Repo.GetWorkflow(projectID)
+	if err != nil {
+		return nil, err
+	}
+	labels, err := s.Repo.GetLabelsByProjectID(projectID)
+	if err != nil {
+		return nil, err
+	}
+	fields, err := s.Repo.GetCustomFieldsByProjectID(projectID)
+	if err != nil {
+		return nil, err
+	}
+	template := models.ProjectTemplate{
+		Name:         strings.TrimSpace(input.Name),
+		Description:  input.Description,
+		Category:     project.Category,
+		CreatorID:    actorID,
+		Workflow:     []models.TemplateStatus{},
+		Labels:       []models.TemplateLabel{},
+		CustomFields: []models.TemplateField{},
+		Tasks:        []models.TemplateTask{},
+		Members:      []models.TemplateMember{},
+	}
+	if template.Name == "" {
+		return nil, invalidInput("template name is required")
+	}
+	if template.Description == "" {
+		template.Description = project.Description
+	}
+	for _, status := range statuses {
+		template.Workflow = append(template.Workflow, models.TemplateStatus{Name: status.Name, Category: status.Category})
+	}
+	for _, label := range labels {
+		template.Labels = append(template.Labels, models.TemplateLabel{Name: label.Name, Color: label.Color})
+	}
+	fieldNames := map[uint]string{}
+	for _, field := range fields {
+		fieldNames[field.ID] = field.Name
+		template.CustomFields = append(template.CustomFields, models.TemplateField{
+			Name:         field.Name,
+			Type:         field.Type,
+			Required:     field.Required,
+			Options:      field.Options,
+			DefaultValue: field.DefaultValue,
+		})
+	}
+	tasks := make([]*models.Task, 0, len(project.Tasks))
+	for i := range project.Tasks {
+		tasks = append(tasks, &project.Tasks[i])
+	}
+	if len(tasks) > maxTemplateTasks {
+		return nil, invalidInput("a template holds at most %d tasks, the project has %d", maxTemplateTasks, len(tasks))
+	}
+	roots, children := taskTree(tasks)
+	base := earliestDue(tasks)
+	for _, task := range roots {
+		template.Tasks = append(template.Tasks, templateTaskFrom(task, children, fieldNames, base, 1))
+	}
+	if input.IncludeMembers {
+		for _, role := range project.Users {
+			template.Members = append(template.Members, models.TemplateMember{UserID: role.UserID, Role: role.Role})
+		}
+	}
+	if err := s.Repo.CreateProjectTemplate(&template); err != nil {
+		logrus.WithFields(logrus.Fields{
+			"projectID": projectID,
+			"error":     err,
+		}).Error("Failed to save project as template")
+		return nil, err
+	}
+	logrus.WithFields(logrus.Fields{
+		"projectID":  projectID,
+		"templateID": template.ID,
+	}).Info("Project saved as template successfully")
+	return &template, nil
+}
+
+func (s *Service) GetTaskTemplates(projectID uint) ([]models.TaskTemplate, error) {
+	templates, err := s.Repo.GetTaskTemplatesByProjectID(projectID)
+	if err != nil {
+		logrus.WithFields(logrus.Fields{
+			"projectID": projectID,
+			"error":     err,
+		}).Error("Failed to retrieve task templates")
+		return nil, err
+	}
+	return templates, nil
+}
+
+func (s *Service) GetTaskTemplate(templateID uint) (*models.TaskTemplate, error) {
+	template, err := s.Repo.GetTaskTemplateByID(templateID)
+	if err != nil {
+		logrus.WithFields(logrus.Fields{
+			"templateID": templateID,
+			"error":      err,
+		}).Warn("Task template not found")
+		return nil, err
+	}
+	return template, nil
+}
+
+// CreateTaskTemplate saves a task template for the project; its task may
+// refer to the project's statuses, labels and custom fields by name
+func (s *Service) CreateTaskTemplate(actorID, projectID uint, input dto.TaskTemplateInput) (*models.TaskTemplate, error) {
+	target, err := loadTemplateTarget(s.Repo, projectID, time.Time{})
+	if err != nil {
+		return nil, err
+	}
+	scope := &templateScope{labels: map[string]bool{}, fields: target.fields}
+	if len(target.categories) > 0 {
+		scope.statuses = map[string]bool{}
+		for name := range target.categories {
+			scope.statuses[name] = true
+		}
+	}
+	for name := range target.labels {
+		scope.labels[name] = true
+	}
+	template := &models.TaskTemplate{
+		ProjectID: projectID,
+		Name:      strings.TrimSpace(input.Name),
+		CreatorID: actorID,
+		Task:      input.Task,
+	}
+	if template.Name == "" {
+		return nil, invalidInput("template name is required")
+	}
+	tasks := []models.TemplateTask{template.Task}
+	if err := s.validateTemplateTasks(tasks, scope, 1); err != nil {
+		logrus.WithFields(logrus.Fields{
+			"projectID": projectID,
+			"error":     err,
+		}).Warn("Invalid task template")
+		return nil, err
+	}
+	template.Task = tasks[0]
+	if err := s.Repo.CreateTaskTemplate(template); err != nil {
+		logrus.WithFields(logrus.Fields{
+			"projectID": projectID,
+			"error":     err,
+		}).Error("Failed to create task template")
+		return nil, err
+	}
+	logrus.WithFields(logrus.Fields{
+		"templateID": template.ID,
+		"projectID":  projectID,
+	}).Info("Task template created successfully")
+	return template, nil
+}
+
+// loadTaskTree loads the subtasks of a task, down to maxSubtaskDepth levels
+// and at most maxTemplateTasks tasks. It returns the task and its subtasks,
+// and the subtasks by parent.
+func (s *Service) loadTaskTree(root *models.Task) ([]*models.Task, map[uint][]*models.Task, error) {
+	tasks := []*models.Task{root}
+	children := map[uint][]*models.Task{}
+	level := []*models.Task{root}
+	for depth := 1; depth < maxSubtaskDepth && len(level) > 0; depth++ {
+		var next []*models.Task
+		for _, parent := range level {
+			subtasks, err := s.Repo.GetSubtasks(parent.ID)
+			if err != nil {
+				return nil, nil, err
+			}
+			for i := range subtasks {
+				children[parent.ID] = append(children[parent.ID], &subtasks[i])
+				next = append(next, &subtasks[i])
+			}
+		}
+		tasks = append(tasks, next...)
+		if len(tasks) > maxTemplateTasks {
+			return nil, nil, invalidInput("at most %d tasks can be copied at once", maxTemplateTasks)
+		}
+		level = next
+	}
+	return tasks, children, nil
+}
+
+// SaveTaskAsTemplate saves a task and its subtasks as a task template of its
+// project. Due offsets count from the earliest due date among them.
+func (s *Service) SaveTaskAsTemplate(actorID, taskID uint, input dto.SaveAsTemplateInput) (*models.TaskTemplate, error) {
+	root, err := s.Repo.GetTaskByID(taskID)
+	if err != nil {
+		return nil, err
+	}
+	name := strings.TrimSpace(input.Name)
+	if name == "" {
+		return nil, invalidInput("template name is required")
+	}
+	tasks, children, err := s.loadTaskTree(root)
+	if err != nil {
+		return nil, err
+	}
+	fields, err := s.Repo.GetCustomFieldsByProjectID(root.ProjectID)
+	if err != nil {
+		return nil, err
+	}
+	fieldNames := map[uint]string{}
+	for _, field := range fields {
+		fieldNames[field.ID] = field.Name
+	}
+	template := &models.TaskTemplate{
+		ProjectID: root.ProjectID,
+		Name:      name,
+		CreatorID: actorID,
+		Task:      templateTaskFrom(root, children, fieldNames, earliestDue(tasks), 1),
+	}
+	if err := s.Repo.CreateTaskTemplate(template); err != nil {
+		logrus.WithFields(logrus.Fields{
+			"taskID": taskID,
+			"error":  err,
+		}).Error("Failed to save task as template")
+		return nil, err
+	}
+	logrus.WithFields(logrus.Fields{
+		"taskID":     taskID,
+		"templateID": template.ID,
+	}).Info("Task saved as template successfully")
+	return template, nil
+}
+
+func (s *Service) DeleteTaskTemplate(templateID uint) error {
+	if err := s.Repo.DeleteTaskTemplate(templateID); err != nil {
+		logrus.WithFields(logrus.Fields{
+			"templateID": templateID,
+			"error":      err,
+		}).Error("Failed to delete task template")
+		return err
+	}
+	logrus.WithFields(logrus.Fields{
+		"templateID": templateID,
+	}).Info("Task template deleted successfully")
+	return nil
+}
+
+// CreateTasksFromTemplate adds the task of a task template, with its
+// subtasks, to the template's project and returns the top-level task
+func (s *Service) CreateTasksFromTemplate(actorID, templateID uint, input dto.FromTemplateInput) (*models.Task, error) {
+	template, err := s.GetTaskTemplate(templateID)
+	if err != nil {
+		return nil, err
+	}
+	var parentID *uint
+	if input.ParentID != 0 {
+		if err := s.validateParent(template.ProjectID, 0, input.ParentID); err != nil {
+			return nil, err
+		}
+		parentID = &input.ParentID
+	}
+	var target *templateTarget
+	err = s.transaction(func(repo *repository.Repository) error {
+		target, err = loadTemplateTarget(repo, template.ProjectID, startDate(input.StartDate))
+		if err != nil {
+			return err
+		}
+		target.reporterID = actorID
+		if err := s.createTemplateTasks(repo, target, []models.TemplateTask{template.Task}, parentID); err != nil {
+			return err
+		}
+		for _, task := range target.created {
+			if err := s.recordEvent(repo, Event{
+				Type:      EventTaskCreated,
+				ProjectID: task.ProjectID,
+				TaskID:    task.ID,
+				ActorID:   actorID,
+				Message:   fmt.Sprintf("created task %q", task.Title),
+				Data:      map[string]interface{}{"task_template_id": template.ID},
+			}); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		logrus.WithFields(logrus.Fields{
+			"templateID": templateID,
+			"error":      err,
+		}).Error("Failed to create tasks from template")
+		return nil, err
+	}
+	logrus.WithFields(logrus.Fields{
+		"templateID": templateID,
+		"taskCount":  len(target.created),
+	}).Info("Tasks created from template successfully")
+	return s.Repo.GetTaskByID(target.created[0].ID)
+}
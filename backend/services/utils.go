@@ -1,18 +1,61 @@
 package services
 
 import (
+	"errors"
+	"fmt"
+
+	"work-management/jobs"
+	"work-management/mailer"
+	"work-management/realtime"
 	"work-management/repository"
 )
 
 // Service struct to hold the repository dependency
 type Service struct {
-	Repo *repository.Repository
+	Repo        *repository.Repository
+	Mailer      mailer.Mailer
+	Jobs        *jobs.Queue
+	Realtime    *realtime.Hub
+	subscribers []subscriber
+	wakeRelay   func()
 }
 
-// NewService creates a new Service instance
-func NewService(repo *repository.Repository) *Service {
-	return &Service{Repo: repo}
+// NewService creates a new Service instance with the built-in event
+// subscribers (in-app notifications, email, webhooks, WebSocket fan-out, SLA
+// clocks and automation rules) registered, and its background jobs and
+// outbox relay added to the queue
+func NewService(repo *repository.Repository, mail mailer.Mailer, queue *jobs.Queue) *Service {
+	s := &Service{Repo: repo, Mailer: mail, Jobs: queue, Realtime: realtime.NewHub()}
+	s.registerJobs()
+	s.Subscribe("notifications", s.deliverNotifications)
+	s.Subscribe("email", s.sendNotificationEmails)
+	s.Subscribe("webhooks", s.queueWebhookDeliveries)
+	s.Subscribe("realtime", s.pushRealtimeEvent)
+	s.Subscribe("sla", s.trackSLAs)
+	s.Subscribe("automation", s.runAutomations)
+	return s
 }
 
 // SecretKey for JWT signing (move to config in production)
 const SecretKey = "Banana"
+
+// ErrInvalidInput is wrapped by errors caused by bad client input so that
+// handlers can answer with 400 instead of 500.
+var ErrInvalidInput = errors.New("invalid input")
+
+// ErrForbidden is wrapped by errors raised when the caller is not allowed to
+// perform an action the service checks itself.
+var ErrForbidden = errors.New("insufficient permission")
+
+// ErrProjectArchived is wrapped by errors raised when changing an archived
+// project, which is read-only until it is unarchived.
+var ErrProjectArchived = errors.New("project is archived")
+
+// ErrConflict is wrapped by errors raised when a change clashes with the
+// current state, e.g. a board move over a WIP limit or next to a task that
+// someone else just moved away.
+var ErrConflict = errors.New("conflict")
+
+func invalidInput(format string, args ...interface{}) error {
+	return fmt.Errorf("%w: %s", ErrInvalidInput, fmt.Sprintf(format, args...))
+}
@@ -0,0 +1,275 @@
+// View-related services (saved views and their swimlanes)
+package services
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	"work-management/dto"
+	"work-management/models"
+	"work-management/repository"
+
+	"github.com/sirupsen/logrus"
+)
+
+var validGroupings = map[string]bool{
+	models.GroupByNone:     true,
+	models.GroupByStatus:   true,
+	models.GroupByAssignee: true,
+	models.GroupByPriority: true,
+	models.GroupByLabel:    true,
+}
+
+func validateViewInput(input *dto.ViewInput) error {
+	input.Name = strings.TrimSpace(input.Name)
+	if input.Name == "" {
+		return invalidInput("view name is required")
+	}
+	if !validGroupings[input.GroupBy] {
+		return invalidInput("group_by must be one of status, assignee, priority, label")
+	}
+	seen := map[string]bool{}
+	for _, column := range input.Columns {
+		if _, isCustom := repository.CustomFieldSortID(column); !isCustom && !models.ViewColumns[column] {
+			return invalidInput("unknown column %q", column)
+		}
+		if seen[column] {
+			return invalidInput("column %q is listed twice", column)
+		}
+		seen[column] = true
+	}
+	return nil
+}
+
+// prepareView validates a view's settings; filter is its parsed Filter
+func (s *Service) prepareView(actorID, projectID uint, input *dto.ViewInput, filter dto.TaskFilter) error {
+	if err := validateViewInput(input); err != nil {
+		return err
+	}
+	filter.Sort = input.Sort
+	if err := s.prepareTaskFilter(&filter); err != nil {
+		return err
+	}
+	if input.Shared {
+		canModify, err := s.CanModifyProject(actorID, projectID)
+		if err != nil {
+			return err
+		}
+		if !canModify {
+			return fmt.Errorf("%w: only editors can share views with the project", ErrForbidden)
+		}
+	}
+	return nil
+}
+
+// GetViews lists the views of a project the user can see
+func (s *Service) GetViews(userID, projectID uint) ([]models.View, error) {
+	views, err := s.Repo.GetViews(projectID, userID)
+	if err != nil {
+		logrus.WithFields(logrus.Fields{
+			"projectID": projectID,
+			"error":     err,
+		}).Error("Failed to retrieve views")
+		return nil, err
+	}
+	return views, nil
+}
+
+// GetView returns a view if the user can see it: they belong to its project
+// and the view is theirs or shared
+func (s *Service) GetView(userID, viewID uint) (*models.View, error) {
+	view, err := s.Repo.GetViewByID(viewID)
+	if err != nil {
+		return nil, err
+	}
+	if !s.IsProjectMember(userID, view.ProjectID) {
+		return nil, fmt.Errorf("%w: not a member of the view's project", ErrForbidden)
+	}
+	if view.OwnerID != userID && !view.Shared {
+		return nil, fmt.Errorf("%w: the view is not shared with you", ErrForbidden)
+	}
+	return view, nil
+}
+
+// viewForOwner loads a view the actor may change: their own, or a shared
+// view of a project they administer
+func (s *Service) viewForOwner(actorID, viewID uint) (*models.View, error) {
+	view, err := s.Repo.GetViewByID(viewID)
+	if err != nil {
+		return nil, err
+	}
+	if view.OwnerID == actorID {
+		return view, nil
+	}
+	if view.Shared {
+		if isAdmin, err := s.AdminOnly(actorID, view.ProjectID); err == nil && isAdmin {
+			return view, nil
+		}
+	}
+	return nil, fmt.Errorf("%w: only the owner can change a view", ErrForbidden)
+}
+
+// CreateView saves a view of a project; filter is the parsed input.Filter
+func (s *Service) CreateView(ownerID, projectID uint, input dto.ViewInput, filter dto.TaskFilter) (*models.View, error) {
+	if err := s.prepareView(ownerID, projectID, &input, filter); err != nil {
+		return nil, err
+	}
+	view := &models.View{
+		ProjectID: projectID,
+		OwnerID:   ownerID,
+		Name:      input.Name,
+		Shared:    input.Shared,
+		Filter:    input.Filter,
+		GroupBy:   input.GroupBy,
+		Sort:      input.Sort,
+		Columns:   input.Columns,
+	}
+	if err := s.Repo.CreateView(view); err != nil {
+		logrus.WithFields(logrus.Fields{
+			"projectID": projectID,
+			"error":     err,
+		}).Error("Failed to create view")
+		return nil, err
+	}
+	logrus.WithFields(logrus.Fields{
+		"projectID": projectID,
+		"viewID":    view.ID,
+	}).Info("View created successfully")
+	return view, nil
+}
+
+// UpdateView replaces a view's settings; filter is the parsed input.Filter
+func (s *Service) UpdateView(actorID, viewID uint, input dto.ViewInput, filter dto.TaskFilter) (*models.View, error) {
+	view, err := s.viewForOwner(actorID, viewID)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.prepareView(actorID, view.ProjectID, &input, filter); err != nil {
+		return nil, err
+	}
+	view.Name = input.Name
+	view.Shared = input.Shared
+	view.Filter = input.Filter
+	view.GroupBy = input.GroupBy
+	view.Sort = input.Sort
+	view.Columns = input.Columns
+	if err := s.Repo.UpdateView(view); err != nil {
+		logrus.WithFields(logrus.Fields{
+			"viewID": viewID,
+			"error":  err,
+		}).Error("Failed to update view")
+		return nil, err
+	}
+	return view, nil
+}
+
+func (s *Service) DeleteView(actorID, viewID uint) error {
+	if _, err := s.viewForOwner(actorID, viewID); err != nil {
+		return err
+	}
+	if err := s.Repo.DeleteView(viewID); err != nil {
+		logrus.WithFields(logrus.Fields{
+			"viewID": viewID,
+			"error":  err,
+		}).Error("Failed to delete view")
+		return err
+	}
+	return nil
+}
+
+// RunView lists the tasks of a view grouped into its swimlanes; filter is
+// the parsed view.Filter
+func (s *Service) RunView(view *models.View, filter dto.TaskFilter) (*dto.ViewResult, error) {
+	filter.Sort = view.Sort
+	tasks, err := s.GetTasksByProjectID(view.ProjectID, filter)
+	if err != nil {
+		return nil, err
+	}
+	groups, err := s.groupTasks(view.ProjectID, view.GroupBy, tasks)
+	if err != nil {
+		return nil, err
+	}
+	return &dto.ViewResult{View: *view, Total: len(tasks), Groups: groups}, nil
+}
+
+// groupTasks splits tasks into swimlanes, keeping their order within each
+// lane. Status lanes follow the workflow and include empty statuses;
+// priority lanes go from urgent to low; assignee and label lanes are sorted
+// by name. Tasks without an assignee or label come last.
+func (s *Service) groupTasks(projectID uint, groupBy string, tasks []models.Task) ([]dto.ViewGroup, error) {
+	var groups []*dto.ViewGroup
+	byKey := map[string]*dto.ViewGroup{}
+	add := func(key, name string, task *models.Task) {
+		group, ok := byKey[key]
+		if !ok {
+			group = &dto.ViewGroup{Key: key, Name: name, Tasks: []models.Task{}}
+			byKey[key] = group
+			groups = append(groups, group)
+		}
+		if task != nil {
+			group.Tasks = append(group.Tasks, *task)
+		}
+	}
+	switch groupBy {
+	case models.GroupByNone:
+		add("", "", nil)
+		for i := range tasks {
+			add("", "", &tasks[i])
+		}
+	case models.GroupByStatus:
+		workflow, err := s.GetWorkflow(projectID)
+		if err != nil {
+			return nil, err
+		}
+		for _, status := range workflow {
+			add(status.Name, status.Name, nil)
+		}
+		for i := range tasks {
+			add(tasks[i].Status, tasks[i].Status, &tasks[i])
+		}
+	case models.GroupByPriority:
+		for i := range tasks {
+			add(tasks[i].Priority, tasks[i].Priority, &tasks[i])
+		}
+		sort.SliceStable(groups, func(i, j int) bool {
+			return models.PriorityRank[groups[i].Key] > models.PriorityRank[groups[j].Key]
+		})
+	case models.GroupByAssignee:
+		for i := range tasks {
+			for _, user := range tasks[i].Assignees {
+				add(strconv.FormatUint(uint64(user.ID), 10), user.Name, &tasks[i])
+			}
+		}
+		sortGroupsByName(groups)
+		for i := range tasks {
+			if len(tasks[i].Assignees) == 0 {
+				add("", "Unassigned", &tasks[i])
+			}
+		}
+	case models.GroupByLabel:
+		for i := range tasks {
+			for _, label := range tasks[i].Labels {
+				add(strconv.FormatUint(uint64(label.ID), 10), label.Name, &tasks[i])
+			}
+		}
+		sortGroupsByName(groups)
+		for i := range tasks {
+			if len(tasks[i].Labels) == 0 {
+				add("", "No label", &tasks[i])
+			}
+		}
+	}
+	result := make([]dto.ViewGroup, 0, len(groups))
+	for _, group := range groups {
+		result = append(result, *group)
+	}
+	return result, nil
+}
+
+func sortGroupsByName(groups []*dto.ViewGroup) {
+	sort.SliceStable(groups, func(i, j int) bool {
+		return strings.ToLower(groups[i].Name) < strings.ToLower(groups[j].Name)
+	})
+}
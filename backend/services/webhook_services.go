@@ -0,0 +1,440 @@
+// Webhook-related services (registration, signed deliveries and retries)
+package services
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+
+	"work-management/jobs"
+	"work-management/models"
+
+	"github.com/sirupsen/logrus"
+	"gorm.io/gorm"
+)
+
+// Webhook delivery settings
+const (
+	// webhookMaxAttempts is the number of attempts before a delivery is
+	// marked failed; the wait between attempts doubles from webhookBackoff.
+	webhookMaxAttempts = 6
+	webhookBackoff     = 30 * time.Second
+	webhookTimeout     = 10 * time.Second
+	// maxWebhookResponseBody is how much of a successful response is kept
+	// for display
+	maxWebhookResponseBody = 2048
+)
+
+// Webhook request headers
+const (
+	WebhookSignatureHeader = "X-Webhook-Signature"
+	WebhookEventHeader     = "X-Webhook-Event"
+	WebhookDeliveryHeader  = "X-Webhook-Delivery"
+)
+
+// webhookClient only connects to public addresses, checked when dialing so
+// that a host name resolving to an internal address is refused too, and
+// does not follow redirects
+var webhookClient = &http.Client{
+	Timeout: webhookTimeout,
+	Transport: &http.Transport{
+		DialContext: (&net.Dialer{
+			Timeout: webhookTimeout,
+			Control: func(network, address string, _ syscall.RawConn) error {
+				host, _, err := net.SplitHostPort(address)
+				if err != nil {
+					return err
+				}
+				if ip := net.ParseIP(host); ip == nil || !isPublicIP(ip) {
+					return fmt.Errorf("webhook address %s is not public", host)
+				}
+				return nil
+			},
+		}).DialContext,
+		TLSHandshakeTimeout: webhookTimeout,
+	},
+	CheckRedirect: func(*http.Request, []*http.Request) error {
+		return http.ErrUseLastResponse
+	},
+}
+
+// isPublicIP reports whether webhooks may be sent to the address: loopback,
+// private, link-local (cloud metadata), multicast and unspecified addresses
+// are internal
+func isPublicIP(ip net.IP) bool {
+	return !ip.IsLoopback() && !ip.IsPrivate() && !ip.IsLinkLocalUnicast() && !ip.IsLinkLocalMulticast() &&
+		!ip.IsInterfaceLocalMulticast() && !ip.IsMulticast() && !ip.IsUnspecified()
+}
+
+// eventPayload is the public form of an event: the JSON body posted to
+// webhooks and pushed to WebSocket clients
+type eventPayload struct {
+	Event      string                 `json:"event"`
+	ProjectID  uint                   `json:"project_id"`
+	TaskID     uint                   `json:"task_id,omitempty"`
+	ActorID    uint                   `json:"actor_id,omitempty"`
+	Message    string                 `json:"message,omitempty"`
+	Data       map[string]interface{} `json:"data,omitempty"`
+	OccurredAt time.Time              `json:"occurred_at"`
+}
+
+func newEventPayload(event Event) eventPayload {
+	return eventPayload{
+		Event:      event.Type,
+		ProjectID:  event.ProjectID,
+		TaskID:     event.TaskID,
+		ActorID:    event.ActorID,
+		Message:    event.Message,
+		Data:       event.Data,
+		OccurredAt: event.OccurredAt,
+	}
+}
+
+// SignWebhookPayload returns the signature header value for a payload:
+// "sha256=" followed by the hex HMAC-SHA256 of the body keyed by the secret
+func SignWebhookPayload(secret string, payload []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(payload)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+func generateWebhookSecret() (string, error) {
+	key := make([]byte, 32)
+	if _, err := rand.Read(key); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(key), nil
+}
+
+func validateWebhook(webhook *models.Webhook) error {
+	target, err := url.Parse(webhook.URL)
+	if err != nil || (target.Scheme != "http" && target.Scheme != "https") || target.Host == "" {
+		return invalidInput("url must be an absolute http or https URL")
+	}
+	// Host names are checked again each time a delivery connects
+	host := target.Hostname()
+	if ip := net.ParseIP(host); strings.EqualFold(host, "localhost") || (ip != nil && !isPublicIP(ip)) {
+		return invalidInput("url must point to a public address")
+	}
+	if len(webhook.Events) == 0 {
+		return invalidInput("at least one event type is required")
+	}
+	for _, event := range webhook.Events {
+		if event != "*" && !isEventType(event) {
+			return invalidInput("unknown event type %q", event)
+		}
+	}
+	return nil
+}
+
+// CreateWebhook registers a webhook; a signing secret is generated when none
+// is given
+func (s *Service) CreateWebhook(webhook *models.Webhook) (*models.Webhook, error) {
+	if err := validateWebhook(webhook); err != nil {
+		logrus.WithFields(logrus.Fields{
+			"projectID": webhook.ProjectID,
+			"error":     err,
+		}).Warn("Invalid webhook")
+		return nil, err
+	}
+	if webhook.Secret == "" {
+		secret, err := generateWebhookSecret()
+		if err != nil {
+			return nil, err
+		}
+		webhook.Secret = secret
+	}
+	if err := s.Repo.CreateWebhook(webhook); err != nil {
+		logrus.WithFields(logrus.Fields{
+			"projectID": webhook.ProjectID,
+			"error":     err,
+		}).Error("Failed to create webhook")
+		return nil, err
+	}
+	logrus.WithFields(logrus.Fields{
+		"webhookID": webhook.ID,
+		"projectID": webhook.ProjectID,
+	}).Info("Webhook created successfully")
+	return webhook, nil
+}
+
+func (s *Service) GetWebhooksByProjectID(projectID uint) ([]models.Webhook, error) {
+	webhooks, err := s.Repo.GetWebhooksByProjectID(projectID)
+	if err != nil {
+		logrus.WithFields(logrus.Fields{
+			"projectID": projectID,
+			"error":     err,
+		}).Error("Failed to retrieve webhooks")
+		return nil, err
+	}
+	return webhooks, nil
+}
+
+// getProjectWebhook returns a webhook only if it belongs to the project
+func (s *Service) getProjectWebhook(projectID, webhookID uint) (*models.Webhook, error) {
+	webhook, err := s.Repo.GetWebhookByID(webhookID)
+	if err != nil {
+		logrus.WithFields(logrus.Fields{
+			"webhookID": webhookID,
+			"error":     err,
+		}).Warn("Webhook not found")
+		return nil, err
+	}
+	if webhook.ProjectID != projectID {
+		return nil, invalidInput("webhook %d does not belong to this project", webhookID)
+	}
+	return webhook, nil
+}
+
+// UpdateWebhook changes a webhook's URL, events and active flag, and its
+// secret if a new one is given
+func (s *Service) UpdateWebhook(projectID, webhookID uint, update models.Webhook) (*models.Webhook, error) {
+	webhook, err := s.getProjectWebhook(projectID, webhookID)
+	if err != nil {
+		return nil, err
+	}
+	webhook.URL = update.URL
+	webhook.Events = update.Events
+	webhook.Active = update.Active
+	if update.Secret != "" {
+		webhook.Secret = update.Secret
+	}
+	if err := validateWebhook(webhook); err != nil {
+		return nil, err
+	}
+	if err := s.Repo.UpdateWebhook(webhook); err != nil {
+		logrus.WithFields(logrus.Fields{
+			"webhookID": webhookID,
+			"error":     err,
+		}).Error("Failed to update webhook")
+		return nil, err
+	}
+	logrus.WithFields(logrus.Fields{
+		"webhookID": webhookID,
+	}).Info("Webhook updated successfully")
+	return webhook, nil
+}
+
+func (s *Service) DeleteWebhook(projectID, webhookID uint) error {
+	if _, err := s.getProjectWebhook(projectID, webhookID); err != nil {
+		return err
+	}
+	if err := s.Repo.DeleteWebhook(webhookID); err != nil {
+		logrus.WithFields(logrus.Fields{
+			"webhookID": webhookID,
+			"error":     err,
+		}).Error("Failed to delete webhook")
+		return err
+	}
+	logrus.WithFields(logrus.Fields{
+		"webhookID": webhookID,
+	}).Info("Webhook deleted successfully")
+	return nil
+}
+
+// GetWebhookDeliveries lists the latest deliveries of a webhook
+func (s *Service) GetWebhookDeliveries(projectID, webhookID uint, limit int) ([]models.WebhookDelivery, error) {
+	if _, err := s.getProjectWebhook(projectID, webhookID); err != nil {
+		return nil, err
+	}
+	deliveries, err := s.Repo.GetWebhookDeliveries(webhookID, limit)
+	if err != nil {
+		logrus.WithFields(logrus.Fields{
+			"webhookID": webhookID,
+			"error":     err,
+		}).Error("Failed to retrieve webhook deliveries")
+		return nil, err
+	}
+	return deliveries, nil
+}
+
+// RedeliverWebhook queues a new delivery with the payload of an earlier one
+func (s *Service) RedeliverWebhook(projectID, webhookID, deliveryID uint) (*models.WebhookDelivery, error) {
+	if _, err := s.getProjectWebhook(projectID, webhookID); err != nil {
+		return nil, err
+	}
+	original, err := s.Repo.GetWebhookDeliveryByID(deliveryID)
+	if err != nil {
+		return nil, err
+	}
+	if original.WebhookID != webhookID {
+		return nil, invalidInput("delivery %d does not belong to this webhook", deliveryID)
+	}
+	now := time.Now()
+	delivery := &models.WebhookDelivery{
+		WebhookID:     webhookID,
+		EventType:     original.EventType,
+		Payload:       original.Payload,
+		Status:        models.DeliveryPending,
+		NextAttemptAt: &now,
+	}
+	if err := s.Repo.CreateWebhookDelivery(delivery); err != nil {
+		logrus.WithFields(logrus.Fields{
+			"deliveryID": deliveryID,
+			"error":      err,
+		}).Error("Failed to queue webhook redelivery")
+		return nil, err
+	}
+	if _, err := s.Jobs.Enqueue(JobDeliverWebhook, webhookJob{DeliveryID: delivery.ID}); err != nil {
+		return nil, err
+	}
+	logrus.WithFields(logrus.Fields{
+		"deliveryID": delivery.ID,
+		"originalID": deliveryID,
+	}).Info("Webhook redelivery queued")
+	return delivery, nil
+}
+
+// queueWebhookDeliveries records a pending delivery of the event for every
+// active webhook of its project that subscribes to it
+func (s *Service) queueWebhookDeliveries(event Event) error {
+	if event.ProjectID == 0 {
+		return nil
+	}
+	webhooks, err := s.Repo.GetActiveWebhooks(event.ProjectID)
+	if err != nil {
+		logrus.WithFields(logrus.Fields{
+			"projectID": event.ProjectID,
+			"error":     err,
+		}).Error("Failed to retrieve webhooks for event")
+		return err
+	}
+	if len(webhooks) == 0 {
+		return nil
+	}
+	payload, err := json.Marshal(newEventPayload(event))
+	if err != nil {
+		logrus.WithFields(logrus.Fields{
+			"event": event.Type,
+			"error": err,
+		}).Error("Failed to encode webhook payload")
+		return err
+	}
+	for _, webhook := range webhooks {
+		if !webhook.Subscribes(event.Type) {
+			continue
+		}
+		now := time.Now()
+		delivery := &models.WebhookDelivery{
+			WebhookID:     webhook.ID,
+			EventType:     event.Type,
+			Payload:       string(payload),
+			Status:        models.DeliveryPending,
+			NextAttemptAt: &now,
+		}
+		if err := s.Repo.CreateWebhookDelivery(delivery); err != nil {
+			logrus.WithFields(logrus.Fields{
+				"webhookID": webhook.ID,
+				"event":     event.Type,
+				"error":     err,
+			}).Error("Failed to queue webhook delivery")
+			return err
+		}
+		// Enqueue logs its own failures
+		if _, err := s.Jobs.Enqueue(JobDeliverWebhook, webhookJob{DeliveryID: delivery.ID}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// attemptWebhookDelivery posts a delivery once and records the outcome,
+// scheduling the next attempt with exponential backoff on failure
+func (s *Service) attemptWebhookDelivery(ctx context.Context, delivery *models.WebhookDelivery) error {
+	webhook, err := s.Repo.GetWebhookByID(delivery.WebhookID)
+	if err != nil {
+		return err
+	}
+	delivery.Attempts++
+	delivery.Error = ""
+	delivery.ResponseCode = 0
+	delivery.ResponseBody = ""
+
+	body := []byte(delivery.Payload)
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, webhook.URL, bytes.NewReader(body))
+	if err == nil {
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set(WebhookEventHeader, delivery.EventType)
+		req.Header.Set(WebhookDeliveryHeader, strconv.FormatUint(uint64(delivery.ID), 10))
+		req.Header.Set(WebhookSignatureHeader, SignWebhookPayload(webhook.Secret, body))
+		var resp *http.Response
+		resp, err = webhookClient.Do(req)
+		if err == nil {
+			response, _ := io.ReadAll(io.LimitReader(resp.Body, maxWebhookResponseBody))
+			resp.Body.Close()
+			delivery.ResponseCode = resp.StatusCode
+			if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+				// Redirects are not followed and end up here too
+				err = fmt.Errorf("endpoint answered %d", resp.StatusCode)
+			} else {
+				delivery.ResponseBody = strings.ToValidUTF8(string(response), "")
+			}
+		}
+	}
+
+	now := time.Now()
+	switch {
+	case err == nil:
+		delivery.Status = models.DeliverySucceeded
+		delivery.DeliveredAt = &now
+		delivery.NextAttemptAt = nil
+	case delivery.Attempts >= webhookMaxAttempts:
+		delivery.Status = models.DeliveryFailed
+		delivery.Error = err.Error()
+		delivery.NextAttemptAt = nil
+	default:
+		delivery.Error = err.Error()
+		next := now.Add(webhookBackoff << (delivery.Attempts - 1))
+		delivery.NextAttemptAt = &next
+	}
+	logrus.WithFields(logrus.Fields{
+		"deliveryID": delivery.ID,
+		"webhookID":  delivery.WebhookID,
+		"attempt":    delivery.Attempts,
+		"status":     delivery.Status,
+		"code":       delivery.ResponseCode,
+	}).Info("Webhook delivery attempted")
+	return s.Repo.UpdateWebhookDelivery(delivery)
+}
+
+// webhookJob is the payload of JobDeliverWebhook
+type webhookJob struct {
+	DeliveryID uint `json:"delivery_id"`
+}
+
+// runWebhookDelivery makes the next attempt of a delivery and, if it failed
+// but has attempts left, schedules the following one
+func (s *Service) runWebhookDelivery(ctx context.Context, payload webhookJob) error {
+	delivery, err := s.Repo.GetWebhookDeliveryByID(payload.DeliveryID)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		// Purged along with its project
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	if delivery.Status != models.DeliveryPending {
+		return nil
+	}
+	if err := s.attemptWebhookDelivery(ctx, delivery); err != nil {
+		return err
+	}
+	if delivery.Status == models.DeliveryPending && delivery.NextAttemptAt != nil {
+		_, err = s.Jobs.Enqueue(JobDeliverWebhook, payload, jobs.At(*delivery.NextAttemptAt))
+	}
+	return err
+}
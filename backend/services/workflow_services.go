@@ -0,0 +1,217 @@
+// Workflow-related services (project statuses and their categories)
+package services
+
+import (
+	"fmt"
+	"strings"
+
+	"work-management/dto"
+	"work-management/models"
+	"work-management/repository"
+
+	"github.com/sirupsen/logrus"
+)
+
+// maxWorkflowStatuses bounds the length of a workflow
+const maxWorkflowStatuses = 20
+
+var validStatusCategories = map[string]bool{
+	models.CategoryToDo:       true,
+	models.CategoryInProgress: true,
+	models.CategoryDone:       true,
+}
+
+// validateWorkflow checks that status names are unique and that the
+// workflow has somewhere for tasks to start and to finish
+func validateWorkflow(statuses []models.WorkflowStatus) error {
+	if len(statuses) > maxWorkflowStatuses {
+		return invalidInput("a workflow has at most %d statuses", maxWorkflowStatuses)
+	}
+	seen := map[string]bool{}
+	categories := map[string]bool{}
+	for i := range statuses {
+		status := &statuses[i]
+		status.Name = strings.TrimSpace(status.Name)
+		if status.Name == "" {
+			return invalidInput("status name is required")
+		}
+		if seen[status.Name] {
+			return invalidInput("status %q is listed twice", status.Name)
+		}
+		seen[status.Name] = true
+		if !validStatusCategories[status.Category] {
+			return invalidInput("status category must be one of todo, in_progress, done")
+		}
+		categories[status.Category] = true
+		status.Position = i
+		if status.WIPLimit < 0 {
+			return invalidInput("WIP limit of status %q cannot be negative", status.Name)
+		}
+		switch status.WIPPolicy {
+		case "":
+			status.WIPPolicy = models.WIPWarn
+		case models.WIPWarn, models.WIPReject:
+		default:
+			return invalidInput("WIP policy must be one of warn, reject")
+		}
+	}
+	if !categories[models.CategoryToDo] || !categories[models.CategoryDone] {
+		return invalidInput("a workflow needs a todo and a done status")
+	}
+	return nil
+}
+
+// GetWorkflow returns the project's statuses, or the default workflow if it
+// has not configured one
+func (s *Service) GetWorkflow(projectID uint) ([]models.WorkflowStatus, error) {
+	statuses, err := s.Repo.GetWorkflow(projectID)
+	if err != nil {
+		logrus.WithFields(logrus.Fields{
+			"projectID": projectID,
+			"error":     err,
+		}).Error("Failed to retrieve workflow")
+		return nil, err
+	}
+	if len(statuses) == 0 {
+		return models.DefaultWorkflow(), nil
+	}
+	return statuses, nil
+}
+
+// UpdateWorkflow replaces the project's statuses. Tasks in a renamed status
+// follow it; removing a status that tasks are still in is refused. An empty
+// list returns the project to the default workflow.
+func (s *Service) UpdateWorkflow(actorID, projectID uint, input dto.WorkflowInput) ([]models.WorkflowStatus, error) {
+	statuses := make([]models.WorkflowStatus, 0, len(input.Statuses))
+	for _, status := range input.Statuses {
+		statuses = append(statuses, models.WorkflowStatus{
+			Name:      status.Name,
+			Category:  status.Category,
+			WIPLimit:  status.WIPLimit,
+			WIPPolicy: status.WIPPolicy,
+		})
+	}
+	if len(statuses) > 0 {
+		if err := validateWorkflow(statuses); err != nil {
+			return nil, err
+		}
+	}
+	names := make([]string, 0, len(statuses))
+	renamed := map[string]bool{}
+	for i, status := range input.Statuses {
+		names = append(names, statuses[i].Name)
+		from := strings.TrimSpace(status.RenamedFrom)
+		if from != "" && from != statuses[i].Name {
+			if renamed[from] {
+				return nil, invalidInput("status %q is renamed twice", from)
+			}
+			renamed[from] = true
+		}
+	}
+	err := s.transaction(func(repo *repository.Repository) error {
+		for i, status := range input.Statuses {
+			from := strings.TrimSpace(status.RenamedFrom)
+			if from != "" && from != statuses[i].Name {
+				if err := repo.RenameTaskStatus(projectID, from, statuses[i].Name); err != nil {
+					return err
+				}
+			}
+		}
+		if len(names) > 0 {
+			stranded, err := repo.CountTasksOutsideStatuses(projectID, names)
+			if err != nil {
+				return err
+			}
+			if stranded > 0 {
+				return invalidInput("%d tasks are in statuses missing from the workflow; move or rename them first", stranded)
+			}
+		}
+		if err := repo.ReplaceWorkflow(projectID, statuses); err != nil {
+			return err
+		}
+		return s.recordEvent(repo, Event{
+			Type:      EventProjectUpdated,
+			ProjectID: projectID,
+			ActorID:   actorID,
+			Message:   fmt.Sprintf("changed the workflow to %s", strings.Join(names, ", ")),
+			Data:      map[string]interface{}{"workflow": names},
+		})
+	})
+	if err != nil {
+		logrus.WithFields(logrus.Fields{
+			"projectID": projectID,
+			"error":     err,
+		}).Error("Failed to update workflow")
+		return nil, err
+	}
+	logrus.WithFields(logrus.Fields{
+		"projectID": projectID,
+		"statuses":  names,
+	}).Info("Workflow updated successfully")
+	return s.GetWorkflow(projectID)
+}
+
+// isCompletedStatus validates a task status against the project's workflow
+// and reports whether it completes the task. Projects on the default
+// workflow accept any status and complete tasks in Completed or Done.
+func (s *Service) isCompletedStatus(repo *repository.Repository, projectID uint, status string) (bool, error) {
+	statuses, err := repo.GetWorkflow(projectID)
+	if err != nil {
+		return false, err
+	}
+	if len(statuses) == 0 {
+		return models.IsCompletedStatus(status), nil
+	}
+	for _, candidate := range statuses {
+		if candidate.Name == status {
+			return candidate.Category == models.CategoryDone, nil
+		}
+	}
+	return false, invalidInput("status %q is not part of the project's workflow", status)
+}
+
+// initialStatus returns the status new tasks of the project start in: the
+// first todo status of its workflow
+func (s *Service) initialStatus(repo *repository.Repository, projectID uint) (string, error) {
+	statuses, err := repo.GetWorkflow(projectID)
+	if err != nil {
+		return "", err
+	}
+	for _, status := range statuses {
+		if status.Category == models.CategoryToDo {
+			return status.Name, nil
+		}
+	}
+	return models.StatusToDo, nil
+}
+
+// statusCategories returns a function giving the category of a status of the
+// project's workflow. On the default workflow completed statuses are done,
+// In Progress is in progress and any other status is still to do.
+func (s *Service) statusCategories(repo *repository.Repository, projectID uint) (func(status string) string, error) {
+	statuses, err := repo.GetWorkflow(projectID)
+	if err != nil {
+		return nil, err
+	}
+	if len(statuses) == 0 {
+		return func(status string) string {
+			switch {
+			case models.IsCompletedStatus(status):
+				return models.CategoryDone
+			case status == models.StatusInProgress:
+				return models.CategoryInProgress
+			}
+			return models.CategoryToDo
+		}, nil
+	}
+	categories := make(map[string]string, len(statuses))
+	for _, status := range statuses {
+		categories[status.Name] = status.Category
+	}
+	return func(status string) string {
+		if category, ok := categories[status]; ok {
+			return category
+		}
+		return models.CategoryToDo
+	}, nil
+}
@@ -0,0 +1,239 @@
+// Time tracking services (work logs, timers and timesheets)
+package services
+
+import (
+	"time"
+
+	"work-management/models"
+	"work-management/repository"
+
+	"github.com/sirupsen/logrus"
+)
+
+// maxTimesheetRange bounds how much history a single timesheet request can scan
+const maxTimesheetRange = 366 * 24 * time.Hour
+
+func validateWorkLog(startedAt time.Time, duration int) error {
+	if startedAt.IsZero() {
+		return invalidInput("started_at is required")
+	}
+	if startedAt.After(time.Now()) {
+		return invalidInput("started_at cannot be in the future")
+	}
+	if duration <= 0 || duration > models.MaxWorkLogMinutes {
+		return invalidInput("duration must be between 1 and %d minutes", models.MaxWorkLogMinutes)
+	}
+	return nil
+}
+
+func (s *Service) LogWork(taskID, userID uint, startedAt time.Time, duration int, note string) (*models.WorkLog, error) {
+	if err := validateWorkLog(startedAt, duration); err != nil {
+		logrus.WithFields(logrus.Fields{
+			"taskID": taskID,
+			"error":  err,
+		}).Warn("Invalid work log")
+		return nil, err
+	}
+	workLog := &models.WorkLog{
+		TaskID:    taskID,
+		UserID:    userID,
+		StartedAt: startedAt,
+		Duration:  duration,
+		Note:      note,
+	}
+	if err := s.Repo.CreateWorkLog(workLog); err != nil {
+		logrus.WithFields(logrus.Fields{
+			"taskID": taskID,
+			"userID": userID,
+			"error":  err,
+		}).Error("Failed to log work")
+		return nil, err
+	}
+	logrus.WithFields(logrus.Fields{
+		"workLogID": workLog.ID,
+		"taskID":    taskID,
+		"userID":    userID,
+	}).Info("Work logged successfully")
+	return workLog, nil
+}
+
+func (s *Service) GetWorkLogsByTaskID(taskID uint) ([]models.WorkLog, error) {
+	workLogs, err := s.Repo.GetWorkLogsByTaskID(taskID)
+	if err != nil {
+		logrus.WithFields(logrus.Fields{
+			"taskID": taskID,
+			"error":  err,
+		}).Error("Failed to retrieve work logs for task")
+		return nil, err
+	}
+	return workLogs, nil
+}
+
+// getEditableWorkLog loads a work log the user may change: their own entries,
+// or any entry in a project where they are an admin. Work logs of archived
+// projects cannot be changed, like the rest of the project.
+func (s *Service) getEditableWorkLog(workLogID, userID uint) (*models.WorkLog, error) {
+	workLog, err := s.Repo.GetWorkLogByID(workLogID)
+	if err != nil {
+		logrus.WithFields(logrus.Fields{
+			"workLogID": workLogID,
+			"error":     err,
+		}).Warn("Work log not found")
+		return nil, err
+	}
+	task, err := s.Repo.GetTaskByID(workLog.TaskID)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.ensureNotArchived(task.ProjectID); err != nil {
+		return nil, err
+	}
+	if workLog.UserID == userID {
+		return workLog, nil
+	}
+	if isAdmin, _ := s.AdminOnly(userID, task.ProjectID); !isAdmin {
+		logrus.WithFields(logrus.Fields{
+			"workLogID": workLogID,
+			"userID":    userID,
+		}).Warn("User cannot edit another user's work log")
+		return nil, ErrForbidden
+	}
+	return workLog, nil
+}
+
+func (s *Service) UpdateWorkLog(workLogID, userID uint, startedAt time.Time, duration int, note string) (*models.WorkLog, error) {
+	if err := validateWorkLog(startedAt, duration); err != nil {
+		logrus.WithFields(logrus.Fields{
+			"workLogID": workLogID,
+			"error":     err,
+		}).Warn("Invalid work log")
+		return nil, err
+	}
+	workLog, err := s.getEditableWorkLog(workLogID, userID)
+	if err != nil {
+		return nil, err
+	}
+	workLog.StartedAt = startedAt
+	workLog.Duration = duration
+	workLog.Note = note
+	if err := s.Repo.UpdateWorkLog(workLog); err != nil {
+		logrus.WithFields(logrus.Fields{
+			"workLogID": workLogID,
+			"error":     err,
+		}).Error("Failed to update work log")
+		return nil, err
+	}
+	logrus.WithFields(logrus.Fields{
+		"workLogID": workLogID,
+		"userID":    userID,
+	}).Info("Work log updated successfully")
+	return workLog, nil
+}
+
+func (s *Service) DeleteWorkLog(workLogID, userID uint) error {
+	if _, err := s.getEditableWorkLog(workLogID, userID); err != nil {
+		return err
+	}
+	if err := s.Repo.DeleteWorkLog(workLogID); err != nil {
+		logrus.WithFields(logrus.Fields{
+			"workLogID": workLogID,
+			"error":     err,
+		}).Error("Failed to delete work log")
+		return err
+	}
+	logrus.WithFields(logrus.Fields{
+		"workLogID": workLogID,
+		"userID":    userID,
+	}).Info("Work log deleted successfully")
+	return nil
+}
+
+// StartTimer starts a timer on a task. A timer already running for the user
+// is stopped and logged first; that work log is returned alongside the timer.
+func (s *Service) StartTimer(taskID, userID uint, note string) (*models.Timer, *models.WorkLog, error) {
+	timer := &models.Timer{
+		UserID:    userID,
+		TaskID:    taskID,
+		StartedAt: time.Now(),
+		Note:      note,
+	}
+	stopped, err := s.Repo.StartTimer(timer)
+	if err != nil {
+		logrus.WithFields(logrus.Fields{
+			"taskID": taskID,
+			"userID": userID,
+			"error":  err,
+		}).Error("Failed to start timer")
+		return nil, nil, err
+	}
+	logrus.WithFields(logrus.Fields{
+		"taskID":          taskID,
+		"userID":          userID,
+		"stoppedPrevious": stopped != nil,
+	}).Info("Timer started successfully")
+	return timer, stopped, nil
+}
+
+func (s *Service) StopTimer(userID uint) (*models.WorkLog, error) {
+	workLog, err := s.Repo.StopTimer(userID, time.Now())
+	if err != nil {
+		logrus.WithFields(logrus.Fields{
+			"userID": userID,
+			"error":  err,
+		}).Warn("Failed to stop timer")
+		return nil, err
+	}
+	logrus.WithFields(logrus.Fields{
+		"workLogID": workLog.ID,
+		"userID":    userID,
+	}).Info("Timer stopped successfully")
+	return workLog, nil
+}
+
+func (s *Service) GetRunningTimer(userID uint) (*models.Timer, error) {
+	return s.Repo.GetTimerByUserID(userID)
+}
+
+// GetTimesheet aggregates logged minutes over [from, to] (whole days) for the
+// projects the user belongs to.
+func (s *Service) GetTimesheet(userID uint, from, to time.Time, projectID, filterUserID uint, groupBy []string) ([]repository.TimesheetRow, error) {
+	if to.Before(from) {
+		return nil, invalidInput("to must not be before from")
+	}
+	end := to.AddDate(0, 0, 1)
+	if end.Sub(from) > maxTimesheetRange {
+		return nil, invalidInput("timesheets cover at most one year")
+	}
+	if len(groupBy) == 0 {
+		groupBy = []string{"user", "project", "day"}
+	}
+	for _, group := range groupBy {
+		if !repository.IsTimesheetGrouping(group) {
+			return nil, invalidInput("cannot group timesheet by %q", group)
+		}
+	}
+	projectIDs, err := s.Repo.GetProjectIDsForUser(userID)
+	if err != nil {
+		logrus.WithFields(logrus.Fields{
+			"userID": userID,
+			"error":  err,
+		}).Error("Failed to load project memberships")
+		return nil, err
+	}
+	rows, err := s.Repo.GetTimesheet(repository.TimesheetQuery{
+		From:       from,
+		To:         end,
+		ProjectIDs: projectIDs,
+		ProjectID:  projectID,
+		UserID:     filterUserID,
+		GroupBy:    groupBy,
+	})
+	if err != nil {
+		return nil, err
+	}
+	logrus.WithFields(logrus.Fields{
+		"userID":   userID,
+		"rowCount": len(rows),
+	}).Info("Timesheet generated successfully")
+	return rows, nil
+}